@@ -0,0 +1,54 @@
+// Command calibrate fits IRT item parameters from historical exam attempts
+// and stores them for adaptive exams. Run it periodically, e.g. nightly.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"go_version/internal/api"
+	"go_version/internal/irt"
+)
+
+func main() {
+	model := flag.String("model", irt.MODEL_2PL, "IRT model to fit: 2PL or 3PL")
+	category := flag.String("category", "", "only calibrate questions of this category")
+	min_responses := flag.Int("min-responses", api.DEFAULT_CALIBRATION_MIN_RESPONSES, "minimum answered responses for a question to be calibrated")
+	flag.Parse()
+
+	if *model != irt.MODEL_2PL && *model != irt.MODEL_3PL {
+		log.Fatal("model must be 2PL or 3PL")
+	}
+
+	app_requirements, err := api.LoadRequirements()
+	if err != nil {
+		log.Fatal("error while loading app requirements: " + err.Error())
+	}
+
+	app_config := api.AppConfig{
+		REQUIREMENTS: app_requirements,
+	}
+	err = app_config.LoadConfig()
+	if err != nil {
+		log.Fatal("error while loading app configs: " + err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	summary, err := app_config.CalibrateItemParameters(ctx, api.ItemCalibrationOptions{
+		Model:        *model,
+		Category:     *category,
+		MinResponses: *min_responses,
+	})
+	if err != nil {
+		log.Fatal("error while calibrating item parameters: " + err.Error())
+	}
+
+	log.Printf(
+		"calibrated %d questions from %d attempts (%d skipped, %d iterations, converged: %t)",
+		summary.Calibrated, summary.Attempts, summary.Skipped, summary.Iterations, summary.Converged,
+	)
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
+	_ "time/tzdata" // streaks are counted in the user's timezone, don't depend on the host's zoneinfo
 
 	"go_version/internal/api"
+	"go_version/internal/models"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -50,8 +53,263 @@ func main() {
 		r.Get("/me", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetUserProfile)))
 		r.Put("/update-profile", app_config.Handle(app_config.MiddlewareAuthorize(app_config.UpdateUserProfile)))
 		r.Put("/change-password", app_config.Handle(app_config.MiddlewareAuthorize(app_config.ChangePassword)))
+		r.Get("/timezone", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetTimezone)))
+		r.Put("/timezone", app_config.Handle(app_config.MiddlewareAuthorize(app_config.UpdateTimezone)))
+		r.Post("/stream-ticket", app_config.Handle(app_config.MiddlewareAuthorize(app_config.CreateStreamTicket)))
 	})
 
+	admin := func(handler api.HandlerFunc) http.HandlerFunc {
+		return app_config.Handle(app_config.MiddlewareAuthorize(app_config.MiddlewareRequireRole(handler, models.ROLE_ADMIN)))
+	}
+	recruiter := func(handler api.HandlerFunc) http.HandlerFunc {
+		return app_config.Handle(app_config.MiddlewareAuthorize(app_config.MiddlewareRequireRole(handler, models.ROLE_COMPANY, models.ROLE_ADMIN)))
+	}
+
+	router.Route("/api/questions", func(r chi.Router) {
+		r.Get("/bookmarks", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetBookmarks)))
+		r.Get("/notes", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetQuestionNotes)))
+		r.Get("/flags/mine", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetMyFlags)))
+		r.Put("/{id}/bookmark", app_config.Handle(app_config.MiddlewareAuthorize(app_config.BookmarkQuestion)))
+		r.Delete("/{id}/bookmark", app_config.Handle(app_config.MiddlewareAuthorize(app_config.RemoveBookmark)))
+		r.Get("/{id}/note", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetQuestionNote)))
+		r.Put("/{id}/note", app_config.Handle(app_config.MiddlewareAuthorize(app_config.SaveQuestionNote)))
+		r.Delete("/{id}/note", app_config.Handle(app_config.MiddlewareAuthorize(app_config.DeleteQuestionNote)))
+		r.Post("/{id}/flags", app_config.Handle(app_config.MiddlewareAuthorize(app_config.FlagQuestion)))
+	})
+
+	router.Route("/api/questions/admin", func(r chi.Router) {
+		r.Get("/all", admin(app_config.GetAllQuestions))
+		r.Post("/create", admin(app_config.CreateQuestion))
+		r.Get("/stats/items", admin(app_config.GetItemAnalysis))
+		r.Get("/flags", admin(app_config.GetFlagQueue))
+		r.Post("/flags/{id}/resolve", admin(app_config.ResolveQuestionFlag))
+		r.Post("/flags/{id}/reject", admin(app_config.RejectQuestionFlag))
+		r.Get("/{id}", admin(app_config.GetQuestionForAdmin))
+		r.Put("/{id}", admin(app_config.UpdateQuestion))
+		r.Delete("/{id}", admin(app_config.DeleteQuestion))
+		r.Get("/{id}/revisions", admin(app_config.GetQuestionRevisions))
+		r.Get("/{id}/revisions/diff", admin(app_config.DiffQuestionRevisions))
+		r.Get("/{id}/revisions/{revision}", admin(app_config.GetQuestionRevision))
+		r.Post("/{id}/revisions/{revision}/rollback", admin(app_config.RollbackQuestion))
+		r.Post("/{id}/rescore", admin(app_config.RescoreQuestionAttempts))
+	})
+
+	router.Route("/api/practice", func(r chi.Router) {
+		r.Get("/questions", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetPracticeQuestions)))
+		r.Get("/companies", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetPracticeCompanies)))
+		r.Get("/tags", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetPracticeTags)))
+		r.Post("/check-answer", app_config.Handle(app_config.MiddlewareAuthorize(app_config.CheckPracticeAnswer)))
+		r.Get("/review/due", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetDueReviews)))
+		r.Get("/review/stats", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetReviewStats)))
+	})
+
+	router.Route("/api/challenges", func(r chi.Router) {
+		r.Get("/today", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetTodayChallenge)))
+		r.Post("/submit", app_config.Handle(app_config.MiddlewareAuthorize(app_config.SubmitChallengeAnswer)))
+		r.Get("/streak", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetUserStreak)))
+		r.Get("/history", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetChallengeHistory)))
+	})
+
+	router.Route("/api/achievements", func(r chi.Router) {
+		r.Get("/", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetAchievements)))
+		r.Get("/progress", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetAchievementProgress)))
+
+		// Admin
+		r.Get("/definitions", admin(app_config.GetAchievementDefinitions))
+		r.Post("/definitions", admin(app_config.CreateAchievementDefinition))
+		r.Put("/definitions/{type}", admin(app_config.UpdateAchievementDefinition))
+		r.Delete("/definitions/{type}", admin(app_config.DeleteAchievementDefinition))
+	})
+
+	router.Route("/api/exams", func(r chi.Router) {
+		r.Post("/sessions", app_config.Handle(app_config.MiddlewareAuthorize(app_config.StartExamSession)))
+		r.Post("/adaptive", app_config.Handle(app_config.MiddlewareAuthorize(app_config.StartAdaptiveExam)))
+		r.Post("/adaptive/{id}/answer", app_config.Handle(app_config.MiddlewareAuthorize(app_config.AnswerAdaptiveQuestion)))
+		r.Get("/sessions/active", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetActiveExamSession)))
+		r.Get("/sessions/{id}", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetExamSession)))
+		r.Put("/sessions/{id}/answers", app_config.Handle(app_config.MiddlewareAuthorize(app_config.AnswerExamQuestion)))
+		r.Post("/sessions/{id}/submit", app_config.Handle(app_config.MiddlewareAuthorize(app_config.SubmitExamSession)))
+		r.Get("/sessions/{id}/proctoring", app_config.Handle(app_config.MiddlewareStreamTicket(app_config.ConnectExamProctoring)))
+		r.Get("/blueprints", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetExamBlueprints)))
+
+		// Sponsoring companies and admins
+		r.Get("/attempts/sponsored", recruiter(app_config.GetSponsoredExamAttempts))
+		r.Get("/attempts/{id}/review", recruiter(app_config.GetExamAttemptReview))
+
+		// Admin
+		r.Post("/blueprints", admin(app_config.CreateExamBlueprint))
+		r.Put("/blueprints/{id}", admin(app_config.UpdateExamBlueprint))
+		r.Delete("/blueprints/{id}", admin(app_config.DeleteExamBlueprint))
+		r.Get("/blueprints/{id}/preview", admin(app_config.PreviewExamBlueprint))
+		r.Get("/admin/sessions/{id}/paper", admin(app_config.ReproduceExamPaper))
+	})
+
+	router.Route("/api/leaderboards", func(r chi.Router) {
+		r.Get("/", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetLeaderboard)))
+		r.Get("/me", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetMyLeaderboardRank)))
+	})
+
+	router.Route("/api/ratings", func(r chi.Router) {
+		r.Get("/me", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetMyRatings)))
+		r.Get("/me/history", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetMyRatingHistory)))
+
+		// Companies and admins
+		r.Get("/students", recruiter(app_config.GetRatedStudents))
+		r.Get("/students/{id}", recruiter(app_config.GetStudentRatings))
+	})
+
+	company := func(handler api.HandlerFunc) http.HandlerFunc {
+		return app_config.Handle(app_config.MiddlewareAuthorize(app_config.MiddlewareRequireRole(handler, models.ROLE_COMPANY)))
+	}
+
+	router.Route("/api/jobs", func(r chi.Router) {
+		r.Get("/", app_config.Handle(app_config.SearchJobPostings))
+		r.Get("/{id}", app_config.Handle(app_config.GetJobPosting))
+
+		// Owning company
+		r.Get("/company", company(app_config.GetMyJobPostings))
+		r.Post("/company", company(app_config.CreateJobPosting))
+		r.Get("/company/{id}", company(app_config.GetMyJobPosting))
+		r.Put("/company/{id}", company(app_config.UpdateJobPosting))
+		r.Put("/company/{id}/status", company(app_config.UpdateJobPostingStatus))
+		r.Delete("/company/{id}", company(app_config.DeleteJobPosting))
+		r.Get("/company/{id}/stages", company(app_config.GetPipelineStages))
+		r.Put("/company/{id}/stages", company(app_config.UpdatePipelineStages))
+		r.Get("/company/{id}/applications", company(app_config.GetPostingApplications))
+	})
+
+	student := func(handler api.HandlerFunc) http.HandlerFunc {
+		return app_config.Handle(app_config.MiddlewareAuthorize(app_config.MiddlewareRequireRole(handler, models.ROLE_STUDENT)))
+	}
+
+	router.Route("/api/applications", func(r chi.Router) {
+		r.Post("/", student(app_config.ApplyToJobPosting))
+		r.Get("/me", student(app_config.GetMyApplications))
+		r.Get("/me/{id}", student(app_config.GetMyApplication))
+		r.Post("/me/{id}/withdraw", student(app_config.WithdrawApplication))
+
+		// Company reviewing its applicants
+		r.Get("/company/{id}", company(app_config.GetCompanyApplication))
+		r.Put("/company/{id}/stage", company(app_config.MoveApplicationStage))
+		r.Post("/company/{id}/notes", company(app_config.AddApplicationNote))
+		r.Put("/company/{id}/rating", company(app_config.RateApplication))
+	})
+
+	router.Route("/api/interviews", func(r chi.Router) {
+		r.Post("/slots", company(app_config.CreateInterviewSlots))
+		r.Get("/slots", company(app_config.GetInterviewSlots))
+		r.Delete("/slots/{id}", company(app_config.DeleteInterviewSlot))
+		r.Get("/company", company(app_config.GetCompanyInterviews))
+		r.Post("/company/{id}/reschedule", company(app_config.RescheduleCompanyInterview))
+		r.Post("/company/{id}/cancel", company(app_config.CancelCompanyInterview))
+
+		// Student booking
+		r.Get("/me", student(app_config.GetMyInterviews))
+		r.Post("/me", student(app_config.BookInterview))
+		r.Get("/me/applications/{id}/slots", student(app_config.GetApplicationInterviewSlots))
+		r.Post("/me/{id}/reschedule", student(app_config.RescheduleMyInterview))
+		r.Post("/me/{id}/cancel", student(app_config.CancelMyInterview))
+	})
+
+	router.Route("/api/matches", func(r chi.Router) {
+		r.Get("/jobs", student(app_config.GetJobRecommendations))
+		r.Get("/jobs/{id}", student(app_config.GetJobMatch))
+		r.Get("/postings/{id}/students", company(app_config.GetPostingCandidates))
+	})
+
+	router.Route("/api/talent", func(r chi.Router) {
+		r.Get("/search", company(app_config.SearchTalent))
+
+		// Admins verifying the companies that may search
+		r.Get("/admin/companies", admin(app_config.GetCompanies))
+		r.Put("/admin/companies/{id}/verification", admin(app_config.SetCompanyVerification))
+	})
+
+	router.Route("/api/privacy", func(r chi.Router) {
+		r.Get("/", student(app_config.GetPrivacySettings))
+		r.Put("/", student(app_config.UpdatePrivacySettings))
+	})
+
+	router.Route("/api/profiles", func(r chi.Router) {
+		r.Get("/me/handle", student(app_config.GetProfileHandle))
+		r.Put("/me/handle", student(app_config.UpdateProfileHandle))
+		r.Get("/{handle}", app_config.Handle(app_config.GetPublicStudentProfile))
+	})
+
+	router.Route("/api/users", func(r chi.Router) {
+		r.Get("/{id}", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetUserProfileByID)))
+	})
+
+	router.Route("/api/contact-requests", func(r chi.Router) {
+		r.Post("/", company(app_config.RequestContact))
+		r.Get("/company", company(app_config.GetCompanyContactRequests))
+
+		// Student answering requests
+		r.Get("/me", student(app_config.GetMyContactRequests))
+		r.Put("/me/{id}", student(app_config.RespondToContactRequest))
+		r.Post("/me/{id}/revoke", student(app_config.RevokeContactRequest))
+	})
+
+	router.Route("/api/conversations", func(r chi.Router) {
+		r.Post("/", company(app_config.StartConversation))
+		r.Get("/", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetConversations)))
+		r.Get("/unread", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetUnreadMessageCount)))
+		r.Post("/attachments", app_config.Handle(app_config.MiddlewareAuthorize(app_config.UploadMessageAttachment)))
+		r.Get("/{id}/messages", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetConversationMessages)))
+		r.Post("/{id}/messages", app_config.Handle(app_config.MiddlewareAuthorize(app_config.SendMessage)))
+		r.Post("/{id}/read", app_config.Handle(app_config.MiddlewareAuthorize(app_config.MarkConversationRead)))
+	})
+
+	router.Route("/api/blocks", func(r chi.Router) {
+		r.Get("/", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetBlockedUsers)))
+		r.Post("/", app_config.Handle(app_config.MiddlewareAuthorize(app_config.BlockUser)))
+		r.Delete("/{id}", app_config.Handle(app_config.MiddlewareAuthorize(app_config.UnblockUser)))
+	})
+
+	router.Route("/api/notifications", func(r chi.Router) {
+		r.Get("/stream", app_config.Handle(app_config.MiddlewareStreamTicket(app_config.StreamNotifications)))
+		r.Get("/", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetNotifications)))
+		r.Post("/read-all", app_config.Handle(app_config.MiddlewareAuthorize(app_config.MarkAllNotificationsRead)))
+		r.Post("/{id}/read", app_config.Handle(app_config.MiddlewareAuthorize(app_config.MarkNotificationRead)))
+		r.Get("/preferences", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetNotificationPreferences)))
+		r.Put("/preferences", app_config.Handle(app_config.MiddlewareAuthorize(app_config.UpdateNotificationPreferences)))
+	})
+
+	router.Route("/api/certificates", func(r chi.Router) {
+		r.Get("/me", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetMyCertificates)))
+		r.Post("/", app_config.Handle(app_config.MiddlewareAuthorize(app_config.IssueCertificate)))
+
+		// Public
+		r.Get("/public-key", app_config.Handle(app_config.GetCertificatePublicKey))
+		r.Get("/verify/{code}", app_config.Handle(app_config.VerifyCertificate))
+		r.Get("/{code}/pdf", app_config.Handle(app_config.GetCertificatePDF))
+
+		// Admin
+		r.Post("/{code}/revoke", admin(app_config.RevokeCertificate))
+	})
+
+	router.Route("/api/analytics", func(r chi.Router) {
+		r.Get("/student", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetStudentAnalytics)))
+		r.Get("/student/trends", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetScoreTrends)))
+		r.Get("/student/topics", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetTopicAccuracy)))
+		r.Get("/student/timing", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetTimingDistribution)))
+		r.Get("/student/peers", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetPeerComparison)))
+		r.Get("/leaderboard", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetLeaderboardCompat)))
+		r.Get("/leaderboard/position", app_config.Handle(app_config.MiddlewareAuthorize(app_config.GetLeaderboardPositionCompat)))
+	})
+
+	router.Route("/api/events/admin", func(r chi.Router) {
+		r.Get("/dead-letters", admin(app_config.GetDeadLetters))
+		r.Post("/dead-letters/{id}/retry", admin(app_config.RetryDeadLetter))
+	})
+
+	// Background workers
+	go app_config.StartExamSweeper(context.Background(), 15*time.Second)
+	go app_config.StartOutboxWorkers(context.Background(), 4, 5*time.Second)
+	go app_config.StartLeaderboardRefresher(context.Background(), time.Minute)
+	go app_config.StartJobPostingCloser(context.Background(), 5*time.Minute)
+	go app_config.StartMatchRefresher(context.Background(), time.Minute)
+
 	srv := &http.Server{
 		Addr:              ":" + app_requirements.Server.Port,
 		Handler:           router,
@@ -0,0 +1,195 @@
+// Package achievements turns badge definitions into Mongo aggregations over a
+// user's recorded domain events. A definition selects the events of one type
+// matching all of its conditions, counts them (or sums or maxes a payload
+// field) and is unlocked once that value reaches the threshold.
+package achievements
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"go_version/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// EventFields lists the payload fields each event type carries, rules may
+// only refer to these.
+var EventFields = map[string][]string{
+	models.EVENT_EXAM_COMPLETED: {
+		"category", "difficulty", "mode", "score", "correctCount", "totalQuestions", "timeSpent", "scoreImprovement",
+	},
+	models.EVENT_PRACTICE_ANSWERED: {
+		"category", "difficulty", "isCorrect", "timeSpent", "sessionStart",
+	},
+	models.EVENT_CHALLENGE_COMPLETED: {
+		"category", "difficulty", "isCorrect", "streak", "totalCompleted",
+	},
+}
+
+// Types double as $facet field names so they are kept to snake case
+var type_pattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// Validate checks that a definition can be evaluated.
+func Validate(def models.AchievementDefinition) error {
+	if !type_pattern.MatchString(def.Type) {
+		return fmt.Errorf("type must be 2-50 lowercase letters, digits or underscores")
+	}
+	fields, ok := EventFields[def.Event]
+	if !ok {
+		return fmt.Errorf("unknown event %q", def.Event)
+	}
+
+	switch def.Aggregate {
+	case models.ACHIEVEMENT_AGGREGATE_COUNT:
+		if def.Field != "" {
+			return fmt.Errorf("count doesn't take a field")
+		}
+	case models.ACHIEVEMENT_AGGREGATE_SUM, models.ACHIEVEMENT_AGGREGATE_MAX:
+		if !slices.Contains(fields, def.Field) {
+			return fmt.Errorf("field %q is not part of %s events", def.Field, def.Event)
+		}
+	default:
+		return fmt.Errorf("unknown aggregate %q", def.Aggregate)
+	}
+
+	if def.Threshold <= 0 {
+		return fmt.Errorf("threshold must be greater than 0")
+	}
+
+	for _, condition := range def.Conditions {
+		if !slices.Contains(fields, condition.Field) {
+			return fmt.Errorf("condition field %q is not part of %s events", condition.Field, def.Event)
+		}
+		if _, ok := operators[condition.Op]; !ok {
+			return fmt.Errorf("unknown condition operator %q", condition.Op)
+		}
+		switch condition.Value.(type) {
+		case string, bool, float64, int32, int64:
+		default:
+			return fmt.Errorf("condition on %q needs a string, number or boolean value", condition.Field)
+		}
+	}
+
+	return nil
+}
+
+var operators = map[string]string{
+	models.CONDITION_OP_EQ:  "$eq",
+	models.CONDITION_OP_NE:  "$ne",
+	models.CONDITION_OP_GT:  "$gt",
+	models.CONDITION_OP_GTE: "$gte",
+	models.CONDITION_OP_LT:  "$lt",
+	models.CONDITION_OP_LTE: "$lte",
+}
+
+// Match selects the events a definition applies to.
+func Match(def models.AchievementDefinition) bson.M {
+	match := bson.M{"type": def.Event}
+	if len(def.Conditions) == 0 {
+		return match
+	}
+
+	conditions := make(bson.A, 0, len(def.Conditions))
+	for _, condition := range def.Conditions {
+		conditions = append(conditions, bson.M{
+			"payload." + condition.Field: bson.M{operators[condition.Op]: condition.Value},
+		})
+	}
+	match["$and"] = conditions
+	return match
+}
+
+// Pipeline computes the definition's progress value as {value: <number>}, it
+// yields no document when no event matches.
+func Pipeline(def models.AchievementDefinition) mongo.Pipeline {
+	var accumulator bson.M
+	switch def.Aggregate {
+	case models.ACHIEVEMENT_AGGREGATE_SUM:
+		accumulator = bson.M{"$sum": "$payload." + def.Field}
+	case models.ACHIEVEMENT_AGGREGATE_MAX:
+		accumulator = bson.M{"$max": "$payload." + def.Field}
+	default:
+		accumulator = bson.M{"$sum": 1}
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: Match(def)}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "value": accumulator}}},
+	}
+}
+
+// Facet evaluates several definitions in one aggregation, each under its type.
+func Facet(defs []models.AchievementDefinition) bson.D {
+	facets := bson.M{}
+	for _, def := range defs {
+		facets[def.Type] = Pipeline(def)
+	}
+	return bson.D{{Key: "$facet", Value: facets}}
+}
+
+// Percent is how far value is towards the threshold, capped at 100.
+func Percent(value, threshold float64) float64 {
+	if threshold <= 0 || value >= threshold {
+		return 100
+	}
+	if value <= 0 {
+		return 0
+	}
+	return float64(int(value/threshold*1000)) / 10
+}
+
+// Defaults are the badges of the Node backend expressed as rules. They are
+// only seeded, admins can edit or add definitions afterwards.
+func Defaults() []models.AchievementDefinition {
+	count := func(order int32, badge_type, title, description, icon, event string, threshold float64, conditions ...models.AchievementCondition) models.AchievementDefinition {
+		if conditions == nil {
+			conditions = []models.AchievementCondition{}
+		}
+		return models.AchievementDefinition{
+			Type:        badge_type,
+			Title:       title,
+			Description: description,
+			Icon:        icon,
+			Event:       event,
+			Conditions:  conditions,
+			Aggregate:   models.ACHIEVEMENT_AGGREGATE_COUNT,
+			Threshold:   threshold,
+			IsActive:    true,
+			SortOrder:   order,
+		}
+	}
+	highest := func(def models.AchievementDefinition, field string) models.AchievementDefinition {
+		def.Aggregate = models.ACHIEVEMENT_AGGREGATE_MAX
+		def.Field = field
+		return def
+	}
+	where := func(field, op string, value any) models.AchievementCondition {
+		return models.AchievementCondition{Field: field, Op: op, Value: value}
+	}
+
+	return []models.AchievementDefinition{
+		count(1, "first_exam", "First Steps", "Complete your first exam", "🎯", models.EVENT_EXAM_COMPLETED, 1),
+		highest(count(2, "exam_streak_3", "3-Day Streak", "Complete daily challenges for 3 consecutive days", "🔥", models.EVENT_CHALLENGE_COMPLETED, 3), "streak"),
+		highest(count(3, "exam_streak_7", "Week Warrior", "Complete daily challenges for 7 consecutive days", "💪", models.EVENT_CHALLENGE_COMPLETED, 7), "streak"),
+		highest(count(4, "exam_streak_30", "Monthly Master", "Complete daily challenges for 30 consecutive days", "👑", models.EVENT_CHALLENGE_COMPLETED, 30), "streak"),
+		count(5, "perfect_score", "Perfect Score", "Score 100% on any exam", "⭐", models.EVENT_EXAM_COMPLETED, 1,
+			where("score", models.CONDITION_OP_GTE, float64(100))),
+		count(6, "speed_master", "Speed Master", "Complete an exam in under 5 minutes", "⚡", models.EVENT_EXAM_COMPLETED, 1,
+			where("timeSpent", models.CONDITION_OP_LT, float64(300))),
+		highest(count(7, "improvement_champion", "Improvement Champion", "Score 20 points above your previous average", "📈", models.EVENT_EXAM_COMPLETED, 20), "scoreImprovement"),
+		count(8, "category_master_frontend", "Frontend Master", "Complete 20 exams in Frontend category", "🎨", models.EVENT_EXAM_COMPLETED, 20,
+			where("category", models.CONDITION_OP_EQ, models.CATEGORY_FRONTEND)),
+		count(9, "category_master_backend", "Backend Master", "Complete 20 exams in Backend category", "⚙️", models.EVENT_EXAM_COMPLETED, 20,
+			where("category", models.CONDITION_OP_EQ, models.CATEGORY_BACKEND)),
+		count(10, "exam_count_10", "Getting Started", "Complete 10 exams", "🎓", models.EVENT_EXAM_COMPLETED, 10),
+		count(11, "exam_count_50", "Dedicated Learner", "Complete 50 exams", "📚", models.EVENT_EXAM_COMPLETED, 50),
+		count(12, "exam_count_100", "Century Club", "Complete 100 exams", "💯", models.EVENT_EXAM_COMPLETED, 100),
+		count(13, "hard_questions_10", "Challenge Accepted", "Complete 10 hard difficulty exams", "🏆", models.EVENT_EXAM_COMPLETED, 10,
+			where("difficulty", models.CONDITION_OP_EQ, models.DIFFICULTY_HARD)),
+		count(14, "practice_warrior", "Practice Warrior", "Complete 25 practice mode sessions", "🥋", models.EVENT_PRACTICE_ANSWERED, 25,
+			where("sessionStart", models.CONDITION_OP_EQ, true)),
+	}
+}
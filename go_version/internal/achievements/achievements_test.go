@@ -0,0 +1,186 @@
+package achievements
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go_version/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func exams(threshold float64) models.AchievementDefinition {
+	return models.AchievementDefinition{
+		Type:      "exam_count",
+		Event:     models.EVENT_EXAM_COMPLETED,
+		Aggregate: models.ACHIEVEMENT_AGGREGATE_COUNT,
+		Threshold: threshold,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		def  func(def *models.AchievementDefinition)
+		err  string
+	}{
+		{"count", func(def *models.AchievementDefinition) {}, ""},
+		{"sum of a payload field", func(def *models.AchievementDefinition) {
+			def.Aggregate, def.Field = models.ACHIEVEMENT_AGGREGATE_SUM, "correctCount"
+		}, ""},
+		{"conditions of every value type", func(def *models.AchievementDefinition) {
+			def.Conditions = []models.AchievementCondition{
+				{Field: "category", Op: models.CONDITION_OP_EQ, Value: models.CATEGORY_BACKEND},
+				{Field: "score", Op: models.CONDITION_OP_GTE, Value: float64(90)},
+				{Field: "timeSpent", Op: models.CONDITION_OP_LT, Value: int32(300)},
+				{Field: "correctCount", Op: models.CONDITION_OP_GT, Value: int64(5)},
+			}
+		}, ""},
+		{"type with capitals", func(def *models.AchievementDefinition) { def.Type = "ExamCount" }, "type must be"},
+		{"type too short", func(def *models.AchievementDefinition) { def.Type = "e" }, "type must be"},
+		{"unknown event", func(def *models.AchievementDefinition) { def.Event = "exam.started" }, "unknown event"},
+		{"count with a field", func(def *models.AchievementDefinition) { def.Field = "score" }, "count doesn't take a field"},
+		{"max of a field of another event", func(def *models.AchievementDefinition) {
+			def.Aggregate, def.Field = models.ACHIEVEMENT_AGGREGATE_MAX, "streak"
+		}, `field "streak" is not part of`},
+		{"unknown aggregate", func(def *models.AchievementDefinition) { def.Aggregate = "avg" }, "unknown aggregate"},
+		{"zero threshold", func(def *models.AchievementDefinition) { def.Threshold = 0 }, "threshold must be greater than 0"},
+		{"condition on an unknown field", func(def *models.AchievementDefinition) {
+			def.Conditions = []models.AchievementCondition{{Field: "password", Op: models.CONDITION_OP_EQ, Value: "x"}}
+		}, `condition field "password"`},
+		{"unknown operator", func(def *models.AchievementDefinition) {
+			def.Conditions = []models.AchievementCondition{{Field: "score", Op: "regex", Value: "x"}}
+		}, "unknown condition operator"},
+		{"operator as value", func(def *models.AchievementDefinition) {
+			def.Conditions = []models.AchievementCondition{{Field: "score", Op: models.CONDITION_OP_EQ, Value: bson.M{"$gt": 0}}}
+		}, "needs a string, number or boolean value"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			def := exams(10)
+			test.def(&def)
+			err := Validate(def)
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("Validate() = %v, want no error", err)
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Errorf("Validate() = %v, want an error containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestDefaultsAreValid(t *testing.T) {
+	types := map[string]bool{}
+	for _, def := range Defaults() {
+		if err := Validate(def); err != nil {
+			t.Errorf("default %s: %v", def.Type, err)
+		}
+		if types[def.Type] {
+			t.Errorf("default %s is defined twice", def.Type)
+		}
+		types[def.Type] = true
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	tests := []struct {
+		name string
+		def  models.AchievementDefinition
+		want mongo.Pipeline
+	}{
+		{
+			name: "count without conditions",
+			def:  exams(10),
+			want: mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"type": models.EVENT_EXAM_COMPLETED}}},
+				{{Key: "$group", Value: bson.M{"_id": nil, "value": bson.M{"$sum": 1}}}},
+			},
+		},
+		{
+			name: "max with conditions",
+			def: models.AchievementDefinition{
+				Type:      "streak",
+				Event:     models.EVENT_CHALLENGE_COMPLETED,
+				Aggregate: models.ACHIEVEMENT_AGGREGATE_MAX,
+				Field:     "streak",
+				Threshold: 7,
+				Conditions: []models.AchievementCondition{
+					{Field: "isCorrect", Op: models.CONDITION_OP_EQ, Value: true},
+					{Field: "difficulty", Op: models.CONDITION_OP_NE, Value: models.DIFFICULTY_EASY},
+				},
+			},
+			want: mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{
+					"type": models.EVENT_CHALLENGE_COMPLETED,
+					"$and": bson.A{
+						bson.M{"payload.isCorrect": bson.M{"$eq": true}},
+						bson.M{"payload.difficulty": bson.M{"$ne": models.DIFFICULTY_EASY}},
+					},
+				}}},
+				{{Key: "$group", Value: bson.M{"_id": nil, "value": bson.M{"$max": "$payload.streak"}}}},
+			},
+		},
+		{
+			name: "sum",
+			def: models.AchievementDefinition{
+				Type:      "correct_answers",
+				Event:     models.EVENT_EXAM_COMPLETED,
+				Aggregate: models.ACHIEVEMENT_AGGREGATE_SUM,
+				Field:     "correctCount",
+				Threshold: 100,
+			},
+			want: mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"type": models.EVENT_EXAM_COMPLETED}}},
+				{{Key: "$group", Value: bson.M{"_id": nil, "value": bson.M{"$sum": "$payload.correctCount"}}}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Pipeline(test.def); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Pipeline() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFacet(t *testing.T) {
+	first, second := exams(1), exams(10)
+	second.Type = "exam_count_10"
+
+	facet := Facet([]models.AchievementDefinition{first, second})
+	facets, ok := facet[0].Value.(bson.M)
+	if facet[0].Key != "$facet" || !ok {
+		t.Fatalf("Facet() = %v, want a $facet stage", facet)
+	}
+	for _, def := range []models.AchievementDefinition{first, second} {
+		if !reflect.DeepEqual(facets[def.Type], Pipeline(def)) {
+			t.Errorf("facet %s = %v, want its pipeline", def.Type, facets[def.Type])
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		value     float64
+		threshold float64
+		want      float64
+	}{
+		{0, 10, 0},
+		{-5, 10, 0},
+		{1, 3, 33.3},
+		{2, 3, 66.6},
+		{5, 10, 50},
+		{10, 10, 100},
+		{25, 10, 100},
+		{5, 0, 100},
+	}
+	for _, test := range tests {
+		if got := Percent(test.value, test.threshold); got != test.want {
+			t.Errorf("Percent(%v, %v) = %v, want %v", test.value, test.threshold, got, test.want)
+		}
+	}
+}
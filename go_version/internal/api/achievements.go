@@ -0,0 +1,854 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go_version/internal/achievements"
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type AchievementDefinitionRequestBody struct {
+	Type        string                        `json:"type" validate:"required,min=2,max=50"`
+	Title       string                        `json:"title" validate:"required,min=2,max=100"`
+	Description string                        `json:"description" validate:"required,max=300"`
+	Icon        string                        `json:"icon" validate:"omitempty,max=20"`
+	Event       string                        `json:"event" validate:"required,oneof=exam_completed practice_answered challenge_completed"`
+	Conditions  []models.AchievementCondition `json:"conditions" validate:"max=10,dive"`
+	Aggregate   string                        `json:"aggregate" validate:"required,oneof=count sum max"`
+	Field       string                        `json:"field" validate:"omitempty,max=50"`
+	Threshold   float64                       `json:"threshold" validate:"required,gt=0"`
+	SortOrder   int32                         `json:"sortOrder"`
+	IsActive    *bool                         `json:"isActive"`
+}
+
+type AchievementView struct {
+	Type        string         `json:"type"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Icon        string         `json:"icon"`
+	Unlocked    bool           `json:"unlocked"`
+	UnlockedAt  *bson.DateTime `json:"unlockedAt"`
+}
+
+type AchievementProgress struct {
+	Type      string  `json:"type"`
+	Title     string  `json:"title"`
+	Event     string  `json:"event"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Percent   float64 `json:"percent"`
+	Unlocked  bool    `json:"unlocked"`
+}
+
+// GetAchievements lists every active badge with whether the user unlocked it.
+// Badges unlocked from a since deactivated definition are still listed.
+func (cfg *AppConfig) GetAchievements(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	if err := cfg.backfillAchievementEvents(ctx, user_id); err != nil {
+		return err
+	}
+	if _, err := cfg.evaluateAchievements(ctx, user_id, ""); err != nil {
+		return err
+	}
+
+	definitions, err := cfg.findAchievementDefinitions(ctx, bson.M{"isActive": true})
+	if err != nil {
+		return err
+	}
+	unlocked, err := cfg.findUserAchievements(ctx, user_id)
+	if err != nil {
+		return err
+	}
+
+	views := make([]AchievementView, 0, len(definitions))
+	for _, definition := range definitions {
+		view := AchievementView{
+			Type:        definition.Type,
+			Title:       definition.Title,
+			Description: definition.Description,
+			Icon:        definition.Icon,
+		}
+		if achievement, ok := unlocked[definition.Type]; ok {
+			view.Unlocked = true
+			view.UnlockedAt = &achievement.UnlockedAt
+			delete(unlocked, definition.Type)
+		}
+		views = append(views, view)
+	}
+	for _, achievement := range unlocked {
+		views = append(views, AchievementView{
+			Type:        achievement.Type,
+			Title:       achievement.Title,
+			Description: achievement.Description,
+			Icon:        achievement.Icon,
+			Unlocked:    true,
+			UnlockedAt:  &achievement.UnlockedAt,
+		})
+	}
+
+	unlocked_count := 0
+	for _, view := range views {
+		if view.Unlocked {
+			unlocked_count++
+		}
+	}
+
+	response_payload := map[string]any{
+		"achievements":  views,
+		"unlockedCount": unlocked_count,
+		"totalCount":    len(views),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Achievements provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetAchievementProgress shows how far the user is towards every active badge.
+func (cfg *AppConfig) GetAchievementProgress(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	if err := cfg.backfillAchievementEvents(ctx, user_id); err != nil {
+		return err
+	}
+
+	definitions, err := cfg.findAchievementDefinitions(ctx, bson.M{"isActive": true})
+	if err != nil {
+		return err
+	}
+	values, err := cfg.achievementValues(ctx, user_id, definitions)
+	if err != nil {
+		return err
+	}
+	unlocked, err := cfg.findUserAchievements(ctx, user_id)
+	if err != nil {
+		return err
+	}
+
+	progress := make([]AchievementProgress, 0, len(definitions))
+	for _, definition := range definitions {
+		_, is_unlocked := unlocked[definition.Type]
+		value := values[definition.Type]
+		percent := achievements.Percent(value, definition.Threshold)
+		if is_unlocked {
+			percent = 100
+		}
+		progress = append(progress, AchievementProgress{
+			Type:      definition.Type,
+			Title:     definition.Title,
+			Event:     definition.Event,
+			Value:     value,
+			Threshold: definition.Threshold,
+			Percent:   percent,
+			Unlocked:  is_unlocked,
+		})
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Achievement progress provided successfully",
+		map[string]any{"progress": progress},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetAchievementDefinitions(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	definitions, err := cfg.findAchievementDefinitions(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Achievement definitions provided successfully",
+		map[string]any{"definitions": definitions},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) CreateAchievementDefinition(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	req_body, err := parseAchievementDefinitionRequestBody(r)
+	if err != nil {
+		return err
+	}
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	definition := models.AchievementDefinition{
+		ID:        bson.NewObjectID(),
+		Type:      req_body.Type,
+		IsActive:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := req_body.applyTo(&definition); err != nil {
+		return err
+	}
+
+	definitions_coll := cfg.DATABASE.Collection(models.ACHIEVEMENT_DEFINITIONS_COLLECTION)
+	_, err = definitions_coll.InsertOne(ctx, definition)
+	if mongo.IsDuplicateKeyError(err) {
+		return utils.NewConflict("An achievement with this type already exists")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Achievement definition created successfully",
+		map[string]any{"definition": definition},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+// UpdateAchievementDefinition edits a badge rule, the type can't change. Rules
+// are evaluated over the recorded events, so a lowered threshold unlocks the
+// badge on the user's next event or visit, while badges already unlocked stay.
+func (cfg *AppConfig) UpdateAchievementDefinition(w http.ResponseWriter, r *http.Request) error {
+	definition_type := chi.URLParam(r, "type")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	req_body, err := parseAchievementDefinitionRequestBody(r)
+	if err != nil {
+		return err
+	}
+	if req_body.Type != definition_type {
+		return utils.NewBadRequest("The achievement type can't be changed")
+	}
+
+	var definition models.AchievementDefinition
+	definitions_coll := cfg.DATABASE.Collection(models.ACHIEVEMENT_DEFINITIONS_COLLECTION)
+	err = definitions_coll.FindOne(ctx, bson.M{"type": definition_type}).Decode(&definition)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Achievement definition not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if err := req_body.applyTo(&definition); err != nil {
+		return err
+	}
+	definition.UpdatedAt = bson.NewDateTimeFromTime(time.Now())
+
+	if _, err := definitions_coll.ReplaceOne(ctx, bson.M{"_id": definition.ID}, definition); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Achievement definition updated successfully",
+		map[string]any{"definition": definition},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) DeleteAchievementDefinition(w http.ResponseWriter, r *http.Request) error {
+	definition_type := chi.URLParam(r, "type")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	definitions_coll := cfg.DATABASE.Collection(models.ACHIEVEMENT_DEFINITIONS_COLLECTION)
+	result, err := definitions_coll.UpdateOne(ctx,
+		bson.M{"type": definition_type},
+		bson.M{"$set": bson.M{"isActive": false, "updatedAt": bson.NewDateTimeFromTime(time.Now())}},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewNotFound("Achievement definition not found")
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Achievement definition deactivated successfully",
+		nil,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func parseAchievementDefinitionRequestBody(r *http.Request) (AchievementDefinitionRequestBody, error) {
+	req_body := AchievementDefinitionRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return req_body, utils.NewAppError("Error while parsing achievement definition request body", http.StatusBadRequest, err)
+	}
+
+	req_body.Type = strings.ToLower(strings.TrimSpace(req_body.Type))
+	req_body.Title = sanitizeInput(req_body.Title)
+	req_body.Description = sanitizeInput(req_body.Description)
+	req_body.Icon = strings.TrimSpace(req_body.Icon)
+	req_body.Event = strings.ToLower(strings.TrimSpace(req_body.Event))
+	req_body.Aggregate = strings.ToLower(strings.TrimSpace(req_body.Aggregate))
+	req_body.Field = strings.TrimSpace(req_body.Field)
+	for i := range req_body.Conditions {
+		condition := &req_body.Conditions[i]
+		condition.Field = strings.TrimSpace(condition.Field)
+		condition.Op = strings.ToLower(strings.TrimSpace(condition.Op))
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return req_body, utils.NewValidationError(field_errors)
+	}
+
+	return req_body, nil
+}
+
+func (req_body AchievementDefinitionRequestBody) applyTo(definition *models.AchievementDefinition) error {
+	definition.Title = req_body.Title
+	definition.Description = req_body.Description
+	definition.Icon = req_body.Icon
+	definition.Event = req_body.Event
+	definition.Conditions = req_body.Conditions
+	if definition.Conditions == nil {
+		definition.Conditions = []models.AchievementCondition{}
+	}
+	definition.Aggregate = req_body.Aggregate
+	definition.Field = req_body.Field
+	definition.Threshold = req_body.Threshold
+	definition.SortOrder = req_body.SortOrder
+	if req_body.IsActive != nil {
+		definition.IsActive = *req_body.IsActive
+	}
+
+	if err := achievements.Validate(*definition); err != nil {
+		return utils.NewBadRequest(err.Error())
+	}
+	return nil
+}
+
+// seedAchievementDefinitions inserts the default badges that don't exist yet,
+// definitions edited by admins are left alone.
+func (cfg *AppConfig) seedAchievementDefinitions() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	writes := []mongo.WriteModel{}
+	for _, definition := range achievements.Defaults() {
+		definition.CreatedAt = now
+		definition.UpdatedAt = now
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"type": definition.Type}).
+			SetUpdate(bson.M{"$setOnInsert": definition}).
+			SetUpsert(true))
+	}
+
+	definitions_coll := cfg.DATABASE.Collection(models.ACHIEVEMENT_DEFINITIONS_COLLECTION)
+	_, err := definitions_coll.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+// publishAchievementEvent records a domain event and unlocks the badges it
+// completes. Replaying an event is harmless: the event id and the unique
+// (userId, type) achievement index keep both steps idempotent. Returns the
+// achievements unlocked by this call.
+func (cfg *AppConfig) publishAchievementEvent(ctx context.Context, event models.AchievementEvent) ([]models.Achievement, error) {
+	if err := cfg.backfillAchievementEvents(ctx, event.UserID); err != nil {
+		return nil, err
+	}
+
+	if event.ID.IsZero() {
+		event.ID = bson.NewObjectID()
+	}
+	events_coll := cfg.DATABASE.Collection(models.ACHIEVEMENT_EVENTS_COLLECTION)
+	_, err := events_coll.InsertOne(ctx, event)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	return cfg.evaluateAchievements(ctx, event.UserID, event.Type)
+}
+
+// publishAchievementEventOrLog is used where achievements are a side effect of
+// something that already succeeded and must not fail the request.
+func (cfg *AppConfig) publishAchievementEventOrLog(ctx context.Context, event models.AchievementEvent) []models.Achievement {
+	unlocked, err := cfg.publishAchievementEvent(ctx, event)
+	if err != nil {
+		log.Printf("Failed to process %s event %s: %s", event.Type, event.EventID, err.Error())
+		return []models.Achievement{}
+	}
+	return unlocked
+}
+
+// evaluateAchievements unlocks every active badge of the event type (or of
+// any type when empty) the user has reached but not unlocked yet.
+func (cfg *AppConfig) evaluateAchievements(ctx context.Context, user_id bson.ObjectID, event_type string) ([]models.Achievement, error) {
+	unlocked, err := cfg.findUserAchievements(ctx, user_id)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"isActive": true}
+	if event_type != "" {
+		filter["event"] = event_type
+	}
+	definitions, err := cfg.findAchievementDefinitions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	definitions = slices.DeleteFunc(definitions, func(definition models.AchievementDefinition) bool {
+		_, ok := unlocked[definition.Type]
+		return ok
+	})
+
+	values, err := cfg.achievementValues(ctx, user_id, definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	achievements_coll := cfg.DATABASE.Collection(models.ACHIEVEMENTS_COLLECTION)
+	new_achievements := []models.Achievement{}
+	for _, definition := range definitions {
+		if values[definition.Type] < definition.Threshold {
+			continue
+		}
+		achievement := models.Achievement{
+			ID:          bson.NewObjectID(),
+			UserID:      user_id,
+			Type:        definition.Type,
+			Title:       definition.Title,
+			Description: definition.Description,
+			Icon:        definition.Icon,
+			UnlockedAt:  now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		_, err := achievements_coll.InsertOne(ctx, achievement)
+		if mongo.IsDuplicateKeyError(err) {
+			// Unlocked concurrently
+			continue
+		} else if err != nil {
+			return nil, utils.NewInternalServerError(err)
+		}
+		new_achievements = append(new_achievements, achievement)
+	}
+
+	return new_achievements, nil
+}
+
+// achievementValues evaluates the definitions over the user's events in a
+// single aggregation, keyed by definition type.
+func (cfg *AppConfig) achievementValues(ctx context.Context, user_id bson.ObjectID, definitions []models.AchievementDefinition) (map[string]float64, error) {
+	values := make(map[string]float64, len(definitions))
+	if len(definitions) == 0 {
+		return values, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userId": user_id}}},
+		achievements.Facet(definitions),
+	}
+
+	events_coll := cfg.DATABASE.Collection(models.ACHIEVEMENT_EVENTS_COLLECTION)
+	cursor, err := events_coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	results := []map[string][]struct {
+		Value float64 `bson:"value"`
+	}{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	if len(results) > 0 {
+		for definition_type, groups := range results[0] {
+			if len(groups) > 0 {
+				values[definition_type] = groups[0].Value
+			}
+		}
+	}
+	return values, nil
+}
+
+func (cfg *AppConfig) findAchievementDefinitions(ctx context.Context, filter bson.M) ([]models.AchievementDefinition, error) {
+	definitions_coll := cfg.DATABASE.Collection(models.ACHIEVEMENT_DEFINITIONS_COLLECTION)
+	cursor, err := definitions_coll.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "sortOrder", Value: 1}, {Key: "type", Value: 1}}),
+	)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	definitions := []models.AchievementDefinition{}
+	if err := cursor.All(ctx, &definitions); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	return definitions, nil
+}
+
+func (cfg *AppConfig) findUserAchievements(ctx context.Context, user_id bson.ObjectID) (map[string]models.Achievement, error) {
+	achievements_coll := cfg.DATABASE.Collection(models.ACHIEVEMENTS_COLLECTION)
+	cursor, err := achievements_coll.Find(ctx, bson.M{"userId": user_id})
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	unlocked := []models.Achievement{}
+	if err := cursor.All(ctx, &unlocked); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	by_type := make(map[string]models.Achievement, len(unlocked))
+	for _, achievement := range unlocked {
+		by_type[achievement.Type] = achievement
+	}
+	return by_type, nil
+}
+
+func examCompletedEvent(attempt models.TestAttempt, session models.ExamSession, previous_average float64, has_previous bool) models.AchievementEvent {
+	improvement := 0.0
+	if has_previous {
+		improvement = float64(attempt.Score) - previous_average
+	}
+	mode := session.Mode
+	if mode == "" {
+		mode = models.EXAM_MODE_FIXED
+	}
+
+	return models.AchievementEvent{
+		EventID:    "exam:" + attempt.ID.Hex(),
+		UserID:     attempt.UserID,
+		Type:       models.EVENT_EXAM_COMPLETED,
+		OccurredAt: attempt.CompletedAt,
+		Payload: bson.M{
+			"category":         attempt.Category,
+			"difficulty":       session.Difficulty,
+			"mode":             mode,
+			"score":            attempt.Score,
+			"correctCount":     attempt.CorrectCount,
+			"totalQuestions":   attempt.TotalQuestions,
+			"timeSpent":        attempt.TimeSpent,
+			"scoreImprovement": improvement,
+		},
+	}
+}
+
+func practiceAnsweredEvent(attempt models.TestAttempt, answer models.AttemptQuestion, difficulty string, session_start bool, answered_at bson.DateTime) models.AchievementEvent {
+	return models.AchievementEvent{
+		EventID:    "practice:" + attempt.ID.Hex() + ":" + answer.QuestionID.Hex(),
+		UserID:     attempt.UserID,
+		Type:       models.EVENT_PRACTICE_ANSWERED,
+		OccurredAt: answered_at,
+		Payload: bson.M{
+			"category":     attempt.Category,
+			"difficulty":   difficulty,
+			"isCorrect":    answer.IsCorrect,
+			"timeSpent":    answer.TimeSpent,
+			"sessionStart": session_start,
+		},
+	}
+}
+
+func challengeCompletedEvent(challenge models.DailyChallenge, streak models.UserStreak) models.AchievementEvent {
+	return models.AchievementEvent{
+		EventID:    "challenge:" + challenge.ID.Hex(),
+		UserID:     challenge.UserID,
+		Type:       models.EVENT_CHALLENGE_COMPLETED,
+		OccurredAt: challenge.CompletedAt,
+		Payload: bson.M{
+			"category":       challenge.Category,
+			"difficulty":     challenge.Difficulty,
+			"isCorrect":      challenge.IsCorrect,
+			"streak":         streak.CurrentStreak,
+			"totalCompleted": streak.TotalChallengesCompleted,
+		},
+	}
+}
+
+// averageExamScore is the mean score of the user's recorded exams.
+func (cfg *AppConfig) averageExamScore(ctx context.Context, user_id bson.ObjectID) (float64, bool, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userId": user_id, "type": models.EVENT_EXAM_COMPLETED}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "average": bson.M{"$avg": "$payload.score"}}}},
+	}
+
+	events_coll := cfg.DATABASE.Collection(models.ACHIEVEMENT_EVENTS_COLLECTION)
+	cursor, err := events_coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, false, utils.NewInternalServerError(err)
+	}
+	results := []struct {
+		Average float64 `bson:"average"`
+	}{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, false, utils.NewInternalServerError(err)
+	}
+	if len(results) == 0 {
+		return 0, false, nil
+	}
+	return results[0].Average, true, nil
+}
+
+// publishExamCompleted records a graded exam, the improvement is measured
+// against the exams recorded before it. Redeliveries record nothing new, the
+// achievement event id is the attempt's.
+func (cfg *AppConfig) publishExamCompleted(ctx context.Context, event models.DomainEvent) error {
+	attempt, session, found, err := cfg.findGradedExam(ctx, event)
+	if err != nil || !found {
+		return err
+	}
+	if err := cfg.backfillAchievementEvents(ctx, attempt.UserID); err != nil {
+		return err
+	}
+	average, has_previous, err := cfg.averageExamScore(ctx, attempt.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = cfg.publishAchievementEvent(ctx, examCompletedEvent(attempt, session, average, has_previous))
+	return err
+}
+
+// publishChallengeCompleted records a completed daily challenge with the
+// streak it counts towards. Redeliveries record nothing new, the achievement
+// event id is the challenge's.
+func (cfg *AppConfig) publishChallengeCompleted(ctx context.Context, event models.DomainEvent) error {
+	challenge, found, err := cfg.findCompletedChallenge(ctx, event)
+	if err != nil || !found {
+		return err
+	}
+
+	var streak models.UserStreak
+	streaks_coll := cfg.DATABASE.Collection(models.USER_STREAKS_COLLECTION)
+	err = streaks_coll.FindOne(ctx, bson.M{"userId": challenge.UserID}).Decode(&streak)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to load the streak of user %s: %w", challenge.UserID.Hex(), err)
+	}
+
+	_, err = cfg.publishAchievementEvent(ctx, challengeCompletedEvent(challenge, streak))
+	return err
+}
+
+// backfillAchievementEvents records the exams, practice answers and challenges
+// a user completed before events were recorded, the first time they are
+// needed. Event ids match the live ones so nothing is counted twice.
+func (cfg *AppConfig) backfillAchievementEvents(ctx context.Context, user_id bson.ObjectID) error {
+	events_coll := cfg.DATABASE.Collection(models.ACHIEVEMENT_EVENTS_COLLECTION)
+	err := events_coll.FindOne(ctx, bson.M{"userId": user_id}).Err()
+	if err == nil {
+		return nil
+	} else if err != mongo.ErrNoDocuments {
+		return utils.NewInternalServerError(err)
+	}
+
+	events := []any{}
+
+	exam_events, err := cfg.backfillExamEvents(ctx, user_id)
+	if err != nil {
+		return err
+	}
+	events = append(events, exam_events...)
+
+	practice_events, err := cfg.backfillPracticeEvents(ctx, user_id)
+	if err != nil {
+		return err
+	}
+	events = append(events, practice_events...)
+
+	challenge_events, err := cfg.backfillChallengeEvents(ctx, user_id)
+	if err != nil {
+		return err
+	}
+	events = append(events, challenge_events...)
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	// Unordered so events recorded concurrently by a live request are skipped, not fatal
+	_, err = events_coll.InsertMany(ctx, events, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return utils.NewInternalServerError(err)
+	}
+	return nil
+}
+
+func (cfg *AppConfig) backfillExamEvents(ctx context.Context, user_id bson.ObjectID) ([]any, error) {
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err := attempts_coll.Find(ctx,
+		bson.M{"userId": user_id, "isPracticeMode": bson.M{"$ne": true}},
+		options.Find().SetSort(bson.D{{Key: "completedAt", Value: 1}}),
+	)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	attempts := []models.TestAttempt{}
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	if len(attempts) == 0 {
+		return nil, nil
+	}
+
+	// Difficulty and mode only exist on the server-timed sessions
+	session_ids := []bson.ObjectID{}
+	for _, attempt := range attempts {
+		if !attempt.ExamSessionID.IsZero() {
+			session_ids = append(session_ids, attempt.ExamSessionID)
+		}
+	}
+	sessions := map[bson.ObjectID]models.ExamSession{}
+	if len(session_ids) > 0 {
+		exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+		cursor, err := exam_sessions_coll.Find(ctx,
+			bson.M{"_id": bson.M{"$in": session_ids}},
+			options.Find().SetProjection(bson.M{"difficulty": 1, "mode": 1}),
+		)
+		if err != nil {
+			return nil, utils.NewInternalServerError(err)
+		}
+		found := []models.ExamSession{}
+		if err := cursor.All(ctx, &found); err != nil {
+			return nil, utils.NewInternalServerError(err)
+		}
+		for _, session := range found {
+			sessions[session.ID] = session
+		}
+	}
+
+	events := make([]any, 0, len(attempts))
+	total := 0.0
+	for i, attempt := range attempts {
+		event := examCompletedEvent(attempt, sessions[attempt.ExamSessionID], total/float64(max(i, 1)), i > 0)
+		event.ID = bson.NewObjectID()
+		events = append(events, event)
+		total += float64(attempt.Score)
+	}
+	return events, nil
+}
+
+func (cfg *AppConfig) backfillPracticeEvents(ctx context.Context, user_id bson.ObjectID) ([]any, error) {
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err := attempts_coll.Find(ctx,
+		bson.M{"userId": user_id, "isPracticeMode": true},
+		options.Find().SetSort(bson.D{{Key: "completedAt", Value: 1}}),
+	)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	attempts := []models.TestAttempt{}
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	if len(attempts) == 0 {
+		return nil, nil
+	}
+
+	question_ids := []bson.ObjectID{}
+	for _, attempt := range attempts {
+		for _, answer := range attempt.Questions {
+			question_ids = append(question_ids, answer.QuestionID)
+		}
+	}
+	difficulties := map[bson.ObjectID]string{}
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	cursor, err = questions_coll.Find(ctx,
+		bson.M{"_id": bson.M{"$in": question_ids}},
+		options.Find().SetProjection(bson.M{"difficulty": 1}),
+	)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	questions := []models.Question{}
+	if err := cursor.All(ctx, &questions); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	for _, question := range questions {
+		difficulties[question.ID] = question.Difficulty
+	}
+
+	events := []any{}
+	for _, attempt := range attempts {
+		for i, answer := range attempt.Questions {
+			if answer.UserAnswer == "" {
+				continue
+			}
+			event := practiceAnsweredEvent(attempt, answer, difficulties[answer.QuestionID], i == 0, attempt.CompletedAt)
+			event.ID = bson.NewObjectID()
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (cfg *AppConfig) backfillChallengeEvents(ctx context.Context, user_id bson.ObjectID) ([]any, error) {
+	challenges_coll := cfg.DATABASE.Collection(models.DAILY_CHALLENGES_COLLECTION)
+	cursor, err := challenges_coll.Find(ctx,
+		bson.M{"userId": user_id, "completed": true},
+		options.Find().SetSort(bson.D{{Key: "date", Value: 1}}),
+	)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	challenges := []models.DailyChallenge{}
+	if err := cursor.All(ctx, &challenges); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	// Replaying the completions rebuilds the streak each challenge was part of
+	streak := models.UserStreak{}
+	events := make([]any, 0, len(challenges))
+	for _, challenge := range challenges {
+		day := challenge.Day
+		if day == "" {
+			day = challenge.Date.Time().UTC().Format(time.DateOnly)
+		}
+		streak.RecordCompletion(day)
+		event := challengeCompletedEvent(challenge, streak)
+		event.ID = bson.NewObjectID()
+		events = append(events, event)
+	}
+	return events, nil
+}
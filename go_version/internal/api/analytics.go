@@ -0,0 +1,890 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	// Topics need this many answers before they can be called weak
+	MIN_TOPIC_ANSWERS = 5
+
+	DEFAULT_WEAK_TOPICS = 5
+	MAX_WEAK_TOPICS     = 20
+)
+
+// Lower bounds in seconds of the time-per-question histogram buckets, the
+// last one is open ended
+var timingBoundaries = []int32{1, 10, 20, 30, 45, 60, 90, 120, 180, 300}
+
+type AnalyticsFilter struct {
+	TimeRange string
+	Since     time.Time
+	Category  string
+}
+
+type ScoreTrendPoint struct {
+	Period         time.Time `json:"period"`
+	Attempts       int32     `json:"attempts"`
+	AverageScore   int32     `json:"averageScore"`
+	HighestScore   int32     `json:"highestScore"`
+	Accuracy       int32     `json:"accuracy"`
+	AverageTime    int32     `json:"averageTimePerQuestion"`
+	TotalQuestions int32     `json:"totalQuestions"`
+}
+
+type TopicAccuracy struct {
+	Tag          string        `json:"tag"`
+	Answers      int32         `json:"answers"`
+	Correct      int32         `json:"correct"`
+	Accuracy     int32         `json:"accuracy"`
+	AverageTime  int32         `json:"averageTime,omitempty"`
+	LastAnswered bson.DateTime `json:"lastAnswered"`
+}
+
+type TimingBucket struct {
+	From     int32  `json:"from"`
+	To       int32  `json:"to,omitempty"`
+	Label    string `json:"label"`
+	Answers  int32  `json:"answers"`
+	Accuracy int32  `json:"accuracy"`
+}
+
+type TimingStats struct {
+	Key     string `json:"key"`
+	Answers int32  `json:"answers"`
+	Average int32  `json:"average"`
+	Median  int32  `json:"median"`
+	P90     int32  `json:"p90"`
+}
+
+// GetStudentAnalytics serves the Node /analytics/student shape for the
+// student analytics page. Difficulties come from the answered questions
+// instead of being spread evenly over the attempts.
+func (cfg *AppConfig) GetStudentAnalytics(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter, err := parseAnalyticsFilter(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	type difficultyStat struct {
+		Difficulty string `bson:"_id"`
+		Attempts   int32  `bson:"attempts"`
+		Answers    int32  `bson:"answers"`
+		Correct    int32  `bson:"correct"`
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter.match(user_id)}},
+		{{Key: "$sort", Value: bson.D{{Key: "createdAt", Value: 1}}}},
+		{{Key: "$facet", Value: bson.M{
+			"summary": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":            nil,
+					"totalAttempts":  bson.M{"$sum": 1},
+					"averageScore":   bson.M{"$avg": "$score"},
+					"highestScore":   bson.M{"$max": "$score"},
+					"lowestScore":    bson.M{"$min": "$score"},
+					"totalTimeSpent": bson.M{"$sum": "$timeSpent"},
+					"totalQuestions": bson.M{"$sum": "$totalQuestions"},
+					"scores":         bson.M{"$push": "$score"},
+				}},
+			},
+			"trend": bson.A{
+				bson.M{"$sort": bson.M{"createdAt": -1}},
+				bson.M{"$limit": 10},
+				bson.M{"$sort": bson.M{"createdAt": 1}},
+				bson.M{"$project": bson.M{
+					"_id":      0,
+					"date":     bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$createdAt"}},
+					"score":    1,
+					"category": 1,
+				}},
+			},
+			"categories": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":            "$category",
+					"attempts":       bson.M{"$sum": 1},
+					"averageScore":   bson.M{"$avg": "$score"},
+					"correctAnswers": bson.M{"$sum": "$correctCount"},
+					"totalQuestions": bson.M{"$sum": "$totalQuestions"},
+				}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+			"difficulties": append(answeredQuestionStages(),
+				bson.M{"$group": bson.M{
+					"_id":      "$difficulty",
+					"attempts": bson.M{"$addToSet": "$_id"},
+					"answers":  bson.M{"$sum": 1},
+					"correct":  bson.M{"$sum": bson.M{"$cond": bson.A{"$questions.isCorrect", 1, 0}}},
+				}},
+				bson.M{"$project": bson.M{
+					"attempts": bson.M{"$size": "$attempts"},
+					"answers":  1,
+					"correct":  1,
+				}},
+			),
+		}}},
+	}
+
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err := attempts_coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	var results []struct {
+		Summary []struct {
+			TotalAttempts  int32   `bson:"totalAttempts"`
+			AverageScore   float64 `bson:"averageScore"`
+			HighestScore   int32   `bson:"highestScore"`
+			LowestScore    int32   `bson:"lowestScore"`
+			TotalTimeSpent int64   `bson:"totalTimeSpent"`
+			TotalQuestions int64   `bson:"totalQuestions"`
+			Scores         []int32 `bson:"scores"`
+		} `bson:"summary"`
+		Trend []struct {
+			Date     string `bson:"date"`
+			Score    int32  `bson:"score"`
+			Category string `bson:"category"`
+		} `bson:"trend"`
+		Categories []struct {
+			Category       string  `bson:"_id"`
+			Attempts       int32   `bson:"attempts"`
+			AverageScore   float64 `bson:"averageScore"`
+			CorrectAnswers int32   `bson:"correctAnswers"`
+			TotalQuestions int32   `bson:"totalQuestions"`
+		} `bson:"categories"`
+		Difficulties []difficultyStat `bson:"difficulties"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	response_payload := map[string]any{
+		"totalAttempts":          0,
+		"averageScore":           0,
+		"highestScore":           0,
+		"lowestScore":            0,
+		"totalTimeSpent":         0,
+		"averageTimePerQuestion": 0,
+		"performanceTrend":       []map[string]any{},
+		"categoryBreakdown":      []map[string]any{},
+		"difficultyBreakdown":    []map[string]any{},
+		"improvementRate":        0,
+		"strongTopics":           []map[string]any{},
+		"weakTopics":             []map[string]any{},
+	}
+	if len(results) == 0 || len(results[0].Summary) == 0 {
+		utils.SuccessResponseWriter(w, "Analytics provided successfully", response_payload, http.StatusOK)
+		return nil
+	}
+	result := results[0]
+	summary := result.Summary[0]
+
+	average_time := 0.0
+	if summary.TotalQuestions > 0 {
+		average_time = float64(summary.TotalTimeSpent) / float64(summary.TotalQuestions)
+	}
+
+	trend := make([]map[string]any, 0, len(result.Trend))
+	for i, point := range result.Trend {
+		trend = append(trend, map[string]any{
+			"attempt":  i + 1,
+			"date":     point.Date,
+			"score":    point.Score,
+			"category": point.Category,
+		})
+	}
+
+	categories := make([]map[string]any, 0, len(result.Categories))
+	for _, stat := range result.Categories {
+		categories = append(categories, map[string]any{
+			"category":     stat.Category,
+			"attempts":     stat.Attempts,
+			"averageScore": int32(math.Round(stat.AverageScore)),
+			"accuracy":     models.CalculateScore(stat.CorrectAnswers, stat.TotalQuestions),
+		})
+	}
+
+	difficulties := []map[string]any{}
+	for _, difficulty := range models.GetValidDifficulties() {
+		index := slices.IndexFunc(result.Difficulties, func(stat difficultyStat) bool {
+			return stat.Difficulty == difficulty
+		})
+		if index == -1 {
+			continue
+		}
+		stat := result.Difficulties[index]
+		accuracy := models.CalculateScore(stat.Correct, stat.Answers)
+		difficulties = append(difficulties, map[string]any{
+			"difficulty": difficulty,
+			"attempts":   stat.Attempts,
+			// The score on questions of this difficulty
+			"averageScore": accuracy,
+			"accuracy":     accuracy,
+		})
+	}
+
+	// Node compares the first and last quarter of the attempts
+	improvement_rate := 0
+	if total := len(summary.Scores); total >= 4 {
+		quarter := max(1, total/4)
+		first, last := averageInt32(summary.Scores[:quarter]), averageInt32(summary.Scores[total-quarter:])
+		if first > 0 {
+			improvement_rate = int(math.Round((last - first) / first * 100))
+		}
+	}
+
+	sorted := slices.Clone(categories)
+	slices.SortStableFunc(sorted, func(a, b map[string]any) int {
+		return int(b["averageScore"].(int32) - a["averageScore"].(int32))
+	})
+	topic := func(stat map[string]any) map[string]any {
+		return map[string]any{"category": stat["category"], "score": stat["averageScore"]}
+	}
+	strong, weak := []map[string]any{}, []map[string]any{}
+	for _, stat := range sorted[:min(2, len(sorted))] {
+		strong = append(strong, topic(stat))
+	}
+	for _, stat := range sorted[max(0, len(sorted)-2):] {
+		weak = append(weak, topic(stat))
+	}
+
+	response_payload["totalAttempts"] = summary.TotalAttempts
+	response_payload["averageScore"] = int32(math.Round(summary.AverageScore))
+	response_payload["highestScore"] = summary.HighestScore
+	response_payload["lowestScore"] = summary.LowestScore
+	response_payload["totalTimeSpent"] = summary.TotalTimeSpent
+	response_payload["averageTimePerQuestion"] = int32(math.Round(average_time))
+	response_payload["performanceTrend"] = trend
+	response_payload["categoryBreakdown"] = categories
+	response_payload["difficultyBreakdown"] = difficulties
+	response_payload["improvementRate"] = improvement_rate
+	response_payload["strongTopics"] = strong
+	response_payload["weakTopics"] = weak
+
+	utils.SuccessResponseWriter(
+		w,
+		"Analytics provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetScoreTrends buckets exam scores by day, week or month in the user's
+// time zone and fits a line through them to tell the direction.
+func (cfg *AppConfig) GetScoreTrends(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter, err := parseAnalyticsFilter(r)
+	if err != nil {
+		return err
+	}
+	location := userLocation(user)
+	interval := strings.ToLower(r.URL.Query().Get("interval"))
+	if interval == "" {
+		interval = "week"
+	}
+	if !slices.Contains([]string{"day", "week", "month"}, interval) {
+		return utils.NewBadRequest("Invalid interval, expected day, week or month")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter.match(user_id)}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateTrunc": bson.M{
+				"date":        "$createdAt",
+				"unit":        interval,
+				"timezone":    location.String(),
+				"startOfWeek": "monday",
+			}},
+			"attempts":       bson.M{"$sum": 1},
+			"averageScore":   bson.M{"$avg": "$score"},
+			"highestScore":   bson.M{"$max": "$score"},
+			"correct":        bson.M{"$sum": "$correctCount"},
+			"totalQuestions": bson.M{"$sum": "$totalQuestions"},
+			"timeSpent":      bson.M{"$sum": "$timeSpent"},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err := attempts_coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	var buckets []struct {
+		Period         time.Time `bson:"_id"`
+		Attempts       int32     `bson:"attempts"`
+		AverageScore   float64   `bson:"averageScore"`
+		HighestScore   int32     `bson:"highestScore"`
+		Correct        int32     `bson:"correct"`
+		TotalQuestions int32     `bson:"totalQuestions"`
+		TimeSpent      int32     `bson:"timeSpent"`
+	}
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	points := make([]ScoreTrendPoint, 0, len(buckets))
+	scores := make([]float64, 0, len(buckets))
+	for _, bucket := range buckets {
+		point := ScoreTrendPoint{
+			Period:         bucket.Period.In(location),
+			Attempts:       bucket.Attempts,
+			AverageScore:   int32(math.Round(bucket.AverageScore)),
+			HighestScore:   bucket.HighestScore,
+			Accuracy:       models.CalculateScore(bucket.Correct, bucket.TotalQuestions),
+			TotalQuestions: bucket.TotalQuestions,
+		}
+		if bucket.TotalQuestions > 0 {
+			point.AverageTime = int32(math.Round(float64(bucket.TimeSpent) / float64(bucket.TotalQuestions)))
+		}
+		points = append(points, point)
+		scores = append(scores, bucket.AverageScore)
+	}
+
+	slope := trendSlope(scores)
+	direction := "steady"
+	if slope >= 1 {
+		direction = "improving"
+	} else if slope <= -1 {
+		direction = "declining"
+	}
+
+	response_payload := map[string]any{
+		"interval":  interval,
+		"timeRange": filter.TimeRange,
+		"category":  filter.Category,
+		"points":    points,
+		// Average score points gained per interval
+		"slope":     math.Round(slope*10) / 10,
+		"direction": direction,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Score trends provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetTopicAccuracy breaks exam answers down by question tag and lists the
+// weakest topics, those with enough answers and the lowest accuracy.
+func (cfg *AppConfig) GetTopicAccuracy(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter, err := parseAnalyticsFilter(r)
+	if err != nil {
+		return err
+	}
+	weakest_limit, err := strconv.Atoi(r.URL.Query().Get("weakest"))
+	if err != nil || weakest_limit < 1 {
+		weakest_limit = DEFAULT_WEAK_TOPICS
+	}
+	weakest_limit = min(weakest_limit, MAX_WEAK_TOPICS)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: filter.match(user_id)}}}
+	for _, stage := range answeredQuestionStages() {
+		pipeline = append(pipeline, stage.(bson.D))
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$unwind", Value: "$question.tags"}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":          bson.M{"$toLower": "$question.tags"},
+			"answers":      bson.M{"$sum": 1},
+			"correct":      bson.M{"$sum": bson.M{"$cond": bson.A{"$questions.isCorrect", 1, 0}}},
+			"averageTime":  bson.M{"$avg": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$questions.timeSpent", 0}}, "$questions.timeSpent", nil}}},
+			"lastAnswered": bson.M{"$max": "$createdAt"},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "answers", Value: -1}, {Key: "_id", Value: 1}}}},
+	)
+
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err := attempts_coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	var rows []struct {
+		Tag          string        `bson:"_id"`
+		Answers      int32         `bson:"answers"`
+		Correct      int32         `bson:"correct"`
+		AverageTime  float64       `bson:"averageTime"`
+		LastAnswered bson.DateTime `bson:"lastAnswered"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	topics := make([]TopicAccuracy, 0, len(rows))
+	for _, row := range rows {
+		topics = append(topics, TopicAccuracy{
+			Tag:          row.Tag,
+			Answers:      row.Answers,
+			Correct:      row.Correct,
+			Accuracy:     models.CalculateScore(row.Correct, row.Answers),
+			AverageTime:  int32(math.Round(row.AverageTime)),
+			LastAnswered: row.LastAnswered,
+		})
+	}
+
+	// Smoothed towards 50% so two lucky answers don't beat twenty good ones
+	smoothed := func(topic TopicAccuracy) float64 {
+		return float64(topic.Correct+1) / float64(topic.Answers+2)
+	}
+	weakest := []TopicAccuracy{}
+	for _, topic := range topics {
+		if topic.Answers >= MIN_TOPIC_ANSWERS {
+			weakest = append(weakest, topic)
+		}
+	}
+	slices.SortStableFunc(weakest, func(a, b TopicAccuracy) int {
+		if smoothed(a) < smoothed(b) {
+			return -1
+		} else if smoothed(a) > smoothed(b) {
+			return 1
+		}
+		return 0
+	})
+	weakest = weakest[:min(weakest_limit, len(weakest))]
+
+	response_payload := map[string]any{
+		"timeRange":  filter.TimeRange,
+		"category":   filter.Category,
+		"topics":     topics,
+		"weakest":    weakest,
+		"minAnswers": MIN_TOPIC_ANSWERS,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Topic accuracy provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetTimingDistribution returns how long the user takes per question. Only
+// server-timed exams record per-question times.
+func (cfg *AppConfig) GetTimingDistribution(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter, err := parseAnalyticsFilter(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	timed := bson.M{"$match": bson.M{"questions.timeSpent": bson.M{"$gt": 0}}}
+	correct := bson.M{"$sum": bson.M{"$cond": bson.A{"$questions.isCorrect", 1, 0}}}
+	times := bson.M{"$push": "$questions.timeSpent"}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter.match(user_id)}},
+		{{Key: "$facet", Value: bson.M{
+			"histogram": bson.A{
+				bson.M{"$unwind": "$questions"},
+				timed,
+				bson.M{"$bucket": bson.M{
+					"groupBy":    "$questions.timeSpent",
+					"boundaries": timingBoundaries,
+					"default":    timingBoundaries[len(timingBoundaries)-1],
+					"output":     bson.M{"answers": bson.M{"$sum": 1}, "correct": correct},
+				}},
+			},
+			"difficulties": append(answeredQuestionStages(),
+				timed,
+				bson.M{"$group": bson.M{"_id": "$difficulty", "times": times}},
+			),
+			"outcomes": bson.A{
+				bson.M{"$unwind": "$questions"},
+				timed,
+				bson.M{"$group": bson.M{"_id": "$questions.isCorrect", "times": times}},
+			},
+		}}},
+	}
+
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err := attempts_coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	var results []struct {
+		Histogram []struct {
+			From    int32 `bson:"_id"`
+			Answers int32 `bson:"answers"`
+			Correct int32 `bson:"correct"`
+		} `bson:"histogram"`
+		Difficulties []struct {
+			Difficulty string  `bson:"_id"`
+			Times      []int32 `bson:"times"`
+		} `bson:"difficulties"`
+		Outcomes []struct {
+			IsCorrect bool    `bson:"_id"`
+			Times     []int32 `bson:"times"`
+		} `bson:"outcomes"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	histogram := []TimingBucket{}
+	by_difficulty := []TimingStats{}
+	by_outcome := []TimingStats{}
+	var all_times []int32
+	if len(results) > 0 {
+		result := results[0]
+		for _, row := range result.Histogram {
+			bucket := TimingBucket{From: row.From, Answers: row.Answers, Accuracy: models.CalculateScore(row.Correct, row.Answers)}
+			if index := slices.Index(timingBoundaries, row.From); index < len(timingBoundaries)-1 {
+				bucket.To = timingBoundaries[index+1]
+				bucket.Label = strconv.Itoa(int(bucket.From)) + "-" + strconv.Itoa(int(bucket.To)) + "s"
+			} else {
+				bucket.Label = strconv.Itoa(int(bucket.From)) + "s+"
+			}
+			histogram = append(histogram, bucket)
+		}
+		for _, difficulty := range models.GetValidDifficulties() {
+			for _, row := range result.Difficulties {
+				if row.Difficulty == difficulty {
+					by_difficulty = append(by_difficulty, newTimingStats(difficulty, row.Times))
+				}
+			}
+		}
+		for _, row := range result.Outcomes {
+			key := "incorrect"
+			if row.IsCorrect {
+				key = "correct"
+			}
+			by_outcome = append(by_outcome, newTimingStats(key, row.Times))
+			all_times = append(all_times, row.Times...)
+		}
+		slices.SortFunc(by_outcome, func(a, b TimingStats) int { return strings.Compare(a.Key, b.Key) })
+	}
+
+	response_payload := map[string]any{
+		"timeRange":    filter.TimeRange,
+		"category":     filter.Category,
+		"overall":      newTimingStats("all", all_times),
+		"histogram":    histogram,
+		"byDifficulty": by_difficulty,
+		"byOutcome":    by_outcome,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Timing distribution provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetPeerComparison compares the user's exam results with students of the
+// same major (scope=major, the default) or university (scope=university).
+func (cfg *AppConfig) GetPeerComparison(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter, err := parseAnalyticsFilter(r)
+	if err != nil {
+		return err
+	}
+	scope := strings.ToLower(r.URL.Query().Get("scope"))
+	if scope == "" {
+		scope = models.LEADERBOARD_SCOPE_MAJOR
+	}
+	var value string
+	switch scope {
+	case models.LEADERBOARD_SCOPE_MAJOR:
+		value = user.Major
+	case models.LEADERBOARD_SCOPE_UNIVERSITY:
+		value = user.University
+	default:
+		return utils.NewBadRequest("Invalid scope, expected major or university")
+	}
+	if value == "" {
+		return utils.NewBadRequest("Add your " + scope + " to your profile to compare with peers")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	var peers []bson.ObjectID
+	err = users_coll.Distinct(ctx, "_id", bson.M{scope: value, "role": models.ROLE_STUDENT, "isActive": true}).Decode(&peers)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	match := filter.match(user_id)
+	match["userId"] = bson.M{"$in": peers}
+
+	per_user := bson.M{
+		"_id":          "$userId",
+		"attempts":     bson.M{"$sum": 1},
+		"averageScore": bson.M{"$avg": "$score"},
+		"correct":      bson.M{"$sum": "$correctCount"},
+		"questions":    bson.M{"$sum": "$totalQuestions"},
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: per_user}},
+		{{Key: "$addFields", Value: bson.M{
+			"accuracy": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$questions", 0}},
+				bson.M{"$multiply": bson.A{bson.M{"$divide": bson.A{"$correct", "$questions"}}, 100}},
+				0,
+			}},
+		}}},
+		{{Key: "$setWindowFields", Value: bson.M{
+			"sortBy": bson.M{"averageScore": -1},
+			"output": bson.M{"rank": bson.M{"$rank": bson.M{}}},
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"me": bson.A{bson.M{"$match": bson.M{"_id": user_id}}},
+			"peers": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":          nil,
+					"count":        bson.M{"$sum": 1},
+					"averageScore": bson.M{"$avg": "$averageScore"},
+					"accuracy":     bson.M{"$avg": "$accuracy"},
+					"attempts":     bson.M{"$avg": "$attempts"},
+				}},
+			},
+			"distribution": bson.A{
+				bson.M{"$bucket": bson.M{
+					"groupBy":    "$averageScore",
+					"boundaries": bson.A{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 101},
+					"default":    "other",
+					"output":     bson.M{"students": bson.M{"$sum": 1}},
+				}},
+			},
+		}}},
+	}
+
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err := attempts_coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	type stats struct {
+		Count        int64   `bson:"count"`
+		Attempts     float64 `bson:"attempts"`
+		AverageScore float64 `bson:"averageScore"`
+		Accuracy     float64 `bson:"accuracy"`
+		Rank         int64   `bson:"rank"`
+	}
+	var results []struct {
+		Me           []stats `bson:"me"`
+		Peers        []stats `bson:"peers"`
+		Distribution []struct {
+			From     int32 `bson:"_id"`
+			Students int64 `bson:"students"`
+		} `bson:"distribution"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	view := func(s stats) map[string]any {
+		return map[string]any{
+			"averageScore": int32(math.Round(s.AverageScore)),
+			"accuracy":     int32(math.Round(s.Accuracy)),
+			"attempts":     math.Round(s.Attempts*10) / 10,
+		}
+	}
+	response_payload := map[string]any{
+		"scope":        scope,
+		"value":        value,
+		"timeRange":    filter.TimeRange,
+		"category":     filter.Category,
+		"peers":        0,
+		"me":           nil,
+		"peerAverage":  nil,
+		"distribution": []map[string]any{},
+	}
+	if len(results) > 0 && len(results[0].Peers) > 0 {
+		result := results[0]
+		peer_stats := result.Peers[0]
+		response_payload["peers"] = peer_stats.Count
+		response_payload["peerAverage"] = view(peer_stats)
+		if len(result.Me) > 0 {
+			me := view(result.Me[0])
+			me["rank"] = result.Me[0].Rank
+			// Share of peers scoring below the user
+			me["percentile"] = int32(math.Round(float64(peer_stats.Count-result.Me[0].Rank) / float64(peer_stats.Count) * 100))
+			response_payload["me"] = me
+		}
+		distribution := []map[string]any{}
+		for _, bucket := range result.Distribution {
+			distribution = append(distribution, map[string]any{
+				"from":     bucket.From,
+				"to":       min(bucket.From+10, 100),
+				"students": bucket.Students,
+			})
+		}
+		response_payload["distribution"] = distribution
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Peer comparison provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// parseAnalyticsFilter reads timeRange (all|week|month|year, rolling like the
+// Node API) and an optional category.
+func parseAnalyticsFilter(r *http.Request) (AnalyticsFilter, error) {
+	params := r.URL.Query()
+	filter := AnalyticsFilter{
+		TimeRange: strings.ToLower(params.Get("timeRange")),
+		Category:  strings.ToLower(params.Get("category")),
+	}
+
+	now := time.Now()
+	switch filter.TimeRange {
+	case "", "all":
+		filter.TimeRange = "all"
+	case "week":
+		filter.Since = now.AddDate(0, 0, -7)
+	case "month":
+		filter.Since = now.AddDate(0, 0, -30)
+	case "year":
+		filter.Since = now.AddDate(0, 0, -365)
+	default:
+		return filter, utils.NewBadRequest("Invalid timeRange, expected all, week, month or year")
+	}
+
+	if filter.Category != "" && !slices.Contains(models.GetValidCategories(), filter.Category) {
+		return filter, utils.NewBadRequest("Invalid category")
+	}
+
+	return filter, nil
+}
+
+// match selects the user's graded exams, practice attempts are left out.
+func (f AnalyticsFilter) match(user_id bson.ObjectID) bson.M {
+	match := bson.M{"userId": user_id, "isPracticeMode": bson.M{"$ne": true}}
+	if !f.Since.IsZero() {
+		match["createdAt"] = bson.M{"$gte": bson.NewDateTimeFromTime(f.Since)}
+	}
+	if f.Category != "" {
+		match["category"] = f.Category
+	}
+	return match
+}
+
+// answeredQuestionStages unwinds attempts into their answers and joins the
+// tags and difficulty of each question, unlabelled ones count as medium.
+func answeredQuestionStages() bson.A {
+	return bson.A{
+		bson.D{{Key: "$unwind", Value: "$questions"}},
+		bson.D{{Key: "$lookup", Value: bson.M{
+			"from":         models.QUESTIONS_COLLECTION,
+			"localField":   "questions.questionId",
+			"foreignField": "_id",
+			"pipeline":     bson.A{bson.M{"$project": bson.M{"tags": 1, "difficulty": 1}}},
+			"as":           "question",
+		}}},
+		bson.D{{Key: "$unwind", Value: bson.M{"path": "$question", "preserveNullAndEmptyArrays": true}}},
+		bson.D{{Key: "$addFields", Value: bson.M{
+			"difficulty": bson.M{"$ifNull": bson.A{"$question.difficulty", models.DIFFICULTY_MEDIUM}},
+		}}},
+	}
+}
+
+func newTimingStats(key string, times []int32) TimingStats {
+	stats := TimingStats{Key: key, Answers: int32(len(times))}
+	if len(times) == 0 {
+		return stats
+	}
+	sorted := slices.Clone(times)
+	slices.Sort(sorted)
+	var total int64
+	for _, t := range sorted {
+		total += int64(t)
+	}
+	stats.Average = int32(math.Round(float64(total) / float64(len(sorted))))
+	stats.Median = sorted[len(sorted)/2]
+	stats.P90 = sorted[min(len(sorted)-1, len(sorted)*9/10)]
+	return stats
+}
+
+func averageInt32(values []int32) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var total int64
+	for _, v := range values {
+		total += int64(v)
+	}
+	return float64(total) / float64(len(values))
+}
+
+// trendSlope is the least-squares slope of values over their index.
+func trendSlope(values []float64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return 0
+	}
+	var sum_x, sum_y, sum_xy, sum_xx float64
+	for i, y := range values {
+		x := float64(i)
+		sum_x += x
+		sum_y += y
+		sum_xy += x * y
+		sum_xx += x * x
+	}
+	return (n*sum_xy - sum_x*sum_y) / (n*sum_xx - sum_x*sum_x)
+}
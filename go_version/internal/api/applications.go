@@ -0,0 +1,966 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_APPLICATIONS_LIMIT = 20
+	MAX_APPLICATIONS_LIMIT     = 100
+
+	MAX_PIPELINE_STAGES = 12
+)
+
+var reStageKey = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+var applicationStatusLabels = map[string]string{
+	models.APPLICATION_STATUS_SUBMITTED:    "Submitted",
+	models.APPLICATION_STATUS_IN_REVIEW:    "Under review",
+	models.APPLICATION_STATUS_INTERVIEWING: "Interviewing",
+	models.APPLICATION_STATUS_OFFERED:      "Offer extended",
+	models.APPLICATION_STATUS_NOT_SELECTED: "Not selected",
+	models.APPLICATION_STATUS_WITHDRAWN:    "Withdrawn",
+}
+
+type ApplyRequestBody struct {
+	PostingID   string `json:"postingId" validate:"required,mongodb"`
+	CoverLetter string `json:"coverLetter" validate:"max=5000"`
+	ResumeURL   string `json:"resumeUrl" validate:"omitempty,url,max=500"`
+}
+
+type PipelineStagesRequestBody struct {
+	Stages []models.PipelineStage `json:"stages" validate:"required,min=2,dive"`
+}
+
+type ApplicationStageRequestBody struct {
+	Stage string `json:"stage" validate:"required"`
+}
+
+type ApplicationNoteRequestBody struct {
+	Content string `json:"content" validate:"required,max=2000"`
+}
+
+type ApplicationRatingRequestBody struct {
+	Rating int32 `json:"rating" validate:"required,min=1,max=5"`
+}
+
+type PostingSummary struct {
+	ID             bson.ObjectID `json:"_id"`
+	Title          string        `json:"title"`
+	CompanyName    string        `json:"companyName"`
+	City           string        `json:"city"`
+	EmploymentType string        `json:"employmentType"`
+	Status         string        `json:"status"`
+}
+
+type StudentStatusChange struct {
+	Status    string        `json:"status"`
+	Label     string        `json:"label"`
+	ChangedAt bson.DateTime `json:"changedAt"`
+}
+
+// StudentApplicationView is what students see of their application, the
+// company's stages, notes and rating stay private.
+type StudentApplicationView struct {
+	ID          bson.ObjectID         `json:"_id"`
+	Posting     PostingSummary        `json:"posting"`
+	CoverLetter string                `json:"coverLetter,omitempty"`
+	ResumeURL   string                `json:"resumeUrl,omitempty"`
+	Status      string                `json:"status"`
+	StatusLabel string                `json:"statusLabel"`
+	History     []StudentStatusChange `json:"history"`
+	WithdrawnAt bson.DateTime         `json:"withdrawnAt,omitempty"`
+	CreatedAt   bson.DateTime         `json:"createdAt"`
+	UpdatedAt   bson.DateTime         `json:"updatedAt"`
+}
+
+type ApplicantSummary struct {
+	ID             bson.ObjectID `json:"_id"`
+	FullName       string        `json:"fullName"`
+	Email          string        `json:"email"`
+	Phone          string        `json:"phone,omitempty"`
+	City           string        `json:"city,omitempty"`
+	University     string        `json:"university,omitempty"`
+	Major          string        `json:"major,omitempty"`
+	GraduationYear string        `json:"graduationYear,omitempty"`
+	LinkedInURL    string        `json:"linkedInUrl,omitempty"`
+	ProfileImage   string        `json:"profileImage,omitempty"`
+}
+
+type CompanyApplicationView struct {
+	models.Application
+	StageName string           `json:"stageName"`
+	Applicant ApplicantSummary `json:"applicant"`
+}
+
+// Student endpoints
+
+func (cfg *AppConfig) ApplyToJobPosting(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	req_body := ApplyRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing application request body", http.StatusBadRequest, err)
+	}
+	req_body.CoverLetter = sanitizeInput(req_body.CoverLetter)
+	req_body.ResumeURL = strings.TrimSpace(req_body.ResumeURL)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+	posting_id, _ := bson.ObjectIDFromHex(req_body.PostingID)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var posting models.JobPosting
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	err = job_postings_coll.FindOne(ctx, bson.M{"_id": posting_id, "status": models.JOB_STATUS_PUBLISHED}).Decode(&posting)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Job posting not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if !posting.Deadline.Time().After(time.Now()) {
+		return utils.NewBadRequest("The application deadline has passed")
+	}
+
+	first_stage := posting.PipelineStages()[0]
+	now := bson.NewDateTimeFromTime(time.Now())
+	application := models.Application{
+		ID:            bson.NewObjectID(),
+		PostingID:     posting.ID,
+		CompanyID:     posting.CompanyID,
+		StudentID:     user_id,
+		CoverLetter:   req_body.CoverLetter,
+		ResumeURL:     req_body.ResumeURL,
+		Stage:         first_stage.Key,
+		StudentStatus: first_stage.StudentStatus,
+		History: []models.StageChange{{
+			To:            first_stage.Key,
+			StudentStatus: first_stage.StudentStatus,
+			ChangedBy:     user_id,
+			ChangedAt:     now,
+		}},
+		Notes:     []models.ApplicationNote{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	applications_coll := cfg.DATABASE.Collection(models.APPLICATIONS_COLLECTION)
+	_, err = applications_coll.InsertOne(ctx, application)
+	if mongo.IsDuplicateKeyError(err) {
+		return utils.NewConflict("You already applied to this job posting")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Application submitted successfully",
+		map[string]any{"application": newStudentApplicationView(application, posting)},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetMyApplications(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter := bson.M{"studentId": user_id}
+	if status := r.URL.Query().Get("status"); status != "" {
+		if _, ok := applicationStatusLabels[status]; !ok {
+			return utils.NewBadRequest("Invalid status")
+		}
+		filter["studentStatus"] = status
+	}
+	page, limit := parsePagination(r, DEFAULT_APPLICATIONS_LIMIT, MAX_APPLICATIONS_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	applications, total, err := cfg.findApplications(ctx, filter, page, limit)
+	if err != nil {
+		return err
+	}
+	postings, err := cfg.findPostingsByID(ctx, applications)
+	if err != nil {
+		return err
+	}
+
+	views := make([]StudentApplicationView, 0, len(applications))
+	for _, application := range applications {
+		views = append(views, newStudentApplicationView(application, postings[application.PostingID]))
+	}
+
+	response_payload := map[string]any{
+		"applications": views,
+		"pagination":   newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Applications provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetMyApplication(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	application_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	application, err := cfg.findApplication(ctx, bson.M{"_id": application_id, "studentId": user_id})
+	if err != nil {
+		return err
+	}
+	postings, err := cfg.findPostingsByID(ctx, []models.Application{application})
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Application provided successfully",
+		map[string]any{"application": newStudentApplicationView(application, postings[application.PostingID])},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) WithdrawApplication(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	application_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	application, err := cfg.findApplication(ctx, bson.M{"_id": application_id, "studentId": user_id})
+	if err != nil {
+		return err
+	}
+	if application.IsWithdrawn() {
+		return utils.NewConflict("Application is already withdrawn")
+	}
+	if application.StudentStatus == models.APPLICATION_STATUS_NOT_SELECTED {
+		return utils.NewConflict("The application was already closed")
+	}
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	applications_coll := cfg.DATABASE.Collection(models.APPLICATIONS_COLLECTION)
+	err = applications_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": application.ID, "stage": application.Stage, "studentStatus": application.StudentStatus},
+		bson.M{
+			"$set": bson.M{"studentStatus": models.APPLICATION_STATUS_WITHDRAWN, "withdrawnAt": now, "updatedAt": now},
+			"$push": bson.M{"history": models.StageChange{
+				From:          application.Stage,
+				To:            application.Stage,
+				StudentStatus: models.APPLICATION_STATUS_WITHDRAWN,
+				ChangedBy:     user_id,
+				ChangedAt:     now,
+			}},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&application)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewConflict("The application changed, please reload it")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	if err := cfg.cancelApplicationInterview(ctx, application.ID, user_id, "The application was withdrawn"); err != nil {
+		return err
+	}
+
+	postings, err := cfg.findPostingsByID(ctx, []models.Application{application})
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Application withdrawn successfully",
+		map[string]any{"application": newStudentApplicationView(application, postings[application.PostingID])},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// Company endpoints
+
+func (cfg *AppConfig) GetPipelineStages(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	posting_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	posting, err := cfg.findOwnedJobPosting(ctx, user_id, posting_id)
+	if err != nil {
+		return err
+	}
+
+	// Applicants per stage
+	applications_coll := cfg.DATABASE.Collection(models.APPLICATIONS_COLLECTION)
+	cursor, err := applications_coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"postingId": posting.ID, "studentStatus": bson.M{"$ne": models.APPLICATION_STATUS_WITHDRAWN}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$stage", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	var counts []struct {
+		Stage string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &counts); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	applicants := map[string]int64{}
+	for _, count := range counts {
+		applicants[count.Stage] = count.Count
+	}
+
+	response_payload := map[string]any{
+		"stages":     posting.PipelineStages(),
+		"applicants": applicants,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Pipeline stages provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// UpdatePipelineStages configures a posting's stages. The first one is where
+// new applications land, and at least one must reject so applicants can be
+// turned down. Stages that still hold applicants can't be removed.
+func (cfg *AppConfig) UpdatePipelineStages(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	posting_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := PipelineStagesRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing pipeline stages request body", http.StatusBadRequest, err)
+	}
+	for i := range req_body.Stages {
+		stage := &req_body.Stages[i]
+		stage.Key = strings.ToLower(strings.TrimSpace(stage.Key))
+		stage.Name = sanitizeInput(stage.Name)
+		stage.StudentStatus = strings.ToLower(strings.TrimSpace(stage.StudentStatus))
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+	if err := validatePipelineStages(req_body.Stages); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	posting, err := cfg.findOwnedJobPosting(ctx, user_id, posting_id)
+	if err != nil {
+		return err
+	}
+
+	removed := []string{}
+	for _, stage := range posting.PipelineStages() {
+		if !slices.ContainsFunc(req_body.Stages, func(s models.PipelineStage) bool { return s.Key == stage.Key }) {
+			removed = append(removed, stage.Key)
+		}
+	}
+	applications_coll := cfg.DATABASE.Collection(models.APPLICATIONS_COLLECTION)
+	if len(removed) != 0 {
+		err := applications_coll.FindOne(ctx, bson.M{"postingId": posting.ID, "stage": bson.M{"$in": removed}}).Err()
+		if err == nil {
+			return utils.NewConflict("Move the applicants out of a stage before removing it")
+		} else if err != mongo.ErrNoDocuments {
+			return utils.NewInternalServerError(err)
+		}
+	}
+
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	_, err = job_postings_coll.UpdateOne(ctx,
+		bson.M{"_id": posting.ID},
+		bson.M{"$set": bson.M{"stages": req_body.Stages, "updatedAt": bson.NewDateTimeFromTime(time.Now())}},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Pipeline stages updated successfully",
+		map[string]any{"stages": req_body.Stages},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetPostingApplications lists a posting's applicants, optionally in one stage.
+func (cfg *AppConfig) GetPostingApplications(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	posting_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+	page, limit := parsePagination(r, DEFAULT_APPLICATIONS_LIMIT, MAX_APPLICATIONS_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	posting, err := cfg.findOwnedJobPosting(ctx, user_id, posting_id)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"postingId": posting.ID}
+	if stage := r.URL.Query().Get("stage"); stage != "" {
+		if _, ok := posting.FindStage(stage); !ok {
+			return utils.NewBadRequest("Invalid stage")
+		}
+		filter["stage"] = stage
+	}
+	if r.URL.Query().Get("includeWithdrawn") != "true" {
+		filter["studentStatus"] = bson.M{"$ne": models.APPLICATION_STATUS_WITHDRAWN}
+	}
+
+	applications, total, err := cfg.findApplications(ctx, filter, page, limit)
+	if err != nil {
+		return err
+	}
+	views, err := cfg.newCompanyApplicationViews(ctx, posting, applications)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"applications": views,
+		"pagination":   newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Applications provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetCompanyApplication(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	application_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	application, posting, err := cfg.findCompanyApplication(ctx, user_id, application_id)
+	if err != nil {
+		return err
+	}
+	views, err := cfg.newCompanyApplicationViews(ctx, posting, []models.Application{application})
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Application provided successfully",
+		map[string]any{"application": views[0]},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// MoveApplicationStage moves an applicant to another stage of the posting's
+// pipeline and notifies the student through the event bus.
+func (cfg *AppConfig) MoveApplicationStage(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	application_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := ApplicationStageRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing application stage request body", http.StatusBadRequest, err)
+	}
+	req_body.Stage = strings.ToLower(strings.TrimSpace(req_body.Stage))
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	application, posting, err := cfg.findCompanyApplication(ctx, user_id, application_id)
+	if err != nil {
+		return err
+	}
+	if application.IsWithdrawn() {
+		return utils.NewConflict("The applicant withdrew the application")
+	}
+	stage, ok := posting.FindStage(req_body.Stage)
+	if !ok {
+		return utils.NewBadRequest("Invalid stage")
+	}
+	if stage.Key == application.Stage {
+		return utils.NewConflict("The application is already in this stage")
+	}
+
+	now := time.Now()
+	event := models.NewDomainEvent(models.EVENT_APPLICATION_STAGE_CHANGED, application.ID, bson.M{
+		"postingId":            posting.ID,
+		"studentId":            application.StudentID,
+		"from":                 application.Stage,
+		"to":                   stage.Key,
+		"previousStatus":       application.StudentStatus,
+		"studentStatus":        stage.StudentStatus,
+		"studentStatusChanged": stage.StudentStatus != application.StudentStatus,
+	})
+	update := withEvents(bson.M{
+		"$set": bson.M{
+			"stage":         stage.Key,
+			"studentStatus": stage.StudentStatus,
+			"updatedAt":     bson.NewDateTimeFromTime(now),
+		},
+	}, event)
+	update["$push"].(bson.M)["history"] = models.StageChange{
+		From:          application.Stage,
+		To:            stage.Key,
+		StudentStatus: stage.StudentStatus,
+		ChangedBy:     user_id,
+		ChangedAt:     bson.NewDateTimeFromTime(now),
+	}
+
+	// Guarded on the current stage so two reviewers can't both move it
+	applications_coll := cfg.DATABASE.Collection(models.APPLICATIONS_COLLECTION)
+	err = applications_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": application.ID, "stage": application.Stage, "studentStatus": application.StudentStatus},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&application)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewConflict("The application changed, please reload it")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	cfg.publishPendingEvents(ctx, models.APPLICATIONS_COLLECTION, application.ID)
+
+	views, err := cfg.newCompanyApplicationViews(ctx, posting, []models.Application{application})
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Application stage updated successfully",
+		map[string]any{"application": views[0]},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) AddApplicationNote(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	application_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := ApplicationNoteRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing application note request body", http.StatusBadRequest, err)
+	}
+	req_body.Content = sanitizeInput(req_body.Content)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	application, _, err := cfg.findCompanyApplication(ctx, user_id, application_id)
+	if err != nil {
+		return err
+	}
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	note := models.ApplicationNote{
+		ID:        bson.NewObjectID(),
+		AuthorID:  user_id,
+		Content:   req_body.Content,
+		CreatedAt: now,
+	}
+	applications_coll := cfg.DATABASE.Collection(models.APPLICATIONS_COLLECTION)
+	_, err = applications_coll.UpdateOne(ctx,
+		bson.M{"_id": application.ID},
+		bson.M{"$push": bson.M{"notes": note}, "$set": bson.M{"updatedAt": now}},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Note added successfully",
+		map[string]any{"note": note},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) RateApplication(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	application_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := ApplicationRatingRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing application rating request body", http.StatusBadRequest, err)
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	application, _, err := cfg.findCompanyApplication(ctx, user_id, application_id)
+	if err != nil {
+		return err
+	}
+
+	applications_coll := cfg.DATABASE.Collection(models.APPLICATIONS_COLLECTION)
+	_, err = applications_coll.UpdateOne(ctx,
+		bson.M{"_id": application.ID},
+		bson.M{"$set": bson.M{"rating": req_body.Rating, "updatedAt": bson.NewDateTimeFromTime(time.Now())}},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Application rated successfully",
+		map[string]any{"rating": req_body.Rating},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// sendApplicationStatusEmail tells the student their application moved. Moves
+// between stages the student sees as the same status aren't announced, they
+// would only leak the company's internal stages.
+func (cfg *AppConfig) sendApplicationStatusEmail(ctx context.Context, event models.DomainEvent) error {
+	if changed, _ := event.Payload["studentStatusChanged"].(bool); !changed {
+		return nil
+	}
+	status, _ := event.Payload["studentStatus"].(string)
+	posting_id, _ := event.Payload["postingId"].(bson.ObjectID)
+	student_id, _ := event.Payload["studentId"].(bson.ObjectID)
+
+	var student models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err := users_coll.FindOne(ctx, bson.M{"_id": student_id}).Decode(&student)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var posting models.JobPosting
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	err = job_postings_coll.FindOne(ctx, bson.M{"_id": posting_id}).Decode(&posting)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	subject := "Update on your application to " + posting.CompanyName + " - " + cfg.REQUIREMENTS.SMTP.AppName
+	applications_url := cfg.REQUIREMENTS.Server.FrontendURL + "/student/applications"
+	body := utils.ApplicationStatusEmailBody(student.FullName, posting.Title, posting.CompanyName, applicationStatusLabels[status], applications_url)
+	return cfg.deliverEmail(student.Email, subject, body)
+}
+
+func validatePipelineStages(stages []models.PipelineStage) error {
+	if len(stages) > MAX_PIPELINE_STAGES {
+		return utils.NewBadRequest("A pipeline can't have more than 12 stages")
+	}
+
+	keys := map[string]bool{}
+	rejects := false
+	for _, stage := range stages {
+		if !reStageKey.MatchString(stage.Key) {
+			return utils.NewBadRequest("Stage keys may only contain lowercase letters, digits and underscores")
+		}
+		if keys[stage.Key] {
+			return utils.NewBadRequest("Stage keys must be unique")
+		}
+		keys[stage.Key] = true
+		if stage.StudentStatus == models.APPLICATION_STATUS_NOT_SELECTED {
+			rejects = true
+		}
+	}
+
+	if stages[0].StudentStatus != models.APPLICATION_STATUS_SUBMITTED {
+		return utils.NewBadRequest("The first stage must show students their application as submitted")
+	}
+	if !rejects {
+		return utils.NewBadRequest("At least one stage must turn applicants down")
+	}
+
+	return nil
+}
+
+func newStudentApplicationView(application models.Application, posting models.JobPosting) StudentApplicationView {
+	history := []StudentStatusChange{}
+	for _, change := range application.History {
+		if len(history) > 0 && history[len(history)-1].Status == change.StudentStatus {
+			continue
+		}
+		history = append(history, StudentStatusChange{
+			Status:    change.StudentStatus,
+			Label:     applicationStatusLabels[change.StudentStatus],
+			ChangedAt: change.ChangedAt,
+		})
+	}
+
+	return StudentApplicationView{
+		ID:          application.ID,
+		Posting:     newPostingSummary(posting),
+		CoverLetter: application.CoverLetter,
+		ResumeURL:   application.ResumeURL,
+		Status:      application.StudentStatus,
+		StatusLabel: applicationStatusLabels[application.StudentStatus],
+		History:     history,
+		WithdrawnAt: application.WithdrawnAt,
+		CreatedAt:   application.CreatedAt,
+		UpdatedAt:   application.UpdatedAt,
+	}
+}
+
+func (cfg *AppConfig) newCompanyApplicationViews(ctx context.Context, posting models.JobPosting, applications []models.Application) ([]CompanyApplicationView, error) {
+	student_ids := make([]bson.ObjectID, 0, len(applications))
+	for _, application := range applications {
+		student_ids = append(student_ids, application.StudentID)
+	}
+
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	cursor, err := users_coll.Find(ctx, bson.M{"_id": bson.M{"$in": student_ids}})
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	students := []models.User{}
+	if err := cursor.All(ctx, &students); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	by_id := make(map[bson.ObjectID]models.User, len(students))
+	for _, student := range students {
+		by_id[student.ID] = student
+	}
+
+	views := make([]CompanyApplicationView, 0, len(applications))
+	for _, application := range applications {
+		// Applying shares the student's contact details with the company
+		student := by_id[application.StudentID]
+		student = student.VisibleTo(models.ProfileViewer{ID: posting.CompanyID, Role: models.ROLE_COMPANY, ContactShared: true})
+		stage, _ := posting.FindStage(application.Stage)
+		views = append(views, CompanyApplicationView{
+			Application: application,
+			StageName:   stage.Name,
+			Applicant: ApplicantSummary{
+				ID:             application.StudentID,
+				FullName:       student.FullName,
+				Email:          student.Email,
+				Phone:          student.Phone,
+				City:           student.City,
+				University:     student.University,
+				Major:          student.Major,
+				GraduationYear: student.GraduationYear,
+				LinkedInURL:    student.LinkedInURL,
+				ProfileImage:   student.ProfileImage,
+			},
+		})
+	}
+	return views, nil
+}
+
+func (cfg *AppConfig) findApplication(ctx context.Context, filter bson.M) (models.Application, error) {
+	var application models.Application
+	applications_coll := cfg.DATABASE.Collection(models.APPLICATIONS_COLLECTION)
+	err := applications_coll.FindOne(ctx, filter).Decode(&application)
+	if err == mongo.ErrNoDocuments {
+		return application, utils.NewNotFound("Application not found")
+	} else if err != nil {
+		return application, utils.NewInternalServerError(err)
+	}
+	return application, nil
+}
+
+// findCompanyApplication loads an application to one of the company's
+// postings, together with the posting.
+func (cfg *AppConfig) findCompanyApplication(ctx context.Context, company_id, application_id bson.ObjectID) (models.Application, models.JobPosting, error) {
+	application, err := cfg.findApplication(ctx, bson.M{"_id": application_id, "companyId": company_id})
+	if err != nil {
+		return application, models.JobPosting{}, err
+	}
+	posting, err := cfg.findOwnedJobPosting(ctx, company_id, application.PostingID)
+	if err != nil {
+		return application, posting, err
+	}
+	return application, posting, nil
+}
+
+func (cfg *AppConfig) findApplications(ctx context.Context, filter bson.M, page, limit int64) ([]models.Application, int64, error) {
+	applications_coll := cfg.DATABASE.Collection(models.APPLICATIONS_COLLECTION)
+	total, err := applications_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updatedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := applications_coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+	applications := []models.Application{}
+	if err := cursor.All(ctx, &applications); err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+	return applications, total, nil
+}
+
+func (cfg *AppConfig) findPostingsByID(ctx context.Context, applications []models.Application) (map[bson.ObjectID]models.JobPosting, error) {
+	posting_ids := make([]bson.ObjectID, 0, len(applications))
+	for _, application := range applications {
+		posting_ids = append(posting_ids, application.PostingID)
+	}
+
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	cursor, err := job_postings_coll.Find(ctx, bson.M{"_id": bson.M{"$in": posting_ids}})
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	postings := []models.JobPosting{}
+	if err := cursor.All(ctx, &postings); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	by_id := make(map[bson.ObjectID]models.JobPosting, len(postings))
+	for _, posting := range postings {
+		by_id[posting.ID] = posting
+	}
+	return by_id, nil
+}
@@ -2,9 +2,8 @@ package api
 
 import (
 	"context"
-	"log"
 	"net/http"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
@@ -157,7 +156,9 @@ func (cfg *AppConfig) SignupHandler(w http.ResponseWriter, r *http.Request) erro
 	verification_expires := time.Now().Add(24 * time.Hour)
 
 	now := time.Now()
+	user_id := bson.NewObjectID()
 	user_doc := models.User{
+		ID:                       user_id,
 		FullName:                 req_body.FullName,
 		Email:                    req_body.Email,
 		Password:                 string(hashed_password),
@@ -181,27 +182,22 @@ func (cfg *AppConfig) SignupHandler(w http.ResponseWriter, r *http.Request) erro
 		IsActive:                 true,
 		CreatedAt:                bson.NewDateTimeFromTime(now),
 		UpdatedAt:                bson.NewDateTimeFromTime(now),
-	}
 
-	result, err := users_coll.InsertOne(ctx, user_doc)
-	if err != nil {
-		return utils.NewInternalServerError(err)
+		// The verification email is sent by the event's subscriber
+		PendingEvents: []models.DomainEvent{
+			models.NewDomainEvent(models.EVENT_USER_REGISTERED, user_id, bson.M{"role": req_body.Role}),
+		},
 	}
 
-	smtp_port, err := strconv.Atoi(cfg.REQUIREMENTS.SMTP.SMTPPort)
+	_, err = users_coll.InsertOne(ctx, user_doc)
 	if err != nil {
 		return utils.NewInternalServerError(err)
 	}
-	error_chan := make(chan error)
-	go utils.SendVerificationEmail(error_chan, cfg.REQUIREMENTS.SMTP.AppName, cfg.REQUIREMENTS.SMTP.EmailFrom, req_body.Email, cfg.REQUIREMENTS.Server.FrontendURL, verification_token, req_body.FullName, cfg.REQUIREMENTS.SMTP.SMTPHost, cfg.REQUIREMENTS.SMTP.SMTPUser, cfg.REQUIREMENTS.SMTP.SMTPPass, smtp_port)
-	go func() {
-		if err := <-error_chan; err != nil {
-			// log it and move on
-			log.Printf("Failed to send verification email: %s", err.Error())
-		}
-	}()
+	cfg.publishPendingEvents(ctx, models.USERS_COLLECTION, user_id)
+	if err := cfg.refreshSearchText(ctx, user_id); err != nil {
+		return err
+	}
 
-	user_id, _ := result.InsertedID.(bson.ObjectID)
 	response_payload := map[string]any{
 		"user": map[string]any{
 			"_id":             user_id.Hex(),
@@ -293,7 +289,7 @@ func (cfg *AppConfig) LoginHandler(w http.ResponseWriter, r *http.Request) error
 	}
 
 	response_payload := map[string]any{
-		"user":        user.GetPublicProfile(),
+		"user":        user.GetPublicProfile(user.AsViewer()),
 		"accessToken": access_token,
 	}
 
@@ -343,6 +339,7 @@ func (cfg *AppConfig) VerifyEmailHandler(w http.ResponseWriter, r *http.Request)
 			"emailVerificationExpires": nil,
 		},
 	}
+	updated_user = withEvents(updated_user, models.NewDomainEvent(models.EVENT_EMAIL_VERIFIED, user.ID, nil))
 
 	access_token, refresh_token, refresh_token_hashed, refresh_token_exp, is_new_refresh_token, err := cfg.refreshTokens(user)
 	if err != nil {
@@ -366,9 +363,10 @@ func (cfg *AppConfig) VerifyEmailHandler(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		return utils.NewInternalServerError(err)
 	}
+	cfg.publishPendingEvents(ctx, models.USERS_COLLECTION, user.ID)
 
 	response_payload := map[string]any{
-		"user":        user.GetPublicProfile(),
+		"user":        user.GetPublicProfile(user.AsViewer()),
 		"accessToken": access_token,
 	}
 
@@ -395,7 +393,7 @@ func (cfg *AppConfig) GetUserProfile(w http.ResponseWriter, r *http.Request) err
 	}
 
 	response_payload := map[string]any{
-		"user": user.GetPublicProfile(),
+		"user": user.GetPublicProfile(user.AsViewer()),
 	}
 
 	utils.SuccessResponseWriter(
@@ -420,6 +418,7 @@ type UpdateUserProfileRequestBody struct {
 	GraduationYear string   `json:"graduationYear" validate:"omitempty"`
 	Interests      []string `json:"interests"`
 	Bio            string   `json:"bio" validate:"omitempty,min=30,max=500"`
+	ResumeText     string   `json:"resumeText" validate:"omitempty,min=30,max=20000"`
 
 	// company specific field
 	CompanyName     string `json:"companyName"`
@@ -531,6 +530,12 @@ func (cfg *AppConfig) UpdateUserProfile(w http.ResponseWriter, r *http.Request)
 			updated_user["bio"] = req_body.Bio
 			user.Bio = req_body.Bio
 		}
+
+		if req_body.ResumeText != "" {
+			updated_user["resumeText"] = req_body.ResumeText
+			user.ResumeText = req_body.ResumeText
+		}
+
 	}
 
 	// If role is company, validate other company-related fields
@@ -589,18 +594,31 @@ func (cfg *AppConfig) UpdateUserProfile(w http.ResponseWriter, r *http.Request)
 	updated_user["updatedAt"] = bson.NewDateTimeFromTime(time.Now())
 	user.UpdatedAt = bson.NewDateTimeFromTime(time.Now())
 
+	changed_fields := make([]string, 0, len(updated_user))
+	for field := range updated_user {
+		if field != "updatedAt" {
+			changed_fields = append(changed_fields, field)
+		}
+	}
+	slices.Sort(changed_fields)
+	event := models.NewDomainEvent(models.EVENT_PROFILE_UPDATED, user_id, bson.M{"fields": changed_fields})
+
 	// Perform the update
 	_, err = user_coll.UpdateOne(
 		ctx,
 		bson.M{"_id": user_id},
-		bson.M{"$set": updated_user},
+		withEvents(bson.M{"$set": updated_user}, event),
 	)
 	if err != nil {
 		return utils.NewInternalServerError(err)
 	}
+	cfg.publishPendingEvents(ctx, models.USERS_COLLECTION, user_id)
+	if err := cfg.refreshSearchText(ctx, user_id); err != nil {
+		return err
+	}
 
 	response_payload := map[string]any{
-		"user": user.GetPublicProfile(),
+		"user": user.GetPublicProfile(user.AsViewer()),
 	}
 
 	utils.SuccessResponseWriter(
@@ -675,11 +693,12 @@ func (cfg *AppConfig) ChangePassword(w http.ResponseWriter, r *http.Request) err
 	_, err = user_coll.UpdateOne(
 		ctx,
 		bson.M{"_id": user.ID},
-		bson.M{"$set": updated_user},
+		withEvents(bson.M{"$set": updated_user}, models.NewDomainEvent(models.EVENT_PASSWORD_CHANGED, user.ID, nil)),
 	)
 	if err != nil {
 		return utils.NewInternalServerError(err)
 	}
+	cfg.publishPendingEvents(ctx, models.USERS_COLLECTION, user.ID)
 
 	utils.SuccessResponseWriter(
 		w,
@@ -750,28 +769,16 @@ func (cfg *AppConfig) ResendVerification(w http.ResponseWriter, r *http.Request)
 	updated_user["emailVerificationExpires"] = bson.NewDateTimeFromTime(verification_expires)
 	updated_user["updatedAt"] = bson.NewDateTimeFromTime(time.Now())
 
-	// Perform the update
+	// Perform the update, the email is sent by the event's subscriber
 	_, err = user_coll.UpdateOne(
 		ctx,
 		bson.M{"_id": user.ID},
-		bson.M{"$set": updated_user},
+		withEvents(bson.M{"$set": updated_user}, models.NewDomainEvent(models.EVENT_VERIFICATION_REQUESTED, user.ID, nil)),
 	)
 	if err != nil {
 		return utils.NewInternalServerError(err)
 	}
-
-	smtp_port, err := strconv.Atoi(cfg.REQUIREMENTS.SMTP.SMTPPort)
-	if err != nil {
-		return utils.NewInternalServerError(err)
-	}
-	error_chan := make(chan error)
-	go utils.SendVerificationEmail(error_chan, cfg.REQUIREMENTS.SMTP.AppName, cfg.REQUIREMENTS.SMTP.EmailFrom, req_body.Email, cfg.REQUIREMENTS.Server.FrontendURL, verification_token, user.FullName, cfg.REQUIREMENTS.SMTP.SMTPHost, cfg.REQUIREMENTS.SMTP.SMTPUser, cfg.REQUIREMENTS.SMTP.SMTPPass, smtp_port)
-	go func() {
-		if err := <-error_chan; err != nil {
-			// log it and move on
-			log.Printf("Failed to send verification email: %s", err.Error())
-		}
-	}()
+	cfg.publishPendingEvents(ctx, models.USERS_COLLECTION, user.ID)
 
 	utils.SuccessResponseWriter(
 		w,
@@ -57,6 +57,7 @@ func sanitizeUpdateUserProfileRequest(body *UpdateUserProfileRequestBody) {
 		body.Interests[i] = sanitizeInput(interest)
 	}
 	body.Bio = sanitizeInput(body.Bio)
+	body.ResumeText = sanitizeInput(body.ResumeText)
 }
 
 var (
@@ -0,0 +1,433 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_version/internal/certificate"
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Retries on the (unlikely) collision of a random certificate code
+const CERTIFICATE_CODE_RETRIES = 3
+
+type IssueCertificateRequestBody struct {
+	AttemptID string `json:"attemptId" validate:"required,mongodb"`
+}
+
+type RevokeCertificateRequestBody struct {
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+type CertificateView struct {
+	models.Certificate
+	Title       string `json:"title"`
+	VerifyURL   string `json:"verifyUrl"`
+	PDFURL      string `json:"pdfUrl"`
+	LinkedInURL string `json:"linkedInUrl,omitempty"`
+}
+
+func (cfg *AppConfig) GetMyCertificates(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	certificates_coll := cfg.DATABASE.Collection(models.CERTIFICATES_COLLECTION)
+	cursor, err := certificates_coll.Find(ctx,
+		bson.M{"userId": user_id},
+		options.Find().SetSort(bson.D{{Key: "issuedAt", Value: -1}}),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	certificates := []models.Certificate{}
+	if err := cursor.All(ctx, &certificates); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	views := make([]CertificateView, 0, len(certificates))
+	for _, c := range certificates {
+		views = append(views, cfg.newCertificateView(c))
+	}
+
+	response_payload := map[string]any{
+		"certificates": views,
+		"passingScore": cfg.REQUIREMENTS.Certificate.PassingScore,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Certificates provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// IssueCertificate issues the certificate of a passing exam graded before
+// certificates existed, new exams get theirs when they are graded.
+func (cfg *AppConfig) IssueCertificate(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	req_body := IssueCertificateRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing certificate request body", http.StatusBadRequest, err)
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+	attempt_id, _ := bson.ObjectIDFromHex(req_body.AttemptID)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var attempt models.TestAttempt
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	err = attempts_coll.FindOne(ctx, bson.M{"_id": attempt_id, "userId": user_id}).Decode(&attempt)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Exam attempt not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	if attempt.IsPracticeMode || attempt.ExamSessionID.IsZero() {
+		return utils.NewBadRequest("Certificates are only issued for timed exams")
+	}
+	passing_score := cfg.REQUIREMENTS.Certificate.PassingScore
+	if attempt.Score < passing_score {
+		return utils.NewBadRequest("A score of at least " + strconv.Itoa(int(passing_score)) + "% is required for a certificate")
+	}
+
+	issued, created, err := cfg.issueCertificate(ctx, attempt)
+	if err != nil {
+		return err
+	}
+
+	status_code := http.StatusOK
+	if created {
+		status_code = http.StatusCreated
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Certificate issued successfully",
+		map[string]any{"certificate": cfg.newCertificateView(issued)},
+		status_code,
+	)
+
+	return nil
+}
+
+// VerifyCertificate is public: anyone holding a certificate ID can check that
+// it exists, wasn't revoked and its data matches the signature.
+func (cfg *AppConfig) VerifyCertificate(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	issued, err := cfg.findCertificate(ctx, chi.URLParam(r, "code"))
+	if err != nil {
+		return err
+	}
+
+	signature_valid := cfg.CERTIFICATE_KEYS.Verify(issued.KeyID, certificatePayload(issued), issued.Signature)
+
+	reason := ""
+	if !signature_valid {
+		reason = "The certificate data doesn't match its signature"
+	} else if issued.Status == models.CERTIFICATE_STATUS_REVOKED {
+		reason = "The certificate was revoked"
+	}
+
+	response_payload := map[string]any{
+		"valid":          reason == "",
+		"signatureValid": signature_valid,
+		"reason":         reason,
+		"certificate":    cfg.newCertificateView(issued),
+		"payload":        certificatePayload(issued),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Certificate verified successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetCertificatePublicKey publishes the key certificates are signed with, to
+// verify them offline, and every key of the ring by ID for older certificates.
+func (cfg *AppConfig) GetCertificatePublicKey(w http.ResponseWriter, r *http.Request) error {
+	keys := map[string]string{}
+	for key_id, public_key := range cfg.CERTIFICATE_KEYS {
+		keys[key_id] = base64.StdEncoding.EncodeToString(public_key)
+	}
+
+	response_payload := map[string]any{
+		"algorithm": certificate.ALGORITHM,
+		"keyId":     cfg.CERTIFICATES.KeyID(),
+		"publicKey": base64.StdEncoding.EncodeToString(cfg.CERTIFICATES.PublicKey()),
+		"keys":      keys,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Certificate public key provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetCertificatePDF renders the certificate, it is public so it can be linked
+// from LinkedIn.
+func (cfg *AppConfig) GetCertificatePDF(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	issued, err := cfg.findCertificate(ctx, chi.URLParam(r, "code"))
+	if err != nil {
+		return err
+	}
+	if issued.Status == models.CERTIFICATE_STATUS_REVOKED {
+		return utils.NewAppError("Certificate was revoked", http.StatusGone, nil)
+	}
+
+	pdf, err := certificate.RenderPDF(certificate.Document{
+		AppName:   cfg.REQUIREMENTS.SMTP.AppName,
+		FullName:  issued.FullName,
+		Title:     certificateTitle(issued.Category),
+		Score:     issued.Score,
+		IssuedOn:  issued.IssuedAt.Time().UTC().Format("January 2, 2006"),
+		Code:      issued.Code,
+		VerifyURL: cfg.certificateVerifyURL(issued.Code),
+		KeyID:     issued.KeyID,
+		Signature: issued.Signature,
+	})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="certificate-`+issued.Code+`.pdf"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(pdf); err != nil {
+		log.Printf("Failed to write certificate PDF: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (cfg *AppConfig) RevokeCertificate(w http.ResponseWriter, r *http.Request) error {
+	admin_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	req_body := RevokeCertificateRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing revocation request body", http.StatusBadRequest, err)
+	}
+	req_body.Reason = sanitizeInput(req_body.Reason)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	issued, err := cfg.findCertificate(ctx, chi.URLParam(r, "code"))
+	if err != nil {
+		return err
+	}
+	if issued.Status == models.CERTIFICATE_STATUS_REVOKED {
+		return utils.NewConflict("Certificate is already revoked")
+	}
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	certificates_coll := cfg.DATABASE.Collection(models.CERTIFICATES_COLLECTION)
+	err = certificates_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": issued.ID, "status": models.CERTIFICATE_STATUS_VALID},
+		bson.M{"$set": bson.M{
+			"status":           models.CERTIFICATE_STATUS_REVOKED,
+			"revokedAt":        now,
+			"revokedBy":        admin_id,
+			"revocationReason": req_body.Reason,
+			"updatedAt":        now,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&issued)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewConflict("Certificate is already revoked")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Certificate revoked successfully",
+		map[string]any{"certificate": cfg.newCertificateView(issued)},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// issueCertificateIfPassed issues the certificate of a freshly graded exam,
+// at most once per attempt.
+func (cfg *AppConfig) issueCertificateIfPassed(ctx context.Context, event models.DomainEvent) error {
+	attempt, _, found, err := cfg.findGradedExam(ctx, event)
+	if err != nil || !found {
+		return err
+	}
+	if attempt.IsPracticeMode || attempt.Score < cfg.REQUIREMENTS.Certificate.PassingScore {
+		return nil
+	}
+	_, _, err = cfg.issueCertificate(ctx, attempt)
+	return err
+}
+
+// issueCertificate signs and stores the certificate of an attempt, at most
+// one per attempt. It returns the existing one when it was already issued.
+func (cfg *AppConfig) issueCertificate(ctx context.Context, attempt models.TestAttempt) (models.Certificate, bool, error) {
+	var issued models.Certificate
+	certificates_coll := cfg.DATABASE.Collection(models.CERTIFICATES_COLLECTION)
+	err := certificates_coll.FindOne(ctx, bson.M{"attemptId": attempt.ID}).Decode(&issued)
+	if err == nil {
+		return issued, false, nil
+	} else if err != mongo.ErrNoDocuments {
+		return issued, false, utils.NewInternalServerError(err)
+	}
+
+	var user models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err = users_coll.FindOne(ctx, bson.M{"_id": attempt.UserID}).Decode(&user)
+	if err != nil {
+		return issued, false, utils.NewInternalServerError(err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	for range CERTIFICATE_CODE_RETRIES {
+		code, err := certificate.NewCode()
+		if err != nil {
+			return issued, false, utils.NewInternalServerError(err)
+		}
+
+		issued = models.Certificate{
+			ID:        bson.NewObjectID(),
+			Code:      code,
+			UserID:    attempt.UserID,
+			AttemptID: attempt.ID,
+			FullName:  user.FullName,
+			Category:  attempt.Category,
+			Score:     attempt.Score,
+			IssuedAt:  bson.NewDateTimeFromTime(now),
+			KeyID:     cfg.CERTIFICATES.KeyID(),
+			Status:    models.CERTIFICATE_STATUS_VALID,
+			CreatedAt: bson.NewDateTimeFromTime(now),
+			UpdatedAt: bson.NewDateTimeFromTime(now),
+		}
+		issued.Signature = cfg.CERTIFICATES.Sign(certificatePayload(issued))
+
+		_, err = certificates_coll.InsertOne(ctx, issued)
+		if err == nil {
+			return issued, true, nil
+		} else if !mongo.IsDuplicateKeyError(err) {
+			return issued, false, utils.NewInternalServerError(err)
+		}
+
+		// Issued concurrently, or the code was taken
+		err = certificates_coll.FindOne(ctx, bson.M{"attemptId": attempt.ID}).Decode(&issued)
+		if err == nil {
+			return issued, false, nil
+		} else if err != mongo.ErrNoDocuments {
+			return issued, false, utils.NewInternalServerError(err)
+		}
+	}
+
+	return issued, false, utils.NewConflict("Could not issue the certificate, please try again")
+}
+
+func (cfg *AppConfig) findCertificate(ctx context.Context, code string) (models.Certificate, error) {
+	var issued models.Certificate
+	certificates_coll := cfg.DATABASE.Collection(models.CERTIFICATES_COLLECTION)
+	err := certificates_coll.FindOne(ctx, bson.M{"code": strings.ToUpper(strings.TrimSpace(code))}).Decode(&issued)
+	if err == mongo.ErrNoDocuments {
+		return issued, utils.NewNotFound("Certificate not found")
+	} else if err != nil {
+		return issued, utils.NewInternalServerError(err)
+	}
+	return issued, nil
+}
+
+func certificatePayload(c models.Certificate) certificate.Payload {
+	return certificate.NewPayload(c.Code, c.UserID.Hex(), c.FullName, c.Category, c.Score, c.AttemptID.Hex(), c.IssuedAt.Time())
+}
+
+func (cfg *AppConfig) newCertificateView(c models.Certificate) CertificateView {
+	view := CertificateView{
+		Certificate: c,
+		Title:       certificateTitle(c.Category),
+		VerifyURL:   cfg.certificateVerifyURL(c.Code),
+		PDFURL:      cfg.REQUIREMENTS.Server.BackendURL + "/api/certificates/" + c.Code + "/pdf",
+	}
+	if c.Status == models.CERTIFICATE_STATUS_VALID {
+		view.LinkedInURL = cfg.linkedInCertificationURL(c, view)
+	}
+	return view
+}
+
+func (cfg *AppConfig) certificateVerifyURL(code string) string {
+	return cfg.REQUIREMENTS.Server.FrontendURL + "/certificates/" + code
+}
+
+// linkedInCertificationURL pre-fills LinkedIn's "Add license or certification" form.
+func (cfg *AppConfig) linkedInCertificationURL(c models.Certificate, view CertificateView) string {
+	issued_at := c.IssuedAt.Time().UTC()
+	params := url.Values{}
+	params.Set("startTask", "CERTIFICATION_NAME")
+	params.Set("name", view.Title)
+	params.Set("organizationName", cfg.REQUIREMENTS.SMTP.AppName)
+	params.Set("issueYear", strconv.Itoa(issued_at.Year()))
+	params.Set("issueMonth", strconv.Itoa(int(issued_at.Month())))
+	params.Set("certUrl", view.VerifyURL)
+	params.Set("certId", c.Code)
+	return "https://www.linkedin.com/profile/add?" + params.Encode()
+}
+
+func certificateTitle(category string) string {
+	if category == "" {
+		return "Skill Assessment"
+	}
+	return strings.ToUpper(category[:1]) + category[1:] + " Development"
+}
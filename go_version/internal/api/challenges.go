@@ -0,0 +1,520 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_CHALLENGE_HISTORY_LIMIT = 10
+	MAX_CHALLENGE_HISTORY_LIMIT     = 50
+)
+
+type SubmitChallengeRequestBody struct {
+	UserAnswer string `json:"userAnswer" validate:"required"`
+}
+
+type ChallengeView struct {
+	models.DailyChallenge
+	Question      models.PublicQuestion `json:"question"`
+	CorrectAnswer string                `json:"correctAnswer,omitempty"`
+	Explanation   string                `json:"explanation,omitempty"`
+}
+
+type StreakView struct {
+	CurrentStreak            int32    `json:"currentStreak"`
+	LongestStreak            int32    `json:"longestStreak"`
+	TotalChallengesCompleted int32    `json:"totalChallengesCompleted"`
+	LastCompletedDay         string   `json:"lastCompletedDay,omitempty"`
+	CompletedToday           bool     `json:"completedToday"`
+	FreezeTokens             int32    `json:"freezeTokens"`
+	FrozenDays               []string `json:"frozenDays"`
+	Timezone                 string   `json:"timezone"`
+	Today                    string   `json:"today"`
+}
+
+// GetTodayChallenge returns the user's challenge for their local today,
+// creating it on first request. Every user gets the same question on the same
+// date, the pick is derived from the date alone.
+func (cfg *AppConfig) GetTodayChallenge(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	streak, location, err := cfg.loadUserStreak(ctx, user)
+	if err != nil {
+		return err
+	}
+	today := time.Now().In(location).Format(time.DateOnly)
+
+	challenge, err := cfg.findOrCreateDailyChallenge(ctx, user_id, today, location.String())
+	if err != nil {
+		return err
+	}
+
+	view, err := cfg.buildChallengeView(ctx, challenge)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"challenge": view,
+		"streak":    newStreakView(streak, today),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Daily challenge provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) SubmitChallengeAnswer(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	req_body := SubmitChallengeRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing challenge answer request body", http.StatusBadRequest, err)
+	}
+	req_body.UserAnswer = strings.TrimSpace(req_body.UserAnswer)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	streak, location, err := cfg.loadUserStreak(ctx, user)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	today := now.In(location).Format(time.DateOnly)
+
+	// A timezone moved back can make today a day the streak already counted
+	if gap, ok := models.DaysBetween(streak.LastCompletedDay, today); ok && gap <= 0 {
+		return utils.NewBadRequest("Your streak already counts a challenge for " + streak.LastCompletedDay + ", come back tomorrow")
+	}
+
+	var challenge models.DailyChallenge
+	challenges_coll := cfg.DATABASE.Collection(models.DAILY_CHALLENGES_COLLECTION)
+	err = challenges_coll.FindOne(ctx, bson.M{"userId": user_id, "day": today}).Decode(&challenge)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("No challenge found for today")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if challenge.Completed {
+		return utils.NewBadRequest("Challenge already completed")
+	}
+
+	content, err := cfg.challengeContent(ctx, challenge)
+	if err != nil {
+		return err
+	}
+	is_correct := req_body.UserAnswer == content.CorrectAnswer
+
+	// Claimed atomically so a double submit can't count twice towards the
+	// streak. Achievements and rating follow from the event, stored with the
+	// claim so they survive a crash right after it
+	err = challenges_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": challenge.ID, "completed": false},
+		withEvents(
+			bson.M{"$set": bson.M{
+				"completed":   true,
+				"userAnswer":  req_body.UserAnswer,
+				"isCorrect":   is_correct,
+				"completedAt": bson.NewDateTimeFromTime(now),
+				"updatedAt":   bson.NewDateTimeFromTime(now),
+			}},
+			models.NewDomainEvent(models.EVENT_CHALLENGE_COMPLETED, challenge.ID, nil),
+		),
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&challenge)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewBadRequest("Challenge already completed")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	frozen, err := cfg.recordStreakCompletion(ctx, &streak, today, now)
+	if err != nil {
+		return err
+	}
+
+	// Relayed once the streak counts the challenge, the badges read it
+	cfg.publishPendingEvents(ctx, models.DAILY_CHALLENGES_COLLECTION, challenge.ID)
+
+	view, err := cfg.buildChallengeView(ctx, challenge)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"challenge":     view,
+		"streak":        newStreakView(streak, today),
+		"isCorrect":     is_correct,
+		"correctAnswer": content.CorrectAnswer,
+		"frozenDays":    frozen,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Challenge answer submitted successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetUserStreak(w http.ResponseWriter, r *http.Request) error {
+	_, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	streak, location, err := cfg.loadUserStreak(ctx, user)
+	if err != nil {
+		return err
+	}
+	today := time.Now().In(location).Format(time.DateOnly)
+
+	utils.SuccessResponseWriter(
+		w,
+		"Streak provided successfully",
+		map[string]any{"streak": newStreakView(streak, today)},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetChallengeHistory(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	page, limit := parsePagination(r, DEFAULT_CHALLENGE_HISTORY_LIMIT, MAX_CHALLENGE_HISTORY_LIMIT)
+	filter := bson.M{"userId": user_id, "completed": true}
+
+	challenges_coll := cfg.DATABASE.Collection(models.DAILY_CHALLENGES_COLLECTION)
+	cursor, err := challenges_coll.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "date", Value: -1}}).
+		SetSkip((page-1)*limit).
+		SetLimit(limit),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	challenges := []models.DailyChallenge{}
+	if err := cursor.All(ctx, &challenges); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	total, err := challenges_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	views := make([]ChallengeView, 0, len(challenges))
+	for _, challenge := range challenges {
+		view, err := cfg.buildChallengeView(ctx, challenge)
+		if err != nil {
+			return err
+		}
+		views = append(views, view)
+	}
+
+	response_payload := map[string]any{
+		"challenges": views,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Challenge history provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// findCompletedChallenge loads the challenge a challenge_completed event is
+// about, a deleted one isn't an error.
+func (cfg *AppConfig) findCompletedChallenge(ctx context.Context, event models.DomainEvent) (models.DailyChallenge, bool, error) {
+	var challenge models.DailyChallenge
+	challenges_coll := cfg.DATABASE.Collection(models.DAILY_CHALLENGES_COLLECTION)
+	err := challenges_coll.FindOne(ctx, bson.M{"_id": event.AggregateID, "completed": true}).Decode(&challenge)
+	if err == mongo.ErrNoDocuments {
+		return challenge, false, nil
+	} else if err != nil {
+		return challenge, false, fmt.Errorf("failed to load challenge %s: %w", event.AggregateID.Hex(), err)
+	}
+	return challenge, true, nil
+}
+
+// loadUserStreak returns the user's streak and the timezone their days are
+// counted in, the one stored on their profile.
+func (cfg *AppConfig) loadUserStreak(ctx context.Context, user models.User) (models.UserStreak, *time.Location, error) {
+	streaks_coll := cfg.DATABASE.Collection(models.USER_STREAKS_COLLECTION)
+	now := bson.NewDateTimeFromTime(time.Now())
+
+	var streak models.UserStreak
+	err := streaks_coll.FindOneAndUpdate(ctx,
+		bson.M{"userId": user.ID},
+		bson.M{"$setOnInsert": bson.M{
+			"currentStreak":            0,
+			"longestStreak":            0,
+			"totalChallengesCompleted": 0,
+			"freezeTokens":             0,
+			"createdAt":                now,
+			"updatedAt":                now,
+		}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&streak)
+	if mongo.IsDuplicateKeyError(err) {
+		err = streaks_coll.FindOne(ctx, bson.M{"userId": user.ID}).Decode(&streak)
+	}
+	if err != nil {
+		return streak, nil, utils.NewInternalServerError(err)
+	}
+
+	location := userLocation(user)
+	streak.Timezone = location.String()
+
+	// Streaks written by the Node layer only have the midnight date
+	if streak.LastCompletedDay == "" && streak.LastCompletedDate != 0 {
+		streak.LastCompletedDay = streak.LastCompletedDate.Time().In(location).Format(time.DateOnly)
+	}
+
+	return streak, location, nil
+}
+
+// recordStreakCompletion applies a completion to the streak. The update is
+// guarded on the completion count, on a concurrent change the streak is
+// reloaded and the completion applied again.
+func (cfg *AppConfig) recordStreakCompletion(ctx context.Context, streak *models.UserStreak, today string, now time.Time) ([]string, error) {
+	streaks_coll := cfg.DATABASE.Collection(models.USER_STREAKS_COLLECTION)
+
+	for range 3 {
+		previous_total := streak.TotalChallengesCompleted
+		frozen, ok := streak.RecordCompletion(today)
+		if !ok {
+			return nil, utils.NewBadRequest("Your streak already counts a challenge for " + streak.LastCompletedDay + ", come back tomorrow")
+		}
+
+		result, err := streaks_coll.UpdateOne(ctx,
+			bson.M{"_id": streak.ID, "totalChallengesCompleted": previous_total},
+			bson.M{"$set": bson.M{
+				"currentStreak":            streak.CurrentStreak,
+				"longestStreak":            streak.LongestStreak,
+				"totalChallengesCompleted": streak.TotalChallengesCompleted,
+				"lastCompletedDay":         streak.LastCompletedDay,
+				"lastCompletedDate":        dayToDate(today),
+				"freezeTokens":             streak.FreezeTokens,
+				"frozenDays":               streak.FrozenDays,
+				"timezone":                 streak.Timezone,
+				"updatedAt":                bson.NewDateTimeFromTime(now),
+			}},
+		)
+		if err != nil {
+			return nil, utils.NewInternalServerError(err)
+		}
+		if result.MatchedCount == 1 {
+			return frozen, nil
+		}
+
+		timezone := streak.Timezone
+		if err := streaks_coll.FindOne(ctx, bson.M{"_id": streak.ID}).Decode(streak); err != nil {
+			return nil, utils.NewInternalServerError(err)
+		}
+		streak.Timezone = timezone
+	}
+
+	return nil, utils.NewConflict("Your streak was updated concurrently, please try again")
+}
+
+func (cfg *AppConfig) findOrCreateDailyChallenge(ctx context.Context, user_id bson.ObjectID, day, timezone string) (models.DailyChallenge, error) {
+	var challenge models.DailyChallenge
+	challenges_coll := cfg.DATABASE.Collection(models.DAILY_CHALLENGES_COLLECTION)
+	err := challenges_coll.FindOne(ctx, bson.M{"userId": user_id, "day": day}).Decode(&challenge)
+	if err == nil {
+		return challenge, nil
+	} else if err != mongo.ErrNoDocuments {
+		return challenge, utils.NewInternalServerError(err)
+	}
+
+	question, err := cfg.pickDailyQuestion(ctx, day)
+	if err != nil {
+		return challenge, err
+	}
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	challenge = models.DailyChallenge{
+		ID:         bson.NewObjectID(),
+		UserID:     user_id,
+		Date:       dayToDate(day),
+		Day:        day,
+		Timezone:   timezone,
+		QuestionID: question.ID,
+		Revision:   question.Revision,
+		RevisionID: question.RevisionID,
+		Category:   question.Category,
+		Difficulty: question.Difficulty,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	_, err = challenges_coll.InsertOne(ctx, challenge)
+	if mongo.IsDuplicateKeyError(err) {
+		// Created concurrently, or by the Node layer which only sets the date
+		err = challenges_coll.FindOne(ctx, bson.M{"userId": user_id, "date": challenge.Date}).Decode(&challenge)
+	}
+	if err != nil {
+		return challenge, utils.NewInternalServerError(err)
+	}
+	return challenge, nil
+}
+
+// pickDailyQuestion deterministically picks the question of a date: the date
+// seeds the choice of category, difficulty and question among the active
+// ones, ordered by id so the pick is stable for the whole day.
+func (cfg *AppConfig) pickDailyQuestion(ctx context.Context, day string) (models.Question, error) {
+	hash := fnv.New64a()
+	hash.Write([]byte("daily-challenge:" + day))
+	rng := rand.New(rand.NewPCG(hash.Sum64(), 0))
+
+	categories := models.GetValidCategories()
+	difficulties := models.GetValidDifficulties()
+	category := categories[rng.IntN(len(categories))]
+	difficulty := difficulties[rng.IntN(len(difficulties))]
+
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	find_opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetProjection(bson.M{"_id": 1})
+
+	// Fall back to any active question when the drawn combination is empty
+	filters := []bson.M{
+		{"isActive": true, "category": category, "difficulty": difficulty},
+		{"isActive": true},
+	}
+	for _, filter := range filters {
+		cursor, err := questions_coll.Find(ctx, filter, find_opts)
+		if err != nil {
+			return models.Question{}, utils.NewInternalServerError(err)
+		}
+		candidates := []models.Question{}
+		if err := cursor.All(ctx, &candidates); err != nil {
+			return models.Question{}, utils.NewInternalServerError(err)
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		question, err := cfg.findQuestion(ctx, candidates[rng.IntN(len(candidates))].ID)
+		if err != nil {
+			return question, err
+		}
+		if err := cfg.ensureQuestionRevision(ctx, &question); err != nil {
+			return question, err
+		}
+		return question, nil
+	}
+
+	return models.Question{}, utils.NewNotFound("No questions available for daily challenge")
+}
+
+// buildChallengeView shows the question as it was when the challenge was
+// created, the answer only once the challenge is completed.
+func (cfg *AppConfig) buildChallengeView(ctx context.Context, challenge models.DailyChallenge) (ChallengeView, error) {
+	view := ChallengeView{DailyChallenge: challenge}
+
+	content, err := cfg.challengeContent(ctx, challenge)
+	if err != nil {
+		return view, err
+	}
+
+	question := models.Question{ID: challenge.QuestionID}
+	question.ApplyContent(content)
+	view.Question = question.GetPublicQuestion()
+	if challenge.Completed {
+		view.CorrectAnswer = content.CorrectAnswer
+		view.Explanation = content.Explanation
+	}
+	return view, nil
+}
+
+// challengeContent returns the question revision the challenge was created with.
+func (cfg *AppConfig) challengeContent(ctx context.Context, challenge models.DailyChallenge) (models.QuestionContent, error) {
+	if challenge.RevisionID.IsZero() {
+		// Created by the Node layer, no pinned revision
+		question, err := cfg.findQuestion(ctx, challenge.QuestionID)
+		if err != nil {
+			return models.QuestionContent{}, err
+		}
+		return question.Content(), nil
+	}
+
+	revisions, err := cfg.findRevisionsByID(ctx, []bson.ObjectID{challenge.RevisionID})
+	if err != nil {
+		return models.QuestionContent{}, err
+	}
+	return revisions[challenge.RevisionID].Content, nil
+}
+
+func newStreakView(streak models.UserStreak, today string) StreakView {
+	frozen_days := streak.FrozenDays
+	if frozen_days == nil {
+		frozen_days = []string{}
+	}
+	return StreakView{
+		CurrentStreak:            streak.EffectiveStreak(today),
+		LongestStreak:            streak.LongestStreak,
+		TotalChallengesCompleted: streak.TotalChallengesCompleted,
+		LastCompletedDay:         streak.LastCompletedDay,
+		CompletedToday:           streak.LastCompletedDay == today,
+		FreezeTokens:             streak.FreezeTokens,
+		FrozenDays:               frozen_days,
+		Timezone:                 streak.Timezone,
+		Today:                    today,
+	}
+}
+
+// dayToDate converts a YYYY-MM-DD day to midnight UTC for the date fields.
+func dayToDate(day string) bson.DateTime {
+	date, _ := time.Parse(time.DateOnly, day)
+	return bson.NewDateTimeFromTime(date)
+}
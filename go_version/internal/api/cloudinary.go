@@ -60,3 +60,22 @@ func (cfg *AppConfig) DeleteImageFromCloudinary(ctx context.Context, publicID st
 
 	return nil
 }
+
+// UploadFileToCloudinary stores a file as is, under a unique name, for files
+// other than profile images.
+func (cfg *AppConfig) UploadFileToCloudinary(ctx context.Context, file interface{}, folder string) (*uploader.UploadResult, error) {
+	resp, err := cfg.CLOUDINARY.Upload.Upload(ctx, file, uploader.UploadParams{
+		UniqueFilename: api.Bool(true),
+		Overwrite:      api.Bool(false),
+		Folder:         "talentspal/" + folder,
+		ResourceType:   "auto",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload to Cloudinary: %w", err)
+	}
+	if resp.Error.Message != "" {
+		return nil, fmt.Errorf("failed to upload to Cloudinary: %s", resp.Error.Message)
+	}
+
+	return resp, nil
+}
@@ -1,14 +1,22 @@
 package api
 
 import (
+	"go_version/internal/certificate"
+	"go_version/internal/events"
+	"go_version/internal/pubsub"
+
 	"github.com/cloudinary/cloudinary-go/v2"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 type AppConfig struct {
-	DATABASE     *mongo.Database
-	CLOUDINARY   *cloudinary.Cloudinary
-	REQUIREMENTS *AppRequirements
+	DATABASE         *mongo.Database
+	CLOUDINARY       *cloudinary.Cloudinary
+	REQUIREMENTS     *AppRequirements
+	EVENT_BUS        *events.Bus
+	CERTIFICATES     *certificate.Signer
+	CERTIFICATE_KEYS certificate.KeyRing
+	PUBSUB           pubsub.PubSub
 }
 
 func (cfg *AppConfig) LoadConfig() error {
@@ -17,6 +25,29 @@ func (cfg *AppConfig) LoadConfig() error {
 		return err
 	}
 
+	err = cfg.ensureIndexes()
+	if err != nil {
+		return err
+	}
+
+	cfg.CERTIFICATES, err = certificate.NewSigner(cfg.REQUIREMENTS.Certificate.SigningSeed)
+	if err != nil {
+		return err
+	}
+	cfg.CERTIFICATE_KEYS = certificate.NewKeyRing(append(cfg.REQUIREMENTS.Certificate.RetiredPublicKeys, cfg.CERTIFICATES.PublicKey())...)
+
+	// Notifications reach the streams of this instance only, instances behind
+	// a load balancer need a shared broker implementing pubsub.PubSub
+	cfg.PUBSUB = pubsub.NewMemory()
+
+	cfg.EVENT_BUS = events.NewBus()
+	cfg.registerEventSubscribers()
+
+	err = cfg.seedAchievementDefinitions()
+	if err != nil {
+		return err
+	}
+
 	err = cfg.initCloudinary(cfg.REQUIREMENTS.Cloudinary.CloudName, cfg.REQUIREMENTS.Cloudinary.APIKey, cfg.REQUIREMENTS.Cloudinary.APISecret)
 	if err != nil {
 		return err
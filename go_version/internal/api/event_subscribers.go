@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// registerEventSubscribers wires the side effects of domain events. Handlers
+// may run more than once for the same event and must tolerate it.
+func (cfg *AppConfig) registerEventSubscribers() {
+	cfg.EVENT_BUS.Subscribe(models.EVENT_USER_REGISTERED, "verification_email", cfg.sendVerificationEmailOnEvent)
+	cfg.EVENT_BUS.Subscribe(models.EVENT_VERIFICATION_REQUESTED, "verification_email", cfg.sendVerificationEmailOnEvent)
+	cfg.EVENT_BUS.Subscribe(models.EVENT_PASSWORD_CHANGED, "password_changed_email", cfg.sendPasswordChangedEmail)
+	cfg.EVENT_BUS.Subscribe(models.EVENT_PROFILE_UPDATED, "student_matches", cfg.markMatchesStaleOnProfileUpdate)
+	cfg.EVENT_BUS.Subscribe(models.EVENT_APPLICATION_STAGE_CHANGED, "application_status_email", cfg.sendApplicationStatusEmail)
+	cfg.EVENT_BUS.Subscribe(models.EVENT_APPLICATION_STAGE_CHANGED, "application_interviews", cfg.cancelInterviewOnApplicationClosed)
+	cfg.EVENT_BUS.Subscribe(models.EVENT_APPLICATION_STAGE_CHANGED, "application_notification", cfg.notifyApplicationUpdate)
+	cfg.EVENT_BUS.Subscribe(models.EVENT_EXAM_GRADED, "exam_achievements", cfg.publishExamCompleted)
+	cfg.EVENT_BUS.Subscribe(models.EVENT_EXAM_GRADED, "exam_rating", cfg.rateExamAttempt)
+	cfg.EVENT_BUS.Subscribe(models.EVENT_EXAM_GRADED, "exam_certificate", cfg.issueCertificateIfPassed)
+	cfg.EVENT_BUS.Subscribe(models.EVENT_CHALLENGE_COMPLETED, "challenge_achievements", cfg.publishChallengeCompleted)
+	cfg.EVENT_BUS.Subscribe(models.EVENT_CHALLENGE_COMPLETED, "challenge_rating", cfg.rateChallenge)
+}
+
+// sendVerificationEmailOnEvent mails the user's current verification link, so
+// a late retry never sends a token that was replaced in the meantime.
+func (cfg *AppConfig) sendVerificationEmailOnEvent(ctx context.Context, event models.DomainEvent) error {
+	user, found, err := cfg.findEventUser(ctx, event)
+	if err != nil || !found {
+		return err
+	}
+
+	expired := user.EmailVerificationExpires.Time().Before(time.Now())
+	if user.IsEmailVerified || user.EmailVerificationToken == "" || expired {
+		return nil
+	}
+
+	return cfg.deliverVerificationEmail(user.Email, user.FullName, user.EmailVerificationToken)
+}
+
+func (cfg *AppConfig) sendPasswordChangedEmail(ctx context.Context, event models.DomainEvent) error {
+	user, found, err := cfg.findEventUser(ctx, event)
+	if err != nil || !found {
+		return err
+	}
+
+	subject := "Your password was changed - " + cfg.REQUIREMENTS.SMTP.AppName
+	return cfg.deliverEmail(user.Email, subject, utils.PasswordChangedEmailBody(user.FullName, event.OccurredAt.Time()))
+}
+
+// findEventUser loads the user an event is about, a deleted user isn't an error.
+func (cfg *AppConfig) findEventUser(ctx context.Context, event models.DomainEvent) (models.User, bool, error) {
+	var user models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err := users_coll.FindOne(ctx, bson.M{"_id": event.AggregateID}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, false, nil
+	} else if err != nil {
+		return user, false, fmt.Errorf("failed to load user %s: %w", event.AggregateID.Hex(), err)
+	}
+	return user, true, nil
+}
@@ -0,0 +1,396 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"go_version/internal/assembly"
+	"go_version/internal/irt"
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_ADAPTIVE_TARGET_SE  = 0.3
+	DEFAULT_ADAPTIVE_MIN_LENGTH = 5
+	DEFAULT_ADAPTIVE_MAX_LENGTH = 30
+
+	ADAPTIVE_STOP_TARGET_SE  = "target_se"
+	ADAPTIVE_STOP_MAX_LENGTH = "max_length"
+	ADAPTIVE_STOP_EXHAUSTED  = "pool_exhausted"
+)
+
+type StartAdaptiveExamRequestBody struct {
+	Category  string  `json:"category" validate:"required,oneof=backend frontend"`
+	TargetSE  float64 `json:"targetSE" validate:"omitempty,min=0.1,max=1"`
+	MinLength int32   `json:"minLength" validate:"omitempty,min=1,max=50"`
+	MaxLength int32   `json:"maxLength" validate:"omitempty,min=1,max=50,gtefield=MinLength"`
+}
+
+// StartAdaptiveExam starts a computerized adaptive exam: questions are picked
+// one at a time to be the most informative at the student's current estimated
+// ability, until the estimate is precise enough or the length limit is hit.
+func (cfg *AppConfig) StartAdaptiveExam(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	req_body := StartAdaptiveExamRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing start adaptive exam request body", http.StatusBadRequest, err)
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+	if req_body.TargetSE == 0 {
+		req_body.TargetSE = DEFAULT_ADAPTIVE_TARGET_SE
+	}
+	if req_body.MinLength == 0 {
+		req_body.MinLength = DEFAULT_ADAPTIVE_MIN_LENGTH
+	}
+	if req_body.MaxLength == 0 {
+		req_body.MaxLength = max(DEFAULT_ADAPTIVE_MAX_LENGTH, req_body.MinLength)
+	}
+
+	session, found, err := cfg.findActiveExamSession(ctx, user_id)
+	if err != nil {
+		return err
+	}
+	if found {
+		view, err := cfg.buildExamSessionView(ctx, session)
+		if err != nil {
+			return err
+		}
+		utils.SuccessResponseWriter(
+			w,
+			"You already have an exam in progress, resuming it",
+			map[string]any{"session": view, "resumed": true},
+			http.StatusOK,
+		)
+		return nil
+	}
+
+	now := time.Now().Truncate(time.Millisecond)
+	duration := req_body.MaxLength * EXAM_SECONDS_PER_QUESTION
+	session = models.ExamSession{
+		ID:        bson.NewObjectID(),
+		UserID:    user_id,
+		Category:  req_body.Category,
+		Status:    models.EXAM_SESSION_IN_PROGRESS,
+		Mode:      models.EXAM_MODE_ADAPTIVE,
+		Questions: []models.ExamSessionQuestion{},
+		Adaptive: &models.AdaptiveState{
+			Model:     irt.MODEL_3PL,
+			TargetSE:  req_body.TargetSE,
+			MinLength: req_body.MinLength,
+			MaxLength: req_body.MaxLength,
+			Theta:     0,
+			SE:        1,
+			History:   []models.AbilityEstimate{},
+		},
+		ShuffleOptions:  true,
+		Seed:            assembly.NewSeed(),
+		DurationSeconds: duration,
+		StartedAt:       bson.NewDateTimeFromTime(now),
+		Deadline:        bson.NewDateTimeFromTime(now.Add(time.Duration(duration) * time.Second)),
+		CreatedAt:       bson.NewDateTimeFromTime(now),
+		UpdatedAt:       bson.NewDateTimeFromTime(now),
+	}
+
+	next, found, err := cfg.selectAdaptiveQuestion(ctx, session)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return utils.NewNotFound("No questions available for this category")
+	}
+	session.Questions = append(session.Questions, next)
+
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	_, err = exam_sessions_coll.InsertOne(ctx, session)
+	if mongo.IsDuplicateKeyError(err) {
+		return utils.NewConflict("You already have an exam in progress")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	view, err := cfg.buildExamSessionView(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Adaptive exam started successfully",
+		map[string]any{"session": view, "resumed": false},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+// AnswerAdaptiveQuestion answers the current question, re-estimates ability
+// and either hands out the next question or finishes the exam. Answers can't
+// be changed once given, each one shapes the questions that follow.
+func (cfg *AppConfig) AnswerAdaptiveQuestion(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	session_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := ExamAnswerRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing exam answer request body", http.StatusBadRequest, err)
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	question_id, err := bson.ObjectIDFromHex(req_body.QuestionID)
+	if err != nil {
+		return utils.NewBadRequest("Invalid questionId")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	session, err := cfg.findUserExamSession(ctx, session_id, user_id)
+	if err != nil {
+		return err
+	}
+	if !session.IsAdaptive() {
+		return utils.NewBadRequest("This is not an adaptive exam")
+	}
+	if session.Status != models.EXAM_SESSION_IN_PROGRESS {
+		return utils.NewConflict("This exam was already submitted")
+	}
+
+	now := time.Now()
+	if session.IsExpired(now) {
+		if _, err := cfg.finalizeExamSession(ctx, session.ID, true); err != nil {
+			return err
+		}
+		return utils.NewConflict("Time is up, this exam no longer accepts answers")
+	}
+
+	asked := len(session.Questions)
+	current := &session.Questions[asked-1]
+	if current.QuestionID != question_id || current.Answer != "" {
+		return utils.NewBadRequest("Only the current question can be answered")
+	}
+
+	revision_ids := make([]bson.ObjectID, 0, asked)
+	for _, q := range session.Questions {
+		revision_ids = append(revision_ids, q.RevisionID)
+	}
+	revisions, err := cfg.findRevisionsByID(ctx, revision_ids)
+	if err != nil {
+		return err
+	}
+	content := revisions[current.RevisionID].Content
+	if !slices.Contains(content.Options, req_body.Answer) {
+		return utils.NewBadRequest("Answer must be one of the question's options")
+	}
+
+	current.Answer = req_body.Answer
+	current.AnsweredAt = bson.NewDateTimeFromTime(now)
+
+	// Re-estimate from every answer so far
+	responses := make([]irt.Response, 0, asked)
+	for _, q := range session.Questions {
+		if q.Item == nil || q.Answer == "" {
+			continue
+		}
+		correct := q.Answer == revisions[q.RevisionID].Content.CorrectAnswer
+		responses = append(responses, irt.Response{Item: irt.Item{A: q.Item.A, B: q.Item.B, C: q.Item.C}, Correct: correct})
+	}
+	estimate := irt.EstimateAbility(responses)
+	state := session.Adaptive
+	state.Theta = estimate.Theta
+	state.SE = estimate.SE
+	state.History = append(state.History, models.AbilityEstimate{Theta: estimate.Theta, SE: estimate.SE})
+
+	switch {
+	case int32(asked) >= state.MinLength && estimate.SE <= state.TargetSE:
+		state.StopReason = ADAPTIVE_STOP_TARGET_SE
+	case int32(asked) >= state.MaxLength:
+		state.StopReason = ADAPTIVE_STOP_MAX_LENGTH
+	default:
+		next, found, err := cfg.selectAdaptiveQuestion(ctx, session)
+		if err != nil {
+			return err
+		}
+		if found {
+			session.Questions = append(session.Questions, next)
+		} else {
+			state.StopReason = ADAPTIVE_STOP_EXHAUSTED
+		}
+	}
+
+	// Conditioned on the question count so a double submit can't answer twice
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	result, err := exam_sessions_coll.UpdateOne(ctx,
+		bson.M{
+			"_id":       session.ID,
+			"status":    models.EXAM_SESSION_IN_PROGRESS,
+			"deadline":  bson.M{"$gt": bson.NewDateTimeFromTime(now)},
+			"questions": bson.M{"$size": asked},
+		},
+		bson.M{"$set": bson.M{
+			"questions": session.Questions,
+			"adaptive":  state,
+			"updatedAt": bson.NewDateTimeFromTime(now),
+		}},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewConflict("This question was already answered")
+	}
+
+	response_payload := map[string]any{
+		"answeredCount": asked,
+		"finished":      state.StopReason != "",
+	}
+
+	if state.StopReason != "" {
+		attempt, err := cfg.finalizeExamSession(ctx, session.ID, false)
+		if err != nil {
+			return err
+		}
+		response_payload["stopReason"] = state.StopReason
+		response_payload["attemptId"] = attempt.ID
+		response_payload["score"] = attempt.Score
+		response_payload["ability"] = attempt.Ability
+	} else {
+		view, err := cfg.buildExamSessionView(ctx, session)
+		if err != nil {
+			return err
+		}
+		response_payload["nextQuestion"] = view.Questions[len(view.Questions)-1]
+		response_payload["remainingSeconds"] = view.RemainingSeconds
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Answer saved successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// selectAdaptiveQuestion picks the not yet asked question with the highest
+// information at the session's current ability estimate. Questions that were
+// never calibrated take rough parameters from their difficulty label.
+func (cfg *AppConfig) selectAdaptiveQuestion(ctx context.Context, session models.ExamSession) (models.ExamSessionQuestion, bool, error) {
+	asked := make([]bson.ObjectID, 0, len(session.Questions))
+	for _, q := range session.Questions {
+		asked = append(asked, q.QuestionID)
+	}
+
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	cursor, err := questions_coll.Find(ctx,
+		bson.M{"category": session.Category, "isActive": true, "_id": bson.M{"$nin": asked}},
+		options.Find().SetProjection(bson.M{"_id": 1, "difficulty": 1}),
+	)
+	if err != nil {
+		return models.ExamSessionQuestion{}, false, utils.NewInternalServerError(err)
+	}
+	candidates := []models.Question{}
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return models.ExamSessionQuestion{}, false, utils.NewInternalServerError(err)
+	}
+	if len(candidates) == 0 {
+		return models.ExamSessionQuestion{}, false, nil
+	}
+
+	calibrated, err := cfg.loadItemParameters(ctx, session.Category)
+	if err != nil {
+		return models.ExamSessionQuestion{}, false, err
+	}
+
+	items := make([]irt.Item, len(candidates))
+	for i, candidate := range candidates {
+		if params, ok := calibrated[candidate.ID]; ok {
+			items[i] = irt.Item{A: params.A, B: params.B, C: params.C}
+		} else {
+			items[i] = irt.DefaultItem(candidate.Difficulty)
+		}
+	}
+
+	best := irt.MostInformative(session.Adaptive.Theta, items)
+	question, err := cfg.findQuestion(ctx, candidates[best].ID)
+	if err != nil {
+		return models.ExamSessionQuestion{}, false, err
+	}
+	if err := cfg.ensureQuestionRevision(ctx, &question); err != nil {
+		return models.ExamSessionQuestion{}, false, err
+	}
+
+	_, is_calibrated := calibrated[question.ID]
+	next := models.ExamSessionQuestion{
+		QuestionID: question.ID,
+		Revision:   question.Revision,
+		RevisionID: question.RevisionID,
+		Item: &models.ItemSnapshot{
+			A:          items[best].A,
+			B:          items[best].B,
+			C:          items[best].C,
+			Calibrated: is_calibrated,
+		},
+	}
+	if session.ShuffleOptions {
+		next.OptionOrder = assembly.OptionOrder(session.Seed, question.ID, len(question.Options))
+	}
+
+	return next, true, nil
+}
+
+func (cfg *AppConfig) loadItemParameters(ctx context.Context, category string) (map[bson.ObjectID]models.ItemParameters, error) {
+	filter := bson.M{}
+	if category != "" {
+		filter["category"] = category
+	}
+
+	item_parameters_coll := cfg.DATABASE.Collection(models.ITEM_PARAMETERS_COLLECTION)
+	cursor, err := item_parameters_coll.Find(ctx, filter)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	params := []models.ItemParameters{}
+	if err := cursor.All(ctx, &params); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	by_question := make(map[bson.ObjectID]models.ItemParameters, len(params))
+	for _, p := range params {
+		by_question[p.QuestionID] = p
+	}
+	return by_question, nil
+}
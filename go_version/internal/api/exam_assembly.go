@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go_version/internal/assembly"
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// assemblePaper builds a paper for the user from the bank as it was at as_of.
+// Pools are read from the revision history rather than the live questions, so
+// passing a session's seed and start time rebuilds exactly the paper it got.
+func (cfg *AppConfig) assemblePaper(ctx context.Context, user_id bson.ObjectID, sections []models.BlueprintSection, exclude_seen, shuffle_options bool, seed int64, as_of time.Time, exclude_session bson.ObjectID) ([]assembly.Pick, error) {
+	pools := make([]assembly.SectionPool, 0, len(sections))
+	for _, section := range sections {
+		candidates, err := cfg.loadSectionPoolAsOf(ctx, section, as_of)
+		if err != nil {
+			return nil, err
+		}
+		pools = append(pools, assembly.SectionPool{Section: section, Candidates: candidates})
+	}
+
+	seen := map[bson.ObjectID]bool{}
+	if exclude_seen && !user_id.IsZero() {
+		var err error
+		seen, err = cfg.loadSeenQuestionIDs(ctx, user_id, as_of, exclude_session)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	picks, err := assembly.Assemble(seed, pools, seen, shuffle_options)
+	if errors.Is(err, assembly.ErrNotEnoughQuestions) {
+		return nil, utils.NewAppError("The question bank can't fill this exam: "+err.Error(), http.StatusUnprocessableEntity, err)
+	} else if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	return picks, nil
+}
+
+// importLegacyQuestions gives questions matching the sections an import
+// revision, questions without one are invisible to loadSectionPoolAsOf.
+func (cfg *AppConfig) importLegacyQuestions(ctx context.Context, sections []models.BlueprintSection) error {
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	for _, section := range sections {
+		filter := bson.M{
+			"category": section.Category,
+			"isActive": true,
+			"revision": bson.M{"$exists": false},
+		}
+		if section.Difficulty != "" {
+			filter["difficulty"] = section.Difficulty
+		}
+		if len(section.Tags) != 0 {
+			filter["tags"] = bson.M{"$all": section.Tags}
+		}
+
+		cursor, err := questions_coll.Find(ctx, filter)
+		if err != nil {
+			return utils.NewInternalServerError(err)
+		}
+		questions := []models.Question{}
+		if err := cursor.All(ctx, &questions); err != nil {
+			return utils.NewInternalServerError(err)
+		}
+
+		for i := range questions {
+			if err := cfg.ensureQuestionRevision(ctx, &questions[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadSectionPoolAsOf returns, for every question matching the section at
+// as_of, the revision that was current at that time.
+func (cfg *AppConfig) loadSectionPoolAsOf(ctx context.Context, section models.BlueprintSection, as_of time.Time) ([]models.QuestionRevision, error) {
+	match := bson.M{
+		"content.category": section.Category,
+		"content.isActive": true,
+	}
+	if section.Difficulty != "" {
+		match["content.difficulty"] = section.Difficulty
+	}
+	if len(section.Tags) != 0 {
+		match["content.tags"] = bson.M{"$all": section.Tags}
+	}
+
+	revisions_coll := cfg.DATABASE.Collection(models.QUESTION_REVISIONS_COLLECTION)
+	cursor, err := revisions_coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"createdAt": bson.M{"$lte": bson.NewDateTimeFromTime(as_of)}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "question", Value: 1}, {Key: "revision", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$question", "latest": bson.M{"$first": "$$ROOT"}}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$latest"}}},
+		{{Key: "$match", Value: match}},
+	})
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	revisions := []models.QuestionRevision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	return revisions, nil
+}
+
+// loadSeenQuestionIDs collects the questions the user answered in attempts or
+// was shown in exam sessions before the given time.
+func (cfg *AppConfig) loadSeenQuestionIDs(ctx context.Context, user_id bson.ObjectID, before time.Time, exclude_session bson.ObjectID) (map[bson.ObjectID]bool, error) {
+	seen := map[bson.ObjectID]bool{}
+	before_dt := bson.NewDateTimeFromTime(before)
+
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	var attempt_ids []bson.ObjectID
+	err := attempts_coll.Distinct(ctx, "questions.questionId", bson.M{
+		"userId":      user_id,
+		"completedAt": bson.M{"$lt": before_dt},
+	}).Decode(&attempt_ids)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	var session_ids []bson.ObjectID
+	err = exam_sessions_coll.Distinct(ctx, "questions.questionId", bson.M{
+		"userId":    user_id,
+		"_id":       bson.M{"$ne": exclude_session},
+		"startedAt": bson.M{"$lt": before_dt},
+	}).Decode(&session_ids)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	for _, id := range append(attempt_ids, session_ids...) {
+		seen[id] = true
+	}
+	return seen, nil
+}
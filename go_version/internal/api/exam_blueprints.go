@@ -0,0 +1,387 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_version/internal/assembly"
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type ExamBlueprintRequestBody struct {
+	Name            string                    `json:"name" validate:"required,min=3,max=100"`
+	Description     string                    `json:"description" validate:"omitempty,max=500"`
+	Category        string                    `json:"category" validate:"required,oneof=backend frontend"`
+	Sections        []models.BlueprintSection `json:"sections" validate:"required,min=1,max=20,dive"`
+	DurationSeconds int32                     `json:"durationSeconds" validate:"omitempty,min=60,max=14400"`
+	ExcludeSeen     bool                      `json:"excludeSeen"`
+	ShuffleOptions  bool                      `json:"shuffleOptions"`
+	IsActive        *bool                     `json:"isActive"`
+	SponsorID       string                    `json:"sponsorId" validate:"omitempty,mongodb"`
+}
+
+func (cfg *AppConfig) GetExamBlueprints(w http.ResponseWriter, r *http.Request) error {
+	_, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	// Only admins get to see inactive blueprints
+	filter := bson.M{"isActive": true}
+	if user.Role == models.ROLE_ADMIN && r.URL.Query().Get("includeInactive") == "true" {
+		filter = bson.M{}
+	}
+
+	blueprints_coll := cfg.DATABASE.Collection(models.EXAM_BLUEPRINTS_COLLECTION)
+	cursor, err := blueprints_coll.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	blueprints := []models.ExamBlueprint{}
+	if err := cursor.All(ctx, &blueprints); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Exam blueprints provided successfully",
+		map[string]any{"blueprints": blueprints},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) CreateExamBlueprint(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	req_body, err := parseExamBlueprintRequestBody(r)
+	if err != nil {
+		return err
+	}
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	blueprint := models.ExamBlueprint{
+		ID:        bson.NewObjectID(),
+		CreatedBy: user_id,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	req_body.applyTo(&blueprint)
+	if err := cfg.checkExamSponsor(ctx, blueprint.SponsorID); err != nil {
+		return err
+	}
+
+	blueprints_coll := cfg.DATABASE.Collection(models.EXAM_BLUEPRINTS_COLLECTION)
+	if _, err := blueprints_coll.InsertOne(ctx, blueprint); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Exam blueprint created successfully",
+		map[string]any{"blueprint": blueprint},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) UpdateExamBlueprint(w http.ResponseWriter, r *http.Request) error {
+	blueprint_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	req_body, err := parseExamBlueprintRequestBody(r)
+	if err != nil {
+		return err
+	}
+
+	blueprint, err := cfg.findExamBlueprint(ctx, blueprint_id)
+	if err != nil {
+		return err
+	}
+	req_body.applyTo(&blueprint)
+	blueprint.UpdatedAt = bson.NewDateTimeFromTime(time.Now())
+	if err := cfg.checkExamSponsor(ctx, blueprint.SponsorID); err != nil {
+		return err
+	}
+
+	// Sessions keep their own copy of the sections, editing a blueprint never
+	// changes a paper that was already handed out.
+	blueprints_coll := cfg.DATABASE.Collection(models.EXAM_BLUEPRINTS_COLLECTION)
+	if _, err := blueprints_coll.ReplaceOne(ctx, bson.M{"_id": blueprint_id}, blueprint); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Exam blueprint updated successfully",
+		map[string]any{"blueprint": blueprint},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) DeleteExamBlueprint(w http.ResponseWriter, r *http.Request) error {
+	blueprint_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	blueprints_coll := cfg.DATABASE.Collection(models.EXAM_BLUEPRINTS_COLLECTION)
+	result, err := blueprints_coll.UpdateOne(ctx,
+		bson.M{"_id": blueprint_id},
+		bson.M{"$set": bson.M{"isActive": false, "updatedAt": bson.NewDateTimeFromTime(time.Now())}},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewNotFound("Exam blueprint not found")
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Exam blueprint deactivated successfully",
+		nil,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// PreviewExamBlueprint assembles a paper without starting a session, pass
+// ?seed= to see a specific paper again.
+func (cfg *AppConfig) PreviewExamBlueprint(w http.ResponseWriter, r *http.Request) error {
+	blueprint_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	seed := assembly.NewSeed()
+	if seed_param := r.URL.Query().Get("seed"); seed_param != "" {
+		seed, err = strconv.ParseInt(seed_param, 10, 64)
+		if err != nil || seed < 0 || seed >= assembly.MAX_SEED {
+			return utils.NewBadRequest("Invalid seed")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	blueprint, err := cfg.findExamBlueprint(ctx, blueprint_id)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.importLegacyQuestions(ctx, blueprint.Sections); err != nil {
+		return err
+	}
+
+	picks, err := cfg.assemblePaper(ctx, bson.ObjectID{}, blueprint.Sections, false, blueprint.ShuffleOptions, seed, time.Now(), bson.ObjectID{})
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"seed":  strconv.FormatInt(seed, 10),
+		"paper": buildPaperView(picks),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Exam blueprint preview generated successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// ReproduceExamPaper rebuilds a session's paper from its seed and start time,
+// and reports whether it matches what the student was actually given.
+func (cfg *AppConfig) ReproduceExamPaper(w http.ResponseWriter, r *http.Request) error {
+	session_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	var session models.ExamSession
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	err = exam_sessions_coll.FindOne(ctx, bson.M{"_id": session_id}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Exam session not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	picks, err := cfg.assemblePaper(ctx, session.UserID, session.Sections, session.ExcludeSeen, session.ShuffleOptions, session.Seed, session.StartedAt.Time(), session.ID)
+	if err != nil {
+		return err
+	}
+
+	matches := len(picks) == len(session.Questions)
+	for i := 0; matches && i < len(picks); i++ {
+		given := session.Questions[i]
+		matches = picks[i].Revision.ID == given.RevisionID && slices.Equal(picks[i].OptionOrder, given.OptionOrder)
+	}
+
+	response_payload := map[string]any{
+		"sessionId": session.ID,
+		"seed":      strconv.FormatInt(session.Seed, 10),
+		"startedAt": session.StartedAt,
+		"matches":   matches,
+		"paper":     buildPaperView(picks),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Exam paper reproduced successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+type PaperQuestionView struct {
+	ID            bson.ObjectID `json:"_id"`
+	Revision      int32         `json:"revision"`
+	Section       int           `json:"section"`
+	Repeated      bool          `json:"repeated"`
+	Question      string        `json:"question"`
+	Options       []string      `json:"options"`
+	CorrectAnswer string        `json:"correctAnswer"`
+	Difficulty    string        `json:"difficulty"`
+	Tags          []string      `json:"tags"`
+}
+
+func buildPaperView(picks []assembly.Pick) []PaperQuestionView {
+	paper := make([]PaperQuestionView, 0, len(picks))
+	for _, pick := range picks {
+		content := pick.Revision.Content
+		paper = append(paper, PaperQuestionView{
+			ID:            pick.Revision.QuestionID,
+			Revision:      pick.Revision.Revision,
+			Section:       pick.Section,
+			Repeated:      pick.Repeated,
+			Question:      content.Question,
+			Options:       assembly.ApplyOptionOrder(content.Options, pick.OptionOrder),
+			CorrectAnswer: content.CorrectAnswer,
+			Difficulty:    content.Difficulty,
+			Tags:          content.Tags,
+		})
+	}
+	return paper
+}
+
+func parseExamBlueprintRequestBody(r *http.Request) (ExamBlueprintRequestBody, error) {
+	req_body := ExamBlueprintRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return req_body, utils.NewAppError("Error while parsing exam blueprint request body", http.StatusBadRequest, err)
+	}
+
+	req_body.Name = sanitizeInput(req_body.Name)
+	req_body.Description = sanitizeInput(req_body.Description)
+	req_body.Category = strings.ToLower(strings.TrimSpace(req_body.Category))
+	for i := range req_body.Sections {
+		section := &req_body.Sections[i]
+		section.Category = strings.ToLower(strings.TrimSpace(section.Category))
+		section.Difficulty = strings.ToLower(strings.TrimSpace(section.Difficulty))
+		for j, tag := range section.Tags {
+			section.Tags[j] = sanitizeInput(tag)
+		}
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return req_body, utils.NewValidationError(field_errors)
+	}
+
+	total := 0
+	for _, section := range req_body.Sections {
+		total += section.Count
+	}
+	if total > MAX_EXAM_QUESTION_COUNT {
+		return req_body, utils.NewBadRequest("A blueprint can't have more than " + strconv.Itoa(MAX_EXAM_QUESTION_COUNT) + " questions")
+	}
+
+	return req_body, nil
+}
+
+func (body *ExamBlueprintRequestBody) applyTo(blueprint *models.ExamBlueprint) {
+	blueprint.Name = body.Name
+	blueprint.Description = body.Description
+	blueprint.Category = body.Category
+	blueprint.Sections = body.Sections
+	blueprint.DurationSeconds = body.DurationSeconds
+	blueprint.ExcludeSeen = body.ExcludeSeen
+	blueprint.ShuffleOptions = body.ShuffleOptions
+	blueprint.SponsorID, _ = bson.ObjectIDFromHex(body.SponsorID)
+	blueprint.IsActive = true
+	if body.IsActive != nil {
+		blueprint.IsActive = *body.IsActive
+	}
+}
+
+// checkExamSponsor makes sure a blueprint's sponsor is an active company
+// account, no sponsor is fine.
+func (cfg *AppConfig) checkExamSponsor(ctx context.Context, sponsor_id bson.ObjectID) error {
+	if sponsor_id.IsZero() {
+		return nil
+	}
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	count, err := users_coll.CountDocuments(ctx, bson.M{"_id": sponsor_id, "role": models.ROLE_COMPANY, "isActive": true})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if count == 0 {
+		return utils.NewBadRequest("The sponsor must be an active company account")
+	}
+	return nil
+}
+
+func (cfg *AppConfig) findExamBlueprint(ctx context.Context, blueprint_id bson.ObjectID) (models.ExamBlueprint, error) {
+	var blueprint models.ExamBlueprint
+	blueprints_coll := cfg.DATABASE.Collection(models.EXAM_BLUEPRINTS_COLLECTION)
+	err := blueprints_coll.FindOne(ctx, bson.M{"_id": blueprint_id}).Decode(&blueprint)
+	if err == mongo.ErrNoDocuments {
+		return blueprint, utils.NewNotFound("Exam blueprint not found")
+	} else if err != nil {
+		return blueprint, utils.NewInternalServerError(err)
+	}
+	return blueprint, nil
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go_version/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const EXAM_SWEEP_BATCH_SIZE = 100
+
+// StartExamSweeper auto-submits exam sessions whose deadline passed while the
+// student was away. It blocks until ctx is cancelled, run it in a goroutine.
+func (cfg *AppConfig) StartExamSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := cfg.sweepExpiredExamSessions(ctx); err != nil {
+				log.Printf("exam sweeper: %s", err.Error())
+			}
+		}
+	}
+}
+
+func (cfg *AppConfig) sweepExpiredExamSessions(ctx context.Context) error {
+	sweep_ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	now := time.Now()
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+
+	// Expired sessions, plus submitted ones a crashed instance never graded
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"status": models.EXAM_SESSION_IN_PROGRESS, "deadline": bson.M{"$lte": bson.NewDateTimeFromTime(now)}},
+			bson.M{"status": models.EXAM_SESSION_SUBMITTED, "attemptId": bson.M{"$exists": false}, "submittedAt": bson.M{"$lte": bson.NewDateTimeFromTime(now.Add(-time.Minute))}},
+		},
+	}
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(EXAM_SWEEP_BATCH_SIZE)
+	cursor, err := exam_sessions_coll.Find(sweep_ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+
+	var sessions []models.ExamSession
+	if err := cursor.All(sweep_ctx, &sessions); err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if _, err := cfg.finalizeExamSession(sweep_ctx, session.ID, true); err != nil {
+			log.Printf("exam sweeper: failed to submit session %s: %s", session.ID.Hex(), err.Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,730 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"go_version/internal/assembly"
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_EXAM_QUESTION_COUNT = 10
+	MAX_EXAM_QUESTION_COUNT     = 50
+
+	// Same allowance the exam page uses: 2 minutes per question
+	EXAM_SECONDS_PER_QUESTION = 120
+)
+
+// StartExamRequestBody either names a blueprint, or describes an ad-hoc exam
+// the way the exam page always has (category, count and difficulty).
+type StartExamRequestBody struct {
+	BlueprintID string `json:"blueprintId"`
+	Category    string `json:"category" validate:"required_without=BlueprintID,omitempty,oneof=backend frontend"`
+	Count       int    `json:"count" validate:"omitempty,min=1,max=50"`
+	Difficulty  string `json:"difficulty" validate:"omitempty,oneof=easy medium hard"`
+}
+
+type ExamSessionQuestionView struct {
+	ID         bson.ObjectID `json:"_id"`
+	QuestionID int32         `json:"questionId"`
+	Question   string        `json:"question"`
+	Options    []string      `json:"options"`
+	Difficulty string        `json:"difficulty"`
+	Tags       []string      `json:"tags"`
+	Answer     string        `json:"answer,omitempty"`
+}
+
+type ExamSessionView struct {
+	ID               bson.ObjectID             `json:"_id"`
+	Category         string                    `json:"category"`
+	Difficulty       string                    `json:"difficulty,omitempty"`
+	Status           string                    `json:"status"`
+	Mode             string                    `json:"mode"`
+	Questions        []ExamSessionQuestionView `json:"questions"`
+	AnsweredCount    int                       `json:"answeredCount"`
+	DurationSeconds  int32                     `json:"durationSeconds"`
+	StartedAt        bson.DateTime             `json:"startedAt"`
+	Deadline         bson.DateTime             `json:"deadline"`
+	ServerTime       bson.DateTime             `json:"serverTime"`
+	RemainingSeconds int64                     `json:"remainingSeconds"`
+	AutoSubmitted    bool                      `json:"autoSubmitted,omitempty"`
+	AttemptID        *bson.ObjectID            `json:"attemptId,omitempty"`
+
+	// Adaptive exams only, the estimate is revealed once the exam is over
+	MaxLength  int32                   `json:"maxLength,omitempty"`
+	Ability    *models.AbilityEstimate `json:"ability,omitempty"`
+	StopReason string                  `json:"stopReason,omitempty"`
+}
+
+// StartExamSession starts a timed exam, or resumes the user's running one so
+// a closed tab or a dropped connection doesn't cost the student their exam.
+func (cfg *AppConfig) StartExamSession(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	req_body := StartExamRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing start exam request body", http.StatusBadRequest, err)
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	session, found, err := cfg.findActiveExamSession(ctx, user_id)
+	if err != nil {
+		return err
+	}
+	if found {
+		view, err := cfg.buildExamSessionView(ctx, session)
+		if err != nil {
+			return err
+		}
+		utils.SuccessResponseWriter(
+			w,
+			"You already have an exam in progress, resuming it",
+			map[string]any{"session": view, "resumed": true},
+			http.StatusOK,
+		)
+		return nil
+	}
+
+	blueprint, err := cfg.resolveExamBlueprint(ctx, req_body)
+	if err != nil {
+		return err
+	}
+
+	session, err = cfg.createExamSession(ctx, user_id, blueprint)
+	if err != nil {
+		return err
+	}
+
+	view, err := cfg.buildExamSessionView(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Exam started successfully",
+		map[string]any{"session": view, "resumed": false},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetActiveExamSession(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	session, found, err := cfg.findActiveExamSession(ctx, user_id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return utils.NewNotFound("You have no exam in progress")
+	}
+
+	view, err := cfg.buildExamSessionView(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Exam session provided successfully",
+		map[string]any{"session": view},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetExamSession(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	session_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	session, err := cfg.findUserExamSession(ctx, session_id, user_id)
+	if err != nil {
+		return err
+	}
+
+	view, err := cfg.buildExamSessionView(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Exam session provided successfully",
+		map[string]any{"session": view},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+type ExamAnswerRequestBody struct {
+	QuestionID string `json:"questionId" validate:"required"`
+	Answer     string `json:"answer" validate:"required"`
+}
+
+// AnswerExamQuestion saves (or changes) one answer. The write is conditioned on
+// the deadline so an answer arriving after time is up is never stored.
+func (cfg *AppConfig) AnswerExamQuestion(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	session_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := ExamAnswerRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing exam answer request body", http.StatusBadRequest, err)
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	question_id, err := bson.ObjectIDFromHex(req_body.QuestionID)
+	if err != nil {
+		return utils.NewBadRequest("Invalid questionId")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	session, err := cfg.findUserExamSession(ctx, session_id, user_id)
+	if err != nil {
+		return err
+	}
+	if session.IsAdaptive() {
+		return utils.NewBadRequest("Adaptive exams are answered one question at a time through the adaptive answer endpoint")
+	}
+
+	index := slices.IndexFunc(session.Questions, func(q models.ExamSessionQuestion) bool {
+		return q.QuestionID == question_id
+	})
+	if index == -1 {
+		return utils.NewBadRequest("This question is not part of the exam")
+	}
+
+	revisions, err := cfg.findRevisionsByID(ctx, []bson.ObjectID{session.Questions[index].RevisionID})
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(revisions[session.Questions[index].RevisionID].Content.Options, req_body.Answer) {
+		return utils.NewBadRequest("Answer must be one of the question's options")
+	}
+
+	now := time.Now()
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	result, err := exam_sessions_coll.UpdateOne(ctx,
+		bson.M{
+			"_id":                  session.ID,
+			"status":               models.EXAM_SESSION_IN_PROGRESS,
+			"deadline":             bson.M{"$gt": bson.NewDateTimeFromTime(now)},
+			"questions.questionId": question_id,
+		},
+		bson.M{"$set": bson.M{
+			"questions.$.answer":     req_body.Answer,
+			"questions.$.answeredAt": bson.NewDateTimeFromTime(now),
+			"updatedAt":              bson.NewDateTimeFromTime(now),
+		}},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		if session.Status == models.EXAM_SESSION_IN_PROGRESS && session.IsExpired(now) {
+			// Time ran out, submit what we have instead of waiting for the sweeper
+			if _, err := cfg.finalizeExamSession(ctx, session.ID, true); err != nil {
+				return err
+			}
+		}
+		return utils.NewConflict("Time is up, this exam no longer accepts answers")
+	}
+
+	response_payload := map[string]any{
+		"questionId":       question_id,
+		"answer":           req_body.Answer,
+		"serverTime":       bson.NewDateTimeFromTime(now),
+		"remainingSeconds": session.RemainingSeconds(now),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Answer saved successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) SubmitExamSession(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	session_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	// Ownership check before finalizing
+	if _, err := cfg.findUserExamSession(ctx, session_id, user_id); err != nil {
+		return err
+	}
+
+	attempt, err := cfg.finalizeExamSession(ctx, session_id, false)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"attemptId":      attempt.ID,
+		"score":          attempt.Score,
+		"correctCount":   attempt.CorrectCount,
+		"totalQuestions": attempt.TotalQuestions,
+		"timeSpent":      attempt.TimeSpent,
+		"results":        attempt.Questions,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Exam submitted successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// resolveExamBlueprint loads the requested blueprint, or turns an ad-hoc
+// request into a single section blueprint so both go through assembly.
+func (cfg *AppConfig) resolveExamBlueprint(ctx context.Context, req_body StartExamRequestBody) (models.ExamBlueprint, error) {
+	if req_body.BlueprintID == "" {
+		if req_body.Count == 0 {
+			req_body.Count = DEFAULT_EXAM_QUESTION_COUNT
+		}
+		return models.ExamBlueprint{
+			Category: req_body.Category,
+			Sections: []models.BlueprintSection{{
+				Category:   req_body.Category,
+				Difficulty: req_body.Difficulty,
+				Count:      req_body.Count,
+			}},
+			ShuffleOptions: true,
+			IsActive:       true,
+		}, nil
+	}
+
+	blueprint_id, err := bson.ObjectIDFromHex(req_body.BlueprintID)
+	if err != nil {
+		return models.ExamBlueprint{}, utils.NewBadRequest("Invalid blueprintId")
+	}
+
+	blueprint, err := cfg.findExamBlueprint(ctx, blueprint_id)
+	if err != nil {
+		return blueprint, err
+	}
+	if !blueprint.IsActive {
+		return blueprint, utils.NewNotFound("Exam blueprint not found")
+	}
+	return blueprint, nil
+}
+
+// createExamSession assembles a paper and pins every question to its revision,
+// what the student sees and what they are graded against can't change mid-exam.
+func (cfg *AppConfig) createExamSession(ctx context.Context, user_id bson.ObjectID, blueprint models.ExamBlueprint) (models.ExamSession, error) {
+	if err := cfg.importLegacyQuestions(ctx, blueprint.Sections); err != nil {
+		return models.ExamSession{}, err
+	}
+
+	// Truncated to what Mongo stores, so reproducing from startedAt sees the same bank
+	now := time.Now().Truncate(time.Millisecond)
+	seed := assembly.NewSeed()
+	picks, err := cfg.assemblePaper(ctx, user_id, blueprint.Sections, blueprint.ExcludeSeen, blueprint.ShuffleOptions, seed, now, bson.ObjectID{})
+	if err != nil {
+		return models.ExamSession{}, err
+	}
+
+	duration := blueprint.DurationSeconds
+	if duration == 0 {
+		duration = int32(len(picks) * EXAM_SECONDS_PER_QUESTION)
+	}
+
+	difficulty := ""
+	if len(blueprint.Sections) == 1 {
+		difficulty = blueprint.Sections[0].Difficulty
+	}
+
+	session := models.ExamSession{
+		ID:              bson.NewObjectID(),
+		UserID:          user_id,
+		Category:        blueprint.Category,
+		Difficulty:      difficulty,
+		Status:          models.EXAM_SESSION_IN_PROGRESS,
+		Questions:       make([]models.ExamSessionQuestion, 0, len(picks)),
+		BlueprintID:     blueprint.ID,
+		SponsorID:       blueprint.SponsorID,
+		Sections:        blueprint.Sections,
+		ExcludeSeen:     blueprint.ExcludeSeen,
+		ShuffleOptions:  blueprint.ShuffleOptions,
+		Seed:            seed,
+		DurationSeconds: duration,
+		StartedAt:       bson.NewDateTimeFromTime(now),
+		Deadline:        bson.NewDateTimeFromTime(now.Add(time.Duration(duration) * time.Second)),
+		CreatedAt:       bson.NewDateTimeFromTime(now),
+		UpdatedAt:       bson.NewDateTimeFromTime(now),
+	}
+
+	for _, pick := range picks {
+		session.Questions = append(session.Questions, models.ExamSessionQuestion{
+			QuestionID:  pick.Revision.QuestionID,
+			Revision:    pick.Revision.Revision,
+			RevisionID:  pick.Revision.ID,
+			OptionOrder: pick.OptionOrder,
+			Section:     int32(pick.Section),
+			Repeated:    pick.Repeated,
+		})
+	}
+
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	_, err = exam_sessions_coll.InsertOne(ctx, session)
+	if mongo.IsDuplicateKeyError(err) {
+		return session, utils.NewConflict("You already have an exam in progress")
+	} else if err != nil {
+		return session, utils.NewInternalServerError(err)
+	}
+
+	return session, nil
+}
+
+// findActiveExamSession returns the user's running session. A session whose
+// deadline already passed is submitted on the spot and not returned.
+func (cfg *AppConfig) findActiveExamSession(ctx context.Context, user_id bson.ObjectID) (models.ExamSession, bool, error) {
+	var session models.ExamSession
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	err := exam_sessions_coll.FindOne(ctx, bson.M{"userId": user_id, "status": models.EXAM_SESSION_IN_PROGRESS}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return session, false, nil
+	} else if err != nil {
+		return session, false, utils.NewInternalServerError(err)
+	}
+
+	if session.IsExpired(time.Now()) {
+		if _, err := cfg.finalizeExamSession(ctx, session.ID, true); err != nil {
+			return session, false, err
+		}
+		return session, false, nil
+	}
+
+	return session, true, nil
+}
+
+func (cfg *AppConfig) findUserExamSession(ctx context.Context, session_id, user_id bson.ObjectID) (models.ExamSession, error) {
+	var session models.ExamSession
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	err := exam_sessions_coll.FindOne(ctx, bson.M{"_id": session_id, "userId": user_id}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return session, utils.NewNotFound("Exam session not found")
+	} else if err != nil {
+		return session, utils.NewInternalServerError(err)
+	}
+	return session, nil
+}
+
+func (cfg *AppConfig) findRevisionsByID(ctx context.Context, revision_ids []bson.ObjectID) (map[bson.ObjectID]models.QuestionRevision, error) {
+	revisions_coll := cfg.DATABASE.Collection(models.QUESTION_REVISIONS_COLLECTION)
+	cursor, err := revisions_coll.Find(ctx, bson.M{"_id": bson.M{"$in": revision_ids}})
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	revisions := []models.QuestionRevision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	by_id := make(map[bson.ObjectID]models.QuestionRevision, len(revisions))
+	for _, revision := range revisions {
+		by_id[revision.ID] = revision
+	}
+	return by_id, nil
+}
+
+func (cfg *AppConfig) buildExamSessionView(ctx context.Context, session models.ExamSession) (ExamSessionView, error) {
+	now := time.Now()
+	view := ExamSessionView{
+		ID:              session.ID,
+		Category:        session.Category,
+		Difficulty:      session.Difficulty,
+		Status:          session.Status,
+		Mode:            models.EXAM_MODE_FIXED,
+		Questions:       make([]ExamSessionQuestionView, 0, len(session.Questions)),
+		DurationSeconds: session.DurationSeconds,
+		StartedAt:       session.StartedAt,
+		Deadline:        session.Deadline,
+		ServerTime:      bson.NewDateTimeFromTime(now),
+		AutoSubmitted:   session.AutoSubmitted,
+	}
+	if session.Status == models.EXAM_SESSION_IN_PROGRESS {
+		view.RemainingSeconds = session.RemainingSeconds(now)
+	}
+	if !session.AttemptID.IsZero() {
+		view.AttemptID = &session.AttemptID
+	}
+	if session.Adaptive != nil {
+		view.Mode = models.EXAM_MODE_ADAPTIVE
+		view.MaxLength = session.Adaptive.MaxLength
+		if session.Status == models.EXAM_SESSION_SUBMITTED {
+			view.Ability = &models.AbilityEstimate{Theta: session.Adaptive.Theta, SE: session.Adaptive.SE}
+			view.StopReason = session.Adaptive.StopReason
+		}
+	}
+
+	revision_ids := make([]bson.ObjectID, 0, len(session.Questions))
+	for _, q := range session.Questions {
+		revision_ids = append(revision_ids, q.RevisionID)
+	}
+	revisions, err := cfg.findRevisionsByID(ctx, revision_ids)
+	if err != nil {
+		return view, err
+	}
+
+	for _, q := range session.Questions {
+		content := revisions[q.RevisionID].Content
+		view.Questions = append(view.Questions, ExamSessionQuestionView{
+			ID:         q.QuestionID,
+			QuestionID: content.QuestionID,
+			Question:   content.Question,
+			Options:    assembly.ApplyOptionOrder(content.Options, q.OptionOrder),
+			Difficulty: content.Difficulty,
+			Tags:       content.Tags,
+			Answer:     q.Answer,
+		})
+		if q.Answer != "" {
+			view.AnsweredCount++
+		}
+	}
+
+	return view, nil
+}
+
+// finalizeExamSession submits a session and grades it into a TestAttempt. It is
+// safe to call more than once and from several instances: the status switch
+// claims the session and the unique examSessionId index keeps a single attempt.
+func (cfg *AppConfig) finalizeExamSession(ctx context.Context, session_id bson.ObjectID, auto_submitted bool) (models.TestAttempt, error) {
+	now := time.Now()
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+
+	var session models.ExamSession
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := exam_sessions_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": session_id, "status": models.EXAM_SESSION_IN_PROGRESS},
+		bson.M{"$set": bson.M{
+			"status":        models.EXAM_SESSION_SUBMITTED,
+			"submittedAt":   bson.NewDateTimeFromTime(now),
+			"autoSubmitted": auto_submitted,
+			"updatedAt":     bson.NewDateTimeFromTime(now),
+		}},
+		opts,
+	).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		// Already submitted, grade it if a previous run stopped half way
+		err = exam_sessions_coll.FindOne(ctx, bson.M{"_id": session_id}).Decode(&session)
+	}
+	if err == mongo.ErrNoDocuments {
+		return models.TestAttempt{}, utils.NewNotFound("Exam session not found")
+	} else if err != nil {
+		return models.TestAttempt{}, utils.NewInternalServerError(err)
+	}
+
+	return cfg.gradeExamSession(ctx, session)
+}
+
+func (cfg *AppConfig) gradeExamSession(ctx context.Context, session models.ExamSession) (models.TestAttempt, error) {
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+
+	var attempt models.TestAttempt
+	err := attempts_coll.FindOne(ctx, bson.M{"examSessionId": session.ID}).Decode(&attempt)
+	if err == nil {
+		return attempt, nil
+	} else if err != mongo.ErrNoDocuments {
+		return attempt, utils.NewInternalServerError(err)
+	}
+
+	revision_ids := make([]bson.ObjectID, 0, len(session.Questions))
+	for _, q := range session.Questions {
+		revision_ids = append(revision_ids, q.RevisionID)
+	}
+	revisions, err := cfg.findRevisionsByID(ctx, revision_ids)
+	if err != nil {
+		return attempt, err
+	}
+
+	// Time spent is capped at the deadline, a late sweep doesn't count against the student
+	completed_at := session.SubmittedAt.Time()
+	if completed_at.After(session.Deadline.Time()) {
+		completed_at = session.Deadline.Time()
+	}
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	attempt = models.TestAttempt{
+		ID:            bson.NewObjectID(),
+		UserID:        session.UserID,
+		Category:      session.Category,
+		ExamSessionID: session.ID,
+		Questions:     make([]models.AttemptQuestion, 0, len(session.Questions)),
+		TimeSpent:     int32(completed_at.Sub(session.StartedAt.Time()).Seconds()),
+		StartedAt:     session.StartedAt,
+		CompletedAt:   bson.NewDateTimeFromTime(completed_at),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	time_spent := answerDurations(session)
+	for _, q := range session.Questions {
+		// The pending question of an adaptive exam was never really attempted
+		if session.IsAdaptive() && q.Answer == "" {
+			continue
+		}
+		correct_answer := revisions[q.RevisionID].Content.CorrectAnswer
+		attempt.Questions = append(attempt.Questions, models.AttemptQuestion{
+			QuestionID:    q.QuestionID,
+			UserAnswer:    q.Answer,
+			CorrectAnswer: correct_answer,
+			IsCorrect:     q.Answer != "" && q.Answer == correct_answer,
+			TimeSpent:     time_spent[q.QuestionID],
+			Revision:      q.Revision,
+			RevisionID:    q.RevisionID,
+		})
+	}
+	attempt.Recount()
+	if session.Adaptive != nil {
+		attempt.Ability = &models.AbilityEstimate{Theta: session.Adaptive.Theta, SE: session.Adaptive.SE}
+	}
+	// Achievements, rating and certificate follow from the event, stored with
+	// the attempt so they survive a crash right after grading
+	attempt.PendingEvents = []models.DomainEvent{
+		models.NewDomainEvent(models.EVENT_EXAM_GRADED, attempt.ID, bson.M{"sessionId": session.ID}),
+	}
+
+	_, err = attempts_coll.InsertOne(ctx, attempt)
+	if mongo.IsDuplicateKeyError(err) {
+		// Graded concurrently by another request or instance
+		err = attempts_coll.FindOne(ctx, bson.M{"examSessionId": session.ID}).Decode(&attempt)
+		if err != nil {
+			return attempt, utils.NewInternalServerError(err)
+		}
+		return attempt, nil
+	} else if err != nil {
+		return attempt, utils.NewInternalServerError(err)
+	}
+
+	_, err = exam_sessions_coll.UpdateOne(ctx,
+		bson.M{"_id": session.ID},
+		bson.M{"$set": bson.M{"attemptId": attempt.ID, "updatedAt": now}},
+	)
+	if err != nil {
+		return attempt, utils.NewInternalServerError(err)
+	}
+
+	cfg.publishPendingEvents(ctx, models.TEST_ATTEMPTS_COLLECTION, attempt.ID)
+
+	return attempt, nil
+}
+
+// findGradedExam loads the attempt an exam_graded event is about with its
+// session, a deleted one isn't an error.
+func (cfg *AppConfig) findGradedExam(ctx context.Context, event models.DomainEvent) (models.TestAttempt, models.ExamSession, bool, error) {
+	var attempt models.TestAttempt
+	var session models.ExamSession
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	err := attempts_coll.FindOne(ctx, bson.M{"_id": event.AggregateID}).Decode(&attempt)
+	if err == mongo.ErrNoDocuments {
+		return attempt, session, false, nil
+	} else if err != nil {
+		return attempt, session, false, fmt.Errorf("failed to load attempt %s: %w", event.AggregateID.Hex(), err)
+	}
+
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	err = exam_sessions_coll.FindOne(ctx, bson.M{"_id": attempt.ExamSessionID}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return attempt, session, false, nil
+	} else if err != nil {
+		return attempt, session, false, fmt.Errorf("failed to load exam session %s: %w", attempt.ExamSessionID.Hex(), err)
+	}
+	return attempt, session, true, nil
+}
+
+// answerDurations estimates the seconds spent on each answered question as the
+// gap since the answer before it. Students can jump around a fixed paper and
+// only the last answer time is kept, so this is an approximation there.
+func answerDurations(session models.ExamSession) map[bson.ObjectID]int32 {
+	answered := make([]models.ExamSessionQuestion, 0, len(session.Questions))
+	for _, q := range session.Questions {
+		if q.Answer != "" && q.AnsweredAt != 0 {
+			answered = append(answered, q)
+		}
+	}
+	slices.SortFunc(answered, func(a, b models.ExamSessionQuestion) int {
+		return cmp.Compare(a.AnsweredAt, b.AnsweredAt)
+	})
+
+	durations := make(map[bson.ObjectID]int32, len(answered))
+	previous := session.StartedAt.Time()
+	for _, q := range answered {
+		durations[q.QuestionID] = int32(q.AnsweredAt.Time().Sub(previous).Seconds())
+		previous = q.AnsweredAt.Time()
+	}
+	return durations
+}
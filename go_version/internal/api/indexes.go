@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_version/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// collectionIndexes lists the indexes the Go layer relies on, Mongoose creates
+// the ones declared on the Node schemas but collections owned by Go need them here.
+var collectionIndexes = map[string][]mongo.IndexModel{
+	models.QUESTION_REVISIONS_COLLECTION: {
+		{Keys: bson.D{{Key: "question", Value: 1}, {Key: "revision", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	models.TEST_ATTEMPTS_COLLECTION: {
+		{Keys: bson.D{{Key: "pendingEvents.occurredAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "questions.questionId", Value: 1}}},
+		// Analytics read a user's exams by date
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: 1}}},
+		{Keys: bson.D{{Key: "examSessionId", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"examSessionId": bson.M{"$exists": true}})},
+	},
+	models.EXAM_SESSIONS_COLLECTION: {
+		// One running exam per user, it is resumed instead of started twice
+		{Keys: bson.D{{Key: "userId", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": models.EXAM_SESSION_IN_PROGRESS})},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "deadline", Value: 1}}},
+		{Keys: bson.D{{Key: "sponsorId", Value: 1}, {Key: "submittedAt", Value: -1}}, Options: options.Index().SetSparse(true)},
+	},
+	models.PROCTORING_SIGNALS_COLLECTION: {
+		{Keys: bson.D{{Key: "sessionId", Value: 1}, {Key: "occurredAt", Value: 1}}},
+	},
+	models.REVIEW_CARDS_COLLECTION: {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "questionId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "dueAt", Value: 1}}},
+	},
+	models.REVIEW_LOGS_COLLECTION: {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "reviewedAt", Value: 1}}},
+	},
+	models.QUESTION_BOOKMARKS_COLLECTION: {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "questionId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	},
+	models.QUESTION_NOTES_COLLECTION: {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "questionId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "updatedAt", Value: -1}}},
+	},
+	models.QUESTION_FLAGS_COLLECTION: {
+		// One open report per student and question
+		{Keys: bson.D{{Key: "reporterId", Value: 1}, {Key: "questionId", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": models.FLAG_STATUS_OPEN})},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: 1}}},
+	},
+	models.DAILY_CHALLENGES_COLLECTION: {
+		{Keys: bson.D{{Key: "pendingEvents.occurredAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "date", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "day", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"day": bson.M{"$exists": true}})},
+	},
+	models.USER_STREAKS_COLLECTION: {
+		{Keys: bson.D{{Key: "userId", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	models.ACHIEVEMENTS_COLLECTION: {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "type", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	models.ACHIEVEMENT_DEFINITIONS_COLLECTION: {
+		{Keys: bson.D{{Key: "type", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	models.ACHIEVEMENT_EVENTS_COLLECTION: {
+		{Keys: bson.D{{Key: "eventId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "type", Value: 1}}},
+	},
+	models.OUTBOX_COLLECTION: {
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "lockedUntil", Value: 1}}},
+	},
+	models.USERS_COLLECTION: {
+		// Finds events a failed relay left behind
+		{Keys: bson.D{{Key: "pendingEvents.occurredAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+		// Peer groups for analytics
+		{Keys: bson.D{{Key: "major", Value: 1}, {Key: "role", Value: 1}}},
+		{Keys: bson.D{{Key: "university", Value: 1}, {Key: "role", Value: 1}}},
+		// Talent search, only on what students don't keep private
+		{
+			Keys:    bson.D{{Key: "searchText.interests", Value: "text"}, {Key: "searchText.bio", Value: "text"}, {Key: "searchText.resumeText", Value: "text"}},
+			Options: options.Index().SetWeights(bson.M{"searchText.interests": 10, "searchText.bio": 5, "searchText.resumeText": 1}),
+		},
+		{Keys: bson.D{{Key: "role", Value: 1}, {Key: "isActive", Value: 1}, {Key: "createdAt", Value: -1}}},
+		// Public profile pages
+		{Keys: bson.D{{Key: "handle", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+		// Students waiting for their matches to be recomputed
+		{Keys: bson.D{{Key: "matchesStaleAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+	},
+	models.LEADERBOARDS_COLLECTION: {
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	models.LEADERBOARD_ENTRIES_COLLECTION: {
+		{Keys: bson.D{{Key: "snapshotId", Value: 1}, {Key: "position", Value: 1}}},
+		{Keys: bson.D{{Key: "snapshotId", Value: 1}, {Key: "userId", Value: 1}}},
+		{Keys: bson.D{{Key: "snapshotId", Value: 1}, {Key: "university", Value: 1}, {Key: "position", Value: 1}}},
+		{Keys: bson.D{{Key: "snapshotId", Value: 1}, {Key: "major", Value: 1}, {Key: "position", Value: 1}}},
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}},
+	},
+	models.USER_RATINGS_COLLECTION: {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "category", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "category", Value: 1}, {Key: "rating", Value: -1}}},
+	},
+	models.JOB_POSTINGS_COLLECTION: {
+		{Keys: bson.D{{Key: "companyId", Value: 1}, {Key: "status", Value: 1}, {Key: "updatedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "publishedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "deadline", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "majors", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "city", Value: 1}}},
+		{Keys: bson.D{{Key: "matchesStaleAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{
+			Keys:    bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}, {Key: "companyName", Value: "text"}},
+			Options: options.Index().SetWeights(bson.M{"title": 10, "companyName": 5, "description": 1}),
+		},
+	},
+	models.APPLICATIONS_COLLECTION: {
+		{Keys: bson.D{{Key: "postingId", Value: 1}, {Key: "studentId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "postingId", Value: 1}, {Key: "stage", Value: 1}, {Key: "updatedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "studentId", Value: 1}, {Key: "updatedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "pendingEvents.occurredAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+	},
+	models.MATCHES_COLLECTION: {
+		{Keys: bson.D{{Key: "studentId", Value: 1}, {Key: "postingId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "studentId", Value: 1}, {Key: "score", Value: -1}}},
+		{Keys: bson.D{{Key: "postingId", Value: 1}, {Key: "score", Value: -1}}},
+		{Keys: bson.D{{Key: "postingId", Value: 1}, {Key: "computedAt", Value: 1}}},
+	},
+	models.PROFILE_HANDLES_COLLECTION: {
+		{Keys: bson.D{{Key: "handle", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "expiresAt", Value: 1}}},
+		// Sweeps handles whose grace period ended
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	models.INTERVIEW_SLOTS_COLLECTION: {
+		{Keys: bson.D{{Key: "companyId", Value: 1}, {Key: "startsAt", Value: 1}}},
+		{Keys: bson.D{{Key: "postingId", Value: 1}, {Key: "startsAt", Value: 1}}},
+	},
+	models.INTERVIEWS_COLLECTION: {
+		// An application holds at most one scheduled interview
+		{
+			Keys:    bson.D{{Key: "applicationId", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": models.INTERVIEW_STATUS_SCHEDULED}),
+		},
+		{Keys: bson.D{{Key: "studentId", Value: 1}, {Key: "startsAt", Value: 1}}},
+		{Keys: bson.D{{Key: "companyId", Value: 1}, {Key: "startsAt", Value: 1}}},
+	},
+	models.CONVERSATIONS_COLLECTION: {
+		{Keys: bson.D{{Key: "companyId", Value: 1}, {Key: "studentId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "companyId", Value: 1}, {Key: "lastMessageAt", Value: -1}}},
+		{Keys: bson.D{{Key: "studentId", Value: 1}, {Key: "lastMessageAt", Value: -1}}},
+		// Daily limit on new conversations
+		{Keys: bson.D{{Key: "companyId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	},
+	models.MESSAGES_COLLECTION: {
+		{Keys: bson.D{{Key: "conversationId", Value: 1}, {Key: "sentAt", Value: -1}}},
+		// Rate limit
+		{Keys: bson.D{{Key: "senderId", Value: 1}, {Key: "sentAt", Value: -1}}},
+	},
+	models.MESSAGE_ATTACHMENTS_COLLECTION: {
+		{Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	},
+	models.USER_BLOCKS_COLLECTION: {
+		{Keys: bson.D{{Key: "blockerId", Value: 1}, {Key: "blockedId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "blockedId", Value: 1}, {Key: "blockerId", Value: 1}}},
+	},
+	models.NOTIFICATIONS_COLLECTION: {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "readAt", Value: 1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "sourceId", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"sourceId": bson.M{"$exists": true}})},
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(NOTIFICATION_RETENTION.Seconds()))},
+	},
+	models.STREAM_TICKETS_COLLECTION: {
+		{Keys: bson.D{{Key: "ticketHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// Sweeps tickets that were never redeemed
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	models.CONTACT_REQUESTS_COLLECTION: {
+		{Keys: bson.D{{Key: "companyId", Value: 1}, {Key: "studentId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "companyId", Value: 1}, {Key: "requestedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "studentId", Value: 1}, {Key: "status", Value: 1}, {Key: "requestedAt", Value: -1}}},
+	},
+	models.CERTIFICATES_COLLECTION: {
+		{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "attemptId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "issuedAt", Value: -1}}},
+	},
+	models.RATING_HISTORY_COLLECTION: {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "sourceId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "category", Value: 1}, {Key: "period", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "category", Value: 1}, {Key: "occurredAt", Value: -1}}},
+	},
+	models.ITEM_PARAMETERS_COLLECTION: {
+		{Keys: bson.D{{Key: "questionId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "category", Value: 1}}},
+	},
+}
+
+func (cfg *AppConfig) ensureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for coll_name, indexes := range collectionIndexes {
+		_, err := cfg.DATABASE.Collection(coll_name).Indexes().CreateMany(ctx, indexes)
+		if err != nil {
+			return fmt.Errorf("failed to create indexes on '%s': %w", coll_name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,885 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go_version/internal/calendar"
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_INTERVIEWS_LIMIT = 20
+	MAX_INTERVIEWS_LIMIT     = 100
+
+	// Slots listed at once, to companies and students alike
+	MAX_LISTED_INTERVIEW_SLOTS = 200
+
+	MIN_INTERVIEW_DURATION = 15 * time.Minute
+	MAX_INTERVIEW_DURATION = 4 * time.Hour
+
+	// Slots can be published this far ahead
+	MAX_INTERVIEW_SLOT_HORIZON = 90 * 24 * time.Hour
+
+	// Slots can't be booked or moved to at shorter notice
+	INTERVIEW_BOOKING_NOTICE = 2 * time.Hour
+)
+
+type InterviewSlotTimeRequestBody struct {
+	StartsAt time.Time `json:"startsAt" validate:"required"`
+	EndsAt   time.Time `json:"endsAt" validate:"required,gtfield=StartsAt"`
+}
+
+type InterviewSlotsRequestBody struct {
+	PostingID  string                         `json:"postingId" validate:"required,mongodb"`
+	Slots      []InterviewSlotTimeRequestBody `json:"slots" validate:"required,min=1,max=50,dive"`
+	Location   string                         `json:"location" validate:"required_without=MeetingURL,max=200"`
+	MeetingURL string                         `json:"meetingUrl" validate:"omitempty,http_url,max=500"`
+}
+
+type BookInterviewRequestBody struct {
+	ApplicationID string `json:"applicationId" validate:"required,mongodb"`
+	SlotID        string `json:"slotId" validate:"required,mongodb"`
+}
+
+type RescheduleInterviewRequestBody struct {
+	SlotID string `json:"slotId" validate:"required,mongodb"`
+}
+
+type CancelInterviewRequestBody struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+type InterviewSlotView struct {
+	models.InterviewSlot
+	Booked bool `json:"booked"`
+}
+
+// Company endpoints
+
+// CreateInterviewSlots publishes times the company is available to interview
+// applicants to one of its postings. Slots can't overlap each other nor the
+// company's other slots, whatever the posting.
+func (cfg *AppConfig) CreateInterviewSlots(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	req_body := InterviewSlotsRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing interview slots request body", http.StatusBadRequest, err)
+	}
+	req_body.Location = sanitizeInput(req_body.Location)
+	req_body.MeetingURL = strings.TrimSpace(req_body.MeetingURL)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+	posting_id, _ := bson.ObjectIDFromHex(req_body.PostingID)
+
+	now := time.Now()
+	slices.SortFunc(req_body.Slots, func(a, b InterviewSlotTimeRequestBody) int {
+		return a.StartsAt.Compare(b.StartsAt)
+	})
+	for i, slot := range req_body.Slots {
+		duration := slot.EndsAt.Sub(slot.StartsAt)
+		if duration < MIN_INTERVIEW_DURATION || duration > MAX_INTERVIEW_DURATION {
+			return utils.NewBadRequest("Interview slots must last between 15 minutes and 4 hours")
+		}
+		if slot.StartsAt.Before(now.Add(INTERVIEW_BOOKING_NOTICE)) {
+			return utils.NewBadRequest("Interview slots must start at least 2 hours from now")
+		}
+		if slot.StartsAt.After(now.Add(MAX_INTERVIEW_SLOT_HORIZON)) {
+			return utils.NewBadRequest("Interview slots can't start more than 90 days from now")
+		}
+		if i > 0 && slot.StartsAt.Before(req_body.Slots[i-1].EndsAt) {
+			return utils.NewBadRequest("Interview slots can't overlap")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	posting, err := cfg.findOwnedJobPosting(ctx, user_id, posting_id)
+	if err != nil {
+		return err
+	}
+	if posting.Status == models.JOB_STATUS_DRAFT {
+		return utils.NewConflict("Publish the job posting before scheduling interviews")
+	}
+
+	overlaps := bson.A{}
+	for _, slot := range req_body.Slots {
+		overlaps = append(overlaps, bson.M{
+			"startsAt": bson.M{"$lt": bson.NewDateTimeFromTime(slot.EndsAt)},
+			"endsAt":   bson.M{"$gt": bson.NewDateTimeFromTime(slot.StartsAt)},
+		})
+	}
+	slots_coll := cfg.DATABASE.Collection(models.INTERVIEW_SLOTS_COLLECTION)
+	overlapping, err := slots_coll.CountDocuments(ctx, bson.M{"companyId": user_id, "$or": overlaps})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if overlapping != 0 {
+		return utils.NewConflict("Interview slots overlap slots you already published")
+	}
+
+	slots := make([]models.InterviewSlot, 0, len(req_body.Slots))
+	for _, slot := range req_body.Slots {
+		slots = append(slots, models.InterviewSlot{
+			ID:         bson.NewObjectID(),
+			CompanyID:  user_id,
+			PostingID:  posting.ID,
+			StartsAt:   bson.NewDateTimeFromTime(slot.StartsAt),
+			EndsAt:     bson.NewDateTimeFromTime(slot.EndsAt),
+			Location:   req_body.Location,
+			MeetingURL: req_body.MeetingURL,
+			CreatedAt:  bson.NewDateTimeFromTime(now),
+			UpdatedAt:  bson.NewDateTimeFromTime(now),
+		})
+	}
+	if _, err := slots_coll.InsertMany(ctx, slots); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	views := make([]InterviewSlotView, 0, len(slots))
+	for _, slot := range slots {
+		views = append(views, InterviewSlotView{InterviewSlot: slot})
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Interview slots created successfully",
+		map[string]any{"slots": views},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+// GetInterviewSlots lists the company's upcoming slots, booked ones included.
+func (cfg *AppConfig) GetInterviewSlots(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter := bson.M{"companyId": user_id, "endsAt": bson.M{"$gt": bson.NewDateTimeFromTime(time.Now())}}
+	if value := r.URL.Query().Get("postingId"); value != "" {
+		posting_id, err := bson.ObjectIDFromHex(value)
+		if err != nil {
+			return utils.NewBadRequest("Invalid postingId")
+		}
+		filter["postingId"] = posting_id
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	slots, err := cfg.findInterviewSlots(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Interview slots provided successfully",
+		map[string]any{"slots": slots},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// DeleteInterviewSlot withdraws a slot nobody booked, booked ones have to be
+// freed by cancelling or rescheduling their interview first.
+func (cfg *AppConfig) DeleteInterviewSlot(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	slot_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	slots_coll := cfg.DATABASE.Collection(models.INTERVIEW_SLOTS_COLLECTION)
+	result, err := slots_coll.DeleteOne(ctx, bson.M{"_id": slot_id, "companyId": user_id, "interviewId": bson.M{"$exists": false}})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if result.DeletedCount == 0 {
+		count, err := slots_coll.CountDocuments(ctx, bson.M{"_id": slot_id, "companyId": user_id})
+		if err != nil {
+			return utils.NewInternalServerError(err)
+		}
+		if count == 0 {
+			return utils.NewNotFound("Interview slot not found")
+		}
+		return utils.NewConflict("The slot is booked, cancel or reschedule its interview first")
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Interview slot deleted successfully",
+		nil,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetCompanyInterviews(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter := bson.M{"companyId": user_id}
+	if value := r.URL.Query().Get("postingId"); value != "" {
+		posting_id, err := bson.ObjectIDFromHex(value)
+		if err != nil {
+			return utils.NewBadRequest("Invalid postingId")
+		}
+		filter["postingId"] = posting_id
+	}
+	if err := applyInterviewFilters(r, filter); err != nil {
+		return err
+	}
+	page, limit := parsePagination(r, DEFAULT_INTERVIEWS_LIMIT, MAX_INTERVIEWS_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	interviews, total, err := cfg.findInterviews(ctx, filter, page, limit)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"interviews": interviews,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Interviews provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) RescheduleCompanyInterview(w http.ResponseWriter, r *http.Request) error {
+	return cfg.handleRescheduleInterview(w, r, "companyId")
+}
+
+func (cfg *AppConfig) CancelCompanyInterview(w http.ResponseWriter, r *http.Request) error {
+	return cfg.handleCancelInterview(w, r, "companyId")
+}
+
+// Student endpoints
+
+func (cfg *AppConfig) GetMyInterviews(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter := bson.M{"studentId": user_id}
+	if err := applyInterviewFilters(r, filter); err != nil {
+		return err
+	}
+	page, limit := parsePagination(r, DEFAULT_INTERVIEWS_LIMIT, MAX_INTERVIEWS_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	interviews, total, err := cfg.findInterviews(ctx, filter, page, limit)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"interviews": interviews,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Interviews provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetApplicationInterviewSlots lists the free slots of the posting the
+// student applied to, once the company moved them to an interview stage.
+func (cfg *AppConfig) GetApplicationInterviewSlots(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	application_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	application, err := cfg.findApplication(ctx, bson.M{"_id": application_id, "studentId": user_id})
+	if err != nil {
+		return err
+	}
+	if err := checkCanBookInterview(application); err != nil {
+		return err
+	}
+
+	slots, err := cfg.findInterviewSlots(ctx, bson.M{
+		"postingId":   application.PostingID,
+		"interviewId": bson.M{"$exists": false},
+		"startsAt":    bson.M{"$gt": bson.NewDateTimeFromTime(time.Now().Add(INTERVIEW_BOOKING_NOTICE))},
+	})
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Interview slots provided successfully",
+		map[string]any{"slots": slots},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// BookInterview books a free slot for one of the student's applications and
+// sends both sides a calendar invite. An application holds at most one
+// scheduled interview, moving it is a reschedule.
+func (cfg *AppConfig) BookInterview(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	req_body := BookInterviewRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing book interview request body", http.StatusBadRequest, err)
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+	application_id, _ := bson.ObjectIDFromHex(req_body.ApplicationID)
+	slot_id, _ := bson.ObjectIDFromHex(req_body.SlotID)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	application, err := cfg.findApplication(ctx, bson.M{"_id": application_id, "studentId": user_id})
+	if err != nil {
+		return err
+	}
+	if err := checkCanBookInterview(application); err != nil {
+		return err
+	}
+	postings, err := cfg.findPostingsByID(ctx, []models.Application{application})
+	if err != nil {
+		return err
+	}
+	posting, ok := postings[application.PostingID]
+	if !ok {
+		return utils.NewNotFound("Job posting not found")
+	}
+
+	now := time.Now()
+	interview := models.Interview{
+		ID:            bson.NewObjectID(),
+		Status:        models.INTERVIEW_STATUS_SCHEDULED,
+		ApplicationID: application.ID,
+		PostingID:     application.PostingID,
+		CompanyID:     application.CompanyID,
+		StudentID:     application.StudentID,
+		JobTitle:      posting.Title,
+		CompanyName:   posting.CompanyName,
+		CreatedAt:     bson.NewDateTimeFromTime(now),
+		UpdatedAt:     bson.NewDateTimeFromTime(now),
+	}
+	interview.UID = interview.ID.Hex() + "@talentspal"
+
+	slot, err := cfg.claimInterviewSlot(ctx, application.PostingID, slot_id, interview.ID, now)
+	if err != nil {
+		return err
+	}
+	interview.SlotID = slot.ID
+	interview.StartsAt = slot.StartsAt
+	interview.EndsAt = slot.EndsAt
+	interview.Location = slot.Location
+	interview.MeetingURL = slot.MeetingURL
+
+	interviews_coll := cfg.DATABASE.Collection(models.INTERVIEWS_COLLECTION)
+	_, err = interviews_coll.InsertOne(ctx, interview)
+	if err != nil {
+		if err := cfg.releaseInterviewSlot(ctx, slot.ID, interview.ID); err != nil {
+			return err
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return utils.NewConflict("You already have an interview for this application, reschedule it instead")
+		}
+		return utils.NewInternalServerError(err)
+	}
+
+	cfg.sendInterviewInvites(ctx, interview, "scheduled")
+
+	utils.SuccessResponseWriter(
+		w,
+		"Interview booked successfully",
+		map[string]any{"interview": interview},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) RescheduleMyInterview(w http.ResponseWriter, r *http.Request) error {
+	return cfg.handleRescheduleInterview(w, r, "studentId")
+}
+
+func (cfg *AppConfig) CancelMyInterview(w http.ResponseWriter, r *http.Request) error {
+	return cfg.handleCancelInterview(w, r, "studentId")
+}
+
+// Shared by companies and students, owner_field is how the interview belongs
+// to the user
+
+// handleRescheduleInterview moves an interview to another free slot of the
+// posting. The calendar event keeps its UID and gets a higher sequence.
+func (cfg *AppConfig) handleRescheduleInterview(w http.ResponseWriter, r *http.Request, owner_field string) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	interview_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := RescheduleInterviewRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing reschedule interview request body", http.StatusBadRequest, err)
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+	slot_id, _ := bson.ObjectIDFromHex(req_body.SlotID)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	interview, err := cfg.findInterview(ctx, bson.M{"_id": interview_id, owner_field: user_id})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := checkCanChangeInterview(interview, now); err != nil {
+		return err
+	}
+	if slot_id == interview.SlotID {
+		return utils.NewBadRequest("The interview is already in this slot")
+	}
+
+	slot, err := cfg.claimInterviewSlot(ctx, interview.PostingID, slot_id, interview.ID, now)
+	if err != nil {
+		return err
+	}
+
+	previous_slot_id := interview.SlotID
+	interviews_coll := cfg.DATABASE.Collection(models.INTERVIEWS_COLLECTION)
+	err = interviews_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": interview.ID, "status": models.INTERVIEW_STATUS_SCHEDULED, "sequence": interview.Sequence},
+		bson.M{
+			"$set": bson.M{
+				"slotId":     slot.ID,
+				"startsAt":   slot.StartsAt,
+				"endsAt":     slot.EndsAt,
+				"location":   slot.Location,
+				"meetingUrl": slot.MeetingURL,
+				"updatedAt":  bson.NewDateTimeFromTime(now),
+			},
+			"$inc": bson.M{"sequence": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&interview)
+	if err == mongo.ErrNoDocuments {
+		if err := cfg.releaseInterviewSlot(ctx, slot.ID, interview.ID); err != nil {
+			return err
+		}
+		return utils.NewConflict("The interview changed, please reload it")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	if err := cfg.releaseInterviewSlot(ctx, previous_slot_id, interview.ID); err != nil {
+		return err
+	}
+
+	cfg.sendInterviewInvites(ctx, interview, "rescheduled")
+
+	utils.SuccessResponseWriter(
+		w,
+		"Interview rescheduled successfully",
+		map[string]any{"interview": interview},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) handleCancelInterview(w http.ResponseWriter, r *http.Request, owner_field string) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	interview_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := CancelInterviewRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing cancel interview request body", http.StatusBadRequest, err)
+	}
+	req_body.Reason = sanitizeInput(req_body.Reason)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	interview, err := cfg.findInterview(ctx, bson.M{"_id": interview_id, owner_field: user_id})
+	if err != nil {
+		return err
+	}
+	if err := checkCanChangeInterview(interview, time.Now()); err != nil {
+		return err
+	}
+
+	interview, err = cfg.cancelInterview(ctx, interview, user_id, req_body.Reason)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Interview cancelled successfully",
+		map[string]any{"interview": interview},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// cancelInterview cancels a scheduled interview, frees its slot and sends the
+// cancellation to both calendars.
+func (cfg *AppConfig) cancelInterview(ctx context.Context, interview models.Interview, cancelled_by bson.ObjectID, reason string) (models.Interview, error) {
+	now := bson.NewDateTimeFromTime(time.Now())
+	interviews_coll := cfg.DATABASE.Collection(models.INTERVIEWS_COLLECTION)
+	err := interviews_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": interview.ID, "status": models.INTERVIEW_STATUS_SCHEDULED, "sequence": interview.Sequence},
+		bson.M{
+			"$set": bson.M{
+				"status":             models.INTERVIEW_STATUS_CANCELLED,
+				"cancelledBy":        cancelled_by,
+				"cancelledAt":        now,
+				"cancellationReason": reason,
+				"updatedAt":          now,
+			},
+			"$inc": bson.M{"sequence": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&interview)
+	if err == mongo.ErrNoDocuments {
+		return interview, utils.NewConflict("The interview changed, please reload it")
+	} else if err != nil {
+		return interview, utils.NewInternalServerError(err)
+	}
+
+	if err := cfg.releaseInterviewSlot(ctx, interview.SlotID, interview.ID); err != nil {
+		return interview, err
+	}
+
+	cfg.sendInterviewInvites(ctx, interview, "cancelled")
+	return interview, nil
+}
+
+// cancelApplicationInterview cancels the interview still scheduled for an
+// application that was withdrawn or closed, if there is one.
+func (cfg *AppConfig) cancelApplicationInterview(ctx context.Context, application_id, cancelled_by bson.ObjectID, reason string) error {
+	var interview models.Interview
+	interviews_coll := cfg.DATABASE.Collection(models.INTERVIEWS_COLLECTION)
+	err := interviews_coll.FindOne(ctx, bson.M{
+		"applicationId": application_id,
+		"status":        models.INTERVIEW_STATUS_SCHEDULED,
+		"startsAt":      bson.M{"$gt": bson.NewDateTimeFromTime(time.Now())},
+	}).Decode(&interview)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	_, err = cfg.cancelInterview(ctx, interview, cancelled_by, reason)
+	return err
+}
+
+// cancelInterviewOnApplicationClosed cancels the interview of an application
+// the company moved to a not selected stage.
+func (cfg *AppConfig) cancelInterviewOnApplicationClosed(ctx context.Context, event models.DomainEvent) error {
+	if status, _ := event.Payload["studentStatus"].(string); status != models.APPLICATION_STATUS_NOT_SELECTED {
+		return nil
+	}
+
+	var application models.Application
+	applications_coll := cfg.DATABASE.Collection(models.APPLICATIONS_COLLECTION)
+	err := applications_coll.FindOne(ctx, bson.M{"_id": event.AggregateID}).Decode(&application)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return cfg.cancelApplicationInterview(ctx, application.ID, application.CompanyID, "The application was closed")
+}
+
+func checkCanBookInterview(application models.Application) error {
+	if application.StudentStatus != models.APPLICATION_STATUS_INTERVIEWING {
+		return utils.NewForbidden("Interviews can be booked once the company invites you to interview")
+	}
+	return nil
+}
+
+func checkCanChangeInterview(interview models.Interview, now time.Time) error {
+	if interview.Status != models.INTERVIEW_STATUS_SCHEDULED {
+		return utils.NewConflict("The interview was already cancelled")
+	}
+	if !now.Before(interview.StartsAt.Time()) {
+		return utils.NewConflict("The interview already started")
+	}
+	return nil
+}
+
+// claimInterviewSlot books a free slot of the posting for an interview. Only
+// one interview can claim a slot, whoever comes second gets a conflict.
+func (cfg *AppConfig) claimInterviewSlot(ctx context.Context, posting_id, slot_id, interview_id bson.ObjectID, now time.Time) (models.InterviewSlot, error) {
+	var slot models.InterviewSlot
+	slots_coll := cfg.DATABASE.Collection(models.INTERVIEW_SLOTS_COLLECTION)
+	err := slots_coll.FindOne(ctx, bson.M{"_id": slot_id, "postingId": posting_id}).Decode(&slot)
+	if err == mongo.ErrNoDocuments {
+		return slot, utils.NewNotFound("Interview slot not found")
+	} else if err != nil {
+		return slot, utils.NewInternalServerError(err)
+	}
+	if slot.StartsAt.Time().Before(now.Add(INTERVIEW_BOOKING_NOTICE)) {
+		return slot, utils.NewConflict("This slot starts too soon, pick a later one")
+	}
+
+	err = slots_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": slot.ID, "interviewId": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"interviewId": interview_id, "updatedAt": bson.NewDateTimeFromTime(now)}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&slot)
+	if err == mongo.ErrNoDocuments {
+		return slot, utils.NewConflict("This slot was just booked, pick another one")
+	} else if err != nil {
+		return slot, utils.NewInternalServerError(err)
+	}
+	return slot, nil
+}
+
+func (cfg *AppConfig) releaseInterviewSlot(ctx context.Context, slot_id, interview_id bson.ObjectID) error {
+	slots_coll := cfg.DATABASE.Collection(models.INTERVIEW_SLOTS_COLLECTION)
+	_, err := slots_coll.UpdateOne(ctx,
+		bson.M{"_id": slot_id, "interviewId": interview_id},
+		bson.M{
+			"$unset": bson.M{"interviewId": ""},
+			"$set":   bson.M{"updatedAt": bson.NewDateTimeFromTime(time.Now())},
+		},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	return nil
+}
+
+// sendInterviewInvites emails the student and the company the current state
+// of the interview, with the calendar event attached, and notifies them.
+// change says what happened, e.g. "rescheduled".
+func (cfg *AppConfig) sendInterviewInvites(ctx context.Context, interview models.Interview, change string) {
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	cursor, err := users_coll.Find(ctx, bson.M{"_id": bson.M{"$in": bson.A{interview.StudentID, interview.CompanyID}}})
+	if err != nil {
+		log.Printf("interviews: %s", err.Error())
+		return
+	}
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		log.Printf("interviews: %s", err.Error())
+		return
+	}
+	var student, company models.User
+	for _, user := range users {
+		switch user.ID {
+		case interview.StudentID:
+			student = user
+		case interview.CompanyID:
+			company = user
+		}
+	}
+	if student.Email == "" || company.Email == "" {
+		return
+	}
+
+	smtp := cfg.REQUIREMENTS.SMTP
+	event := calendar.Event{
+		UID:         interview.UID,
+		Sequence:    interview.Sequence,
+		Summary:     "Interview: " + interview.JobTitle + " at " + interview.CompanyName,
+		Description: student.FullName + " interviews with " + interview.CompanyName + " for " + interview.JobTitle + ".",
+		Location:    interview.Location,
+		URL:         interview.MeetingURL,
+		StartsAt:    interview.StartsAt.Time(),
+		EndsAt:      interview.EndsAt.Time(),
+		Organizer:   calendar.Attendee{Name: smtp.AppName, Email: smtp.EmailFrom},
+		Attendees: []calendar.Attendee{
+			{Name: student.FullName, Email: student.Email},
+			{Name: interview.CompanyName, Email: company.Email},
+		},
+		Cancelled: interview.Status == models.INTERVIEW_STATUS_CANCELLED,
+	}
+	if event.URL != "" && event.Location == "" {
+		event.Location = event.URL
+	}
+	invite := calendar.Render(event, time.Now())
+
+	when := interview.StartsAt.Time().UTC().Format("Mon, 02 Jan 2006 15:04") + " - " + interview.EndsAt.Time().UTC().Format("15:04 MST")
+	headline := "Your interview was " + change + "."
+	subject := "Interview " + change + ": " + interview.JobTitle + " - " + smtp.AppName
+	frontend_url := cfg.REQUIREMENTS.Server.FrontendURL
+	recipients := []struct {
+		user           models.User
+		interviewsPath string
+	}{
+		{student, "/student/interviews"},
+		{company, "/company/interviews"},
+	}
+	for _, recipient := range recipients {
+		body := utils.InterviewEmailBody(recipient.user.FullName, headline, interview.JobTitle, interview.CompanyName, when, interview.Location, interview.MeetingURL, frontend_url+recipient.interviewsPath)
+		cfg.sendCalendarEmail(recipient.user.Email, subject, body, event.Method(), invite)
+		cfg.notifyInBackground(models.Notification{
+			UserID: recipient.user.ID,
+			Type:   models.NOTIFICATION_INTERVIEW,
+			Title:  "Interview " + change + ": " + interview.JobTitle,
+			Body:   interview.CompanyName + ", " + when,
+			Link:   recipient.interviewsPath,
+		})
+	}
+}
+
+func applyInterviewFilters(r *http.Request, filter bson.M) error {
+	params := r.URL.Query()
+	if status := strings.ToLower(params.Get("status")); status != "" {
+		if !slices.Contains(models.GetValidInterviewStatuses(), status) {
+			return utils.NewBadRequest("Invalid status")
+		}
+		filter["status"] = status
+	}
+	if params.Get("upcoming") == "true" {
+		filter["startsAt"] = bson.M{"$gt": bson.NewDateTimeFromTime(time.Now())}
+	}
+	return nil
+}
+
+func (cfg *AppConfig) findInterview(ctx context.Context, filter bson.M) (models.Interview, error) {
+	var interview models.Interview
+	interviews_coll := cfg.DATABASE.Collection(models.INTERVIEWS_COLLECTION)
+	err := interviews_coll.FindOne(ctx, filter).Decode(&interview)
+	if err == mongo.ErrNoDocuments {
+		return interview, utils.NewNotFound("Interview not found")
+	} else if err != nil {
+		return interview, utils.NewInternalServerError(err)
+	}
+	return interview, nil
+}
+
+func (cfg *AppConfig) findInterviews(ctx context.Context, filter bson.M, page, limit int64) ([]models.Interview, int64, error) {
+	interviews_coll := cfg.DATABASE.Collection(models.INTERVIEWS_COLLECTION)
+	total, err := interviews_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+
+	cursor, err := interviews_coll.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "startsAt", Value: 1}, {Key: "_id", Value: 1}}).SetSkip((page-1)*limit).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+	interviews := []models.Interview{}
+	if err := cursor.All(ctx, &interviews); err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+	return interviews, total, nil
+}
+
+func (cfg *AppConfig) findInterviewSlots(ctx context.Context, filter bson.M) ([]InterviewSlotView, error) {
+	slots_coll := cfg.DATABASE.Collection(models.INTERVIEW_SLOTS_COLLECTION)
+	cursor, err := slots_coll.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "startsAt", Value: 1}}).SetLimit(MAX_LISTED_INTERVIEW_SLOTS),
+	)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	slots := []models.InterviewSlot{}
+	if err := cursor.All(ctx, &slots); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	views := make([]InterviewSlotView, 0, len(slots))
+	for _, slot := range slots {
+		views = append(views, InterviewSlotView{InterviewSlot: slot, Booked: slot.IsBooked()})
+	}
+	return views, nil
+}
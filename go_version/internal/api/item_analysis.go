@@ -0,0 +1,369 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	DEFAULT_ITEM_ANALYSIS_MIN_RESPONSES = 20
+	DEFAULT_ITEM_ANALYSIS_PAGE_LIMIT    = 20
+	MAX_ITEM_ANALYSIS_PAGE_LIMIT        = 100
+)
+
+const (
+	ITEM_FLAG_POSSIBLY_MISKEYED  = "possibly_miskeyed"
+	ITEM_FLAG_TOO_EASY           = "too_easy"
+	ITEM_FLAG_TOO_HARD           = "too_hard"
+	ITEM_FLAG_LOW_DISCRIMINATION = "low_discrimination"
+)
+
+// Thresholds follow common classical test theory rules of thumb
+const (
+	ITEM_TOO_EASY_P_VALUE          = 0.9
+	ITEM_TOO_HARD_P_VALUE          = 0.2
+	ITEM_LOW_DISCRIMINATION        = 0.2
+	ITEM_MISKEY_DISCRIMINATION_MAX = 0.0
+)
+
+type DistractorStats struct {
+	Answer    string   `json:"answer"`
+	IsKey     bool     `json:"isKey"`
+	Count     int64    `json:"count"`
+	Rate      float64  `json:"rate"`
+	MeanScore *float64 `json:"meanScore"` // mean rest score of the students who picked it
+}
+
+type ItemStats struct {
+	QuestionID     bson.ObjectID     `json:"_id"`
+	Number         int32             `json:"questionId"`
+	Question       string            `json:"question"`
+	Category       string            `json:"category"`
+	Difficulty     string            `json:"difficulty"`
+	CorrectAnswer  string            `json:"correctAnswer"`
+	IsActive       bool              `json:"isActive"`
+	Responses      int64             `json:"responses"`
+	PValue         float64           `json:"pValue"`
+	PointBiserial  *float64          `json:"pointBiserial"`
+	AvgTimeSeconds *float64          `json:"avgTimeSeconds"`
+	OmitRate       float64           `json:"omitRate"`
+	Distractors    []DistractorStats `json:"distractors"`
+	Flags          []string          `json:"flags"`
+}
+
+// itemAnswerGroup is one (question, answer) bucket of the aggregation below.
+type itemAnswerGroup struct {
+	Answer    string  `bson:"answer"`
+	Count     int64   `bson:"count"`
+	Correct   int64   `bson:"correct"`
+	SumRest   float64 `bson:"sumRest"`
+	SumRest2  float64 `bson:"sumRest2"`
+	SumTime   float64 `bson:"sumTime"`
+	TimeCount int64   `bson:"timeCount"`
+}
+
+type itemAggregate struct {
+	QuestionID bson.ObjectID     `bson:"_id"`
+	Answers    []itemAnswerGroup `bson:"answers"`
+}
+
+// GetItemAnalysis reports classical item statistics for the question bank,
+// computed from graded (non-practice) attempts: difficulty (p-value),
+// discrimination (corrected point-biserial), distractor selection and average
+// answer time. Questions that look miskeyed, too easy or too hard are flagged.
+func (cfg *AppConfig) GetItemAnalysis(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	query := r.URL.Query()
+	category := query.Get("category")
+	if category != "" && !slices.Contains(models.GetValidCategories(), category) {
+		return utils.NewBadRequest("Invalid category")
+	}
+	flag := query.Get("flag")
+	min_responses, err := strconv.ParseInt(query.Get("minResponses"), 10, 64)
+	if err != nil || min_responses < 1 {
+		min_responses = DEFAULT_ITEM_ANALYSIS_MIN_RESPONSES
+	}
+	page, limit := parsePagination(r, DEFAULT_ITEM_ANALYSIS_PAGE_LIMIT, MAX_ITEM_ANALYSIS_PAGE_LIMIT)
+
+	aggregates, err := cfg.aggregateItemResponses(ctx, category)
+	if err != nil {
+		return err
+	}
+
+	question_ids := make([]bson.ObjectID, 0, len(aggregates))
+	for _, aggregate := range aggregates {
+		question_ids = append(question_ids, aggregate.QuestionID)
+	}
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	cursor, err := questions_coll.Find(ctx, bson.M{"_id": bson.M{"$in": question_ids}})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	questions := []models.Question{}
+	if err := cursor.All(ctx, &questions); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	questions_by_id := make(map[bson.ObjectID]models.Question, len(questions))
+	for _, question := range questions {
+		questions_by_id[question.ID] = question
+	}
+
+	flag_counts := map[string]int{}
+	items := []ItemStats{}
+	for _, aggregate := range aggregates {
+		question, ok := questions_by_id[aggregate.QuestionID]
+		if !ok {
+			continue // deleted for good
+		}
+		stats := computeItemStats(question, aggregate.Answers)
+		if stats.Responses < min_responses {
+			continue
+		}
+		for _, f := range stats.Flags {
+			flag_counts[f]++
+		}
+		if flag != "" && !slices.Contains(stats.Flags, flag) {
+			continue
+		}
+		items = append(items, stats)
+	}
+
+	sortItemStats(items, query.Get("sort"))
+
+	total := int64(len(items))
+	start := min((page-1)*limit, total)
+	end := min(start+limit, total)
+
+	response_payload := map[string]any{
+		"items":        items[start:end],
+		"flagCounts":   flag_counts,
+		"minResponses": min_responses,
+		"pagination":   newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Item analysis provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// aggregateItemResponses buckets every graded response by question and
+// answer. The rest score (share of the attempt's other questions answered
+// correctly) is carried along so discrimination can be computed per item
+// without the item inflating its own correlation.
+func (cfg *AppConfig) aggregateItemResponses(ctx context.Context, category string) ([]itemAggregate, error) {
+	match := bson.M{
+		"isPracticeMode": bson.M{"$ne": true},
+		"totalQuestions": bson.M{"$gt": 1},
+	}
+	if category != "" {
+		match["category"] = category
+	}
+
+	is_correct := bson.M{"$cond": bson.A{"$questions.isCorrect", 1, 0}}
+	rest := bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{"$correctCount", is_correct}},
+		bson.M{"$subtract": bson.A{"$totalQuestions", 1}},
+	}}
+
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err := attempts_coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$project", Value: bson.M{"questions": 1, "correctCount": 1, "totalQuestions": 1}}},
+		{{Key: "$unwind", Value: "$questions"}},
+		{{Key: "$project", Value: bson.M{
+			"questionId": "$questions.questionId",
+			"answer":     "$questions.userAnswer",
+			"correct":    is_correct,
+			"rest":       rest,
+			"time":       bson.M{"$ifNull": bson.A{"$questions.timeSpent", 0}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       bson.M{"question": "$questionId", "answer": "$answer"},
+			"count":     bson.M{"$sum": 1},
+			"correct":   bson.M{"$sum": "$correct"},
+			"sumRest":   bson.M{"$sum": "$rest"},
+			"sumRest2":  bson.M{"$sum": bson.M{"$multiply": bson.A{"$rest", "$rest"}}},
+			"sumTime":   bson.M{"$sum": "$time"},
+			"timeCount": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$time", 0}}, 1, 0}}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": "$_id.question",
+			"answers": bson.M{"$push": bson.M{
+				"answer":    "$_id.answer",
+				"count":     "$count",
+				"correct":   "$correct",
+				"sumRest":   "$sumRest",
+				"sumRest2":  "$sumRest2",
+				"sumTime":   "$sumTime",
+				"timeCount": "$timeCount",
+			}},
+		}}},
+	})
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	aggregates := []itemAggregate{}
+	if err := cursor.All(ctx, &aggregates); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	return aggregates, nil
+}
+
+func computeItemStats(question models.Question, groups []itemAnswerGroup) ItemStats {
+	stats := ItemStats{
+		QuestionID:    question.ID,
+		Number:        question.QuestionID,
+		Question:      question.Question,
+		Category:      question.Category,
+		Difficulty:    question.Difficulty,
+		CorrectAnswer: question.CorrectAnswer,
+		IsActive:      question.IsActive,
+		Distractors:   []DistractorStats{},
+		Flags:         []string{},
+	}
+
+	var correct, omitted, time_count int64
+	var sum_rest, sum_rest2, sum_rest_correct, sum_time float64
+	by_answer := map[string]itemAnswerGroup{}
+	for _, group := range groups {
+		stats.Responses += group.Count
+		correct += group.Correct
+		sum_rest += group.SumRest
+		sum_rest2 += group.SumRest2
+		sum_time += group.SumTime
+		time_count += group.TimeCount
+		if group.Correct > 0 {
+			// Mean rest score of the correct group, weighted by how many got it right
+			sum_rest_correct += group.SumRest * float64(group.Correct) / float64(group.Count)
+		}
+		if group.Answer == "" {
+			omitted += group.Count
+			continue
+		}
+		by_answer[group.Answer] = group
+	}
+	if stats.Responses == 0 {
+		return stats
+	}
+
+	n := float64(stats.Responses)
+	stats.PValue = float64(correct) / n
+	stats.OmitRate = float64(omitted) / n
+	if time_count > 0 {
+		avg := sum_time / float64(time_count)
+		stats.AvgTimeSeconds = &avg
+	}
+
+	// r_pb = (M_correct - M_all) / SD_all * sqrt(p / (1 - p))
+	mean := sum_rest / n
+	variance := sum_rest2/n - mean*mean
+	if correct > 0 && correct < stats.Responses && variance > 1e-12 {
+		mean_correct := sum_rest_correct / float64(correct)
+		r_pb := (mean_correct - mean) / math.Sqrt(variance) * math.Sqrt(stats.PValue/(1-stats.PValue))
+		stats.PointBiserial = &r_pb
+	}
+
+	// Options first in their authored order, then answers that are no longer options
+	answers := slices.Clone(question.Options)
+	for answer := range by_answer {
+		if !slices.Contains(answers, answer) {
+			answers = append(answers, answer)
+		}
+	}
+	for _, answer := range answers {
+		group := by_answer[answer]
+		distractor := DistractorStats{
+			Answer: answer,
+			IsKey:  answer == question.CorrectAnswer,
+			Count:  group.Count,
+			Rate:   float64(group.Count) / n,
+		}
+		if group.Count > 0 {
+			mean_score := group.SumRest / float64(group.Count)
+			distractor.MeanScore = &mean_score
+		}
+		stats.Distractors = append(stats.Distractors, distractor)
+	}
+
+	stats.Flags = flagItem(stats)
+	return stats
+}
+
+// flagItem marks items authors should look at. A likely miskey shows as
+// negative discrimination, or a distractor that is both more popular than the
+// key and picked by stronger students.
+func flagItem(stats ItemStats) []string {
+	flags := []string{}
+
+	key_index := slices.IndexFunc(stats.Distractors, func(d DistractorStats) bool { return d.IsKey })
+	miskeyed := stats.PointBiserial != nil && *stats.PointBiserial < ITEM_MISKEY_DISCRIMINATION_MAX
+	if key_index != -1 && !miskeyed {
+		key := stats.Distractors[key_index]
+		for _, d := range stats.Distractors {
+			if d.IsKey || d.MeanScore == nil {
+				continue
+			}
+			if d.Count > key.Count && (key.MeanScore == nil || *d.MeanScore > *key.MeanScore) {
+				miskeyed = true
+				break
+			}
+		}
+	}
+	if miskeyed {
+		flags = append(flags, ITEM_FLAG_POSSIBLY_MISKEYED)
+	}
+
+	if stats.PValue >= ITEM_TOO_EASY_P_VALUE {
+		flags = append(flags, ITEM_FLAG_TOO_EASY)
+	} else if stats.PValue <= ITEM_TOO_HARD_P_VALUE {
+		flags = append(flags, ITEM_FLAG_TOO_HARD)
+	}
+
+	if !miskeyed && stats.PointBiserial != nil && *stats.PointBiserial < ITEM_LOW_DISCRIMINATION {
+		flags = append(flags, ITEM_FLAG_LOW_DISCRIMINATION)
+	}
+
+	return flags
+}
+
+func sortItemStats(items []ItemStats, sort_by string) {
+	discrimination := func(s ItemStats) float64 {
+		if s.PointBiserial == nil {
+			return math.Inf(1)
+		}
+		return *s.PointBiserial
+	}
+
+	slices.SortStableFunc(items, func(a, b ItemStats) int {
+		switch sort_by {
+		case "pValue":
+			return cmp.Compare(b.PValue, a.PValue)
+		case "responses":
+			return cmp.Compare(b.Responses, a.Responses)
+		case "questionId":
+			return cmp.Compare(a.Number, b.Number)
+		default:
+			// Worst discriminating, i.e. most suspicious, first
+			return cmp.Compare(discrimination(a), discrimination(b))
+		}
+	})
+}
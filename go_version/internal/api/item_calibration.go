@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"go_version/internal/irt"
+	"go_version/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const DEFAULT_CALIBRATION_MIN_RESPONSES = 30
+
+type ItemCalibrationOptions struct {
+	Model        string
+	Category     string
+	MinResponses int
+}
+
+type ItemCalibrationSummary struct {
+	Attempts   int
+	Calibrated int
+	Skipped    int
+	Iterations int
+	Converged  bool
+}
+
+// CalibrateItemParameters fits IRT parameters for every question with enough
+// graded exam responses and stores them for the adaptive engine. Practice
+// attempts are left out, students look answers up while practicing.
+func (cfg *AppConfig) CalibrateItemParameters(ctx context.Context, opts ItemCalibrationOptions) (ItemCalibrationSummary, error) {
+	summary := ItemCalibrationSummary{}
+	if opts.Model == "" {
+		opts.Model = irt.MODEL_2PL
+	}
+	if opts.MinResponses == 0 {
+		opts.MinResponses = DEFAULT_CALIBRATION_MIN_RESPONSES
+	}
+
+	filter := bson.M{"isPracticeMode": bson.M{"$ne": true}}
+	if opts.Category != "" {
+		filter["category"] = opts.Category
+	}
+
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err := attempts_coll.Find(ctx, filter,
+		options.Find().SetProjection(bson.M{"category": 1, "questions.questionId": 1, "questions.userAnswer": 1, "questions.isCorrect": 1}),
+	)
+	if err != nil {
+		return summary, err
+	}
+	defer cursor.Close(ctx)
+
+	type itemInfo struct {
+		question_id bson.ObjectID
+		category    string
+		responses   int
+	}
+	index_of := map[bson.ObjectID]int{}
+	items := []itemInfo{}
+	patterns := []irt.Pattern{}
+
+	for cursor.Next(ctx) {
+		var attempt models.TestAttempt
+		if err := cursor.Decode(&attempt); err != nil {
+			return summary, err
+		}
+
+		pattern := irt.Pattern{}
+		for _, q := range attempt.Questions {
+			// Unanswered questions say nothing about the item
+			if q.UserAnswer == "" {
+				continue
+			}
+			index, ok := index_of[q.QuestionID]
+			if !ok {
+				index = len(items)
+				index_of[q.QuestionID] = index
+				items = append(items, itemInfo{question_id: q.QuestionID, category: attempt.Category})
+			}
+			items[index].responses++
+			pattern[index] = q.IsCorrect
+		}
+		if len(pattern) != 0 {
+			patterns = append(patterns, pattern)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return summary, err
+	}
+	summary.Attempts = len(patterns)
+
+	// Re-index to the items with enough responses
+	kept := []itemInfo{}
+	remap := map[int]int{}
+	for i, item := range items {
+		if item.responses < opts.MinResponses {
+			summary.Skipped++
+			continue
+		}
+		remap[i] = len(kept)
+		kept = append(kept, item)
+	}
+	if len(kept) == 0 {
+		return summary, nil
+	}
+
+	filtered := make([]irt.Pattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		next := irt.Pattern{}
+		for index, correct := range pattern {
+			if to, ok := remap[index]; ok {
+				next[to] = correct
+			}
+		}
+		if len(next) != 0 {
+			filtered = append(filtered, next)
+		}
+	}
+
+	result := irt.Calibrate(len(kept), filtered, irt.CalibrationOptions{Model: opts.Model})
+	summary.Iterations = result.Iterations
+	summary.Converged = result.Converged
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	item_parameters_coll := cfg.DATABASE.Collection(models.ITEM_PARAMETERS_COLLECTION)
+	for i, item := range kept {
+		fitted := result.Items[i]
+		_, err := item_parameters_coll.UpdateOne(ctx,
+			bson.M{"questionId": item.question_id},
+			bson.M{"$set": bson.M{
+				"category":     item.category,
+				"model":        opts.Model,
+				"a":            fitted.A,
+				"b":            fitted.B,
+				"c":            fitted.C,
+				"responses":    int32(item.responses),
+				"calibratedAt": now,
+			}},
+			options.UpdateOne().SetUpsert(true),
+		)
+		if err != nil {
+			return summary, err
+		}
+		summary.Calibrated++
+	}
+
+	return summary, nil
+}
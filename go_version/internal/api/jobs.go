@@ -0,0 +1,591 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_JOB_POSTINGS_LIMIT = 20
+	MAX_JOB_POSTINGS_LIMIT     = 100
+
+	// Postings can't stay open for longer than this
+	MAX_JOB_POSTING_DEADLINE = 365 * 24 * time.Hour
+)
+
+type JobPostingRequestBody struct {
+	Title          string    `json:"title" validate:"required,min=3,max=120"`
+	Description    string    `json:"description" validate:"required,min=20,max=10000"`
+	City           string    `json:"city" validate:"required"`
+	Majors         []string  `json:"majors" validate:"max=20"`
+	Interests      []string  `json:"interests" validate:"max=20"`
+	EmploymentType string    `json:"employmentType" validate:"required,oneof=full_time part_time internship contract"`
+	Categories     []string  `json:"categories" validate:"max=5,dive,oneof=backend frontend"`
+	Deadline       time.Time `json:"deadline" validate:"required"`
+
+	// Only on creation, postings start as drafts unless published right away
+	Status string `json:"status" validate:"omitempty,oneof=draft published"`
+}
+
+type JobPostingStatusRequestBody struct {
+	Status string `json:"status" validate:"required,oneof=published closed"`
+}
+
+// Company endpoints
+
+func (cfg *AppConfig) GetMyJobPostings(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter := bson.M{"companyId": user_id}
+	if status := strings.ToLower(r.URL.Query().Get("status")); status != "" {
+		if !slices.Contains(models.GetValidJobStatuses(), status) {
+			return utils.NewBadRequest("Invalid status")
+		}
+		filter["status"] = status
+	}
+	page, limit := parsePagination(r, DEFAULT_JOB_POSTINGS_LIMIT, MAX_JOB_POSTINGS_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	postings, total, err := cfg.findJobPostings(ctx, filter, options.Find().SetSort(bson.D{{Key: "updatedAt", Value: -1}}), page, limit)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"postings":   postings,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Job postings provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetMyJobPosting(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	posting_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	posting, err := cfg.findOwnedJobPosting(ctx, user_id, posting_id)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Job posting provided successfully",
+		map[string]any{"posting": posting},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) CreateJobPosting(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	req_body, err := cfg.parseJobPostingRequestBody(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	posting := models.JobPosting{
+		ID:             bson.NewObjectID(),
+		CompanyID:      user_id,
+		CompanyName:    companyDisplayName(user),
+		Status:         models.JOB_STATUS_DRAFT,
+		MatchesStaleAt: bson.NewDateTimeFromTime(now),
+		CreatedAt:      bson.NewDateTimeFromTime(now),
+		UpdatedAt:      bson.NewDateTimeFromTime(now),
+	}
+	req_body.applyTo(&posting)
+
+	if req_body.Status == models.JOB_STATUS_PUBLISHED {
+		if err := checkCanPublishJobPosting(user, posting, now); err != nil {
+			return err
+		}
+		posting.Status = models.JOB_STATUS_PUBLISHED
+		posting.PublishedAt = bson.NewDateTimeFromTime(now)
+	}
+
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	if _, err := job_postings_coll.InsertOne(ctx, posting); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Job posting created successfully",
+		map[string]any{"posting": posting},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) UpdateJobPosting(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	posting_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	req_body, err := cfg.parseJobPostingRequestBody(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	posting, err := cfg.findOwnedJobPosting(ctx, user_id, posting_id)
+	if err != nil {
+		return err
+	}
+	if posting.Status == models.JOB_STATUS_CLOSED {
+		return utils.NewConflict("Reopen the job posting before editing it")
+	}
+
+	previous_status := posting.Status
+	req_body.applyTo(&posting)
+	posting.UpdatedAt = bson.NewDateTimeFromTime(time.Now())
+	posting.MatchesStaleAt = posting.UpdatedAt
+
+	// The status only changes through its own endpoint, guard it so a
+	// concurrent close isn't overwritten
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	result, err := job_postings_coll.ReplaceOne(ctx, bson.M{"_id": posting.ID, "status": previous_status}, posting)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewConflict("The job posting changed status, please reload it")
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Job posting updated successfully",
+		map[string]any{"posting": posting},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// UpdateJobPostingStatus publishes, closes or reopens a posting.
+func (cfg *AppConfig) UpdateJobPostingStatus(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	posting_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := JobPostingStatusRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing job posting status request body", http.StatusBadRequest, err)
+	}
+	req_body.Status = strings.ToLower(strings.TrimSpace(req_body.Status))
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	posting, err := cfg.findOwnedJobPosting(ctx, user_id, posting_id)
+	if err != nil {
+		return err
+	}
+	if !posting.CanTransitionTo(req_body.Status) {
+		return utils.NewConflict("A " + posting.Status + " job posting can't be " + req_body.Status)
+	}
+
+	now := time.Now()
+	set := bson.M{"status": req_body.Status, "matchesStaleAt": bson.NewDateTimeFromTime(now), "updatedAt": bson.NewDateTimeFromTime(now)}
+	switch req_body.Status {
+	case models.JOB_STATUS_PUBLISHED:
+		if err := checkCanPublishJobPosting(user, posting, now); err != nil {
+			return err
+		}
+		if posting.PublishedAt == 0 {
+			set["publishedAt"] = bson.NewDateTimeFromTime(now)
+		}
+	case models.JOB_STATUS_CLOSED:
+		set["closedAt"] = bson.NewDateTimeFromTime(now)
+	}
+
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	err = job_postings_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": posting.ID, "status": posting.Status},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&posting)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewConflict("The job posting changed status, please reload it")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Job posting "+req_body.Status+" successfully",
+		map[string]any{"posting": posting},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// DeleteJobPosting deletes a draft, postings students could see are closed instead.
+func (cfg *AppConfig) DeleteJobPosting(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	posting_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	posting, err := cfg.findOwnedJobPosting(ctx, user_id, posting_id)
+	if err != nil {
+		return err
+	}
+	if posting.Status != models.JOB_STATUS_DRAFT {
+		return utils.NewConflict("Only drafts can be deleted, close the job posting instead")
+	}
+
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	result, err := job_postings_coll.DeleteOne(ctx, bson.M{"_id": posting.ID, "status": models.JOB_STATUS_DRAFT})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if result.DeletedCount == 0 {
+		return utils.NewConflict("The job posting changed status, please reload it")
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Job posting deleted successfully",
+		nil,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// Public endpoints
+
+// SearchJobPostings lists open postings. Query params: q (full text over
+// title, description and company), city, major, interest and employmentType
+// (repeatable), sort (newest|deadline|relevance) and pagination.
+func (cfg *AppConfig) SearchJobPostings(w http.ResponseWriter, r *http.Request) error {
+	params := r.URL.Query()
+	page, limit := parsePagination(r, DEFAULT_JOB_POSTINGS_LIMIT, MAX_JOB_POSTINGS_LIMIT)
+
+	filter := bson.M{
+		"status":   models.JOB_STATUS_PUBLISHED,
+		"deadline": bson.M{"$gt": bson.NewDateTimeFromTime(time.Now())},
+	}
+	q := strings.TrimSpace(params.Get("q"))
+	if q != "" {
+		filter["$text"] = bson.M{"$search": q}
+	}
+	for param, field := range map[string]string{"city": "city", "major": "majors", "interest": "interests"} {
+		if values := params[param]; len(values) != 0 {
+			filter[field] = bson.M{"$in": values}
+		}
+	}
+	if types := params["employmentType"]; len(types) != 0 {
+		for _, employment_type := range types {
+			if !slices.Contains(models.GetValidEmploymentTypes(), employment_type) {
+				return utils.NewBadRequest("Invalid employmentType")
+			}
+		}
+		filter["employmentType"] = bson.M{"$in": types}
+	}
+
+	sort_by := params.Get("sort")
+	if sort_by == "" {
+		sort_by = "newest"
+		if q != "" {
+			sort_by = "relevance"
+		}
+	}
+	opts := options.Find()
+	switch sort_by {
+	case "newest":
+		opts.SetSort(bson.D{{Key: "publishedAt", Value: -1}, {Key: "_id", Value: -1}})
+	case "deadline":
+		opts.SetSort(bson.D{{Key: "deadline", Value: 1}, {Key: "_id", Value: 1}})
+	case "relevance":
+		if q == "" {
+			return utils.NewBadRequest("Sorting by relevance needs a search query")
+		}
+		opts.SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}})
+		opts.SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "_id", Value: -1}})
+	default:
+		return utils.NewBadRequest("Invalid sort, expected newest, deadline or relevance")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	postings, total, err := cfg.findJobPostings(ctx, filter, opts, page, limit)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"postings":   postings,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Job postings provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetJobPosting shows a published or closed posting, drafts stay private.
+func (cfg *AppConfig) GetJobPosting(w http.ResponseWriter, r *http.Request) error {
+	posting_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var posting models.JobPosting
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	err = job_postings_coll.FindOne(ctx, bson.M{"_id": posting_id, "status": bson.M{"$ne": models.JOB_STATUS_DRAFT}}).Decode(&posting)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Job posting not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Job posting provided successfully",
+		map[string]any{"posting": posting},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// StartJobPostingCloser closes published postings once their deadline passed.
+// It blocks until ctx is cancelled, run it in a goroutine.
+func (cfg *AppConfig) StartJobPostingCloser(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := cfg.closeExpiredJobPostings(ctx); err != nil {
+				log.Printf("job posting closer: %s", err.Error())
+			}
+		}
+	}
+}
+
+func (cfg *AppConfig) closeExpiredJobPostings(ctx context.Context) error {
+	close_ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	_, err := job_postings_coll.UpdateMany(close_ctx,
+		bson.M{"status": models.JOB_STATUS_PUBLISHED, "deadline": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"status": models.JOB_STATUS_CLOSED, "closedAt": now, "matchesStaleAt": now, "updatedAt": now}},
+	)
+	return err
+}
+
+func (cfg *AppConfig) parseJobPostingRequestBody(ctx context.Context, r *http.Request) (JobPostingRequestBody, error) {
+	req_body := JobPostingRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return req_body, utils.NewAppError("Error while parsing job posting request body", http.StatusBadRequest, err)
+	}
+
+	req_body.Title = sanitizeInput(req_body.Title)
+	req_body.Description = sanitizeInput(req_body.Description)
+	req_body.City = sanitizeInput(req_body.City)
+	req_body.EmploymentType = strings.ToLower(strings.TrimSpace(req_body.EmploymentType))
+	req_body.Status = strings.ToLower(strings.TrimSpace(req_body.Status))
+	for i, major := range req_body.Majors {
+		req_body.Majors[i] = sanitizeInput(major)
+	}
+	for i, interest := range req_body.Interests {
+		req_body.Interests[i] = sanitizeInput(interest)
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return req_body, utils.NewValidationError(field_errors)
+	}
+
+	if !req_body.Deadline.After(time.Now()) {
+		return req_body, utils.NewBadRequest("The deadline must be in the future")
+	}
+	if req_body.Deadline.After(time.Now().Add(MAX_JOB_POSTING_DEADLINE)) {
+		return req_body, utils.NewBadRequest("The deadline can't be more than a year away")
+	}
+
+	cities_coll := cfg.DATABASE.Collection(models.CITIES_COLLECTION)
+	if err := valueFoundInDatabase(ctx, cities_coll, "name", req_body.City); err != nil {
+		return req_body, err
+	}
+
+	majors := []string{}
+	majors_coll := cfg.DATABASE.Collection(models.MAJORS_COLLECTION)
+	for _, major := range req_body.Majors {
+		if slices.Contains(majors, major) {
+			continue
+		}
+		if err := valueFoundInDatabase(ctx, majors_coll, "name", major); err != nil {
+			return req_body, err
+		}
+		majors = append(majors, major)
+	}
+	req_body.Majors = majors
+
+	valid, unique_interests, message := validateInterests(req_body.Interests)
+	if !valid {
+		return req_body, utils.NewBadRequest(message)
+	}
+	req_body.Interests = unique_interests
+
+	categories := []string{}
+	for _, category := range req_body.Categories {
+		if !slices.Contains(categories, category) {
+			categories = append(categories, category)
+		}
+	}
+	req_body.Categories = categories
+
+	return req_body, nil
+}
+
+func (body *JobPostingRequestBody) applyTo(posting *models.JobPosting) {
+	posting.Title = body.Title
+	posting.Description = body.Description
+	posting.City = body.City
+	posting.Majors = body.Majors
+	posting.Interests = body.Interests
+	posting.EmploymentType = body.EmploymentType
+	posting.Categories = body.Categories
+	posting.Deadline = bson.NewDateTimeFromTime(body.Deadline)
+}
+
+// checkCanPublishJobPosting keeps postings of unverified companies and
+// postings past their deadline away from students.
+func checkCanPublishJobPosting(company models.User, posting models.JobPosting, now time.Time) error {
+	if !company.IsEmailVerified {
+		return utils.NewForbidden("Verify your email before publishing job postings")
+	}
+	if !posting.Deadline.Time().After(now) {
+		return utils.NewBadRequest("Move the deadline to the future before publishing")
+	}
+	return nil
+}
+
+func companyDisplayName(company models.User) string {
+	if company.CompanyName != "" {
+		return company.CompanyName
+	}
+	return company.FullName
+}
+
+func (cfg *AppConfig) findOwnedJobPosting(ctx context.Context, company_id, posting_id bson.ObjectID) (models.JobPosting, error) {
+	var posting models.JobPosting
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	err := job_postings_coll.FindOne(ctx, bson.M{"_id": posting_id, "companyId": company_id}).Decode(&posting)
+	if err == mongo.ErrNoDocuments {
+		return posting, utils.NewNotFound("Job posting not found")
+	} else if err != nil {
+		return posting, utils.NewInternalServerError(err)
+	}
+	return posting, nil
+}
+
+func (cfg *AppConfig) findJobPostings(ctx context.Context, filter bson.M, opts *options.FindOptionsBuilder, page, limit int64) ([]models.JobPosting, int64, error) {
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	total, err := job_postings_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+
+	cursor, err := job_postings_coll.Find(ctx, filter, opts.SetSkip((page-1)*limit).SetLimit(limit))
+	if err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+	postings := []models.JobPosting{}
+	if err := cursor.All(ctx, &postings); err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+	return postings, total, nil
+}
@@ -0,0 +1,762 @@
+package api
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_LEADERBOARD_LIMIT = 10
+	MAX_LEADERBOARD_LIMIT     = 100
+
+	DEFAULT_LEADERBOARD_NEIGHBORS = 3
+	MAX_LEADERBOARD_NEIGHBORS     = 10
+
+	// Snapshots older than this are rebuilt by the refresher
+	LEADERBOARD_SNAPSHOT_MAX_AGE = 10 * time.Minute
+
+	// Entries of a snapshot that was never swapped in are removed after this
+	LEADERBOARD_ORPHAN_AGE = time.Hour
+
+	LEADERBOARD_WRITE_BATCH_SIZE = 1000
+)
+
+type LeaderboardQuery struct {
+	Window     string
+	Period     string
+	Category   string
+	Scope      string
+	ScopeValue string
+}
+
+type LeaderboardView struct {
+	Window     string        `json:"window"`
+	Period     string        `json:"period"`
+	Category   string        `json:"category"`
+	Scope      string        `json:"scope"`
+	ScopeValue string        `json:"scopeValue,omitempty"`
+	TotalUsers int64         `json:"totalUsers"`
+	ComputedAt bson.DateTime `json:"computedAt"`
+	Final      bool          `json:"final"`
+}
+
+// GetLeaderboard returns a page of a leaderboard snapshot. Query params:
+// window (weekly|monthly|all), period (e.g. 2026-W07, 2026-02, defaults to
+// the current one), category (all|backend|frontend) and scope
+// (global|university|major) with an optional value, by default the user's own.
+func (cfg *AppConfig) GetLeaderboard(w http.ResponseWriter, r *http.Request) error {
+	_, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	query, err := parseLeaderboardQuery(r, user)
+	if err != nil {
+		return err
+	}
+	page, limit := parsePagination(r, DEFAULT_LEADERBOARD_LIMIT, MAX_LEADERBOARD_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	leaderboard, err := cfg.findLeaderboard(ctx, query)
+	if err != nil {
+		return err
+	}
+	entries, total, err := cfg.findLeaderboardEntries(ctx, leaderboard, query, page, limit)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"leaderboard": newLeaderboardView(leaderboard, query, total),
+		"entries":     entries,
+		"pagination":  newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Leaderboard provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetMyLeaderboardRank returns the user's rank in a leaderboard together with
+// the users right above and below (?neighbors=, default 3).
+func (cfg *AppConfig) GetMyLeaderboardRank(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	query, err := parseLeaderboardQuery(r, user)
+	if err != nil {
+		return err
+	}
+	neighbors, err := strconv.ParseInt(r.URL.Query().Get("neighbors"), 10, 64)
+	if err != nil || neighbors < 0 {
+		neighbors = DEFAULT_LEADERBOARD_NEIGHBORS
+	}
+	neighbors = min(neighbors, MAX_LEADERBOARD_NEIGHBORS)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	leaderboard, err := cfg.findLeaderboard(ctx, query)
+	if err != nil {
+		return err
+	}
+	standing, err := cfg.findLeaderboardStanding(ctx, leaderboard, query, user_id, neighbors)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"leaderboard": newLeaderboardView(leaderboard, query, standing.TotalUsers),
+		"rank":        standing.Rank,
+		"totalUsers":  standing.TotalUsers,
+		"points":      standing.Points,
+		"percentile":  standing.Percentile,
+		"entry":       standing.Entry,
+		"above":       standing.Above,
+		"below":       standing.Below,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Leaderboard rank provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetLeaderboardCompat serves the Node /analytics/leaderboard shape: a flat
+// ranked list, with timeRange (week|month|all), category and limit.
+func (cfg *AppConfig) GetLeaderboardCompat(w http.ResponseWriter, r *http.Request) error {
+	query, err := parseLeaderboardCompatQuery(r)
+	if err != nil {
+		return err
+	}
+	limit, err := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+	if err != nil || limit < 1 {
+		limit = DEFAULT_LEADERBOARD_LIMIT
+	}
+	limit = min(limit, MAX_LEADERBOARD_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	leaderboard, err := cfg.findLeaderboard(ctx, query)
+	if err != nil {
+		return err
+	}
+	entries, _, err := cfg.findLeaderboardEntries(ctx, leaderboard, query, 1, limit)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Leaderboard provided successfully",
+		entries,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetLeaderboardPositionCompat serves the Node /analytics/leaderboard/position shape.
+func (cfg *AppConfig) GetLeaderboardPositionCompat(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	query, err := parseLeaderboardCompatQuery(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	leaderboard, err := cfg.findLeaderboard(ctx, query)
+	if err != nil {
+		return err
+	}
+	standing, err := cfg.findLeaderboardStanding(ctx, leaderboard, query, user_id, 0)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"rank":       standing.Rank,
+		"totalUsers": standing.TotalUsers,
+		"points":     standing.Points,
+		"percentile": standing.Percentile,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Leaderboard position provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func parseLeaderboardQuery(r *http.Request, user models.User) (LeaderboardQuery, error) {
+	params := r.URL.Query()
+	query := LeaderboardQuery{
+		Window:     strings.ToLower(params.Get("window")),
+		Period:     strings.TrimSpace(params.Get("period")),
+		Category:   strings.ToLower(params.Get("category")),
+		Scope:      strings.ToLower(params.Get("scope")),
+		ScopeValue: strings.TrimSpace(params.Get("value")),
+	}
+
+	if query.Window == "" {
+		query.Window = models.LEADERBOARD_WINDOW_WEEKLY
+	}
+	if !slices.Contains(models.GetValidLeaderboardWindows(), query.Window) {
+		return query, utils.NewBadRequest("Invalid window, expected weekly, monthly or all")
+	}
+
+	if query.Category == "" {
+		query.Category = models.LEADERBOARD_CATEGORY_ALL
+	}
+	if query.Category != models.LEADERBOARD_CATEGORY_ALL && !slices.Contains(models.GetValidCategories(), query.Category) {
+		return query, utils.NewBadRequest("Invalid category")
+	}
+
+	if query.Period == "" {
+		query.Period, _, _ = models.LeaderboardPeriod(query.Window, time.Now())
+	} else if start, _, ok := models.ParseLeaderboardPeriod(query.Window, query.Period); !ok {
+		return query, utils.NewBadRequest("Invalid period for the " + query.Window + " window")
+	} else if start.After(time.Now()) {
+		return query, utils.NewBadRequest("This period hasn't started yet")
+	}
+
+	switch query.Scope {
+	case "", models.LEADERBOARD_SCOPE_GLOBAL:
+		query.Scope = models.LEADERBOARD_SCOPE_GLOBAL
+		query.ScopeValue = ""
+	case models.LEADERBOARD_SCOPE_UNIVERSITY:
+		if query.ScopeValue == "" {
+			query.ScopeValue = user.University
+		}
+	case models.LEADERBOARD_SCOPE_MAJOR:
+		if query.ScopeValue == "" {
+			query.ScopeValue = user.Major
+		}
+	default:
+		return query, utils.NewBadRequest("Invalid scope, expected global, university or major")
+	}
+	if query.Scope != models.LEADERBOARD_SCOPE_GLOBAL && query.ScopeValue == "" {
+		return query, utils.NewBadRequest("Add your " + query.Scope + " to your profile or pass it as value")
+	}
+
+	return query, nil
+}
+
+func parseLeaderboardCompatQuery(r *http.Request) (LeaderboardQuery, error) {
+	query := LeaderboardQuery{
+		Window:   models.LEADERBOARD_WINDOW_ALL_TIME,
+		Category: strings.ToLower(r.URL.Query().Get("category")),
+		Scope:    models.LEADERBOARD_SCOPE_GLOBAL,
+	}
+	switch r.URL.Query().Get("timeRange") {
+	case "week":
+		query.Window = models.LEADERBOARD_WINDOW_WEEKLY
+	case "month":
+		query.Window = models.LEADERBOARD_WINDOW_MONTHLY
+	}
+	if query.Category == "" {
+		query.Category = models.LEADERBOARD_CATEGORY_ALL
+	}
+	if query.Category != models.LEADERBOARD_CATEGORY_ALL && !slices.Contains(models.GetValidCategories(), query.Category) {
+		return query, utils.NewBadRequest("Invalid category")
+	}
+	query.Period, _, _ = models.LeaderboardPeriod(query.Window, time.Now())
+	return query, nil
+}
+
+func newLeaderboardView(leaderboard models.Leaderboard, query LeaderboardQuery, total int64) LeaderboardView {
+	return LeaderboardView{
+		Window:     leaderboard.Window,
+		Period:     leaderboard.Period,
+		Category:   leaderboard.Category,
+		Scope:      query.Scope,
+		ScopeValue: query.ScopeValue,
+		TotalUsers: total,
+		ComputedAt: leaderboard.ComputedAt,
+		Final:      leaderboard.Final,
+	}
+}
+
+// scopeFilter selects the entries of the current snapshot in the query's scope.
+func scopeFilter(leaderboard models.Leaderboard, query LeaderboardQuery) bson.M {
+	filter := bson.M{"snapshotId": leaderboard.SnapshotID}
+	switch query.Scope {
+	case models.LEADERBOARD_SCOPE_UNIVERSITY:
+		filter["university"] = query.ScopeValue
+	case models.LEADERBOARD_SCOPE_MAJOR:
+		filter["major"] = query.ScopeValue
+	}
+	return filter
+}
+
+// findLeaderboardEntries returns a page of the leaderboard, ranked within the
+// scope: global ranks come from the snapshot, scoped ones are derived from
+// the first entry's rank since entries are ordered by points.
+func (cfg *AppConfig) findLeaderboardEntries(ctx context.Context, leaderboard models.Leaderboard, query LeaderboardQuery, page, limit int64) ([]models.LeaderboardEntry, int64, error) {
+	entries_coll := cfg.DATABASE.Collection(models.LEADERBOARD_ENTRIES_COLLECTION)
+	filter := scopeFilter(leaderboard, query)
+
+	total := leaderboard.TotalUsers
+	if query.Scope != models.LEADERBOARD_SCOPE_GLOBAL {
+		var err error
+		total, err = entries_coll.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, 0, utils.NewInternalServerError(err)
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "position", Value: 1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := entries_coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+	entries := []models.LeaderboardEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+
+	if query.Scope != models.LEADERBOARD_SCOPE_GLOBAL && len(entries) > 0 {
+		first_rank, err := cfg.scopedRank(ctx, leaderboard, query, entries[0].Points)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := range entries {
+			switch {
+			case i == 0:
+				entries[i].Rank = first_rank
+			case entries[i].Points == entries[i-1].Points:
+				entries[i].Rank = entries[i-1].Rank
+			default:
+				entries[i].Rank = (page-1)*limit + int64(i) + 1
+			}
+		}
+	}
+
+	return entries, total, nil
+}
+
+// scopedRank is the competition rank of a score within the query's scope.
+func (cfg *AppConfig) scopedRank(ctx context.Context, leaderboard models.Leaderboard, query LeaderboardQuery, points int64) (int64, error) {
+	filter := scopeFilter(leaderboard, query)
+	filter["points"] = bson.M{"$gt": points}
+	ahead, err := cfg.DATABASE.Collection(models.LEADERBOARD_ENTRIES_COLLECTION).CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, utils.NewInternalServerError(err)
+	}
+	return ahead + 1, nil
+}
+
+type LeaderboardStanding struct {
+	Rank       *int64                    `json:"rank"`
+	TotalUsers int64                     `json:"totalUsers"`
+	Points     int64                     `json:"points"`
+	Percentile int64                     `json:"percentile"`
+	Entry      *models.LeaderboardEntry  `json:"entry"`
+	Above      []models.LeaderboardEntry `json:"above"`
+	Below      []models.LeaderboardEntry `json:"below"`
+}
+
+// findLeaderboardStanding looks up the user's entry and its neighbors through
+// the (snapshotId, scope, position) indexes, without scanning the board.
+func (cfg *AppConfig) findLeaderboardStanding(ctx context.Context, leaderboard models.Leaderboard, query LeaderboardQuery, user_id bson.ObjectID, neighbors int64) (LeaderboardStanding, error) {
+	entries_coll := cfg.DATABASE.Collection(models.LEADERBOARD_ENTRIES_COLLECTION)
+	standing := LeaderboardStanding{
+		TotalUsers: leaderboard.TotalUsers,
+		Above:      []models.LeaderboardEntry{},
+		Below:      []models.LeaderboardEntry{},
+	}
+
+	filter := scopeFilter(leaderboard, query)
+	if query.Scope != models.LEADERBOARD_SCOPE_GLOBAL {
+		total, err := entries_coll.CountDocuments(ctx, filter)
+		if err != nil {
+			return standing, utils.NewInternalServerError(err)
+		}
+		standing.TotalUsers = total
+	}
+
+	var entry models.LeaderboardEntry
+	err := entries_coll.FindOne(ctx, bson.M{"snapshotId": leaderboard.SnapshotID, "userId": user_id}).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		// Not ranked in this window
+		return standing, nil
+	} else if err != nil {
+		return standing, utils.NewInternalServerError(err)
+	}
+	if query.Scope == models.LEADERBOARD_SCOPE_UNIVERSITY && entry.University != query.ScopeValue ||
+		query.Scope == models.LEADERBOARD_SCOPE_MAJOR && entry.Major != query.ScopeValue {
+		return standing, nil
+	}
+
+	if query.Scope != models.LEADERBOARD_SCOPE_GLOBAL {
+		entry.Rank, err = cfg.scopedRank(ctx, leaderboard, query, entry.Points)
+		if err != nil {
+			return standing, err
+		}
+	}
+	standing.Entry = &entry
+	standing.Rank = &entry.Rank
+	standing.Points = entry.Points
+	standing.Percentile = int64(math.Round((1 - float64(entry.Rank)/float64(standing.TotalUsers)) * 100))
+
+	if neighbors == 0 {
+		return standing, nil
+	}
+
+	above_filter := scopeFilter(leaderboard, query)
+	above_filter["position"] = bson.M{"$lt": entry.Position}
+	cursor, err := entries_coll.Find(ctx, above_filter,
+		options.Find().SetSort(bson.D{{Key: "position", Value: -1}}).SetLimit(neighbors),
+	)
+	if err != nil {
+		return standing, utils.NewInternalServerError(err)
+	}
+	if err := cursor.All(ctx, &standing.Above); err != nil {
+		return standing, utils.NewInternalServerError(err)
+	}
+	slices.Reverse(standing.Above)
+
+	below_filter := scopeFilter(leaderboard, query)
+	below_filter["position"] = bson.M{"$gt": entry.Position}
+	cursor, err = entries_coll.Find(ctx, below_filter,
+		options.Find().SetSort(bson.D{{Key: "position", Value: 1}}).SetLimit(neighbors),
+	)
+	if err != nil {
+		return standing, utils.NewInternalServerError(err)
+	}
+	if err := cursor.All(ctx, &standing.Below); err != nil {
+		return standing, utils.NewInternalServerError(err)
+	}
+
+	// Scoped neighbors are ranked relative to the user
+	if query.Scope != models.LEADERBOARD_SCOPE_GLOBAL {
+		if err := cfg.rankScopedNeighbors(ctx, leaderboard, query, standing.Above); err != nil {
+			return standing, err
+		}
+		if err := cfg.rankScopedNeighbors(ctx, leaderboard, query, standing.Below); err != nil {
+			return standing, err
+		}
+	}
+
+	return standing, nil
+}
+
+func (cfg *AppConfig) rankScopedNeighbors(ctx context.Context, leaderboard models.Leaderboard, query LeaderboardQuery, entries []models.LeaderboardEntry) error {
+	for i := range entries {
+		if i > 0 && entries[i].Points == entries[i-1].Points {
+			entries[i].Rank = entries[i-1].Rank
+			continue
+		}
+		rank, err := cfg.scopedRank(ctx, leaderboard, query, entries[i].Points)
+		if err != nil {
+			return err
+		}
+		entries[i].Rank = rank
+	}
+	return nil
+}
+
+// findLeaderboard returns the snapshot for the query, building it on the spot
+// the first time a board is asked for and once more after its period ended.
+func (cfg *AppConfig) findLeaderboard(ctx context.Context, query LeaderboardQuery) (models.Leaderboard, error) {
+	key := models.LeaderboardKey(query.Window, query.Period, query.Category)
+
+	var leaderboard models.Leaderboard
+	leaderboards_coll := cfg.DATABASE.Collection(models.LEADERBOARDS_COLLECTION)
+	err := leaderboards_coll.FindOne(ctx, bson.M{"key": key}).Decode(&leaderboard)
+	if err == nil && (leaderboard.Final || !leaderboardPeriodEnded(leaderboard, time.Now())) {
+		return leaderboard, nil
+	} else if err != nil && err != mongo.ErrNoDocuments {
+		return leaderboard, utils.NewInternalServerError(err)
+	}
+
+	leaderboard, err = cfg.buildLeaderboardSnapshot(ctx, query.Window, query.Period, query.Category)
+	if err != nil {
+		return leaderboard, utils.NewInternalServerError(err)
+	}
+	return leaderboard, nil
+}
+
+func leaderboardPeriodEnded(leaderboard models.Leaderboard, now time.Time) bool {
+	return leaderboard.PeriodEnd != 0 && !now.Before(leaderboard.PeriodEnd.Time())
+}
+
+// buildLeaderboardSnapshot ranks the exam results of a period into a new set
+// of entries and swaps it in. Readers keep using the previous snapshot until
+// the swap, which then deletes it. Points follow the Node formula:
+// attempts*10 + average score*2 + highest score.
+//
+// Everyone can read a leaderboard, so entries only hold what students show
+// publicly, and students who opted out of search aren't ranked.
+func (cfg *AppConfig) buildLeaderboardSnapshot(ctx context.Context, window, period, category string) (models.Leaderboard, error) {
+	now := time.Now()
+	start, end, _ := models.ParseLeaderboardPeriod(window, period)
+	key := models.LeaderboardKey(window, period, category)
+	snapshot_id := bson.NewObjectID()
+
+	match := bson.M{"isPracticeMode": bson.M{"$ne": true}}
+	if category != models.LEADERBOARD_CATEGORY_ALL {
+		match["category"] = category
+	}
+	if window != models.LEADERBOARD_WINDOW_ALL_TIME {
+		match["completedAt"] = bson.M{"$gte": bson.NewDateTimeFromTime(start), "$lt": bson.NewDateTimeFromTime(end)}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":            "$userId",
+			"totalAttempts":  bson.M{"$sum": 1},
+			"averageScore":   bson.M{"$avg": "$score"},
+			"highestScore":   bson.M{"$max": "$score"},
+			"totalCorrect":   bson.M{"$sum": "$correctCount"},
+			"totalQuestions": bson.M{"$sum": "$totalQuestions"},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         models.USERS_COLLECTION,
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$match", Value: bson.M{
+			"user.isActive":                 bson.M{"$ne": false},
+			"user.privacy.hiddenFromSearch": bson.M{"$ne": true},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"userId":          "$_id",
+			"user._id":        1,
+			"user.role":       1,
+			"user.fullName":   1,
+			"user.university": 1,
+			"user.major":      1,
+			"user.privacy":    1,
+			"totalAttempts":   1,
+			"averageScore":    bson.M{"$round": bson.A{"$averageScore", 0}},
+			"highestScore":    bson.M{"$round": bson.A{"$highestScore", 0}},
+			"accuracy": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$totalQuestions", 0}},
+				bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{bson.M{"$divide": bson.A{"$totalCorrect", "$totalQuestions"}}, 100}}, 0}},
+				0,
+			}},
+			"points": bson.M{"$round": bson.A{bson.M{"$add": bson.A{
+				bson.M{"$multiply": bson.A{"$totalAttempts", 10}},
+				bson.M{"$multiply": bson.A{"$averageScore", 2}},
+				"$highestScore",
+			}}, 0}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "points", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err := attempts_coll.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return models.Leaderboard{}, err
+	}
+	defer cursor.Close(ctx)
+
+	entries_coll := cfg.DATABASE.Collection(models.LEADERBOARD_ENTRIES_COLLECTION)
+	created_at := bson.NewDateTimeFromTime(now)
+	batch := make([]any, 0, LEADERBOARD_WRITE_BATCH_SIZE)
+	var position, rank int64
+	var previous_points int64 = math.MinInt64
+	for cursor.Next(ctx) {
+		var row struct {
+			UserID        bson.ObjectID `bson:"userId"`
+			User          models.User   `bson:"user"`
+			TotalAttempts int32         `bson:"totalAttempts"`
+			AverageScore  float64       `bson:"averageScore"`
+			HighestScore  float64       `bson:"highestScore"`
+			Accuracy      float64       `bson:"accuracy"`
+			Points        float64       `bson:"points"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return models.Leaderboard{}, err
+		}
+
+		visible := row.User.VisibleTo(models.ProfileViewer{})
+
+		position++
+		points := int64(row.Points)
+		if points != previous_points {
+			rank = position
+			previous_points = points
+		}
+		batch = append(batch, models.LeaderboardEntry{
+			ID:            bson.NewObjectID(),
+			Key:           key,
+			SnapshotID:    snapshot_id,
+			UserID:        row.UserID,
+			FullName:      visible.FullName,
+			University:    visible.University,
+			Major:         visible.Major,
+			Rank:          rank,
+			Position:      position,
+			Points:        points,
+			TotalAttempts: row.TotalAttempts,
+			AverageScore:  int32(row.AverageScore),
+			HighestScore:  int32(row.HighestScore),
+			Accuracy:      int32(row.Accuracy),
+			CreatedAt:     created_at,
+		})
+
+		if len(batch) == LEADERBOARD_WRITE_BATCH_SIZE {
+			if _, err := entries_coll.InsertMany(ctx, batch); err != nil {
+				return models.Leaderboard{}, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return models.Leaderboard{}, err
+	}
+	if len(batch) > 0 {
+		if _, err := entries_coll.InsertMany(ctx, batch); err != nil {
+			return models.Leaderboard{}, err
+		}
+	}
+
+	leaderboard := models.Leaderboard{
+		Key:        key,
+		Window:     window,
+		Period:     period,
+		Category:   category,
+		SnapshotID: snapshot_id,
+		TotalUsers: position,
+		ComputedAt: bson.NewDateTimeFromTime(now),
+		Final:      window != models.LEADERBOARD_WINDOW_ALL_TIME && !now.Before(end),
+		UpdatedAt:  bson.NewDateTimeFromTime(now),
+	}
+	if window != models.LEADERBOARD_WINDOW_ALL_TIME {
+		leaderboard.PeriodStart = bson.NewDateTimeFromTime(start)
+		leaderboard.PeriodEnd = bson.NewDateTimeFromTime(end)
+	}
+
+	// Swap the snapshot in, the replaced one is returned so it can be removed
+	var previous models.Leaderboard
+	leaderboards_coll := cfg.DATABASE.Collection(models.LEADERBOARDS_COLLECTION)
+	err = leaderboards_coll.FindOneAndUpdate(ctx,
+		bson.M{"key": key},
+		bson.M{
+			"$set":         leaderboard,
+			"$setOnInsert": bson.M{"createdAt": bson.NewDateTimeFromTime(now)},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&previous)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another instance created the board at the same time, ours is as good
+		err = leaderboards_coll.FindOneAndUpdate(ctx,
+			bson.M{"key": key},
+			bson.M{"$set": leaderboard},
+			options.FindOneAndUpdate().SetReturnDocument(options.Before),
+		).Decode(&previous)
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return models.Leaderboard{}, err
+	}
+
+	if !previous.SnapshotID.IsZero() {
+		if _, err := entries_coll.DeleteMany(ctx, bson.M{"snapshotId": previous.SnapshotID}); err != nil {
+			log.Printf("leaderboards: failed to delete snapshot %s of %s: %s", previous.SnapshotID.Hex(), key, err.Error())
+		}
+	}
+
+	return leaderboard, nil
+}
+
+// StartLeaderboardRefresher keeps the snapshots of the current periods fresh,
+// for every window and category. It blocks until ctx is cancelled, run it in
+// a goroutine.
+func (cfg *AppConfig) StartLeaderboardRefresher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := cfg.refreshLeaderboards(ctx); err != nil {
+				log.Printf("leaderboards: %s", err.Error())
+			}
+		}
+	}
+}
+
+func (cfg *AppConfig) refreshLeaderboards(ctx context.Context) error {
+	refresh_ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	now := time.Now()
+	leaderboards_coll := cfg.DATABASE.Collection(models.LEADERBOARDS_COLLECTION)
+	categories := append([]string{models.LEADERBOARD_CATEGORY_ALL}, models.GetValidCategories()...)
+
+	for _, window := range models.GetValidLeaderboardWindows() {
+		period, _, _ := models.LeaderboardPeriod(window, now)
+		for _, category := range categories {
+			key := models.LeaderboardKey(window, period, category)
+
+			var leaderboard models.Leaderboard
+			err := leaderboards_coll.FindOne(refresh_ctx, bson.M{"key": key}).Decode(&leaderboard)
+			if err == nil && now.Sub(leaderboard.ComputedAt.Time()) < LEADERBOARD_SNAPSHOT_MAX_AGE {
+				continue
+			} else if err != nil && err != mongo.ErrNoDocuments {
+				return err
+			}
+
+			if _, err := cfg.buildLeaderboardSnapshot(refresh_ctx, window, period, category); err != nil {
+				log.Printf("leaderboards: failed to build %s: %s", key, err.Error())
+			}
+		}
+	}
+
+	// Entries of builds that crashed before their swap
+	live_snapshots := []bson.ObjectID{}
+	if err := leaderboards_coll.Distinct(refresh_ctx, "snapshotId", bson.M{}).Decode(&live_snapshots); err != nil {
+		return err
+	}
+	_, err := cfg.DATABASE.Collection(models.LEADERBOARD_ENTRIES_COLLECTION).DeleteMany(refresh_ctx, bson.M{
+		"createdAt":  bson.M{"$lt": bson.NewDateTimeFromTime(now.Add(-LEADERBOARD_ORPHAN_AGE))},
+		"snapshotId": bson.M{"$nin": live_snapshots},
+	})
+	return err
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"go_version/internal/utils"
+)
+
+// sendEmail sends an email in the background, failures are only logged.
+func (cfg *AppConfig) sendEmail(to, subject, html_body string) {
+	smtp := cfg.REQUIREMENTS.SMTP
+	smtp_port, err := strconv.Atoi(smtp.SMTPPort)
+	if err != nil {
+		log.Printf("Failed to send email %q: invalid SMTP_PORT: %s", subject, err.Error())
+		return
+	}
+
+	// Buffered & closed so neither goroutine leaks when sending succeeds
+	error_chan := make(chan error, 1)
+	go func() {
+		defer close(error_chan)
+		utils.SendEmail(error_chan, smtp.AppName, smtp.EmailFrom, to, subject, html_body, smtp.SMTPHost, smtp.SMTPUser, smtp.SMTPPass, smtp_port)
+	}()
+	go func() {
+		if err := <-error_chan; err != nil {
+			log.Printf("Failed to send email: %s", err.Error())
+		}
+	}()
+}
+
+// sendCalendarEmail sends an email with an iCalendar invite in the background.
+func (cfg *AppConfig) sendCalendarEmail(to, subject, html_body, method string, invite []byte) {
+	smtp := cfg.REQUIREMENTS.SMTP
+	smtp_port, err := strconv.Atoi(smtp.SMTPPort)
+	if err != nil {
+		log.Printf("Failed to send email %q: invalid SMTP_PORT: %s", subject, err.Error())
+		return
+	}
+
+	error_chan := make(chan error, 1)
+	go func() {
+		defer close(error_chan)
+		utils.SendCalendarEmail(error_chan, smtp.AppName, smtp.EmailFrom, to, subject, html_body, method, invite, smtp.SMTPHost, smtp.SMTPUser, smtp.SMTPPass, smtp_port)
+	}()
+	go func() {
+		if err := <-error_chan; err != nil {
+			log.Printf("Failed to send email: %s", err.Error())
+		}
+	}()
+}
+
+// deliverEmail sends an email and waits for the result, for callers that retry
+// on failure such as event subscribers.
+func (cfg *AppConfig) deliverEmail(to, subject, html_body string) error {
+	smtp := cfg.REQUIREMENTS.SMTP
+	smtp_port, err := strconv.Atoi(smtp.SMTPPort)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_PORT: %w", err)
+	}
+
+	// Buffered so the sender never blocks, empty when sending succeeded
+	error_chan := make(chan error, 1)
+	utils.SendEmail(error_chan, smtp.AppName, smtp.EmailFrom, to, subject, html_body, smtp.SMTPHost, smtp.SMTPUser, smtp.SMTPPass, smtp_port)
+	close(error_chan)
+	return <-error_chan
+}
+
+func (cfg *AppConfig) deliverVerificationEmail(to, full_name, token string) error {
+	smtp := cfg.REQUIREMENTS.SMTP
+	smtp_port, err := strconv.Atoi(smtp.SMTPPort)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_PORT: %w", err)
+	}
+
+	error_chan := make(chan error, 1)
+	utils.SendVerificationEmail(error_chan, smtp.AppName, smtp.EmailFrom, to, cfg.REQUIREMENTS.Server.FrontendURL, token, full_name, smtp.SMTPHost, smtp.SMTPUser, smtp.SMTPPass, smtp_port)
+	close(error_chan)
+	return <-error_chan
+}
@@ -0,0 +1,725 @@
+package api
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"go_version/internal/glicko"
+	"go_version/internal/matching"
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_MATCHES_LIMIT = 20
+	MAX_MATCHES_LIMIT     = 100
+
+	// Weaker matches aren't stored, they would never be recommended
+	MIN_MATCH_SCORE = 20.0
+
+	MATCH_REFRESH_BATCH_SIZE = 50
+	MATCH_WRITE_BATCH_SIZE   = 500
+)
+
+// Profile fields the matching engine reads, edits to others don't make a
+// student's matches stale
+var matchProfileFields = []string{"city", "major", "graduationYear", "interests", "role"}
+
+type JobRecommendation struct {
+	Posting    PostingSummary       `json:"posting"`
+	Deadline   bson.DateTime        `json:"deadline"`
+	Score      float64              `json:"score"`
+	Reasons    []models.MatchReason `json:"reasons"`
+	Applied    bool                 `json:"applied"`
+	ComputedAt bson.DateTime        `json:"computedAt,omitempty"`
+}
+
+// MatchedCandidate leaves out contact details, companies get those once the
+// student applies.
+type MatchedCandidate struct {
+	StudentID      bson.ObjectID        `json:"studentId"`
+	FullName       string               `json:"fullName"`
+	University     string               `json:"university,omitempty"`
+	Major          string               `json:"major,omitempty"`
+	City           string               `json:"city,omitempty"`
+	GraduationYear string               `json:"graduationYear,omitempty"`
+	ProfileImage   string               `json:"profileImage,omitempty"`
+	Score          float64              `json:"score"`
+	Reasons        []models.MatchReason `json:"reasons"`
+	Applied        bool                 `json:"applied"`
+	ComputedAt     bson.DateTime        `json:"computedAt"`
+}
+
+// Student endpoints
+
+// GetJobRecommendations ranks open postings for the student from their
+// precomputed matches.
+func (cfg *AppConfig) GetJobRecommendations(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	min_score, err := parseMinScore(r)
+	if err != nil {
+		return err
+	}
+	page, limit := parsePagination(r, DEFAULT_MATCHES_LIMIT, MAX_MATCHES_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"studentId": user_id, "score": bson.M{"$gte": min_score}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         models.JOB_POSTINGS_COLLECTION,
+			"localField":   "postingId",
+			"foreignField": "_id",
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"status": models.JOB_STATUS_PUBLISHED, "deadline": bson.M{"$gt": bson.NewDateTimeFromTime(time.Now())}}},
+			},
+			"as": "posting",
+		}}},
+		{{Key: "$unwind", Value: "$posting"}},
+		{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "posting.deadline", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$facet", Value: bson.M{
+			"matches": bson.A{
+				bson.M{"$skip": (page - 1) * limit},
+				bson.M{"$limit": limit},
+			},
+			"total": bson.A{bson.M{"$count": "count"}},
+		}}},
+	}
+
+	matches_coll := cfg.DATABASE.Collection(models.MATCHES_COLLECTION)
+	cursor, err := matches_coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	var results []struct {
+		Matches []struct {
+			models.Match `bson:",inline"`
+			Posting      models.JobPosting `bson:"posting"`
+		} `bson:"matches"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	recommendations := []JobRecommendation{}
+	var total int64
+	if len(results) > 0 {
+		posting_ids := make([]bson.ObjectID, 0, len(results[0].Matches))
+		for _, row := range results[0].Matches {
+			posting_ids = append(posting_ids, row.PostingID)
+		}
+		applied, err := cfg.appliedIDs(ctx, "postingId", bson.M{"studentId": user_id, "postingId": bson.M{"$in": posting_ids}})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range results[0].Matches {
+			recommendations = append(recommendations, JobRecommendation{
+				Posting:    newPostingSummary(row.Posting),
+				Deadline:   row.Posting.Deadline,
+				Score:      row.Score,
+				Reasons:    row.Reasons,
+				Applied:    slices.Contains(applied, row.PostingID),
+				ComputedAt: row.ComputedAt,
+			})
+		}
+		if len(results[0].Total) > 0 {
+			total = results[0].Total[0].Count
+		}
+	}
+
+	response_payload := map[string]any{
+		"recommendations": recommendations,
+		"pagination":      newPagination(page, limit, total),
+		// Matches are being recomputed after a profile change
+		"updating": user.MatchesStaleAt != 0,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Recommendations provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetJobMatch explains how the student matches one posting, computed fresh
+// so it reflects edits the stored matches haven't caught up with yet.
+func (cfg *AppConfig) GetJobMatch(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	posting_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var posting models.JobPosting
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	err = job_postings_coll.FindOne(ctx, bson.M{"_id": posting_id, "status": bson.M{"$ne": models.JOB_STATUS_DRAFT}}).Decode(&posting)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Job posting not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	skills, err := cfg.findSkillLevels(ctx, []bson.ObjectID{user_id}, time.Now())
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	result := matching.Score(newMatchingStudent(user, skills[user_id]), newMatchingPosting(posting), time.Now())
+
+	response_payload := map[string]any{
+		"posting": newPostingSummary(posting),
+		"score":   result.Score,
+		"reasons": result.Reasons,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Job match provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// Company endpoints
+
+// GetPostingCandidates ranks students for one of the company's postings.
+func (cfg *AppConfig) GetPostingCandidates(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	posting_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+	min_score, err := parseMinScore(r)
+	if err != nil {
+		return err
+	}
+	page, limit := parsePagination(r, DEFAULT_MATCHES_LIMIT, MAX_MATCHES_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	posting, err := cfg.findOwnedJobPosting(ctx, user_id, posting_id)
+	if err != nil {
+		return err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"postingId": posting.ID, "score": bson.M{"$gte": min_score}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         models.USERS_COLLECTION,
+			"localField":   "studentId",
+			"foreignField": "_id",
+			"as":           "student",
+		}}},
+		{{Key: "$unwind", Value: "$student"}},
+		{{Key: "$match", Value: bson.M{
+			"student.role":                     models.ROLE_STUDENT,
+			"student.isActive":                 true,
+			"student.privacy.hiddenFromSearch": bson.M{"$ne": true},
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"matches": bson.A{
+				bson.M{"$skip": (page - 1) * limit},
+				bson.M{"$limit": limit},
+			},
+			"total": bson.A{bson.M{"$count": "count"}},
+		}}},
+	}
+
+	matches_coll := cfg.DATABASE.Collection(models.MATCHES_COLLECTION)
+	cursor, err := matches_coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	var results []struct {
+		Matches []struct {
+			models.Match `bson:",inline"`
+			Student      models.User `bson:"student"`
+		} `bson:"matches"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	candidates := []MatchedCandidate{}
+	var total int64
+	if len(results) > 0 {
+		student_ids := make([]bson.ObjectID, 0, len(results[0].Matches))
+		for _, row := range results[0].Matches {
+			student_ids = append(student_ids, row.StudentID)
+		}
+		applied, err := cfg.appliedIDs(ctx, "studentId", bson.M{"postingId": posting.ID, "studentId": bson.M{"$in": student_ids}})
+		if err != nil {
+			return err
+		}
+
+		viewer := models.ProfileViewer{ID: user_id, Role: models.ROLE_COMPANY}
+		for _, row := range results[0].Matches {
+			visible := row.Student.VisibleTo(viewer)
+			candidates = append(candidates, MatchedCandidate{
+				StudentID:      row.StudentID,
+				FullName:       visible.FullName,
+				University:     visible.University,
+				Major:          visible.Major,
+				City:           visible.City,
+				GraduationYear: visible.GraduationYear,
+				ProfileImage:   visible.ProfileImage,
+				Score:          row.Score,
+				Reasons:        visibleReasons(row.Student, viewer, row.Reasons),
+				Applied:        slices.Contains(applied, row.StudentID),
+				ComputedAt:     row.ComputedAt,
+			})
+		}
+		if len(results[0].Total) > 0 {
+			total = results[0].Total[0].Count
+		}
+	}
+
+	response_payload := map[string]any{
+		"candidates": candidates,
+		"pagination": newPagination(page, limit, total),
+		"updating":   posting.MatchesStaleAt != 0,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Candidates provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// Incremental recompute
+
+// markStudentMatchesStale queues a student's matches for recomputing.
+func (cfg *AppConfig) markStudentMatchesStale(ctx context.Context, user_id bson.ObjectID) error {
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	_, err := users_coll.UpdateOne(ctx,
+		bson.M{"_id": user_id, "role": models.ROLE_STUDENT},
+		bson.M{"$set": bson.M{"matchesStaleAt": bson.NewDateTimeFromTime(time.Now())}},
+	)
+	return err
+}
+
+func (cfg *AppConfig) markMatchesStaleOnProfileUpdate(ctx context.Context, event models.DomainEvent) error {
+	var fields []string
+	switch value := event.Payload["fields"].(type) {
+	case []string:
+		fields = value
+	case bson.A:
+		for _, field := range value {
+			if name, ok := field.(string); ok {
+				fields = append(fields, name)
+			}
+		}
+	}
+	if !slices.ContainsFunc(fields, func(field string) bool { return slices.Contains(matchProfileFields, field) }) {
+		return nil
+	}
+	return cfg.markStudentMatchesStale(ctx, event.AggregateID)
+}
+
+// StartMatchRefresher recomputes the matches of stale postings and students.
+// Anything that never had its matches computed is marked stale on startup.
+func (cfg *AppConfig) StartMatchRefresher(ctx context.Context, interval time.Duration) {
+	if err := cfg.markUncomputedMatchesStale(ctx); err != nil {
+		log.Printf("matches: %s", err.Error())
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := cfg.refreshMatches(ctx); err != nil {
+				log.Printf("matches: %s", err.Error())
+			}
+		}
+	}
+}
+
+func (cfg *AppConfig) markUncomputedMatchesStale(ctx context.Context) error {
+	mark_ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	mark := bson.M{"$set": bson.M{"matchesStaleAt": bson.NewDateTimeFromTime(time.Now())}}
+
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	_, err := job_postings_coll.UpdateMany(mark_ctx, bson.M{
+		"status":            models.JOB_STATUS_PUBLISHED,
+		"matchesComputedAt": bson.M{"$exists": false},
+		"matchesStaleAt":    bson.M{"$exists": false},
+	}, mark)
+	if err != nil {
+		return err
+	}
+
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	_, err = users_coll.UpdateMany(mark_ctx, bson.M{
+		"role":              models.ROLE_STUDENT,
+		"matchesComputedAt": bson.M{"$exists": false},
+		"matchesStaleAt":    bson.M{"$exists": false},
+	}, mark)
+	return err
+}
+
+// refreshMatches drains the stale postings, then the stale students. A
+// document edited during its recompute keeps its newer stale mark and is
+// picked up again.
+func (cfg *AppConfig) refreshMatches(ctx context.Context) error {
+	refresh_ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	failed := []bson.ObjectID{}
+	for {
+		var postings []models.JobPosting
+		if err := findStale(refresh_ctx, job_postings_coll, failed, &postings); err != nil {
+			return err
+		}
+		for _, posting := range postings {
+			if err := cfg.recomputePostingMatches(refresh_ctx, posting); err != nil {
+				log.Printf("matches: failed to recompute posting %s: %s", posting.ID.Hex(), err.Error())
+				failed = append(failed, posting.ID)
+				continue
+			}
+			if err := clearStale(refresh_ctx, job_postings_coll, posting.ID, posting.MatchesStaleAt); err != nil {
+				return err
+			}
+		}
+		if len(postings) < MATCH_REFRESH_BATCH_SIZE {
+			break
+		}
+	}
+
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	failed = []bson.ObjectID{}
+	for {
+		var students []models.User
+		if err := findStale(refresh_ctx, users_coll, failed, &students); err != nil {
+			return err
+		}
+		for _, student := range students {
+			if err := cfg.recomputeStudentMatches(refresh_ctx, student); err != nil {
+				log.Printf("matches: failed to recompute student %s: %s", student.ID.Hex(), err.Error())
+				failed = append(failed, student.ID)
+				continue
+			}
+			if err := clearStale(refresh_ctx, users_coll, student.ID, student.MatchesStaleAt); err != nil {
+				return err
+			}
+		}
+		if len(students) < MATCH_REFRESH_BATCH_SIZE {
+			break
+		}
+	}
+
+	return nil
+}
+
+func findStale(ctx context.Context, coll *mongo.Collection, skip []bson.ObjectID, results any) error {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "matchesStaleAt", Value: 1}}).
+		SetLimit(MATCH_REFRESH_BATCH_SIZE)
+	cursor, err := coll.Find(ctx, bson.M{"matchesStaleAt": bson.M{"$exists": true}, "_id": bson.M{"$nin": skip}}, opts)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
+
+// clearStale only clears the mark the recompute started from.
+func clearStale(ctx context.Context, coll *mongo.Collection, id bson.ObjectID, stale_at bson.DateTime) error {
+	_, err := coll.UpdateOne(ctx,
+		bson.M{"_id": id, "matchesStaleAt": stale_at},
+		bson.M{
+			"$unset": bson.M{"matchesStaleAt": ""},
+			"$set":   bson.M{"matchesComputedAt": bson.NewDateTimeFromTime(time.Now())},
+		},
+	)
+	return err
+}
+
+// recomputePostingMatches scores every student against the posting. Postings
+// students can't apply to anymore lose their matches.
+func (cfg *AppConfig) recomputePostingMatches(ctx context.Context, posting models.JobPosting) error {
+	now := time.Now()
+	matches_coll := cfg.DATABASE.Collection(models.MATCHES_COLLECTION)
+	if posting.Status != models.JOB_STATUS_PUBLISHED || !posting.Deadline.Time().After(now) {
+		_, err := matches_coll.DeleteMany(ctx, bson.M{"postingId": posting.ID})
+		return err
+	}
+
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	cursor, err := users_coll.Find(ctx, bson.M{"role": models.ROLE_STUDENT, "isActive": true})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	matching_posting := newMatchingPosting(posting)
+	computed_at := bson.NewDateTimeFromTime(now)
+	score_batch := func(students []models.User) error {
+		student_ids := make([]bson.ObjectID, 0, len(students))
+		for _, student := range students {
+			student_ids = append(student_ids, student.ID)
+		}
+		skills, err := cfg.findSkillLevels(ctx, student_ids, now)
+		if err != nil {
+			return err
+		}
+
+		writes := []mongo.WriteModel{}
+		for _, student := range students {
+			result := matching.Score(newMatchingStudent(student, skills[student.ID]), matching_posting, now)
+			writes = appendMatchWrite(writes, student.ID, posting, result, computed_at)
+		}
+		return writeMatches(ctx, matches_coll, writes)
+	}
+
+	students := make([]models.User, 0, MATCH_WRITE_BATCH_SIZE)
+	for cursor.Next(ctx) {
+		var student models.User
+		if err := cursor.Decode(&student); err != nil {
+			return err
+		}
+		students = append(students, student)
+		if len(students) == MATCH_WRITE_BATCH_SIZE {
+			if err := score_batch(students); err != nil {
+				return err
+			}
+			students = students[:0]
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if err := score_batch(students); err != nil {
+		return err
+	}
+
+	// Matches this run didn't refresh fell below the threshold
+	_, err = matches_coll.DeleteMany(ctx, bson.M{"postingId": posting.ID, "computedAt": bson.M{"$lt": computed_at}})
+	return err
+}
+
+// recomputeStudentMatches scores the student against every open posting.
+func (cfg *AppConfig) recomputeStudentMatches(ctx context.Context, student models.User) error {
+	now := time.Now()
+	computed_at := bson.NewDateTimeFromTime(now)
+	matches_coll := cfg.DATABASE.Collection(models.MATCHES_COLLECTION)
+	if student.Role != models.ROLE_STUDENT || !student.IsActive {
+		_, err := matches_coll.DeleteMany(ctx, bson.M{"studentId": student.ID})
+		return err
+	}
+
+	skills, err := cfg.findSkillLevels(ctx, []bson.ObjectID{student.ID}, now)
+	if err != nil {
+		return err
+	}
+	matching_student := newMatchingStudent(student, skills[student.ID])
+
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	cursor, err := job_postings_coll.Find(ctx, bson.M{"status": models.JOB_STATUS_PUBLISHED, "deadline": bson.M{"$gt": computed_at}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	writes := []mongo.WriteModel{}
+	for cursor.Next(ctx) {
+		var posting models.JobPosting
+		if err := cursor.Decode(&posting); err != nil {
+			return err
+		}
+		result := matching.Score(matching_student, newMatchingPosting(posting), now)
+		writes = appendMatchWrite(writes, student.ID, posting, result, computed_at)
+		if len(writes) == MATCH_WRITE_BATCH_SIZE {
+			if err := writeMatches(ctx, matches_coll, writes); err != nil {
+				return err
+			}
+			writes = writes[:0]
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if err := writeMatches(ctx, matches_coll, writes); err != nil {
+		return err
+	}
+
+	_, err = matches_coll.DeleteMany(ctx, bson.M{"studentId": student.ID, "computedAt": bson.M{"$lt": computed_at}})
+	return err
+}
+
+func appendMatchWrite(writes []mongo.WriteModel, student_id bson.ObjectID, posting models.JobPosting, result matching.Result, computed_at bson.DateTime) []mongo.WriteModel {
+	if result.Score < MIN_MATCH_SCORE {
+		return writes
+	}
+	match := models.Match{
+		StudentID:  student_id,
+		PostingID:  posting.ID,
+		CompanyID:  posting.CompanyID,
+		Score:      result.Score,
+		Reasons:    result.Reasons,
+		ComputedAt: computed_at,
+	}
+	return append(writes, mongo.NewReplaceOneModel().
+		SetFilter(bson.M{"studentId": student_id, "postingId": posting.ID}).
+		SetReplacement(match).
+		SetUpsert(true))
+}
+
+func writeMatches(ctx context.Context, coll *mongo.Collection, writes []mongo.WriteModel) error {
+	if len(writes) == 0 {
+		return nil
+	}
+	_, err := coll.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// findSkillLevels turns skill ratings into 0 to 1 levels: the chance of
+// answering an average question, taken at the low end of the rating's
+// interval so unproven ratings count for little.
+func (cfg *AppConfig) findSkillLevels(ctx context.Context, user_ids []bson.ObjectID, now time.Time) (map[bson.ObjectID]map[string]float64, error) {
+	levels := make(map[bson.ObjectID]map[string]float64, len(user_ids))
+	if len(user_ids) == 0 {
+		return levels, nil
+	}
+
+	ratings_coll := cfg.DATABASE.Collection(models.USER_RATINGS_COLLECTION)
+	cursor, err := ratings_coll.Find(ctx, bson.M{"userId": bson.M{"$in": user_ids}})
+	if err != nil {
+		return nil, err
+	}
+	ratings := []models.UserRating{}
+	if err := cursor.All(ctx, &ratings); err != nil {
+		return nil, err
+	}
+
+	for _, rating := range ratings {
+		low, _ := decayRating(rating, now).Interval()
+		if levels[rating.UserID] == nil {
+			levels[rating.UserID] = map[string]float64{}
+		}
+		levels[rating.UserID][rating.Category] = 1 / (1 + math.Exp(-(low-glicko.DEFAULT_RATING)/glicko.SCALE))
+	}
+	return levels, nil
+}
+
+// Profile field each match factor explains
+var matchFactorFields = map[string]string{
+	matching.FACTOR_INTERESTS:  models.PRIVACY_FIELD_INTERESTS,
+	matching.FACTOR_MAJOR:      models.PRIVACY_FIELD_MAJOR,
+	matching.FACTOR_CITY:       models.PRIVACY_FIELD_CITY,
+	matching.FACTOR_GRADUATION: models.PRIVACY_FIELD_GRADUATION_YEAR,
+}
+
+// visibleReasons drops the explanations that would give away fields the
+// student hides from the viewer, the score itself still counts them.
+func visibleReasons(student models.User, viewer models.ProfileViewer, reasons []models.MatchReason) []models.MatchReason {
+	visible := []models.MatchReason{}
+	for _, reason := range reasons {
+		if field, ok := matchFactorFields[reason.Factor]; ok && !student.CanSee(viewer, field) {
+			continue
+		}
+		visible = append(visible, reason)
+	}
+	return visible
+}
+
+func newMatchingStudent(student models.User, skills map[string]float64) matching.Student {
+	return matching.Student{
+		Major:          student.Major,
+		City:           student.City,
+		GraduationYear: student.GraduationYear,
+		Interests:      student.Interests,
+		Skills:         skills,
+	}
+}
+
+func newMatchingPosting(posting models.JobPosting) matching.Posting {
+	return matching.Posting{
+		City:           posting.City,
+		Majors:         posting.Majors,
+		Interests:      posting.Interests,
+		Categories:     posting.Categories,
+		EmploymentType: posting.EmploymentType,
+		Deadline:       posting.Deadline.Time(),
+	}
+}
+
+func newPostingSummary(posting models.JobPosting) PostingSummary {
+	return PostingSummary{
+		ID:             posting.ID,
+		Title:          posting.Title,
+		CompanyName:    posting.CompanyName,
+		City:           posting.City,
+		EmploymentType: posting.EmploymentType,
+		Status:         posting.Status,
+	}
+}
+
+// appliedIDs returns the field values of the applications matching filter.
+func (cfg *AppConfig) appliedIDs(ctx context.Context, field string, filter bson.M) ([]bson.ObjectID, error) {
+	ids := []bson.ObjectID{}
+	applications_coll := cfg.DATABASE.Collection(models.APPLICATIONS_COLLECTION)
+	if err := applications_coll.Distinct(ctx, field, filter).Decode(&ids); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	return ids, nil
+}
+
+func parseMinScore(r *http.Request) (float64, error) {
+	value := r.URL.Query().Get("minScore")
+	if value == "" {
+		return 0, nil
+	}
+	min_score, err := strconv.ParseFloat(value, 64)
+	if err != nil || min_score < 0 || min_score > 100 {
+		return 0, utils.NewBadRequest("minScore must be between 0 and 100")
+	}
+	return min_score, nil
+}
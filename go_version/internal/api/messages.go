@@ -0,0 +1,929 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_CONVERSATIONS_LIMIT = 20
+	MAX_CONVERSATIONS_LIMIT     = 50
+	DEFAULT_MESSAGES_LIMIT      = 50
+	MAX_MESSAGES_LIMIT          = 100
+
+	// Messages a user can send per minute
+	MAX_MESSAGES_PER_MINUTE = 20
+
+	// New conversations a company can start per day
+	MAX_DAILY_CONVERSATIONS = 30
+
+	MAX_MESSAGE_ATTACHMENTS    = 5
+	MAX_ATTACHMENT_SIZE        = 10 << 20
+	MAX_HOURLY_ATTACHMENTS     = 30
+	MAX_ATTACHMENT_NAME_LENGTH = 200
+
+	// Characters of the last message shown in conversation lists
+	MESSAGE_PREVIEW_LENGTH = 140
+)
+
+// Sniffed content types files can be attached with
+var allowedAttachmentTypes = []string{
+	"application/pdf", "image/png", "image/jpeg", "image/gif", "image/webp", "text/plain; charset=utf-8",
+}
+
+type StartConversationRequestBody struct {
+	StudentID     string   `json:"studentId" validate:"required,mongodb"`
+	PostingID     string   `json:"postingId" validate:"omitempty,mongodb"`
+	Body          string   `json:"body" validate:"max=5000"`
+	AttachmentIDs []string `json:"attachmentIds" validate:"max=5,dive,mongodb"`
+}
+
+type MessageRequestBody struct {
+	Body          string   `json:"body" validate:"max=5000"`
+	AttachmentIDs []string `json:"attachmentIds" validate:"max=5,dive,mongodb"`
+}
+
+type BlockUserRequestBody struct {
+	UserID string `json:"userId" validate:"required,mongodb"`
+}
+
+type ConversationCounterpart struct {
+	ID   bson.ObjectID `json:"_id"`
+	Name string        `json:"name"`
+	Role string        `json:"role"`
+}
+
+// ConversationView is a conversation as one participant sees it.
+// CounterpartReadAt is the read receipt of the other side.
+type ConversationView struct {
+	ID                bson.ObjectID           `json:"_id"`
+	Counterpart       ConversationCounterpart `json:"counterpart"`
+	PostingID         *bson.ObjectID          `json:"postingId,omitempty"`
+	LastMessage       *models.MessagePreview  `json:"lastMessage,omitempty"`
+	LastMessageAt     bson.DateTime           `json:"lastMessageAt"`
+	UnreadCount       int32                   `json:"unreadCount"`
+	CounterpartReadAt *bson.DateTime          `json:"counterpartReadAt"`
+	CreatedAt         bson.DateTime           `json:"createdAt"`
+}
+
+// Conversations
+
+// StartConversation lets a company message a student who applied to one of
+// its postings or shared contact details with it. Messaging a student the
+// company already talks to continues that conversation.
+func (cfg *AppConfig) StartConversation(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+	if !user.IsEmailVerified {
+		return utils.NewForbidden("Verify your email before messaging students")
+	}
+
+	req_body := StartConversationRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing conversation request body", http.StatusBadRequest, err)
+	}
+	req_body.Body = sanitizeInput(req_body.Body)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+	if req_body.Body == "" && len(req_body.AttachmentIDs) == 0 {
+		return utils.NewBadRequest("A message needs a body or an attachment")
+	}
+	student_id, _ := bson.ObjectIDFromHex(req_body.StudentID)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var student models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err = users_coll.FindOne(ctx, bson.M{"_id": student_id, "role": models.ROLE_STUDENT, "isActive": true}).Decode(&student)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Student not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	consented, err := cfg.findContactConsents(ctx, user_id, []bson.ObjectID{student.ID})
+	if err != nil {
+		return err
+	}
+	if len(consented) == 0 {
+		return utils.NewForbidden("You can only message students who applied to your postings or shared their contact details with you")
+	}
+	if err := cfg.checkNotBlocked(ctx, user_id, student.ID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := cfg.checkMessageRateLimit(ctx, user_id, now); err != nil {
+		return err
+	}
+
+	var conversation models.Conversation
+	conversations_coll := cfg.DATABASE.Collection(models.CONVERSATIONS_COLLECTION)
+	err = conversations_coll.FindOne(ctx, bson.M{"companyId": user_id, "studentId": student.ID}).Decode(&conversation)
+	if err == mongo.ErrNoDocuments {
+		started, err := conversations_coll.CountDocuments(ctx, bson.M{
+			"companyId": user_id,
+			"createdAt": bson.M{"$gte": bson.NewDateTimeFromTime(now.Add(-24 * time.Hour))},
+		})
+		if err != nil {
+			return utils.NewInternalServerError(err)
+		}
+		if started >= MAX_DAILY_CONVERSATIONS {
+			return utils.NewAppError("Daily conversation limit reached, try again tomorrow", http.StatusTooManyRequests, nil)
+		}
+
+		conversation = models.Conversation{
+			ID:            bson.NewObjectID(),
+			CompanyID:     user_id,
+			CompanyName:   companyDisplayName(user),
+			StudentID:     student.ID,
+			StudentName:   student.FullName,
+			LastMessageAt: bson.NewDateTimeFromTime(now),
+			CreatedAt:     bson.NewDateTimeFromTime(now),
+			UpdatedAt:     bson.NewDateTimeFromTime(now),
+		}
+		if req_body.PostingID != "" {
+			posting_id, _ := bson.ObjectIDFromHex(req_body.PostingID)
+			posting, err := cfg.findOwnedJobPosting(ctx, user_id, posting_id)
+			if err != nil {
+				return err
+			}
+			conversation.PostingID = posting.ID
+		}
+
+		_, err = conversations_coll.InsertOne(ctx, conversation)
+		if mongo.IsDuplicateKeyError(err) {
+			err = conversations_coll.FindOne(ctx, bson.M{"companyId": user_id, "studentId": student.ID}).Decode(&conversation)
+		}
+		if err != nil {
+			return utils.NewInternalServerError(err)
+		}
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	message, conversation, err := cfg.postMessage(ctx, conversation, user_id, req_body.Body, req_body.AttachmentIDs, now)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"conversation": newConversationView(conversation, user_id),
+		"message":      message,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Message sent successfully",
+		response_payload,
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetConversations(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+	side, err := messagingSide(user)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{side + "Id": user_id, "lastMessage": bson.M{"$exists": true}}
+	if r.URL.Query().Get("unread") == "true" {
+		filter[side+"Unread"] = bson.M{"$gt": 0}
+	}
+	page, limit := parsePagination(r, DEFAULT_CONVERSATIONS_LIMIT, MAX_CONVERSATIONS_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	conversations_coll := cfg.DATABASE.Collection(models.CONVERSATIONS_COLLECTION)
+	total, err := conversations_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	cursor, err := conversations_coll.Find(ctx, filter,
+		options.Find().
+			SetSort(bson.D{{Key: "lastMessageAt", Value: -1}, {Key: "_id", Value: -1}}).
+			SetSkip((page-1)*limit).
+			SetLimit(limit),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	conversations := []models.Conversation{}
+	if err := cursor.All(ctx, &conversations); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	views := make([]ConversationView, 0, len(conversations))
+	for _, conversation := range conversations {
+		views = append(views, newConversationView(conversation, user_id))
+	}
+
+	response_payload := map[string]any{
+		"conversations": views,
+		"pagination":    newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Conversations provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetUnreadMessageCount counts the user's unread messages across
+// conversations, for badges.
+func (cfg *AppConfig) GetUnreadMessageCount(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+	side, err := messagingSide(user)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	conversations_coll := cfg.DATABASE.Collection(models.CONVERSATIONS_COLLECTION)
+	cursor, err := conversations_coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{side + "Id": user_id, side + "Unread": bson.M{"$gt": 0}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           nil,
+			"messages":      bson.M{"$sum": "$" + side + "Unread"},
+			"conversations": bson.M{"$sum": 1},
+		}}},
+	})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	var counts []struct {
+		Messages      int64 `bson:"messages"`
+		Conversations int64 `bson:"conversations"`
+	}
+	if err := cursor.All(ctx, &counts); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	response_payload := map[string]any{"messages": int64(0), "conversations": int64(0)}
+	if len(counts) > 0 {
+		response_payload["messages"] = counts[0].Messages
+		response_payload["conversations"] = counts[0].Conversations
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Unread count provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetConversationMessages(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	conversation_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+	page, limit := parsePagination(r, DEFAULT_MESSAGES_LIMIT, MAX_MESSAGES_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	conversation, err := cfg.findConversation(ctx, conversation_id, user_id)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"conversationId": conversation.ID}
+	messages_coll := cfg.DATABASE.Collection(models.MESSAGES_COLLECTION)
+	total, err := messages_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	cursor, err := messages_coll.Find(ctx, filter,
+		options.Find().
+			SetSort(bson.D{{Key: "sentAt", Value: -1}, {Key: "_id", Value: -1}}).
+			SetSkip((page-1)*limit).
+			SetLimit(limit),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	messages := []models.Message{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	for i := range messages {
+		if messages[i].Attachments == nil {
+			messages[i].Attachments = []models.AttachmentRef{}
+		}
+	}
+
+	response_payload := map[string]any{
+		"conversation": newConversationView(conversation, user_id),
+		"messages":     messages,
+		"pagination":   newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Messages provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// SendMessage replies in a conversation the user takes part in.
+func (cfg *AppConfig) SendMessage(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	conversation_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := MessageRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing message request body", http.StatusBadRequest, err)
+	}
+	req_body.Body = sanitizeInput(req_body.Body)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+	if req_body.Body == "" && len(req_body.AttachmentIDs) == 0 {
+		return utils.NewBadRequest("A message needs a body or an attachment")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	conversation, err := cfg.findConversation(ctx, conversation_id, user_id)
+	if err != nil {
+		return err
+	}
+	if err := cfg.checkNotBlocked(ctx, conversation.CompanyID, conversation.StudentID); err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := cfg.checkMessageRateLimit(ctx, user_id, now); err != nil {
+		return err
+	}
+
+	message, conversation, err := cfg.postMessage(ctx, conversation, user_id, req_body.Body, req_body.AttachmentIDs, now)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"conversation": newConversationView(conversation, user_id),
+		"message":      message,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Message sent successfully",
+		response_payload,
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+// MarkConversationRead clears the user's unread count and sets the read
+// receipt of the messages they received.
+func (cfg *AppConfig) MarkConversationRead(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	conversation_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	conversation, err := cfg.findConversation(ctx, conversation_id, user_id)
+	if err != nil {
+		return err
+	}
+
+	conversation, err = cfg.markConversationRead(ctx, conversation, user_id, time.Now())
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Conversation marked as read",
+		map[string]any{"conversation": newConversationView(conversation, user_id)},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// Attachments
+
+// UploadMessageAttachment stores a file in the media store, the returned ID is
+// then referenced by the message it is sent with.
+func (cfg *AppConfig) UploadMessageAttachment(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+	if _, err := messagingSide(user); err != nil {
+		return err
+	}
+
+	// Room for the multipart envelope around the file
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_ATTACHMENT_SIZE+1<<20)
+	if err := r.ParseMultipartForm(MAX_ATTACHMENT_SIZE); err != nil {
+		return utils.NewAppError("Attachments can't be larger than 10 MB", http.StatusRequestEntityTooLarge, err)
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return utils.NewAppError("Error while reading the attached file", http.StatusBadRequest, err)
+	}
+	defer file.Close()
+	if header.Size > MAX_ATTACHMENT_SIZE {
+		return utils.NewAppError("Attachments can't be larger than 10 MB", http.StatusRequestEntityTooLarge, nil)
+	}
+
+	sniff := make([]byte, 512)
+	read, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return utils.NewAppError("Error while reading the attached file", http.StatusBadRequest, err)
+	}
+	content_type := http.DetectContentType(sniff[:read])
+	if !slices.Contains(allowedAttachmentTypes, content_type) {
+		return utils.NewBadRequest("Only PDF, image and plain text files can be attached")
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	name := sanitizeInput(filepath.Base(header.Filename))
+	if name == "" || name == "." {
+		name = "attachment"
+	}
+	if utf8.RuneCountInString(name) > MAX_ATTACHMENT_NAME_LENGTH {
+		name = string([]rune(name)[:MAX_ATTACHMENT_NAME_LENGTH])
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	attachments_coll := cfg.DATABASE.Collection(models.MESSAGE_ATTACHMENTS_COLLECTION)
+	uploaded, err := attachments_coll.CountDocuments(ctx, bson.M{
+		"ownerId":   user_id,
+		"createdAt": bson.M{"$gte": bson.NewDateTimeFromTime(now.Add(-time.Hour))},
+	})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if uploaded >= MAX_HOURLY_ATTACHMENTS {
+		return utils.NewAppError("Attachment limit reached, try again later", http.StatusTooManyRequests, nil)
+	}
+
+	result, err := cfg.UploadFileToCloudinary(ctx, file, "messages/"+user_id.Hex())
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	attachment := models.MessageAttachment{
+		ID:          bson.NewObjectID(),
+		OwnerID:     user_id,
+		PublicID:    result.PublicID,
+		URL:         result.SecureURL,
+		Name:        name,
+		ContentType: content_type,
+		Size:        header.Size,
+		CreatedAt:   bson.NewDateTimeFromTime(now),
+	}
+	if _, err := attachments_coll.InsertOne(ctx, attachment); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Attachment uploaded successfully",
+		map[string]any{"attachment": attachment},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+// Blocking
+
+func (cfg *AppConfig) GetBlockedUsers(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	blocks_coll := cfg.DATABASE.Collection(models.USER_BLOCKS_COLLECTION)
+	cursor, err := blocks_coll.Find(ctx,
+		bson.M{"blockerId": user_id},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	blocks := []models.UserBlock{}
+	if err := cursor.All(ctx, &blocks); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Blocked users provided successfully",
+		map[string]any{"blocks": blocks},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// BlockUser stops the user and another one from messaging each other, and
+// keeps companies the student blocked from starting a conversation.
+func (cfg *AppConfig) BlockUser(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	req_body := BlockUserRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing block request body", http.StatusBadRequest, err)
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+	blocked_id, _ := bson.ObjectIDFromHex(req_body.UserID)
+	if blocked_id == user_id {
+		return utils.NewBadRequest("You can't block yourself")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var blocked models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err = users_coll.FindOne(ctx, bson.M{"_id": blocked_id, "role": bson.M{"$in": bson.A{models.ROLE_COMPANY, models.ROLE_STUDENT}}}).Decode(&blocked)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("User not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	block := models.UserBlock{
+		ID:          bson.NewObjectID(),
+		BlockerID:   user_id,
+		BlockedID:   blocked.ID,
+		BlockedName: blocked.FullName,
+		CreatedAt:   bson.NewDateTimeFromTime(time.Now()),
+	}
+	if blocked.Role == models.ROLE_COMPANY {
+		block.BlockedName = companyDisplayName(blocked)
+	}
+	blocks_coll := cfg.DATABASE.Collection(models.USER_BLOCKS_COLLECTION)
+	_, err = blocks_coll.InsertOne(ctx, block)
+	if mongo.IsDuplicateKeyError(err) {
+		return utils.NewConflict("You already blocked this user")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"User blocked successfully",
+		map[string]any{"block": block},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) UnblockUser(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	blocked_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	blocks_coll := cfg.DATABASE.Collection(models.USER_BLOCKS_COLLECTION)
+	result, err := blocks_coll.DeleteOne(ctx, bson.M{"blockerId": user_id, "blockedId": blocked_id})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if result.DeletedCount == 0 {
+		return utils.NewNotFound("Block not found")
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"User unblocked successfully",
+		nil,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// messagingSide is the prefix of the user's fields on a conversation.
+func messagingSide(user models.User) (string, error) {
+	switch user.Role {
+	case models.ROLE_COMPANY:
+		return "company", nil
+	case models.ROLE_STUDENT:
+		return "student", nil
+	}
+	return "", utils.NewForbidden("Only companies and students can use messaging")
+}
+
+func newConversationView(conversation models.Conversation, user_id bson.ObjectID) ConversationView {
+	view := ConversationView{
+		ID:            conversation.ID,
+		LastMessage:   conversation.LastMessage,
+		LastMessageAt: conversation.LastMessageAt,
+		CreatedAt:     conversation.CreatedAt,
+	}
+	counterpart_read_at := conversation.StudentReadAt
+	if user_id == conversation.CompanyID {
+		view.Counterpart = ConversationCounterpart{ID: conversation.StudentID, Name: conversation.StudentName, Role: models.ROLE_STUDENT}
+		view.UnreadCount = conversation.CompanyUnread
+	} else {
+		view.Counterpart = ConversationCounterpart{ID: conversation.CompanyID, Name: conversation.CompanyName, Role: models.ROLE_COMPANY}
+		view.UnreadCount = conversation.StudentUnread
+		counterpart_read_at = conversation.CompanyReadAt
+	}
+	if !conversation.PostingID.IsZero() {
+		view.PostingID = &conversation.PostingID
+	}
+	if counterpart_read_at != 0 {
+		view.CounterpartReadAt = &counterpart_read_at
+	}
+	return view
+}
+
+// findConversation loads a conversation the user takes part in.
+func (cfg *AppConfig) findConversation(ctx context.Context, conversation_id, user_id bson.ObjectID) (models.Conversation, error) {
+	var conversation models.Conversation
+	conversations_coll := cfg.DATABASE.Collection(models.CONVERSATIONS_COLLECTION)
+	err := conversations_coll.FindOne(ctx, bson.M{
+		"_id": conversation_id,
+		"$or": bson.A{bson.M{"companyId": user_id}, bson.M{"studentId": user_id}},
+	}).Decode(&conversation)
+	if err == mongo.ErrNoDocuments {
+		return conversation, utils.NewNotFound("Conversation not found")
+	} else if err != nil {
+		return conversation, utils.NewInternalServerError(err)
+	}
+	return conversation, nil
+}
+
+// postMessage adds a message to the conversation. Writing a message reads
+// the conversation, the recipient gets one more unread message.
+func (cfg *AppConfig) postMessage(ctx context.Context, conversation models.Conversation, sender_id bson.ObjectID, body string, attachment_ids []string, now time.Time) (models.Message, models.Conversation, error) {
+	message := models.Message{
+		ID:             bson.NewObjectID(),
+		ConversationID: conversation.ID,
+		SenderID:       sender_id,
+		Body:           body,
+		SentAt:         bson.NewDateTimeFromTime(now),
+		CreatedAt:      bson.NewDateTimeFromTime(now),
+	}
+	attachments, err := cfg.claimMessageAttachments(ctx, sender_id, message.ID, attachment_ids)
+	if err != nil {
+		return message, conversation, err
+	}
+	message.Attachments = attachments
+
+	messages_coll := cfg.DATABASE.Collection(models.MESSAGES_COLLECTION)
+	if _, err := messages_coll.InsertOne(ctx, message); err != nil {
+		return message, conversation, utils.NewInternalServerError(err)
+	}
+
+	conversation, err = cfg.markConversationRead(ctx, conversation, sender_id, now)
+	if err != nil {
+		return message, conversation, err
+	}
+
+	preview := body
+	if utf8.RuneCountInString(preview) > MESSAGE_PREVIEW_LENGTH {
+		preview = string([]rune(preview)[:MESSAGE_PREVIEW_LENGTH]) + "…"
+	}
+	recipient := "student"
+	if sender_id == conversation.StudentID {
+		recipient = "company"
+	}
+	conversations_coll := cfg.DATABASE.Collection(models.CONVERSATIONS_COLLECTION)
+	err = conversations_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": conversation.ID},
+		bson.M{
+			"$set": bson.M{
+				"lastMessage":   models.MessagePreview{SenderID: sender_id, Body: preview, SentAt: message.SentAt},
+				"lastMessageAt": message.SentAt,
+				"updatedAt":     bson.NewDateTimeFromTime(now),
+			},
+			"$inc": bson.M{recipient + "Unread": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&conversation)
+	if err != nil {
+		return message, conversation, utils.NewInternalServerError(err)
+	}
+
+	recipient_id, sender_name := conversation.StudentID, conversation.CompanyName
+	if recipient == "company" {
+		recipient_id, sender_name = conversation.CompanyID, conversation.StudentName
+	}
+	if preview == "" {
+		preview = "Sent an attachment"
+	}
+	cfg.notifyInBackground(models.Notification{
+		UserID:   recipient_id,
+		Type:     models.NOTIFICATION_MESSAGE,
+		Title:    "New message from " + sender_name,
+		Body:     preview,
+		Link:     "/messages/" + conversation.ID.Hex(),
+		SourceID: message.ID,
+	})
+
+	if message.Attachments == nil {
+		message.Attachments = []models.AttachmentRef{}
+	}
+	return message, conversation, nil
+}
+
+func (cfg *AppConfig) markConversationRead(ctx context.Context, conversation models.Conversation, user_id bson.ObjectID, now time.Time) (models.Conversation, error) {
+	side, sender_id := "company", conversation.StudentID
+	if user_id == conversation.StudentID {
+		side, sender_id = "student", conversation.CompanyID
+	}
+
+	messages_coll := cfg.DATABASE.Collection(models.MESSAGES_COLLECTION)
+	_, err := messages_coll.UpdateMany(ctx,
+		bson.M{"conversationId": conversation.ID, "senderId": sender_id, "readAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"readAt": bson.NewDateTimeFromTime(now)}},
+	)
+	if err != nil {
+		return conversation, utils.NewInternalServerError(err)
+	}
+
+	conversations_coll := cfg.DATABASE.Collection(models.CONVERSATIONS_COLLECTION)
+	err = conversations_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": conversation.ID},
+		bson.M{"$set": bson.M{side + "Unread": 0, side + "ReadAt": bson.NewDateTimeFromTime(now)}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&conversation)
+	if err != nil {
+		return conversation, utils.NewInternalServerError(err)
+	}
+	return conversation, nil
+}
+
+// claimMessageAttachments attaches uploaded files to a message. Only files
+// the sender uploaded and never attached before can be used.
+func (cfg *AppConfig) claimMessageAttachments(ctx context.Context, sender_id, message_id bson.ObjectID, attachment_ids []string) ([]models.AttachmentRef, error) {
+	ids := []bson.ObjectID{}
+	for _, value := range attachment_ids {
+		id, _ := bson.ObjectIDFromHex(value)
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if len(ids) > MAX_MESSAGE_ATTACHMENTS {
+		return nil, utils.NewBadRequest("A message can have at most 5 attachments")
+	}
+
+	filter := bson.M{"_id": bson.M{"$in": ids}, "ownerId": sender_id, "messageId": bson.M{"$exists": false}}
+	attachments_coll := cfg.DATABASE.Collection(models.MESSAGE_ATTACHMENTS_COLLECTION)
+	cursor, err := attachments_coll.Find(ctx, filter)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	attachments := []models.MessageAttachment{}
+	if err := cursor.All(ctx, &attachments); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	if len(attachments) != len(ids) {
+		return nil, utils.NewBadRequest("Unknown or already sent attachment")
+	}
+
+	result, err := attachments_coll.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"messageId": message_id}})
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	if result.ModifiedCount != int64(len(ids)) {
+		return nil, utils.NewConflict("An attachment was sent with another message")
+	}
+
+	by_id := make(map[bson.ObjectID]models.MessageAttachment, len(attachments))
+	for _, attachment := range attachments {
+		by_id[attachment.ID] = attachment
+	}
+	refs := make([]models.AttachmentRef, 0, len(ids))
+	for _, id := range ids {
+		attachment := by_id[id]
+		refs = append(refs, attachment.Ref())
+	}
+	return refs, nil
+}
+
+// checkNotBlocked fails when either user blocked the other.
+func (cfg *AppConfig) checkNotBlocked(ctx context.Context, a, b bson.ObjectID) error {
+	blocks_coll := cfg.DATABASE.Collection(models.USER_BLOCKS_COLLECTION)
+	blocked, err := blocks_coll.CountDocuments(ctx, bson.M{"$or": bson.A{
+		bson.M{"blockerId": a, "blockedId": b},
+		bson.M{"blockerId": b, "blockedId": a},
+	}})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if blocked != 0 {
+		return utils.NewForbidden("You can't message this user")
+	}
+	return nil
+}
+
+func (cfg *AppConfig) checkMessageRateLimit(ctx context.Context, sender_id bson.ObjectID, now time.Time) error {
+	messages_coll := cfg.DATABASE.Collection(models.MESSAGES_COLLECTION)
+	sent, err := messages_coll.CountDocuments(ctx, bson.M{
+		"senderId": sender_id,
+		"sentAt":   bson.M{"$gte": bson.NewDateTimeFromTime(now.Add(-time.Minute))},
+	})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if sent >= MAX_MESSAGES_PER_MINUTE {
+		return utils.NewAppError("You're sending messages too fast, try again in a minute", http.StatusTooManyRequests, nil)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"slices"
 	"time"
 
 	"go_version/internal/models"
@@ -57,34 +58,100 @@ func (cfg *AppConfig) MiddlewareAuthorize(next func(w http.ResponseWriter, r *ht
 		db_ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 		defer cancel()
 
-		user_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+		user, err := cfg.findAuthorizedUser(db_ctx, user_id)
+		if err != nil {
+			return err
+		}
+
+		req_ctx := context.WithValue(r.Context(), CtxUserID, user_id)
+		req_ctx = context.WithValue(req_ctx, CtxUser, user)
+		err = next(w, r.WithContext(req_ctx))
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// MiddlewareStreamTicket authorizes connections browsers can't set headers
+// on, like a WebSocket or EventSource, with a ticket from CreateStreamTicket passed as the
+// "ticket" query parameter. The ticket is deleted as it's redeemed so a URL
+// that leaks can't be replayed.
+func (cfg *AppConfig) MiddlewareStreamTicket(next func(w http.ResponseWriter, r *http.Request) error) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		raw_ticket := r.URL.Query().Get("ticket")
+		if raw_ticket == "" {
+			return utils.NewAppError("A stream ticket is required", http.StatusUnauthorized, nil)
+		}
 
-		// Find user by id
-		var user models.User
-		err = user_coll.FindOne(db_ctx, bson.M{"_id": user_id}).Decode(&user)
+		db_ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		stream_tickets_coll := cfg.DATABASE.Collection(models.STREAM_TICKETS_COLLECTION)
+
+		var ticket models.StreamTicket
+		err := stream_tickets_coll.FindOneAndDelete(db_ctx, bson.M{
+			"ticketHash": utils.HashStreamTicket(raw_ticket),
+			"expiresAt":  bson.M{"$gt": bson.NewDateTimeFromTime(time.Now())},
+		}).Decode(&ticket)
 		if err == mongo.ErrNoDocuments {
-			return utils.NewAppError("User not found", http.StatusNotFound, nil)
+			return utils.NewAppError("Invalid or expired stream ticket", http.StatusUnauthorized, nil)
 		} else if err != nil {
 			return utils.NewInternalServerError(err)
 		}
 
-		// Check if user is active
-		if !user.IsActive {
-			return utils.NewAppError("Your account has been deactivated", http.StatusUnauthorized, nil)
-		}
-
-		// Check if email is verified
-		if !user.IsEmailVerified {
-			return utils.NewAppError("Please verify your email before logging in. Check your inbox for the verification link.", http.StatusUnauthorized, nil)
+		user, err := cfg.findAuthorizedUser(db_ctx, ticket.UserID)
+		if err != nil {
+			return err
 		}
 
-		req_ctx := context.WithValue(r.Context(), CtxUserID, user_id)
+		req_ctx := context.WithValue(r.Context(), CtxUserID, ticket.UserID)
 		req_ctx = context.WithValue(req_ctx, CtxUser, user)
-		err = next(w, r.WithContext(req_ctx))
+		return next(w, r.WithContext(req_ctx))
+	}
+}
+
+// findAuthorizedUser loads the user a token or ticket belongs to and refuses
+// accounts that can't sign in.
+func (cfg *AppConfig) findAuthorizedUser(ctx context.Context, user_id bson.ObjectID) (models.User, error) {
+	user_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+
+	// Find user by id
+	var user models.User
+	err := user_coll.FindOne(ctx, bson.M{"_id": user_id}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return models.User{}, utils.NewAppError("User not found", http.StatusNotFound, nil)
+	} else if err != nil {
+		return models.User{}, utils.NewInternalServerError(err)
+	}
+
+	// Check if user is active
+	if !user.IsActive {
+		return models.User{}, utils.NewAppError("Your account has been deactivated", http.StatusUnauthorized, nil)
+	}
+
+	// Check if email is verified
+	if !user.IsEmailVerified {
+		return models.User{}, utils.NewAppError("Please verify your email before logging in. Check your inbox for the verification link.", http.StatusUnauthorized, nil)
+	}
+
+	return user, nil
+}
+
+// MiddlewareRequireRole must run inside MiddlewareAuthorize, it rejects users
+// whose role isn't one of the allowed roles.
+func (cfg *AppConfig) MiddlewareRequireRole(next func(w http.ResponseWriter, r *http.Request) error, roles ...string) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		_, user, err := getUserFromContext(r.Context())
 		if err != nil {
-			return err
+			return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
 		}
 
-		return nil
+		if !slices.Contains(roles, user.Role) {
+			return utils.NewForbidden("You do not have permission to perform this action")
+		}
+
+		return next(w, r)
 	}
 }
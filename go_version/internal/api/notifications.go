@@ -0,0 +1,467 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_NOTIFICATIONS_LIMIT = 20
+	MAX_NOTIFICATIONS_LIMIT     = 100
+
+	// Notifications are deleted after this long
+	NOTIFICATION_RETENTION = 90 * 24 * time.Hour
+
+	// Comment lines keeping idle streams open through proxies
+	NOTIFICATION_HEARTBEAT_INTERVAL = 25 * time.Second
+
+	// Streams are closed after this long so clients reconnect with a new
+	// ticket, and the account is checked again
+	MAX_NOTIFICATION_STREAM_DURATION = 30 * time.Minute
+
+	// Notifications replayed to a client reconnecting with Last-Event-ID
+	MAX_MISSED_NOTIFICATIONS = 100
+
+	// Milliseconds clients wait before reconnecting
+	NOTIFICATION_STREAM_RETRY = 5000
+)
+
+type NotificationPreferencesRequestBody struct {
+	Preferences map[string]bool `json:"preferences"`
+}
+
+// Notification center
+
+func (cfg *AppConfig) GetNotifications(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter := bson.M{"userId": user_id}
+	if r.URL.Query().Get("unread") == "true" {
+		filter["readAt"] = bson.M{"$exists": false}
+	}
+	page, limit := parsePagination(r, DEFAULT_NOTIFICATIONS_LIMIT, MAX_NOTIFICATIONS_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	notifications_coll := cfg.DATABASE.Collection(models.NOTIFICATIONS_COLLECTION)
+	total, err := notifications_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	cursor, err := notifications_coll.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetSkip((page-1)*limit).SetLimit(limit),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	notifications := []models.Notification{}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	unread, err := cfg.countUnreadNotifications(ctx, user_id)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"notifications": notifications,
+		"unreadCount":   unread,
+		"pagination":    newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Notifications provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) MarkNotificationRead(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	notification_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var notification models.Notification
+	notifications_coll := cfg.DATABASE.Collection(models.NOTIFICATIONS_COLLECTION)
+	err = notifications_coll.FindOne(ctx, bson.M{"_id": notification_id, "userId": user_id}).Decode(&notification)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Notification not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if notification.ReadAt == 0 {
+		notification.ReadAt = bson.NewDateTimeFromTime(time.Now())
+		_, err = notifications_coll.UpdateOne(ctx,
+			bson.M{"_id": notification.ID, "readAt": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"readAt": notification.ReadAt}},
+		)
+		if err != nil {
+			return utils.NewInternalServerError(err)
+		}
+	}
+
+	unread, err := cfg.countUnreadNotifications(ctx, user_id)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"notification": notification,
+		"unreadCount":  unread,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Notification marked as read",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	notifications_coll := cfg.DATABASE.Collection(models.NOTIFICATIONS_COLLECTION)
+	result, err := notifications_coll.UpdateMany(ctx,
+		bson.M{"userId": user_id, "readAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"readAt": bson.NewDateTimeFromTime(time.Now())}},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Notifications marked as read",
+		map[string]any{"marked": result.ModifiedCount, "unreadCount": 0},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) error {
+	_, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Notification preferences provided successfully",
+		map[string]any{"preferences": user.NotificationPreferences.View()},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// UpdateNotificationPreferences turns notification types on or off, types
+// left out keep their setting.
+func (cfg *AppConfig) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	req_body := NotificationPreferencesRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing notification preferences request body", http.StatusBadRequest, err)
+	}
+	if len(req_body.Preferences) == 0 {
+		return utils.NewBadRequest("No notification preferences provided to update")
+	}
+
+	muted, unmuted := bson.A{}, bson.A{}
+	for notification_type, enabled := range req_body.Preferences {
+		if !slices.Contains(models.GetValidNotificationTypes(), notification_type) {
+			return utils.NewBadRequest("Unknown notification type '" + notification_type + "'")
+		}
+		if enabled {
+			unmuted = append(unmuted, notification_type)
+		} else {
+			muted = append(muted, notification_type)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	// Two updates, a single one can't add to and pull from the same array
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	if len(unmuted) != 0 {
+		_, err := users_coll.UpdateOne(ctx,
+			bson.M{"_id": user_id},
+			bson.M{"$pull": bson.M{"notificationPreferences.muted": bson.M{"$in": unmuted}}},
+		)
+		if err != nil {
+			return utils.NewInternalServerError(err)
+		}
+	}
+	err = users_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": user_id},
+		bson.M{
+			"$addToSet": bson.M{"notificationPreferences.muted": bson.M{"$each": muted}},
+			"$set":      bson.M{"updatedAt": bson.NewDateTimeFromTime(time.Now())},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Notification preferences updated successfully",
+		map[string]any{"preferences": user.NotificationPreferences.View()},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// StreamNotifications pushes the user's new notifications as Server-Sent
+// Events. A client reconnecting with Last-Event-ID first gets the ones it
+// missed, the unread count is sent on connect. The stream is authorized with
+// a ticket from CreateStreamTicket, which works once, so clients open a new
+// EventSource with a new ticket and ?lastEventId= instead of letting the
+// browser reconnect.
+func (cfg *AppConfig) StreamNotifications(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return utils.NewInternalServerError(errors.New("response writer doesn't support streaming"))
+	}
+
+	stream_ctx, cancel := context.WithTimeout(r.Context(), MAX_NOTIFICATION_STREAM_DURATION)
+	defer cancel()
+
+	// Subscribed before reading the backlog so nothing falls in between
+	subscription, err := cfg.PUBSUB.Subscribe(stream_ctx, notificationChannel(user_id))
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	defer subscription.Close()
+
+	missed := []models.Notification{}
+	last_event_id := r.Header.Get("Last-Event-ID")
+	if last_event_id == "" {
+		last_event_id = r.URL.Query().Get("lastEventId")
+	}
+	if last_event_id != "" {
+		last_id, err := bson.ObjectIDFromHex(last_event_id)
+		if err != nil {
+			return utils.NewBadRequest("Invalid Last-Event-ID")
+		}
+		notifications_coll := cfg.DATABASE.Collection(models.NOTIFICATIONS_COLLECTION)
+		cursor, err := notifications_coll.Find(stream_ctx,
+			bson.M{"userId": user_id, "_id": bson.M{"$gt": last_id}},
+			options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(MAX_MISSED_NOTIFICATIONS),
+		)
+		if err != nil {
+			return utils.NewInternalServerError(err)
+		}
+		if err := cursor.All(stream_ctx, &missed); err != nil {
+			return utils.NewInternalServerError(err)
+		}
+	}
+	unread, err := cfg.countUnreadNotifications(stream_ctx, user_id)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Once the stream started errors can only end it
+	fmt.Fprintf(w, "retry: %d\n\n", NOTIFICATION_STREAM_RETRY)
+	unread_payload, _ := json.Marshal(map[string]int64{"count": unread})
+	writeServerSentEvent(w, "", "unread", unread_payload)
+	sent := map[bson.ObjectID]bool{}
+	for _, notification := range missed {
+		payload, err := json.Marshal(notification)
+		if err != nil {
+			log.Printf("notifications: %s", err.Error())
+			return nil
+		}
+		writeServerSentEvent(w, notification.ID.Hex(), "notification", payload)
+		sent[notification.ID] = true
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(NOTIFICATION_HEARTBEAT_INTERVAL)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-stream_ctx.Done():
+			return nil
+
+		case payload, ok := <-subscription.C:
+			if !ok {
+				return nil
+			}
+			var notification models.Notification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				log.Printf("notifications: %s", err.Error())
+				continue
+			}
+			// Published while the backlog was read
+			if sent[notification.ID] {
+				continue
+			}
+			writeServerSentEvent(w, notification.ID.Hex(), "notification", payload)
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// writeServerSentEvent writes one event, the payload is JSON so it holds on a
+// single data line.
+func writeServerSentEvent(w http.ResponseWriter, id, event string, payload []byte) {
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+func notificationChannel(user_id bson.ObjectID) string {
+	return "notifications:" + user_id.Hex()
+}
+
+func (cfg *AppConfig) countUnreadNotifications(ctx context.Context, user_id bson.ObjectID) (int64, error) {
+	notifications_coll := cfg.DATABASE.Collection(models.NOTIFICATIONS_COLLECTION)
+	unread, err := notifications_coll.CountDocuments(ctx, bson.M{"userId": user_id, "readAt": bson.M{"$exists": false}})
+	if err != nil {
+		return 0, utils.NewInternalServerError(err)
+	}
+	return unread, nil
+}
+
+// notify stores a notification and pushes it to the user's open streams,
+// unless the user turned its type off. A notification with a SourceID is only
+// stored once.
+func (cfg *AppConfig) notify(ctx context.Context, notification models.Notification) error {
+	var user models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err := users_coll.FindOne(ctx,
+		bson.M{"_id": notification.UserID, "isActive": true},
+		options.FindOne().SetProjection(bson.M{"notificationPreferences": 1}),
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to load user %s: %w", notification.UserID.Hex(), err)
+	}
+	if !user.NotificationPreferences.Enabled(notification.Type) {
+		return nil
+	}
+
+	notification.ID = bson.NewObjectID()
+	notification.CreatedAt = bson.NewDateTimeFromTime(time.Now())
+	notifications_coll := cfg.DATABASE.Collection(models.NOTIFICATIONS_COLLECTION)
+	_, err = notifications_coll.InsertOne(ctx, notification)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to store notification: %w", err)
+	}
+
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	// Stored already, clients that miss it catch up on their next read
+	if err := cfg.PUBSUB.Publish(ctx, notificationChannel(notification.UserID), payload); err != nil {
+		log.Printf("notifications: failed to publish: %s", err.Error())
+	}
+	return nil
+}
+
+// notifyInBackground notifies from a request handler, a failure doesn't fail
+// the request.
+func (cfg *AppConfig) notifyInBackground(notification models.Notification) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := cfg.notify(ctx, notification); err != nil {
+			log.Printf("notifications: %s", err.Error())
+		}
+	}()
+}
+
+// notifyApplicationUpdate tells the student their application's status
+// changed.
+func (cfg *AppConfig) notifyApplicationUpdate(ctx context.Context, event models.DomainEvent) error {
+	if changed, _ := event.Payload["studentStatusChanged"].(bool); !changed {
+		return nil
+	}
+	status, _ := event.Payload["studentStatus"].(string)
+	posting_id, _ := event.Payload["postingId"].(bson.ObjectID)
+	student_id, _ := event.Payload["studentId"].(bson.ObjectID)
+
+	var posting models.JobPosting
+	job_postings_coll := cfg.DATABASE.Collection(models.JOB_POSTINGS_COLLECTION)
+	err := job_postings_coll.FindOne(ctx, bson.M{"_id": posting_id}).Decode(&posting)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return cfg.notify(ctx, models.Notification{
+		UserID:   student_id,
+		Type:     models.NOTIFICATION_APPLICATION_UPDATE,
+		Title:    "Application update from " + posting.CompanyName,
+		Body:     "Your application for " + posting.Title + " is now: " + applicationStatusLabels[status],
+		Link:     "/student/applications",
+		SourceID: event.ID,
+	})
+}
@@ -0,0 +1,328 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"go_version/internal/events"
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// How long a worker owns a claimed message before another may take it over
+	OUTBOX_LOCK_DURATION = 2 * time.Minute
+
+	// Pending events older than this were left behind by a failed relay
+	OUTBOX_RELAY_GRACE      = 30 * time.Second
+	OUTBOX_RELAY_BATCH_SIZE = 100
+
+	DEFAULT_DEAD_LETTERS_LIMIT = 20
+	MAX_DEAD_LETTERS_LIMIT     = 100
+)
+
+// outboxSources are the collections whose documents carry pendingEvents.
+var outboxSources = []string{models.USERS_COLLECTION, models.APPLICATIONS_COLLECTION, models.TEST_ATTEMPTS_COLLECTION, models.DAILY_CHALLENGES_COLLECTION}
+
+// withEvents adds domain events to an update so they are stored in the same
+// write as the state change they describe.
+func withEvents(update bson.M, domain_events ...models.DomainEvent) bson.M {
+	update["$push"] = bson.M{"pendingEvents": bson.M{"$each": domain_events}}
+	return update
+}
+
+// relayPendingEvents moves a document's pending events to the outbox. Event
+// ids are the outbox ids, so a relay interrupted half way is simply repeated.
+func (cfg *AppConfig) relayPendingEvents(ctx context.Context, source string, id bson.ObjectID) error {
+	source_coll := cfg.DATABASE.Collection(source)
+
+	var document struct {
+		PendingEvents []models.DomainEvent `bson:"pendingEvents"`
+	}
+	err := source_coll.FindOne(ctx,
+		bson.M{"_id": id},
+		options.FindOne().SetProjection(bson.M{"pendingEvents": 1}),
+	).Decode(&document)
+	if err == mongo.ErrNoDocuments || len(document.PendingEvents) == 0 {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	messages := make([]any, 0, len(document.PendingEvents))
+	event_ids := make([]bson.ObjectID, 0, len(document.PendingEvents))
+	for _, event := range document.PendingEvents {
+		messages = append(messages, models.OutboxMessage{
+			DomainEvent:   event,
+			Source:        source,
+			Status:        models.OUTBOX_STATUS_PENDING,
+			NextAttemptAt: now,
+			Delivered:     []string{},
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+		event_ids = append(event_ids, event.ID)
+	}
+
+	outbox_coll := cfg.DATABASE.Collection(models.OUTBOX_COLLECTION)
+	_, err = outbox_coll.InsertMany(ctx, messages, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	_, err = source_coll.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$pull": bson.M{"pendingEvents": bson.M{"_id": bson.M{"$in": event_ids}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	cfg.EVENT_BUS.Wake()
+	return nil
+}
+
+// publishPendingEvents relays right after a handler's write. It never fails the
+// request: whatever isn't relayed here is picked up by the outbox workers.
+func (cfg *AppConfig) publishPendingEvents(ctx context.Context, source string, id bson.ObjectID) {
+	if err := cfg.relayPendingEvents(ctx, source, id); err != nil {
+		log.Printf("outbox: failed to relay events of %s %s: %s", source, id.Hex(), err.Error())
+	}
+}
+
+// StartOutboxWorkers dispatches outbox messages to the event bus subscribers
+// with the given number of workers, and relays events left behind on their
+// documents. It blocks until ctx is cancelled, run it in a goroutine.
+func (cfg *AppConfig) StartOutboxWorkers(ctx context.Context, workers int, interval time.Duration) {
+	for range workers {
+		go cfg.runOutboxWorker(ctx, interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := cfg.relayStalePendingEvents(ctx); err != nil {
+				log.Printf("outbox: %s", err.Error())
+			}
+		}
+	}
+}
+
+func (cfg *AppConfig) runOutboxWorker(ctx context.Context, interval time.Duration) {
+	for {
+		claimed, err := cfg.dispatchNextOutboxMessage(ctx)
+		if err != nil {
+			log.Printf("outbox: %s", err.Error())
+		}
+		// Drain the backlog without waiting
+		if claimed && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-cfg.EVENT_BUS.Woken():
+		case <-time.After(interval):
+		}
+	}
+}
+
+// dispatchNextOutboxMessage claims the next due message and hands it to the
+// bus. Failed deliveries are retried with exponential backoff and dead-lettered
+// after events.MAX_DELIVERY_ATTEMPTS. Reports whether a message was claimed.
+func (cfg *AppConfig) dispatchNextOutboxMessage(ctx context.Context) (bool, error) {
+	outbox_coll := cfg.DATABASE.Collection(models.OUTBOX_COLLECTION)
+	now := time.Now()
+
+	// Due messages, plus ones whose worker died while holding them
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"status": models.OUTBOX_STATUS_PENDING, "nextAttemptAt": bson.M{"$lte": bson.NewDateTimeFromTime(now)}},
+			bson.M{"status": models.OUTBOX_STATUS_PROCESSING, "lockedUntil": bson.M{"$lte": bson.NewDateTimeFromTime(now)}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.OUTBOX_STATUS_PROCESSING,
+			"lockedUntil": bson.NewDateTimeFromTime(now.Add(OUTBOX_LOCK_DURATION)),
+			"updatedAt":   bson.NewDateTimeFromTime(now),
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var message models.OutboxMessage
+	err := outbox_coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&message)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	dispatch_ctx, cancel := context.WithTimeout(ctx, OUTBOX_LOCK_DURATION)
+	delivered, dispatch_err := cfg.EVENT_BUS.Dispatch(dispatch_ctx, message.DomainEvent, message.Delivered)
+	cancel()
+
+	finished_at := time.Now()
+	set := bson.M{"delivered": delivered, "updatedAt": bson.NewDateTimeFromTime(finished_at)}
+	unset := bson.M{"lockedUntil": ""}
+	switch {
+	case dispatch_err == nil:
+		set["status"] = models.OUTBOX_STATUS_DISPATCHED
+		set["dispatchedAt"] = bson.NewDateTimeFromTime(finished_at)
+		unset["lastError"] = ""
+	case message.Attempts >= events.MAX_DELIVERY_ATTEMPTS:
+		set["status"] = models.OUTBOX_STATUS_DEAD
+		set["lastError"] = dispatch_err.Error()
+		log.Printf("outbox: dead-lettered %s event %s after %d attempts: %s", message.Type, message.ID.Hex(), message.Attempts, dispatch_err.Error())
+	default:
+		set["status"] = models.OUTBOX_STATUS_PENDING
+		set["nextAttemptAt"] = bson.NewDateTimeFromTime(finished_at.Add(events.RetryDelay(message.Attempts)))
+		set["lastError"] = dispatch_err.Error()
+	}
+
+	// Only while we still own the claim, a slow worker mustn't overwrite a newer attempt
+	_, err = outbox_coll.UpdateOne(ctx,
+		bson.M{"_id": message.ID, "status": models.OUTBOX_STATUS_PROCESSING, "attempts": message.Attempts},
+		bson.M{"$set": set, "$unset": unset},
+	)
+	if err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// relayStalePendingEvents relays events a crashed or failed request left on
+// their documents.
+func (cfg *AppConfig) relayStalePendingEvents(ctx context.Context) error {
+	relay_ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	before := bson.NewDateTimeFromTime(time.Now().Add(-OUTBOX_RELAY_GRACE))
+	for _, source := range outboxSources {
+		cursor, err := cfg.DATABASE.Collection(source).Find(relay_ctx,
+			bson.M{"pendingEvents.occurredAt": bson.M{"$lte": before}},
+			options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(OUTBOX_RELAY_BATCH_SIZE),
+		)
+		if err != nil {
+			return err
+		}
+
+		var documents []struct {
+			ID bson.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(relay_ctx, &documents); err != nil {
+			return err
+		}
+
+		for _, document := range documents {
+			if err := cfg.relayPendingEvents(relay_ctx, source, document.ID); err != nil {
+				log.Printf("outbox: failed to relay events of %s %s: %s", source, document.ID.Hex(), err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetDeadLetters lists the events whose delivery was given up on, most recent first.
+func (cfg *AppConfig) GetDeadLetters(w http.ResponseWriter, r *http.Request) error {
+	page, limit := parsePagination(r, DEFAULT_DEAD_LETTERS_LIMIT, MAX_DEAD_LETTERS_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"status": models.OUTBOX_STATUS_DEAD}
+	if event_type := r.URL.Query().Get("type"); event_type != "" {
+		filter["type"] = event_type
+	}
+
+	outbox_coll := cfg.DATABASE.Collection(models.OUTBOX_COLLECTION)
+	total, err := outbox_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updatedAt", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := outbox_coll.Find(ctx, filter, opts)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	messages := []models.OutboxMessage{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	response_payload := map[string]any{
+		"deadLetters": messages,
+		"pagination":  newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Dead letters provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// RetryDeadLetter puts a dead-lettered event back in the queue with a fresh
+// attempt budget. Subscribers that already handled it are not run again.
+func (cfg *AppConfig) RetryDeadLetter(w http.ResponseWriter, r *http.Request) error {
+	message_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	outbox_coll := cfg.DATABASE.Collection(models.OUTBOX_COLLECTION)
+	result, err := outbox_coll.UpdateOne(ctx,
+		bson.M{"_id": message_id, "status": models.OUTBOX_STATUS_DEAD},
+		bson.M{"$set": bson.M{
+			"status":        models.OUTBOX_STATUS_PENDING,
+			"attempts":      0,
+			"nextAttemptAt": now,
+			"updatedAt":     now,
+		}},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewNotFound("Dead letter not found")
+	}
+	cfg.EVENT_BUS.Wake()
+
+	utils.SuccessResponseWriter(
+		w,
+		"Event queued for another delivery",
+		nil,
+		http.StatusOK,
+	)
+
+	return nil
+}
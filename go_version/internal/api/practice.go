@@ -0,0 +1,353 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_PRACTICE_PAGE_LIMIT = 20
+	MAX_PRACTICE_PAGE_LIMIT     = 50
+	MAX_PRACTICE_TAGS           = 50
+	MAX_PRACTICE_ATTEMPT_SIZE   = 200
+
+	NO_EXPLANATION_AVAILABLE = "No explanation available"
+)
+
+type CheckPracticeAnswerRequestBody struct {
+	QuestionID int32  `json:"questionId" validate:"required,min=1"`
+	UserAnswer string `json:"userAnswer" validate:"required"`
+	TimeSpent  int32  `json:"timeSpent" validate:"omitempty,min=0,max=3600"` // in seconds, as measured by the client
+
+	// Practice attempt to record the answer in, a new one is opened when empty
+	AttemptID string `json:"attemptId" validate:"omitempty,mongodb"`
+}
+
+// GetPracticeQuestions mirrors getPracticeQuestions in the Node practice
+// controller: active questions filtered by category, difficulty, company and
+// tags, without their answers.
+func (cfg *AppConfig) GetPracticeQuestions(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	query := r.URL.Query()
+	filter := bson.M{"isActive": true}
+	if category := query.Get("category"); category != "" && category != "all" {
+		filter["category"] = category
+	}
+	if difficulty := query.Get("difficulty"); difficulty != "" && difficulty != "all" {
+		filter["difficulty"] = difficulty
+	}
+	if company := query.Get("company"); company != "" {
+		filter["company"] = company
+	}
+	if tags := query.Get("tags"); tags != "" {
+		tag_list := []string{}
+		for tag := range strings.SplitSeq(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tag_list = append(tag_list, tag)
+			}
+		}
+		filter["tags"] = bson.M{"$in": tag_list}
+	}
+
+	page, limit := parsePagination(r, DEFAULT_PRACTICE_PAGE_LIMIT, MAX_PRACTICE_PAGE_LIMIT)
+
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := questions_coll.Find(ctx, filter, opts)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	questions := []models.Question{}
+	if err := cursor.All(ctx, &questions); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	total, err := questions_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	public_questions := make([]models.PublicQuestion, 0, len(questions))
+	for _, question := range questions {
+		public_questions = append(public_questions, question.GetPublicQuestion())
+	}
+
+	response_payload := map[string]any{
+		"questions":  public_questions,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Practice questions provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetPracticeCompanies(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	companies := []string{}
+	err := questions_coll.Distinct(ctx, "company", bson.M{
+		"isActive": true,
+		"company":  bson.M{"$exists": true, "$nin": bson.A{"", nil}},
+	}).Decode(&companies)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	slices.Sort(companies)
+
+	response_payload := map[string]any{
+		"companies": companies,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Companies provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+type PracticeTag struct {
+	Name  string `bson:"_id" json:"name"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+func (cfg *AppConfig) GetPracticeTags(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	cursor, err := questions_coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"isActive": true}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: MAX_PRACTICE_TAGS}},
+	})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	tags := []PracticeTag{}
+	if err := cursor.All(ctx, &tags); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	response_payload := map[string]any{
+		"tags": tags,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Tags provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// CheckPracticeAnswer grades a single practice answer straight away and
+// returns the author's explanation. The answer is recorded in a practice
+// attempt (isPracticeMode) so it never mixes with graded exam results, and
+// reschedules the question in the student's spaced repetition queue.
+func (cfg *AppConfig) CheckPracticeAnswer(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	req_body := CheckPracticeAnswerRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing check answer request body", http.StatusBadRequest, err)
+	}
+	req_body.UserAnswer = strings.TrimSpace(req_body.UserAnswer)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var question models.Question
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	err = questions_coll.FindOne(ctx, bson.M{"questionId": req_body.QuestionID, "isActive": true}).Decode(&question)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Question not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	// Pin the revision so a later answer-key fix can rescore practice too
+	if err := cfg.ensureQuestionRevision(ctx, &question); err != nil {
+		return err
+	}
+
+	is_correct := question.CheckAnswer(req_body.UserAnswer)
+	answer := models.AttemptQuestion{
+		QuestionID:    question.ID,
+		UserAnswer:    req_body.UserAnswer,
+		CorrectAnswer: question.CorrectAnswer,
+		IsCorrect:     is_correct,
+		TimeSpent:     req_body.TimeSpent,
+		Revision:      question.Revision,
+		RevisionID:    question.RevisionID,
+	}
+
+	// Replay older practice first so the schedule doesn't start from this answer
+	if err := cfg.backfillReviewCards(ctx, user_id); err != nil {
+		return err
+	}
+
+	attempt, recorded, err := cfg.recordPracticeAnswer(ctx, user_id, req_body.AttemptID, question.Category, answer)
+	if err != nil {
+		return err
+	}
+	new_achievements := []models.Achievement{}
+	if recorded {
+		now := time.Now()
+		if err := cfg.scheduleReview(ctx, user_id, attempt.ID, question.Category, answer, now); err != nil {
+			return err
+		}
+		event := practiceAnsweredEvent(attempt, answer, question.Difficulty, attempt.TotalQuestions == 1, bson.NewDateTimeFromTime(now))
+		new_achievements = cfg.publishAchievementEventOrLog(ctx, event)
+	}
+
+	explanation := question.Explanation
+	if explanation == "" {
+		explanation = NO_EXPLANATION_AVAILABLE
+	}
+
+	response_payload := map[string]any{
+		"isCorrect":       is_correct,
+		"correctAnswer":   question.CorrectAnswer,
+		"explanation":     explanation,
+		"attemptId":       attempt.ID,
+		"correctCount":    attempt.CorrectCount,
+		"answeredCount":   attempt.TotalQuestions,
+		"newAchievements": new_achievements,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Answer checked successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// recordPracticeAnswer appends the answer to the user's practice attempt, or
+// opens a new one. Only the first answer to a question within an attempt
+// counts, retrying after seeing the explanation would only inflate the score.
+func (cfg *AppConfig) recordPracticeAnswer(ctx context.Context, user_id bson.ObjectID, attempt_hex, category string, answer models.AttemptQuestion) (models.TestAttempt, bool, error) {
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	now := time.Now()
+
+	if attempt_hex == "" {
+		attempt := models.TestAttempt{
+			ID:             bson.NewObjectID(),
+			UserID:         user_id,
+			Category:       category,
+			IsPracticeMode: true,
+			Questions:      []models.AttemptQuestion{answer},
+			TimeSpent:      answer.TimeSpent,
+			StartedAt:      bson.NewDateTimeFromTime(now),
+			CompletedAt:    bson.NewDateTimeFromTime(now),
+			CreatedAt:      bson.NewDateTimeFromTime(now),
+			UpdatedAt:      bson.NewDateTimeFromTime(now),
+		}
+		attempt.Recount()
+
+		if _, err := attempts_coll.InsertOne(ctx, attempt); err != nil {
+			return attempt, false, utils.NewInternalServerError(err)
+		}
+		return attempt, true, nil
+	}
+
+	attempt_id, err := bson.ObjectIDFromHex(attempt_hex)
+	if err != nil {
+		return models.TestAttempt{}, false, utils.NewBadRequest("Invalid attemptId")
+	}
+
+	var attempt models.TestAttempt
+	err = attempts_coll.FindOne(ctx, bson.M{"_id": attempt_id, "userId": user_id, "isPracticeMode": true}).Decode(&attempt)
+	if err == mongo.ErrNoDocuments {
+		return attempt, false, utils.NewNotFound("Practice attempt not found")
+	} else if err != nil {
+		return attempt, false, utils.NewInternalServerError(err)
+	}
+	if attempt.Category != category {
+		return attempt, false, utils.NewBadRequest("A practice attempt can only contain questions of one category")
+	}
+	if len(attempt.Questions) >= MAX_PRACTICE_ATTEMPT_SIZE {
+		return attempt, false, utils.NewBadRequest("This practice attempt is full, start a new one")
+	}
+
+	correct := int32(0)
+	if answer.IsCorrect {
+		correct = 1
+	}
+
+	// The question filter keeps concurrent or repeated checks from recording twice
+	result := attempts_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": attempt.ID, "questions.questionId": bson.M{"$ne": answer.QuestionID}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"questions":      bson.M{"$concatArrays": bson.A{"$questions", bson.M{"$literal": bson.A{answer}}}},
+				"correctCount":   bson.M{"$add": bson.A{"$correctCount", correct}},
+				"totalQuestions": bson.M{"$add": bson.A{"$totalQuestions", 1}},
+				"timeSpent":      bson.M{"$add": bson.A{"$timeSpent", answer.TimeSpent}},
+				"completedAt":    bson.NewDateTimeFromTime(now),
+				"updatedAt":      bson.NewDateTimeFromTime(now),
+			}}},
+			// Same rounding as models.CalculateScore, $round would round half to even
+			{{Key: "$set", Value: bson.M{
+				"score": bson.M{"$floor": bson.M{"$add": bson.A{
+					bson.M{"$divide": bson.A{bson.M{"$multiply": bson.A{"$correctCount", 100}}, "$totalQuestions"}},
+					0.5,
+				}}},
+			}}},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+	err = result.Decode(&attempt)
+	if err == mongo.ErrNoDocuments {
+		// Already answered in this attempt, the first answer stands
+		return attempt, false, nil
+	} else if err != nil {
+		return attempt, false, utils.NewInternalServerError(err)
+	}
+
+	return attempt, true, nil
+}
@@ -0,0 +1,419 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/srs"
+	"go_version/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_REVIEW_QUEUE_LIMIT = 20
+	MAX_REVIEW_QUEUE_LIMIT     = 100
+	DEFAULT_REVIEW_STATS_DAYS  = 30
+	MAX_REVIEW_STATS_DAYS      = 365
+)
+
+type ReviewQueueItem struct {
+	Question     models.PublicQuestion `json:"question"`
+	DueAt        bson.DateTime         `json:"dueAt"`
+	IntervalDays int32                 `json:"intervalDays"`
+	Repetitions  int32                 `json:"repetitions"`
+	Lapses       int32                 `json:"lapses"`
+	Overdue      bool                  `json:"overdue"`
+}
+
+type DailyReviewStats struct {
+	Day            string  `bson:"_id" json:"day"`
+	Reviews        int64   `bson:"reviews" json:"reviews"`
+	Correct        int64   `bson:"correct" json:"correct"`
+	NewCards       int64   `bson:"newCards" json:"newCards"`
+	Accuracy       float64 `bson:"-" json:"accuracy"`
+	AvgTimeSeconds float64 `bson:"avgTime" json:"avgTimeSeconds"`
+}
+
+// GetDueReviews returns the student's review queue for today: practice
+// questions whose spaced repetition interval runs out before the end of the
+// day, most overdue first. Answers go through the regular check-answer route.
+func (cfg *AppConfig) GetDueReviews(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	location := userLocation(user)
+	_, limit := parsePagination(r, DEFAULT_REVIEW_QUEUE_LIMIT, MAX_REVIEW_QUEUE_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	if err := cfg.backfillReviewCards(ctx, user_id); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		"userId": user_id,
+		"dueAt":  bson.M{"$lt": bson.NewDateTimeFromTime(endOfDay(now, location))},
+	}
+	if category := r.URL.Query().Get("category"); category != "" {
+		filter["category"] = category
+	}
+
+	review_cards_coll := cfg.DATABASE.Collection(models.REVIEW_CARDS_COLLECTION)
+	cursor, err := review_cards_coll.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "dueAt", Value: 1}}).SetLimit(limit),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	cards := []models.ReviewCard{}
+	if err := cursor.All(ctx, &cards); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	due_count, err := review_cards_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	question_ids := make([]bson.ObjectID, 0, len(cards))
+	for _, card := range cards {
+		question_ids = append(question_ids, card.QuestionID)
+	}
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	cursor, err = questions_coll.Find(ctx, bson.M{"_id": bson.M{"$in": question_ids}, "isActive": true})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	questions := []models.Question{}
+	if err := cursor.All(ctx, &questions); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	questions_by_id := make(map[bson.ObjectID]models.Question, len(questions))
+	for _, question := range questions {
+		questions_by_id[question.ID] = question
+	}
+
+	start_of_today := startOfDay(now, location)
+	queue := make([]ReviewQueueItem, 0, len(cards))
+	for _, card := range cards {
+		question, ok := questions_by_id[card.QuestionID]
+		if !ok {
+			continue // deactivated since it was practiced
+		}
+		queue = append(queue, ReviewQueueItem{
+			Question:     question.GetPublicQuestion(),
+			DueAt:        card.DueAt,
+			IntervalDays: card.IntervalDays,
+			Repetitions:  card.Repetitions,
+			Lapses:       card.Lapses,
+			Overdue:      card.DueAt.Time().Before(start_of_today),
+		})
+	}
+
+	response_payload := map[string]any{
+		"reviews":  queue,
+		"dueCount": due_count,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Review queue provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetReviewStats returns per-day review counts and accuracy for the last
+// days, plus an overview of the student's cards.
+func (cfg *AppConfig) GetReviewStats(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	location := userLocation(user)
+	days, err := strconv.Atoi(r.URL.Query().Get("days"))
+	if err != nil || days < 1 {
+		days = DEFAULT_REVIEW_STATS_DAYS
+	}
+	days = min(days, MAX_REVIEW_STATS_DAYS)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	if err := cfg.backfillReviewCards(ctx, user_id); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	since := startOfDay(now, location).AddDate(0, 0, -(days - 1))
+
+	review_logs_coll := cfg.DATABASE.Collection(models.REVIEW_LOGS_COLLECTION)
+	cursor, err := review_logs_coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userId": user_id, "reviewedAt": bson.M{"$gte": bson.NewDateTimeFromTime(since)}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateToString": bson.M{
+				"format":   "%Y-%m-%d",
+				"date":     "$reviewedAt",
+				"timezone": location.String(),
+			}},
+			"reviews":  bson.M{"$sum": 1},
+			"correct":  bson.M{"$sum": bson.M{"$cond": bson.A{"$isCorrect", 1, 0}}},
+			"newCards": bson.M{"$sum": bson.M{"$cond": bson.A{"$isNew", 1, 0}}},
+			"avgTime":  bson.M{"$avg": "$timeSpent"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	daily := []DailyReviewStats{}
+	if err := cursor.All(ctx, &daily); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	for i := range daily {
+		daily[i].Accuracy = float64(daily[i].Correct) / float64(daily[i].Reviews)
+	}
+
+	review_cards_coll := cfg.DATABASE.Collection(models.REVIEW_CARDS_COLLECTION)
+	total_cards, err := review_cards_coll.CountDocuments(ctx, bson.M{"userId": user_id})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	mature_cards, err := review_cards_coll.CountDocuments(ctx, bson.M{
+		"userId":       user_id,
+		"intervalDays": bson.M{"$gte": srs.MATURE_INTERVAL_DAYS},
+	})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	due_today, err := review_cards_coll.CountDocuments(ctx, bson.M{
+		"userId": user_id,
+		"dueAt":  bson.M{"$lt": bson.NewDateTimeFromTime(endOfDay(now, location))},
+	})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	today := now.In(location).Format(time.DateOnly)
+	reviewed_today := int64(0)
+	if index := slices.IndexFunc(daily, func(d DailyReviewStats) bool { return d.Day == today }); index != -1 {
+		reviewed_today = daily[index].Reviews
+	}
+
+	response_payload := map[string]any{
+		"daily": daily,
+		"cards": map[string]any{
+			"total":    total_cards,
+			"mature":   mature_cards,
+			"learning": total_cards - mature_cards,
+		},
+		"today": map[string]any{
+			"date":     today,
+			"reviewed": reviewed_today,
+			"due":      due_today,
+		},
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Review statistics provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// scheduleReview reschedules the question for the student after a practice
+// answer. The review counter guards the card, if another answer updated it
+// concurrently this one is dropped rather than applied on stale state.
+func (cfg *AppConfig) scheduleReview(ctx context.Context, user_id, attempt_id bson.ObjectID, category string, answer models.AttemptQuestion, now time.Time) error {
+	review_cards_coll := cfg.DATABASE.Collection(models.REVIEW_CARDS_COLLECTION)
+
+	var card models.ReviewCard
+	err := review_cards_coll.FindOne(ctx, bson.M{"userId": user_id, "questionId": answer.QuestionID}).Decode(&card)
+	if err == mongo.ErrNoDocuments {
+		card = newReviewCard(user_id, answer.QuestionID, category, now)
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	updated, review_log := applyReview(card, attempt_id, answer, now)
+
+	_, err = review_cards_coll.UpdateOne(ctx,
+		bson.M{"userId": user_id, "questionId": answer.QuestionID, "reviews": card.Reviews},
+		bson.M{
+			"$set": bson.M{
+				"category":       updated.Category,
+				"ease":           updated.Ease,
+				"intervalDays":   updated.IntervalDays,
+				"repetitions":    updated.Repetitions,
+				"lapses":         updated.Lapses,
+				"reviews":        updated.Reviews,
+				"lastQuality":    updated.LastQuality,
+				"lastReviewedAt": updated.LastReviewedAt,
+				"dueAt":          updated.DueAt,
+				"updatedAt":      updated.UpdatedAt,
+			},
+			"$setOnInsert": bson.M{"createdAt": updated.CreatedAt},
+		},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	review_logs_coll := cfg.DATABASE.Collection(models.REVIEW_LOGS_COLLECTION)
+	if _, err := review_logs_coll.InsertOne(ctx, review_log); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+// backfillReviewCards builds the schedule of a student who practiced before
+// spaced repetition existed by replaying their practice attempts in order.
+// It only runs once, as soon as the student has any review history it's a no-op.
+func (cfg *AppConfig) backfillReviewCards(ctx context.Context, user_id bson.ObjectID) error {
+	review_logs_coll := cfg.DATABASE.Collection(models.REVIEW_LOGS_COLLECTION)
+	err := review_logs_coll.FindOne(ctx, bson.M{"userId": user_id}).Err()
+	if err == nil {
+		return nil
+	} else if err != mongo.ErrNoDocuments {
+		return utils.NewInternalServerError(err)
+	}
+
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err := attempts_coll.Find(ctx,
+		bson.M{"userId": user_id, "isPracticeMode": true},
+		options.Find().SetSort(bson.D{{Key: "completedAt", Value: 1}}),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	attempts := []models.TestAttempt{}
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if len(attempts) == 0 {
+		return nil
+	}
+
+	cards := map[bson.ObjectID]models.ReviewCard{}
+	order := []bson.ObjectID{}
+	logs := []any{}
+	for _, attempt := range attempts {
+		reviewed_at := attempt.CompletedAt.Time()
+		for _, answer := range attempt.Questions {
+			if answer.UserAnswer == "" {
+				continue
+			}
+			card, ok := cards[answer.QuestionID]
+			if !ok {
+				card = newReviewCard(user_id, answer.QuestionID, attempt.Category, reviewed_at)
+				order = append(order, answer.QuestionID)
+			}
+			updated, review_log := applyReview(card, attempt.ID, answer, reviewed_at)
+			cards[answer.QuestionID] = updated
+			logs = append(logs, review_log)
+		}
+	}
+	if len(logs) == 0 {
+		return nil
+	}
+
+	documents := make([]any, 0, len(order))
+	for _, question_id := range order {
+		documents = append(documents, cards[question_id])
+	}
+
+	// Unordered so cards scheduled concurrently by a live answer are skipped, not fatal
+	review_cards_coll := cfg.DATABASE.Collection(models.REVIEW_CARDS_COLLECTION)
+	_, err = review_cards_coll.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return utils.NewInternalServerError(err)
+	}
+	if _, err := review_logs_coll.InsertMany(ctx, logs); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+func newReviewCard(user_id, question_id bson.ObjectID, category string, now time.Time) models.ReviewCard {
+	return models.ReviewCard{
+		ID:         bson.NewObjectID(),
+		UserID:     user_id,
+		QuestionID: question_id,
+		Category:   category,
+		Ease:       srs.DEFAULT_EASE,
+		CreatedAt:  bson.NewDateTimeFromTime(now),
+	}
+}
+
+func applyReview(card models.ReviewCard, attempt_id bson.ObjectID, answer models.AttemptQuestion, now time.Time) (models.ReviewCard, models.ReviewLog) {
+	state := srs.Card{
+		Ease:         card.Ease,
+		IntervalDays: card.IntervalDays,
+		Repetitions:  card.Repetitions,
+		Lapses:       card.Lapses,
+	}
+	if card.Reviews > 0 {
+		state.Due = card.DueAt.Time()
+	}
+
+	quality := srs.Quality(answer.IsCorrect, answer.TimeSpent)
+	next := srs.Review(state, quality, now)
+
+	review_log := models.ReviewLog{
+		ID:             bson.NewObjectID(),
+		UserID:         card.UserID,
+		QuestionID:     card.QuestionID,
+		AttemptID:      attempt_id,
+		Category:       card.Category,
+		IsCorrect:      answer.IsCorrect,
+		TimeSpent:      answer.TimeSpent,
+		Quality:        int32(quality),
+		IsNew:          card.Reviews == 0,
+		IntervalBefore: card.IntervalDays,
+		IntervalAfter:  next.IntervalDays,
+		ReviewedAt:     bson.NewDateTimeFromTime(now),
+	}
+
+	card.Ease = next.Ease
+	card.IntervalDays = next.IntervalDays
+	card.Repetitions = next.Repetitions
+	card.Lapses = next.Lapses
+	card.Reviews++
+	card.LastQuality = int32(quality)
+	card.LastReviewedAt = bson.NewDateTimeFromTime(now)
+	card.DueAt = bson.NewDateTimeFromTime(next.Due)
+	card.UpdatedAt = bson.NewDateTimeFromTime(now)
+
+	return card, review_log
+}
+
+func startOfDay(t time.Time, location *time.Location) time.Time {
+	local := t.In(location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+}
+
+func endOfDay(t time.Time, location *time.Location) time.Time {
+	return startOfDay(t, location).AddDate(0, 0, 1)
+}
@@ -0,0 +1,557 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_CONTACT_REQUESTS_LIMIT = 20
+	MAX_CONTACT_REQUESTS_LIMIT     = 100
+
+	// A declined or revoked request can be sent again after this
+	CONTACT_REQUEST_COOLDOWN = 30 * 24 * time.Hour
+
+	// Contact requests a company can send per day
+	MAX_DAILY_CONTACT_REQUESTS = 50
+)
+
+type PrivacySettingsRequestBody struct {
+	Fields       map[string]string `json:"fields"`
+	Discoverable *bool             `json:"discoverable"`
+}
+
+type ContactRequestBody struct {
+	StudentID string `json:"studentId" validate:"required,mongodb"`
+	Message   string `json:"message" validate:"max=1000"`
+}
+
+type ContactResponseRequestBody struct {
+	Decision string `json:"decision" validate:"required,oneof=approve decline"`
+}
+
+// Privacy settings
+
+func (cfg *AppConfig) GetPrivacySettings(w http.ResponseWriter, r *http.Request) error {
+	_, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Privacy settings provided successfully",
+		map[string]any{"privacy": user.Privacy.View()},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// UpdatePrivacySettings changes the visibility of the given fields and the
+// discoverable switch, anything left out keeps its setting.
+func (cfg *AppConfig) UpdatePrivacySettings(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	req_body := PrivacySettingsRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing privacy settings request body", http.StatusBadRequest, err)
+	}
+
+	set := bson.M{}
+	for field, visibility := range req_body.Fields {
+		visibility = strings.ToLower(strings.TrimSpace(visibility))
+		if !slices.Contains(models.GetPrivacyFields(), field) {
+			return utils.NewBadRequest("Unknown profile field '" + field + "'")
+		}
+		if !slices.Contains(models.GetValidVisibilities(), visibility) {
+			return utils.NewBadRequest("Visibility must be public, companies or private")
+		}
+		set["privacy.fields."+field] = visibility
+	}
+	if req_body.Discoverable != nil {
+		set["privacy.hiddenFromSearch"] = !*req_body.Discoverable
+	}
+	if len(set) == 0 {
+		return utils.NewBadRequest("No privacy settings provided to update")
+	}
+	set["updatedAt"] = bson.NewDateTimeFromTime(time.Now())
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err = users_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": user_id},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if err := cfg.refreshSearchText(ctx, user_id); err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Privacy settings updated successfully",
+		map[string]any{"privacy": user.Privacy.View()},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetUserProfileByID shows another user's profile as the viewer may see it.
+func (cfg *AppConfig) GetUserProfileByID(w http.ResponseWriter, r *http.Request) error {
+	_, viewer, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	profile_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err = users_coll.FindOne(ctx, bson.M{"_id": profile_id, "isActive": true}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("User not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	profile_viewer, err := cfg.profileViewer(ctx, viewer, user)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Profile provided successfully",
+		map[string]any{"user": user.GetPublicProfile(profile_viewer)},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// Contact requests, company side
+
+// RequestContact asks a student to share contact details with the company.
+func (cfg *AppConfig) RequestContact(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+	if !user.IsEmailVerified {
+		return utils.NewForbidden("Verify your email before contacting students")
+	}
+
+	req_body := ContactRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing contact request body", http.StatusBadRequest, err)
+	}
+	req_body.Message = sanitizeInput(req_body.Message)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+	student_id, _ := bson.ObjectIDFromHex(req_body.StudentID)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var student models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err = users_coll.FindOne(ctx, bson.M{"_id": student_id, "role": models.ROLE_STUDENT, "isActive": true}).Decode(&student)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Student not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	consented, err := cfg.findContactConsents(ctx, user_id, []bson.ObjectID{student.ID})
+	if err != nil {
+		return err
+	}
+	if len(consented) != 0 {
+		return utils.NewConflict("The student already shared their contact details with you")
+	}
+	if student.Privacy.HiddenFromSearch {
+		return utils.NewForbidden("The student isn't accepting contact requests")
+	}
+
+	now := time.Now()
+	contact_requests_coll := cfg.DATABASE.Collection(models.CONTACT_REQUESTS_COLLECTION)
+	sent, err := contact_requests_coll.CountDocuments(ctx, bson.M{
+		"companyId":   user_id,
+		"requestedAt": bson.M{"$gte": bson.NewDateTimeFromTime(now.Add(-24 * time.Hour))},
+	})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if sent >= MAX_DAILY_CONTACT_REQUESTS {
+		return utils.NewAppError("Daily contact request limit reached, try again tomorrow", http.StatusTooManyRequests, nil)
+	}
+
+	request := models.ContactRequest{
+		ID:          bson.NewObjectID(),
+		CompanyID:   user_id,
+		CompanyName: companyDisplayName(user),
+		StudentID:   student.ID,
+		Message:     req_body.Message,
+		Status:      models.CONTACT_REQUEST_STATUS_PENDING,
+		RequestedAt: bson.NewDateTimeFromTime(now),
+		CreatedAt:   bson.NewDateTimeFromTime(now),
+		UpdatedAt:   bson.NewDateTimeFromTime(now),
+	}
+	_, err = contact_requests_coll.InsertOne(ctx, request)
+	if mongo.IsDuplicateKeyError(err) {
+		// Only one request per company and student, an old refusal can be renewed
+		var existing models.ContactRequest
+		err = contact_requests_coll.FindOne(ctx, bson.M{"companyId": user_id, "studentId": student.ID}).Decode(&existing)
+		if err != nil {
+			return utils.NewInternalServerError(err)
+		}
+		if err := checkCanRenewContactRequest(existing, now); err != nil {
+			return err
+		}
+
+		err = contact_requests_coll.FindOneAndUpdate(ctx,
+			bson.M{"_id": existing.ID, "status": existing.Status, "updatedAt": existing.UpdatedAt},
+			bson.M{
+				"$set": bson.M{
+					"status":      models.CONTACT_REQUEST_STATUS_PENDING,
+					"companyName": request.CompanyName,
+					"message":     request.Message,
+					"requestedAt": request.RequestedAt,
+					"updatedAt":   request.UpdatedAt,
+				},
+				"$unset": bson.M{"respondedAt": ""},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&request)
+		if err == mongo.ErrNoDocuments {
+			return utils.NewConflict("The contact request changed, please try again")
+		} else if err != nil {
+			return utils.NewInternalServerError(err)
+		}
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	subject := request.CompanyName + " would like to contact you - " + cfg.REQUIREMENTS.SMTP.AppName
+	requests_url := cfg.REQUIREMENTS.Server.FrontendURL + "/student/contact-requests"
+	cfg.sendEmail(student.Email, subject, utils.ContactRequestEmailBody(student.FullName, request.CompanyName, request.Message, requests_url))
+	cfg.notifyInBackground(models.Notification{
+		UserID: student.ID,
+		Type:   models.NOTIFICATION_CONTACT_REQUEST,
+		Title:  request.CompanyName + " would like to contact you",
+		Body:   request.Message,
+		Link:   "/student/contact-requests",
+	})
+
+	utils.SuccessResponseWriter(
+		w,
+		"Contact request sent successfully",
+		map[string]any{"request": request},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetCompanyContactRequests(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter := bson.M{"companyId": user_id}
+	if err := applyContactRequestStatusFilter(r, filter); err != nil {
+		return err
+	}
+	page, limit := parsePagination(r, DEFAULT_CONTACT_REQUESTS_LIMIT, MAX_CONTACT_REQUESTS_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	requests, total, err := cfg.findContactRequests(ctx, filter, page, limit)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"requests":   requests,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Contact requests provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// Contact requests, student side
+
+func (cfg *AppConfig) GetMyContactRequests(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter := bson.M{"studentId": user_id}
+	if err := applyContactRequestStatusFilter(r, filter); err != nil {
+		return err
+	}
+	page, limit := parsePagination(r, DEFAULT_CONTACT_REQUESTS_LIMIT, MAX_CONTACT_REQUESTS_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	requests, total, err := cfg.findContactRequests(ctx, filter, page, limit)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"requests":   requests,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Contact requests provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// RespondToContactRequest approves or declines a pending request. Approving
+// reveals the student's contact details to the company.
+func (cfg *AppConfig) RespondToContactRequest(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	request_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := ContactResponseRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing contact response request body", http.StatusBadRequest, err)
+	}
+	req_body.Decision = strings.ToLower(strings.TrimSpace(req_body.Decision))
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	status := models.CONTACT_REQUEST_STATUS_DECLINED
+	if req_body.Decision == "approve" {
+		status = models.CONTACT_REQUEST_STATUS_APPROVED
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	request, err := cfg.updateContactRequestStatus(ctx, user_id, request_id, models.CONTACT_REQUEST_STATUS_PENDING, status)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Contact request "+request.Status+" successfully",
+		map[string]any{"request": request},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// RevokeContactRequest hides the student's contact details from the company
+// again, anything it already saw can't be taken back.
+func (cfg *AppConfig) RevokeContactRequest(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	request_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	request, err := cfg.updateContactRequestStatus(ctx, user_id, request_id, models.CONTACT_REQUEST_STATUS_APPROVED, models.CONTACT_REQUEST_STATUS_REVOKED)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Contact request revoked successfully",
+		map[string]any{"request": request},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) updateContactRequestStatus(ctx context.Context, student_id, request_id bson.ObjectID, from, to string) (models.ContactRequest, error) {
+	var request models.ContactRequest
+	now := bson.NewDateTimeFromTime(time.Now())
+	contact_requests_coll := cfg.DATABASE.Collection(models.CONTACT_REQUESTS_COLLECTION)
+	err := contact_requests_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": request_id, "studentId": student_id, "status": from},
+		bson.M{"$set": bson.M{"status": to, "respondedAt": now, "updatedAt": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&request)
+	if err == mongo.ErrNoDocuments {
+		// Tell a missing request apart from one in another status
+		err := contact_requests_coll.FindOne(ctx, bson.M{"_id": request_id, "studentId": student_id}).Decode(&request)
+		if err == mongo.ErrNoDocuments {
+			return request, utils.NewNotFound("Contact request not found")
+		} else if err != nil {
+			return request, utils.NewInternalServerError(err)
+		}
+		return request, utils.NewConflict("The contact request is " + request.Status)
+	} else if err != nil {
+		return request, utils.NewInternalServerError(err)
+	}
+	return request, nil
+}
+
+func checkCanRenewContactRequest(existing models.ContactRequest, now time.Time) error {
+	switch existing.Status {
+	case models.CONTACT_REQUEST_STATUS_PENDING:
+		return utils.NewConflict("You already asked this student, wait for their answer")
+	case models.CONTACT_REQUEST_STATUS_APPROVED:
+		return utils.NewConflict("The student already shared their contact details with you")
+	}
+	if now.Sub(existing.RespondedAt.Time()) < CONTACT_REQUEST_COOLDOWN {
+		return utils.NewConflict("The student " + existing.Status + " your request recently, try again later")
+	}
+	return nil
+}
+
+func applyContactRequestStatusFilter(r *http.Request, filter bson.M) error {
+	if status := strings.ToLower(r.URL.Query().Get("status")); status != "" {
+		if !slices.Contains(models.GetValidContactRequestStatuses(), status) {
+			return utils.NewBadRequest("Invalid status")
+		}
+		filter["status"] = status
+	}
+	return nil
+}
+
+func (cfg *AppConfig) findContactRequests(ctx context.Context, filter bson.M, page, limit int64) ([]models.ContactRequest, int64, error) {
+	contact_requests_coll := cfg.DATABASE.Collection(models.CONTACT_REQUESTS_COLLECTION)
+	total, err := contact_requests_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "requestedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := contact_requests_coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+	requests := []models.ContactRequest{}
+	if err := cursor.All(ctx, &requests); err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+	return requests, total, nil
+}
+
+// findContactConsents returns the students who shared their contact details
+// with the company, by approving its contact request or applying to one of
+// its postings.
+func (cfg *AppConfig) findContactConsents(ctx context.Context, company_id bson.ObjectID, student_ids []bson.ObjectID) ([]bson.ObjectID, error) {
+	if len(student_ids) == 0 {
+		return []bson.ObjectID{}, nil
+	}
+
+	consented, err := cfg.appliedIDs(ctx, "studentId", bson.M{
+		"companyId":     company_id,
+		"studentId":     bson.M{"$in": student_ids},
+		"studentStatus": bson.M{"$ne": models.APPLICATION_STATUS_WITHDRAWN},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	approved := []bson.ObjectID{}
+	contact_requests_coll := cfg.DATABASE.Collection(models.CONTACT_REQUESTS_COLLECTION)
+	err = contact_requests_coll.Distinct(ctx, "studentId", bson.M{
+		"companyId": company_id,
+		"studentId": bson.M{"$in": student_ids},
+		"status":    models.CONTACT_REQUEST_STATUS_APPROVED,
+	}).Decode(&approved)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	for _, student_id := range approved {
+		if !slices.Contains(consented, student_id) {
+			consented = append(consented, student_id)
+		}
+	}
+	return consented, nil
+}
+
+// profileViewer describes the viewer of a profile, including whether the
+// student shared contact details with them.
+func (cfg *AppConfig) profileViewer(ctx context.Context, viewer models.User, profile models.User) (models.ProfileViewer, error) {
+	profile_viewer := viewer.AsViewer()
+	if viewer.Role != models.ROLE_COMPANY || profile.Role != models.ROLE_STUDENT {
+		return profile_viewer, nil
+	}
+
+	consented, err := cfg.findContactConsents(ctx, viewer.ID, []bson.ObjectID{profile.ID})
+	if err != nil {
+		return profile_viewer, err
+	}
+	profile_viewer.ContactShared = len(consented) != 0
+	return profile_viewer, nil
+}
@@ -0,0 +1,486 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+	"go_version/internal/websocket"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// Signals are a few fields of JSON
+	MAX_PROCTORING_MESSAGE_SIZE = 1024
+
+	// Signals kept per session, a client sending more is cut off
+	MAX_PROCTORING_SIGNALS = 500
+
+	// Longest time away a single signal may report
+	MAX_PROCTORING_SIGNAL_DURATION = 4 * time.Hour
+
+	// Client timestamps further off the server's clock than this are replaced
+	// by the time the signal arrived
+	MAX_PROCTORING_CLOCK_SKEW = 2 * time.Minute
+
+	// Signals sent right before the deadline may still be on their way
+	PROCTORING_GRACE_PERIOD = 30 * time.Second
+
+	PROCTORING_PING_INTERVAL = 25 * time.Second
+
+	DEFAULT_SPONSORED_ATTEMPTS_LIMIT = 20
+	MAX_SPONSORED_ATTEMPTS_LIMIT     = 100
+)
+
+// ProctoringSignalMessage is what the exam page sends over the socket.
+type ProctoringSignalMessage struct {
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurredAt"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+type SponsoredAttemptView struct {
+	AttemptID     bson.ObjectID          `json:"attemptId"`
+	SessionID     bson.ObjectID          `json:"sessionId"`
+	BlueprintID   bson.ObjectID          `json:"blueprintId"`
+	Student       interface{}            `json:"student"`
+	Category      string                 `json:"category"`
+	Score         int32                  `json:"score"`
+	AutoSubmitted bool                   `json:"autoSubmitted"`
+	CompletedAt   bson.DateTime          `json:"completedAt"`
+	Integrity     models.IntegrityReport `json:"integrity"`
+}
+
+// ConnectExamProctoring opens the WebSocket the exam page reports tab
+// switches, focus losses, copy/paste and fullscreen exits on. The channel
+// stays open until the client leaves, the session is submitted or time is up.
+// Browsers can't set headers on a WebSocket, so it is authorized with a
+// ticket from CreateStreamTicket as ?ticket=.
+func (cfg *AppConfig) ConnectExamProctoring(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	session_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := websocket.CheckHandshake(r); err != nil {
+		return utils.NewBadRequest(err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	session, err := cfg.findUserExamSession(ctx, session_id, user_id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if session.Status != models.EXAM_SESSION_IN_PROGRESS || session.IsExpired(now) {
+		return utils.NewConflict("This exam is no longer in progress")
+	}
+
+	// Recorded before upgrading so a monitored session is known even if the
+	// client never sends a signal
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	_, err = exam_sessions_coll.UpdateOne(ctx,
+		bson.M{"_id": session.ID},
+		bson.M{
+			"$min": bson.M{"proctoring.firstConnectedAt": bson.NewDateTimeFromTime(now)},
+			"$set": bson.M{"proctoring.lastConnectedAt": bson.NewDateTimeFromTime(now)},
+			"$inc": bson.M{"proctoring.connections": 1, "proctoring.openConnections": 1, "proctoring.signals": 0, "proctoring.connectedMs": 0},
+		},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	conn, err := websocket.Upgrade(w, r, MAX_PROCTORING_MESSAGE_SIZE)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	// The connection is ours from here on, errors only end it
+	cfg.serveProctoring(conn, session)
+	cfg.recordProctoringDisconnect(session, now)
+	return nil
+}
+
+func (cfg *AppConfig) serveProctoring(conn *websocket.Conn, session models.ExamSession) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(PROCTORING_PING_INTERVAL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := conn.Ping(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	conn.SetReadDeadline(session.Deadline.Time().Add(PROCTORING_GRACE_PERIOD))
+	for {
+		message, err := conn.ReadMessage()
+		if err != nil {
+			if !errors.Is(err, websocket.ErrClosed) {
+				conn.Close(websocket.CLOSE_GOING_AWAY, "")
+			}
+			return
+		}
+
+		var signal_message ProctoringSignalMessage
+		if err := json.Unmarshal(message, &signal_message); err != nil {
+			writeProctoringError(conn, "Invalid signal")
+			continue
+		}
+		if !slices.Contains(models.GetValidProctoringSignalTypes(), signal_message.Type) {
+			writeProctoringError(conn, "Unknown signal type '"+signal_message.Type+"'")
+			continue
+		}
+		if signal_message.DurationMs < 0 || signal_message.DurationMs > MAX_PROCTORING_SIGNAL_DURATION.Milliseconds() {
+			writeProctoringError(conn, "Invalid signal duration")
+			continue
+		}
+
+		recorded, err := cfg.recordProctoringSignal(session, signal_message)
+		if err != nil {
+			log.Printf("proctoring: %s", err.Error())
+			conn.Close(websocket.CLOSE_GOING_AWAY, "")
+			return
+		}
+		if !recorded {
+			conn.Close(websocket.CLOSE_POLICY_VIOLATION, "The exam is over or too many signals were sent")
+			return
+		}
+	}
+}
+
+// recordProctoringSignal stores a signal, false when the session no longer
+// takes any because it ended or reached MAX_PROCTORING_SIGNALS.
+func (cfg *AppConfig) recordProctoringSignal(session models.ExamSession, message ProctoringSignalMessage) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	occurred_at := message.OccurredAt
+	if occurred_at.Before(session.StartedAt.Time().Add(-MAX_PROCTORING_CLOCK_SKEW)) || occurred_at.After(now.Add(MAX_PROCTORING_CLOCK_SKEW)) {
+		occurred_at = now
+	}
+
+	// Claims a slot first, so concurrent connections can't go over the limit
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	result, err := exam_sessions_coll.UpdateOne(ctx,
+		bson.M{
+			"_id":                session.ID,
+			"status":             models.EXAM_SESSION_IN_PROGRESS,
+			"proctoring.signals": bson.M{"$lt": MAX_PROCTORING_SIGNALS},
+		},
+		bson.M{"$inc": bson.M{"proctoring.signals": 1}},
+	)
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	signal := models.ProctoringSignal{
+		ID:         bson.NewObjectID(),
+		SessionID:  session.ID,
+		UserID:     session.UserID,
+		Type:       message.Type,
+		OccurredAt: bson.NewDateTimeFromTime(occurred_at),
+		DurationMs: message.DurationMs,
+		ReceivedAt: bson.NewDateTimeFromTime(now),
+	}
+	proctoring_signals_coll := cfg.DATABASE.Collection(models.PROCTORING_SIGNALS_COLLECTION)
+	if _, err := proctoring_signals_coll.InsertOne(ctx, signal); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recordProctoringDisconnect adds the part of a connection within the session
+// to its connected time. The disconnect itself is only recorded while the
+// session runs, leaving after submitting is expected.
+func (cfg *AppConfig) recordProctoringDisconnect(session models.ExamSession, connected_at time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	in_progress := bson.M{"$eq": bson.A{"$status", models.EXAM_SESSION_IN_PROGRESS}}
+	end := bson.M{"$min": bson.A{now, "$deadline", bson.M{"$ifNull": bson.A{"$submittedAt", now}}}}
+
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	_, err := exam_sessions_coll.UpdateOne(ctx,
+		bson.M{"_id": session.ID},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"proctoring.connectedMs": bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$proctoring.connectedMs", 0}},
+				bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{end, bson.NewDateTimeFromTime(connected_at)}}}},
+			}},
+			"proctoring.openConnections": bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{bson.M{"$ifNull": bson.A{"$proctoring.openConnections", 1}}, 1}}}},
+			"proctoring.lastDisconnectedAt": bson.M{"$cond": bson.A{
+				in_progress,
+				now,
+				bson.M{"$ifNull": bson.A{"$proctoring.lastDisconnectedAt", "$$REMOVE"}},
+			}},
+		}}}},
+	)
+	if err != nil {
+		log.Printf("proctoring: failed to record the disconnect of session %s: %s", session.ID.Hex(), err.Error())
+	}
+}
+
+func writeProctoringError(conn *websocket.Conn, message string) {
+	payload, _ := json.Marshal(map[string]string{"type": "error", "message": message})
+	conn.WriteText(payload)
+}
+
+// GetSponsoredExamAttempts lists the graded attempts of a company's sponsored
+// exams with their integrity. Admins see every sponsored attempt, or one
+// company's with ?sponsorId=.
+func (cfg *AppConfig) GetSponsoredExamAttempts(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	filter := bson.M{"sponsorId": user_id, "attemptId": bson.M{"$exists": true}}
+	if user.Role == models.ROLE_ADMIN {
+		filter["sponsorId"] = bson.M{"$exists": true}
+		if sponsor_param := r.URL.Query().Get("sponsorId"); sponsor_param != "" {
+			sponsor_id, err := bson.ObjectIDFromHex(sponsor_param)
+			if err != nil {
+				return utils.NewBadRequest("Invalid sponsorId")
+			}
+			filter["sponsorId"] = sponsor_id
+		}
+	}
+	if blueprint_param := r.URL.Query().Get("blueprintId"); blueprint_param != "" {
+		blueprint_id, err := bson.ObjectIDFromHex(blueprint_param)
+		if err != nil {
+			return utils.NewBadRequest("Invalid blueprintId")
+		}
+		filter["blueprintId"] = blueprint_id
+	}
+	page, limit := parsePagination(r, DEFAULT_SPONSORED_ATTEMPTS_LIMIT, MAX_SPONSORED_ATTEMPTS_LIMIT)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	total, err := exam_sessions_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	cursor, err := exam_sessions_coll.Find(ctx, filter,
+		options.Find().
+			SetSort(bson.D{{Key: "submittedAt", Value: -1}}).
+			SetSkip((page-1)*limit).
+			SetLimit(limit).
+			SetProjection(bson.M{"questions": 0, "adaptive": 0, "sections": 0}),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	sessions := []models.ExamSession{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	session_ids := make([]bson.ObjectID, 0, len(sessions))
+	attempt_ids := make([]bson.ObjectID, 0, len(sessions))
+	student_ids := make([]bson.ObjectID, 0, len(sessions))
+	for _, session := range sessions {
+		session_ids = append(session_ids, session.ID)
+		attempt_ids = append(attempt_ids, session.AttemptID)
+		student_ids = append(student_ids, session.UserID)
+	}
+
+	attempts := map[bson.ObjectID]models.TestAttempt{}
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err = attempts_coll.Find(ctx, bson.M{"_id": bson.M{"$in": attempt_ids}}, options.Find().SetProjection(bson.M{"questions": 0}))
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	for cursor.Next(ctx) {
+		var attempt models.TestAttempt
+		if err := cursor.Decode(&attempt); err != nil {
+			return utils.NewInternalServerError(err)
+		}
+		attempts[attempt.ID] = attempt
+	}
+	if err := cursor.Err(); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	students := map[bson.ObjectID]models.User{}
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	cursor, err = users_coll.Find(ctx, bson.M{"_id": bson.M{"$in": student_ids}})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	for cursor.Next(ctx) {
+		var student models.User
+		if err := cursor.Decode(&student); err != nil {
+			return utils.NewInternalServerError(err)
+		}
+		students[student.ID] = student
+	}
+	if err := cursor.Err(); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	signals, err := cfg.findProctoringSignals(ctx, session_ids)
+	if err != nil {
+		return err
+	}
+
+	views := make([]SponsoredAttemptView, 0, len(sessions))
+	for _, session := range sessions {
+		attempt, found := attempts[session.AttemptID]
+		if !found {
+			continue
+		}
+		student := students[session.UserID]
+		views = append(views, SponsoredAttemptView{
+			AttemptID:     attempt.ID,
+			SessionID:     session.ID,
+			BlueprintID:   session.BlueprintID,
+			Student:       student.GetPublicProfile(user.AsViewer()),
+			Category:      attempt.Category,
+			Score:         attempt.Score,
+			AutoSubmitted: session.AutoSubmitted,
+			CompletedAt:   attempt.CompletedAt,
+			Integrity:     models.ComputeIntegrity(session, signals[session.ID]),
+		})
+	}
+
+	response_payload := map[string]any{
+		"attempts":   views,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Sponsored exam attempts provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetExamAttemptReview shows an exam attempt with its integrity score and the
+// signals behind it, to admins and to the company sponsoring the exam.
+func (cfg *AppConfig) GetExamAttemptReview(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	attempt_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var attempt models.TestAttempt
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	err = attempts_coll.FindOne(ctx, bson.M{"_id": attempt_id, "examSessionId": bson.M{"$exists": true}}).Decode(&attempt)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Exam attempt not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	var session models.ExamSession
+	exam_sessions_coll := cfg.DATABASE.Collection(models.EXAM_SESSIONS_COLLECTION)
+	err = exam_sessions_coll.FindOne(ctx, bson.M{"_id": attempt.ExamSessionID}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Exam attempt not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	// Other companies don't learn the attempt exists
+	if user.Role != models.ROLE_ADMIN && (session.SponsorID.IsZero() || session.SponsorID != user_id) {
+		return utils.NewNotFound("Exam attempt not found")
+	}
+
+	var student models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err = users_coll.FindOne(ctx, bson.M{"_id": attempt.UserID}).Decode(&student)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return utils.NewInternalServerError(err)
+	}
+
+	signals, err := cfg.findProctoringSignals(ctx, []bson.ObjectID{session.ID})
+	if err != nil {
+		return err
+	}
+	session_signals := signals[session.ID]
+	if session_signals == nil {
+		session_signals = []models.ProctoringSignal{}
+	}
+
+	response_payload := map[string]any{
+		"attempt":       attempt,
+		"student":       student.GetPublicProfile(user.AsViewer()),
+		"autoSubmitted": session.AutoSubmitted,
+		"integrity":     models.ComputeIntegrity(session, session_signals),
+		"signals":       session_signals,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Exam attempt review provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// findProctoringSignals loads the signals of the sessions in the order they
+// happened, keyed by session.
+func (cfg *AppConfig) findProctoringSignals(ctx context.Context, session_ids []bson.ObjectID) (map[bson.ObjectID][]models.ProctoringSignal, error) {
+	proctoring_signals_coll := cfg.DATABASE.Collection(models.PROCTORING_SIGNALS_COLLECTION)
+	cursor, err := proctoring_signals_coll.Find(ctx,
+		bson.M{"sessionId": bson.M{"$in": session_ids}},
+		options.Find().SetSort(bson.D{{Key: "sessionId", Value: 1}, {Key: "occurredAt", Value: 1}}),
+	)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	signals := []models.ProctoringSignal{}
+	if err := cursor.All(ctx, &signals); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	by_session := map[bson.ObjectID][]models.ProctoringSignal{}
+	for _, signal := range signals {
+		by_session[signal.SessionID] = append(by_session[signal.SessionID], signal)
+	}
+	return by_session, nil
+}
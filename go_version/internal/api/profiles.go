@@ -0,0 +1,381 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	MIN_HANDLE_LENGTH = 3
+	MAX_HANDLE_LENGTH = 30
+
+	// A previous handle keeps redirecting to the profile for this long
+	HANDLE_GRACE_PERIOD = 30 * 24 * time.Hour
+
+	// Students can change their handle once per cooldown
+	HANDLE_CHANGE_COOLDOWN = 7 * 24 * time.Hour
+)
+
+// Lowercase letters, digits and single hyphens, not at either end
+var reProfileHandle = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// Handles that would clash with routes or could pass for the platform
+var reservedHandles = []string{
+	"about", "account", "admin", "administrator", "api", "app", "auth", "blog", "careers", "certificates",
+	"companies", "company", "contact", "dashboard", "exams", "help", "home", "jobs", "login", "logout",
+	"me", "moderator", "new", "privacy", "profile", "profiles", "register", "root", "security", "settings",
+	"signin", "signup", "staff", "students", "support", "system", "talent", "talentspal", "terms", "www",
+}
+
+type ProfileHandleRequestBody struct {
+	Handle string `json:"handle"`
+}
+
+type ProfileHandleView struct {
+	Handle       string                 `json:"handle"`
+	ProfileURL   string                 `json:"profileUrl,omitempty"`
+	ChangeableAt *bson.DateTime         `json:"changeableAt"`
+	Previous     []models.ProfileHandle `json:"previous"`
+}
+
+// Profile handles
+
+func (cfg *AppConfig) GetProfileHandle(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	view, err := cfg.newProfileHandleView(ctx, user_id, user)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Profile handle provided successfully",
+		map[string]any{"handle": view},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// UpdateProfileHandle claims a new handle for the student. The previous one
+// stays reserved to them and redirects to the profile for a grace period.
+func (cfg *AppConfig) UpdateProfileHandle(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	req_body := ProfileHandleRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing profile handle request body", http.StatusBadRequest, err)
+	}
+
+	handle := strings.ToLower(strings.TrimSpace(req_body.Handle))
+	if err := validateProfileHandle(handle); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if handle == user.Handle {
+		return utils.NewBadRequest("This is already your handle")
+	}
+	now := time.Now()
+	if user.Handle != "" {
+		changeable_at := user.HandleChangedAt.Time().Add(HANDLE_CHANGE_COOLDOWN)
+		if now.Before(changeable_at) {
+			return utils.NewAppError("You can change your handle again after "+changeable_at.UTC().Format("2006-01-02"), http.StatusTooManyRequests, nil)
+		}
+	}
+
+	if err := cfg.claimProfileHandle(ctx, user_id, handle, now); err != nil {
+		return err
+	}
+
+	// The handle is only switched if nobody changed it in the meantime
+	previous := user.Handle
+	current := bson.M{"_id": user_id, "handle": previous}
+	if previous == "" {
+		current["handle"] = bson.M{"$exists": false}
+	}
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err = users_coll.FindOneAndUpdate(ctx,
+		current,
+		bson.M{"$set": bson.M{
+			"handle":          handle,
+			"handleChangedAt": bson.NewDateTimeFromTime(now),
+			"updatedAt":       bson.NewDateTimeFromTime(now),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments || mongo.IsDuplicateKeyError(err) {
+		handles_coll := cfg.DATABASE.Collection(models.PROFILE_HANDLES_COLLECTION)
+		if _, err := handles_coll.DeleteOne(ctx, bson.M{"handle": handle, "userId": user_id}); err != nil {
+			return utils.NewInternalServerError(err)
+		}
+		return utils.NewConflict("Your handle was changed in the meantime, try again")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	if previous != "" {
+		if err := cfg.releaseProfileHandle(ctx, user_id, previous, now); err != nil {
+			return err
+		}
+	}
+
+	view, err := cfg.newProfileHandleView(ctx, user_id, user)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Profile handle updated successfully",
+		map[string]any{"handle": view},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// Public profiles
+
+// GetPublicStudentProfile shows a student's profile page to anyone, with the
+// fields the student made public, their valid certificates and their badges.
+// A previous handle still in its grace period redirects to the current one.
+func (cfg *AppConfig) GetPublicStudentProfile(w http.ResponseWriter, r *http.Request) error {
+	handle := strings.ToLower(chi.URLParam(r, "handle"))
+	if len(handle) > MAX_HANDLE_LENGTH || !reProfileHandle.MatchString(handle) {
+		return utils.NewNotFound("Profile not found")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var student models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err := users_coll.FindOne(ctx, bson.M{"handle": handle, "role": models.ROLE_STUDENT, "isActive": true}).Decode(&student)
+	if err == mongo.ErrNoDocuments {
+		moved_to, err := cfg.findMovedProfileHandle(ctx, handle)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Location", "/api/profiles/"+moved_to)
+		utils.SuccessResponseWriter(
+			w,
+			"Profile moved",
+			map[string]any{"handle": moved_to},
+			http.StatusFound,
+		)
+		return nil
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	certificates_coll := cfg.DATABASE.Collection(models.CERTIFICATES_COLLECTION)
+	cursor, err := certificates_coll.Find(ctx,
+		bson.M{"userId": student.ID, "status": models.CERTIFICATE_STATUS_VALID},
+		options.Find().SetSort(bson.D{{Key: "issuedAt", Value: -1}}),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	certificates := []models.Certificate{}
+	if err := cursor.All(ctx, &certificates); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	certificate_views := make([]CertificateView, 0, len(certificates))
+	for _, c := range certificates {
+		view := cfg.newCertificateView(c)
+		// Adding a certificate to LinkedIn is for its owner only
+		view.LinkedInURL = ""
+		certificate_views = append(certificate_views, view)
+	}
+
+	achievements_coll := cfg.DATABASE.Collection(models.ACHIEVEMENTS_COLLECTION)
+	cursor, err = achievements_coll.Find(ctx,
+		bson.M{"userId": student.ID},
+		options.Find().SetSort(bson.D{{Key: "unlockedAt", Value: -1}}),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	achievements := []models.Achievement{}
+	if err := cursor.All(ctx, &achievements); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	badges := make([]AchievementView, 0, len(achievements))
+	for _, achievement := range achievements {
+		badges = append(badges, AchievementView{
+			Type:        achievement.Type,
+			Title:       achievement.Title,
+			Description: achievement.Description,
+			Icon:        achievement.Icon,
+			Unlocked:    true,
+			UnlockedAt:  &achievement.UnlockedAt,
+		})
+	}
+
+	response_payload := map[string]any{
+		"profile":      student.GetPublicProfile(models.ProfileViewer{}),
+		"certificates": certificate_views,
+		"badges":       badges,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Profile provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func validateProfileHandle(handle string) error {
+	if len(handle) < MIN_HANDLE_LENGTH || len(handle) > MAX_HANDLE_LENGTH || !reProfileHandle.MatchString(handle) {
+		return utils.NewBadRequest("Handle must be 3 to 30 lowercase letters, digits or single hyphens, starting and ending with a letter or digit")
+	}
+	if slices.Contains(reservedHandles, handle) {
+		return utils.NewBadRequest("This handle is reserved")
+	}
+	return nil
+}
+
+// claimProfileHandle reserves a handle for the user. The unique handle index
+// keeps two students from claiming the same one, and a previous handle in its
+// grace period can only be taken back by its owner.
+func (cfg *AppConfig) claimProfileHandle(ctx context.Context, user_id bson.ObjectID, handle string, now time.Time) error {
+	handles_coll := cfg.DATABASE.Collection(models.PROFILE_HANDLES_COLLECTION)
+
+	// A handle whose grace period ended may not have been swept yet
+	_, err := handles_coll.DeleteOne(ctx, bson.M{
+		"handle":    handle,
+		"userId":    bson.M{"$ne": user_id},
+		"expiresAt": bson.M{"$lte": bson.NewDateTimeFromTime(now)},
+	})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	result, err := handles_coll.UpdateOne(ctx,
+		bson.M{"handle": handle, "userId": user_id},
+		bson.M{
+			"$set":   bson.M{"updatedAt": bson.NewDateTimeFromTime(now)},
+			"$unset": bson.M{"releasedAt": "", "expiresAt": ""},
+		},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if result.MatchedCount == 1 {
+		return nil
+	}
+
+	_, err = handles_coll.InsertOne(ctx, models.ProfileHandle{
+		Handle:    handle,
+		UserID:    user_id,
+		CreatedAt: bson.NewDateTimeFromTime(now),
+		UpdatedAt: bson.NewDateTimeFromTime(now),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return utils.NewConflict("This handle is already taken")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	return nil
+}
+
+// releaseProfileHandle starts the grace period of the handle the user moved
+// away from.
+func (cfg *AppConfig) releaseProfileHandle(ctx context.Context, user_id bson.ObjectID, handle string, now time.Time) error {
+	handles_coll := cfg.DATABASE.Collection(models.PROFILE_HANDLES_COLLECTION)
+	_, err := handles_coll.UpdateOne(ctx,
+		bson.M{"handle": handle, "userId": user_id},
+		bson.M{"$set": bson.M{
+			"releasedAt": bson.NewDateTimeFromTime(now),
+			"expiresAt":  bson.NewDateTimeFromTime(now.Add(HANDLE_GRACE_PERIOD)),
+			"updatedAt":  bson.NewDateTimeFromTime(now),
+		}},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	return nil
+}
+
+// findMovedProfileHandle returns the current handle of the student who
+// moved away from a handle still in its grace period.
+func (cfg *AppConfig) findMovedProfileHandle(ctx context.Context, handle string) (string, error) {
+	var previous models.ProfileHandle
+	handles_coll := cfg.DATABASE.Collection(models.PROFILE_HANDLES_COLLECTION)
+	err := handles_coll.FindOne(ctx, bson.M{
+		"handle":    handle,
+		"expiresAt": bson.M{"$gt": bson.NewDateTimeFromTime(time.Now())},
+	}).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return "", utils.NewNotFound("Profile not found")
+	} else if err != nil {
+		return "", utils.NewInternalServerError(err)
+	}
+
+	var student models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err = users_coll.FindOne(ctx, bson.M{
+		"_id":      previous.UserID,
+		"role":     models.ROLE_STUDENT,
+		"isActive": true,
+		"handle":   bson.M{"$exists": true},
+	}).Decode(&student)
+	if err == mongo.ErrNoDocuments {
+		return "", utils.NewNotFound("Profile not found")
+	} else if err != nil {
+		return "", utils.NewInternalServerError(err)
+	}
+	return student.Handle, nil
+}
+
+func (cfg *AppConfig) newProfileHandleView(ctx context.Context, user_id bson.ObjectID, user models.User) (ProfileHandleView, error) {
+	view := ProfileHandleView{Handle: user.Handle, Previous: []models.ProfileHandle{}}
+	if user.Handle == "" {
+		return view, nil
+	}
+	view.ProfileURL = cfg.REQUIREMENTS.Server.FrontendURL + "/profiles/" + user.Handle
+	changeable_at := bson.NewDateTimeFromTime(user.HandleChangedAt.Time().Add(HANDLE_CHANGE_COOLDOWN))
+	view.ChangeableAt = &changeable_at
+
+	handles_coll := cfg.DATABASE.Collection(models.PROFILE_HANDLES_COLLECTION)
+	cursor, err := handles_coll.Find(ctx,
+		bson.M{"userId": user_id, "expiresAt": bson.M{"$gt": bson.NewDateTimeFromTime(time.Now())}},
+		options.Find().SetSort(bson.D{{Key: "releasedAt", Value: -1}}),
+	)
+	if err != nil {
+		return view, utils.NewInternalServerError(err)
+	}
+	if err := cursor.All(ctx, &view.Previous); err != nil {
+		return view, utils.NewInternalServerError(err)
+	}
+	return view, nil
+}
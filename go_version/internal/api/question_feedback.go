@@ -0,0 +1,696 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_FEEDBACK_PAGE_LIMIT = 20
+	MAX_FEEDBACK_PAGE_LIMIT     = 100
+)
+
+type QuestionNoteRequestBody struct {
+	Content string `json:"content" validate:"required,max=5000"`
+}
+
+type QuestionFlagRequestBody struct {
+	Reason  string `json:"reason" validate:"required,oneof=wrong_answer typo unclear outdated other"`
+	Details string `json:"details" validate:"required_if=Reason other,max=1000"`
+}
+
+type ModerateFlagRequestBody struct {
+	Note string `json:"note" validate:"max=1000"`
+}
+
+type BookmarkView struct {
+	Question     models.PublicQuestion `json:"question"`
+	BookmarkedAt bson.DateTime         `json:"bookmarkedAt"`
+}
+
+type NoteView struct {
+	Question  models.PublicQuestion `json:"question"`
+	Content   string                `json:"content"`
+	UpdatedAt bson.DateTime         `json:"updatedAt"`
+}
+
+type FlagView struct {
+	models.QuestionFlag
+	Question     *models.PublicQuestion `json:"question,omitempty"`
+	ReporterName string                 `json:"reporterName,omitempty"`
+}
+
+// Bookmarks
+
+func (cfg *AppConfig) BookmarkQuestion(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if _, err := cfg.findActiveQuestion(ctx, question_id); err != nil {
+		return err
+	}
+
+	// Idempotent, bookmarking twice keeps the original bookmark
+	bookmarks_coll := cfg.DATABASE.Collection(models.QUESTION_BOOKMARKS_COLLECTION)
+	_, err = bookmarks_coll.UpdateOne(ctx,
+		bson.M{"userId": user_id, "questionId": question_id},
+		bson.M{"$setOnInsert": bson.M{"createdAt": bson.NewDateTimeFromTime(time.Now())}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Question bookmarked successfully",
+		map[string]any{"questionId": question_id, "bookmarked": true},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) RemoveBookmark(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	bookmarks_coll := cfg.DATABASE.Collection(models.QUESTION_BOOKMARKS_COLLECTION)
+	if _, err := bookmarks_coll.DeleteOne(ctx, bson.M{"userId": user_id, "questionId": question_id}); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Bookmark removed successfully",
+		map[string]any{"questionId": question_id, "bookmarked": false},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetBookmarks(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	page, limit := parsePagination(r, DEFAULT_FEEDBACK_PAGE_LIMIT, MAX_FEEDBACK_PAGE_LIMIT)
+	filter := bson.M{"userId": user_id}
+
+	bookmarks_coll := cfg.DATABASE.Collection(models.QUESTION_BOOKMARKS_COLLECTION)
+	cursor, err := bookmarks_coll.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip((page-1)*limit).
+		SetLimit(limit),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	bookmarks := []models.QuestionBookmark{}
+	if err := cursor.All(ctx, &bookmarks); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	total, err := bookmarks_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	question_ids := make([]bson.ObjectID, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		question_ids = append(question_ids, bookmark.QuestionID)
+	}
+	questions, err := cfg.findPublicQuestionsByID(ctx, question_ids)
+	if err != nil {
+		return err
+	}
+
+	views := make([]BookmarkView, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		question, ok := questions[bookmark.QuestionID]
+		if !ok {
+			continue
+		}
+		views = append(views, BookmarkView{Question: question, BookmarkedAt: bookmark.CreatedAt})
+	}
+
+	response_payload := map[string]any{
+		"bookmarks":  views,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Bookmarks provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// Notes
+
+func (cfg *AppConfig) GetQuestionNote(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var note models.QuestionNote
+	notes_coll := cfg.DATABASE.Collection(models.QUESTION_NOTES_COLLECTION)
+	err = notes_coll.FindOne(ctx, bson.M{"userId": user_id, "questionId": question_id}).Decode(&note)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Note not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Note provided successfully",
+		map[string]any{"note": note},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// SaveQuestionNote creates or replaces the student's note on a question.
+func (cfg *AppConfig) SaveQuestionNote(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := QuestionNoteRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing note request body", http.StatusBadRequest, err)
+	}
+	// Notes are private and often contain code, so they are only trimmed
+	req_body.Content = strings.TrimSpace(req_body.Content)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if _, err := cfg.findActiveQuestion(ctx, question_id); err != nil {
+		return err
+	}
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	var note models.QuestionNote
+	notes_coll := cfg.DATABASE.Collection(models.QUESTION_NOTES_COLLECTION)
+	err = notes_coll.FindOneAndUpdate(ctx,
+		bson.M{"userId": user_id, "questionId": question_id},
+		bson.M{
+			"$set":         bson.M{"content": req_body.Content, "updatedAt": now},
+			"$setOnInsert": bson.M{"createdAt": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&note)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Note saved successfully",
+		map[string]any{"note": note},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) DeleteQuestionNote(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	notes_coll := cfg.DATABASE.Collection(models.QUESTION_NOTES_COLLECTION)
+	result, err := notes_coll.DeleteOne(ctx, bson.M{"userId": user_id, "questionId": question_id})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if result.DeletedCount == 0 {
+		return utils.NewNotFound("Note not found")
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Note deleted successfully",
+		nil,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetQuestionNotes(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	page, limit := parsePagination(r, DEFAULT_FEEDBACK_PAGE_LIMIT, MAX_FEEDBACK_PAGE_LIMIT)
+	filter := bson.M{"userId": user_id}
+
+	notes_coll := cfg.DATABASE.Collection(models.QUESTION_NOTES_COLLECTION)
+	cursor, err := notes_coll.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "updatedAt", Value: -1}}).
+		SetSkip((page-1)*limit).
+		SetLimit(limit),
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	notes := []models.QuestionNote{}
+	if err := cursor.All(ctx, &notes); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	total, err := notes_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	question_ids := make([]bson.ObjectID, 0, len(notes))
+	for _, note := range notes {
+		question_ids = append(question_ids, note.QuestionID)
+	}
+	questions, err := cfg.findPublicQuestionsByID(ctx, question_ids)
+	if err != nil {
+		return err
+	}
+
+	views := make([]NoteView, 0, len(notes))
+	for _, note := range notes {
+		question, ok := questions[note.QuestionID]
+		if !ok {
+			continue
+		}
+		views = append(views, NoteView{Question: question, Content: note.Content, UpdatedAt: note.UpdatedAt})
+	}
+
+	response_payload := map[string]any{
+		"notes":      views,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Notes provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// Flags
+
+// FlagQuestion reports a mistake in a question. A student can have one open
+// flag per question, reporting again updates it instead of piling up duplicates.
+func (cfg *AppConfig) FlagQuestion(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := QuestionFlagRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing flag request body", http.StatusBadRequest, err)
+	}
+	req_body.Details = sanitizeInput(req_body.Details)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	question, err := cfg.findActiveQuestion(ctx, question_id)
+	if err != nil {
+		return err
+	}
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	var flag models.QuestionFlag
+	flags_coll := cfg.DATABASE.Collection(models.QUESTION_FLAGS_COLLECTION)
+	err = flags_coll.FindOneAndUpdate(ctx,
+		bson.M{"reporterId": user_id, "questionId": question_id, "status": models.FLAG_STATUS_OPEN},
+		bson.M{
+			"$set": bson.M{
+				"revision":  question.Revision,
+				"reason":    req_body.Reason,
+				"details":   req_body.Details,
+				"updatedAt": now,
+			},
+			"$setOnInsert": bson.M{"createdAt": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&flag)
+	if mongo.IsDuplicateKeyError(err) {
+		return utils.NewConflict("You already reported this question")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Thanks for reporting, an admin will review this question",
+		map[string]any{"flag": flag},
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetMyFlags(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	page, limit := parsePagination(r, DEFAULT_FEEDBACK_PAGE_LIMIT, MAX_FEEDBACK_PAGE_LIMIT)
+	views, total, err := cfg.listQuestionFlags(ctx, bson.M{"reporterId": user_id}, page, limit, false)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"flags":      views,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Flags provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetFlagQueue is the admin moderation queue, open flags oldest first by default.
+func (cfg *AppConfig) GetFlagQueue(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = models.FLAG_STATUS_OPEN
+	}
+	if !slices.Contains([]string{models.FLAG_STATUS_OPEN, models.FLAG_STATUS_RESOLVED, models.FLAG_STATUS_REJECTED}, status) {
+		return utils.NewBadRequest("Invalid status")
+	}
+	filter := bson.M{"status": status}
+	if reason := r.URL.Query().Get("reason"); reason != "" {
+		filter["reason"] = reason
+	}
+	if question := r.URL.Query().Get("questionId"); question != "" {
+		question_id, err := bson.ObjectIDFromHex(question)
+		if err != nil {
+			return utils.NewBadRequest("Invalid questionId")
+		}
+		filter["questionId"] = question_id
+	}
+
+	page, limit := parsePagination(r, DEFAULT_FEEDBACK_PAGE_LIMIT, MAX_FEEDBACK_PAGE_LIMIT)
+	views, total, err := cfg.listQuestionFlags(ctx, filter, page, limit, true)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"flags":      views,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Flag queue provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) ResolveQuestionFlag(w http.ResponseWriter, r *http.Request) error {
+	return cfg.moderateQuestionFlag(w, r, models.FLAG_STATUS_RESOLVED)
+}
+
+func (cfg *AppConfig) RejectQuestionFlag(w http.ResponseWriter, r *http.Request) error {
+	return cfg.moderateQuestionFlag(w, r, models.FLAG_STATUS_REJECTED)
+}
+
+// moderateQuestionFlag closes an open flag and emails the reporter the outcome.
+func (cfg *AppConfig) moderateQuestionFlag(w http.ResponseWriter, r *http.Request, status string) error {
+	moderator_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	flag_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := ModerateFlagRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing moderation request body", http.StatusBadRequest, err)
+	}
+	req_body.Note = sanitizeInput(req_body.Note)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	var flag models.QuestionFlag
+	flags_coll := cfg.DATABASE.Collection(models.QUESTION_FLAGS_COLLECTION)
+	err = flags_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": flag_id, "status": models.FLAG_STATUS_OPEN},
+		bson.M{"$set": bson.M{
+			"status":         status,
+			"resolutionNote": req_body.Note,
+			"moderatorId":    moderator_id,
+			"moderatedAt":    now,
+			"updatedAt":      now,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&flag)
+	if err == mongo.ErrNoDocuments {
+		if count, err := flags_coll.CountDocuments(ctx, bson.M{"_id": flag_id}); err == nil && count > 0 {
+			return utils.NewConflict("This flag was already moderated")
+		}
+		return utils.NewNotFound("Flag not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	var reporter models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err = users_coll.FindOne(ctx, bson.M{"_id": flag.ReporterID}).Decode(&reporter)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return utils.NewInternalServerError(err)
+	}
+	question, err := cfg.findQuestion(ctx, flag.QuestionID)
+	if err != nil {
+		return err
+	}
+	if reporter.Email != "" {
+		subject := "Your question report was reviewed - " + cfg.REQUIREMENTS.SMTP.AppName
+		cfg.sendEmail(reporter.Email, subject, utils.FlagOutcomeEmailBody(reporter.FullName, question.Question, status, flag.ResolutionNote))
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Flag "+status+" successfully",
+		map[string]any{"flag": flag},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) listQuestionFlags(ctx context.Context, filter bson.M, page, limit int64, with_reporters bool) ([]FlagView, int64, error) {
+	flags_coll := cfg.DATABASE.Collection(models.QUESTION_FLAGS_COLLECTION)
+	sort := bson.D{{Key: "createdAt", Value: -1}}
+	if filter["status"] == models.FLAG_STATUS_OPEN {
+		sort = bson.D{{Key: "createdAt", Value: 1}}
+	}
+	cursor, err := flags_coll.Find(ctx, filter, options.Find().
+		SetSort(sort).
+		SetSkip((page-1)*limit).
+		SetLimit(limit),
+	)
+	if err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+	flags := []models.QuestionFlag{}
+	if err := cursor.All(ctx, &flags); err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+
+	total, err := flags_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, utils.NewInternalServerError(err)
+	}
+
+	question_ids := make([]bson.ObjectID, 0, len(flags))
+	reporter_ids := make([]bson.ObjectID, 0, len(flags))
+	for _, flag := range flags {
+		question_ids = append(question_ids, flag.QuestionID)
+		reporter_ids = append(reporter_ids, flag.ReporterID)
+	}
+	questions, err := cfg.findPublicQuestionsByID(ctx, question_ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	reporter_names := map[bson.ObjectID]string{}
+	if with_reporters {
+		users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+		cursor, err := users_coll.Find(ctx,
+			bson.M{"_id": bson.M{"$in": reporter_ids}},
+			options.Find().SetProjection(bson.M{"fullName": 1}),
+		)
+		if err != nil {
+			return nil, 0, utils.NewInternalServerError(err)
+		}
+		users := []models.User{}
+		if err := cursor.All(ctx, &users); err != nil {
+			return nil, 0, utils.NewInternalServerError(err)
+		}
+		for _, user := range users {
+			reporter_names[user.ID] = user.FullName
+		}
+	}
+
+	views := make([]FlagView, 0, len(flags))
+	for _, flag := range flags {
+		view := FlagView{QuestionFlag: flag, ReporterName: reporter_names[flag.ReporterID]}
+		if question, ok := questions[flag.QuestionID]; ok {
+			view.Question = &question
+		}
+		views = append(views, view)
+	}
+	return views, total, nil
+}
+
+func (cfg *AppConfig) findActiveQuestion(ctx context.Context, question_id bson.ObjectID) (models.Question, error) {
+	question, err := cfg.findQuestion(ctx, question_id)
+	if err != nil {
+		return question, err
+	}
+	if !question.IsActive {
+		return question, utils.NewNotFound("Question not found")
+	}
+	return question, nil
+}
+
+// findPublicQuestionsByID loads questions without their answers, keyed by id.
+// Deactivated questions are included, a student's saved items outlive them.
+func (cfg *AppConfig) findPublicQuestionsByID(ctx context.Context, question_ids []bson.ObjectID) (map[bson.ObjectID]models.PublicQuestion, error) {
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	cursor, err := questions_coll.Find(ctx, bson.M{"_id": bson.M{"$in": question_ids}})
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	questions := []models.Question{}
+	if err := cursor.All(ctx, &questions); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	by_id := make(map[bson.ObjectID]models.PublicQuestion, len(questions))
+	for _, question := range questions {
+		by_id[question.ID] = question.GetPublicQuestion()
+	}
+	return by_id, nil
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"go_version/internal/models"
+	"slices"
+	"strings"
+)
+
+func sanitizeQuestionRequest(body *QuestionRequestBody) {
+	// Question text, options & explanation may legitimately contain code such as `<div>`,
+	// so they are only trimmed here and escaped by the frontend on display.
+	body.Question = strings.TrimSpace(body.Question)
+	for i, option := range body.Options {
+		body.Options[i] = strings.TrimSpace(option)
+	}
+	body.CorrectAnswer = strings.TrimSpace(body.CorrectAnswer)
+	body.Explanation = strings.TrimSpace(body.Explanation)
+	body.Category = strings.ToLower(strings.TrimSpace(body.Category))
+	body.Difficulty = strings.ToLower(strings.TrimSpace(body.Difficulty))
+	for i, tag := range body.Tags {
+		body.Tags[i] = sanitizeInput(tag)
+	}
+	body.Company = sanitizeInput(body.Company)
+	body.Note = sanitizeInput(body.Note)
+}
+
+// validateQuestionStructure mirrors the Node validateQuestionStructure helper
+// for the rules the validation tags can't express.
+func validateQuestionStructure(body *QuestionRequestBody) (bool, string) {
+	seen := make(map[string]bool, len(body.Options))
+	for _, option := range body.Options {
+		if seen[option] {
+			return false, "Options must be unique"
+		}
+		seen[option] = true
+	}
+
+	if !slices.Contains(body.Options, body.CorrectAnswer) {
+		return false, "Correct answer must be one of the provided options"
+	}
+
+	return true, ""
+}
+
+func (body *QuestionRequestBody) toContent() models.QuestionContent {
+	difficulty := body.Difficulty
+	if difficulty == "" {
+		difficulty = models.DIFFICULTY_MEDIUM
+	}
+
+	is_active := true
+	if body.IsActive != nil {
+		is_active = *body.IsActive
+	}
+
+	tags := body.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return models.QuestionContent{
+		QuestionID:    body.QuestionID,
+		Category:      body.Category,
+		Question:      body.Question,
+		Options:       body.Options,
+		CorrectAnswer: body.CorrectAnswer,
+		Difficulty:    difficulty,
+		Tags:          tags,
+		Company:       body.Company,
+		Explanation:   body.Explanation,
+		IsActive:      is_active,
+	}
+}
+
+type RevisionFieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+func diffQuestionContent(from, to models.QuestionContent) []RevisionFieldChange {
+	changes := []RevisionFieldChange{}
+	add := func(field string, changed bool, from_value, to_value any) {
+		if changed {
+			changes = append(changes, RevisionFieldChange{Field: field, From: from_value, To: to_value})
+		}
+	}
+
+	add("questionId", from.QuestionID != to.QuestionID, from.QuestionID, to.QuestionID)
+	add("category", from.Category != to.Category, from.Category, to.Category)
+	add("question", from.Question != to.Question, from.Question, to.Question)
+	add("options", !slices.Equal(from.Options, to.Options), from.Options, to.Options)
+	add("correctAnswer", from.CorrectAnswer != to.CorrectAnswer, from.CorrectAnswer, to.CorrectAnswer)
+	add("difficulty", from.Difficulty != to.Difficulty, from.Difficulty, to.Difficulty)
+	add("tags", !slices.Equal(from.Tags, to.Tags), from.Tags, to.Tags)
+	add("company", from.Company != to.Company, from.Company, to.Company)
+	add("explanation", from.Explanation != to.Explanation, from.Explanation, to.Explanation)
+	add("isActive", from.IsActive != to.IsActive, from.IsActive, to.IsActive)
+
+	return changes
+}
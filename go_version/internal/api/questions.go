@@ -0,0 +1,922 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_QUESTIONS_PAGE_LIMIT = 50
+	MAX_QUESTIONS_PAGE_LIMIT     = 100
+
+	// A revision the question doesn't point at after this long was left by a
+	// failed edit
+	ABANDONED_REVISION_AGE = time.Minute
+)
+
+type QuestionRequestBody struct {
+	QuestionID    int32    `json:"questionId" validate:"required,min=1"`
+	Category      string   `json:"category" validate:"required,oneof=backend frontend"`
+	Question      string   `json:"question" validate:"required,min=10,max=1000"`
+	Options       []string `json:"options" validate:"required,min=2,max=6,dive,min=1,max=500"`
+	CorrectAnswer string   `json:"correctAnswer" validate:"required"`
+	Difficulty    string   `json:"difficulty" validate:"omitempty,oneof=easy medium hard"`
+	Tags          []string `json:"tags" validate:"max=10"`
+	Company       string   `json:"company" validate:"omitempty,max=100"`
+	Explanation   string   `json:"explanation" validate:"omitempty,max=2000"`
+	IsActive      *bool    `json:"isActive"`
+
+	// Revision metadata
+	Note            string `json:"note" validate:"omitempty,max=500"`
+	RescoreAttempts bool   `json:"rescoreAttempts"`
+}
+
+func (cfg *AppConfig) GetAllQuestions(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	query := r.URL.Query()
+	filter := bson.M{}
+	if category := query.Get("category"); category != "" {
+		filter["category"] = category
+	}
+	if difficulty := query.Get("difficulty"); difficulty != "" {
+		filter["difficulty"] = difficulty
+	}
+	if query.Get("includeInactive") != "true" {
+		filter["isActive"] = true
+	}
+
+	page, limit := parsePagination(r, DEFAULT_QUESTIONS_PAGE_LIMIT, MAX_QUESTIONS_PAGE_LIMIT)
+
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "category", Value: 1}, {Key: "questionId", Value: 1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := questions_coll.Find(ctx, filter, opts)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	questions := []models.Question{}
+	if err := cursor.All(ctx, &questions); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	total, err := questions_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	response_payload := map[string]any{
+		"questions":  questions,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Questions provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetQuestionForAdmin(w http.ResponseWriter, r *http.Request) error {
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	question, err := cfg.findQuestion(ctx, question_id)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"question": question,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Question provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) CreateQuestion(w http.ResponseWriter, r *http.Request) error {
+	_, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	req_body, err := parseQuestionRequestBody(r)
+	if err != nil {
+		return err
+	}
+
+	content := req_body.toContent()
+	now := bson.NewDateTimeFromTime(time.Now())
+	question := models.Question{
+		ID:         bson.NewObjectID(),
+		Revision:   1,
+		RevisionID: bson.NewObjectID(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	question.ApplyContent(content)
+
+	revision := models.QuestionRevision{
+		ID:         question.RevisionID,
+		QuestionID: question.ID,
+		Revision:   question.Revision,
+		Action:     models.REVISION_ACTION_CREATE,
+		Content:    content,
+		AuthorID:   user.ID,
+		AuthorName: user.FullName,
+		Note:       req_body.Note,
+		CreatedAt:  now,
+	}
+	// The revision goes first so the question never points at a missing one
+	revisions_coll := cfg.DATABASE.Collection(models.QUESTION_REVISIONS_COLLECTION)
+	if _, err := revisions_coll.InsertOne(ctx, revision); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	_, err = questions_coll.InsertOne(ctx, question)
+	if err != nil {
+		if _, delete_err := revisions_coll.DeleteOne(ctx, bson.M{"_id": revision.ID}); delete_err != nil {
+			log.Printf("failed to remove the revision of unsaved question %s: %s", question.ID.Hex(), delete_err.Error())
+		}
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return utils.NewConflict("A question with this ID already exists")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	response_payload := map[string]any{
+		"question": question,
+		"revision": revision,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Question created successfully",
+		response_payload,
+		http.StatusCreated,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) UpdateQuestion(w http.ResponseWriter, r *http.Request) error {
+	_, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	req_body, err := parseQuestionRequestBody(r)
+	if err != nil {
+		return err
+	}
+
+	question, err := cfg.findQuestion(ctx, question_id)
+	if err != nil {
+		return err
+	}
+
+	revision, err := cfg.commitQuestionRevision(ctx, &question, req_body.toContent(), models.REVISION_ACTION_UPDATE, user, req_body.Note, 0)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"question": question,
+		"revision": revision,
+	}
+
+	if req_body.RescoreAttempts {
+		summary, err := cfg.rescoreQuestionAttempts(ctx, question, user.ID, false)
+		if err != nil {
+			return err
+		}
+		response_payload["rescore"] = summary
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Question updated successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// DeleteQuestion soft deletes a question, deactivation is recorded as a
+// revision like any other edit so it can be rolled back.
+func (cfg *AppConfig) DeleteQuestion(w http.ResponseWriter, r *http.Request) error {
+	_, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	question, err := cfg.findQuestion(ctx, question_id)
+	if err != nil {
+		return err
+	}
+
+	if !question.IsActive {
+		return utils.NewBadRequest("Question is already deactivated")
+	}
+
+	content := question.Content()
+	content.IsActive = false
+	revision, err := cfg.commitQuestionRevision(ctx, &question, content, models.REVISION_ACTION_DELETE, user, "", 0)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"question": question,
+		"revision": revision,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Question deactivated successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetQuestionRevisions(w http.ResponseWriter, r *http.Request) error {
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	question, err := cfg.findQuestion(ctx, question_id)
+	if err != nil {
+		return err
+	}
+
+	revisions_coll := cfg.DATABASE.Collection(models.QUESTION_REVISIONS_COLLECTION)
+	cursor, err := revisions_coll.Find(ctx, bson.M{"question": question_id}, options.Find().SetSort(bson.D{{Key: "revision", Value: -1}}))
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	revisions := []models.QuestionRevision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	response_payload := map[string]any{
+		"currentRevision": question.Revision,
+		"revisions":       revisions,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Question revisions provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetQuestionRevision(w http.ResponseWriter, r *http.Request) error {
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	revision_number, err := strconv.Atoi(chi.URLParam(r, "revision"))
+	if err != nil || revision_number < 1 {
+		return utils.NewBadRequest("Revision must be a positive number")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	revision, err := cfg.findQuestionRevision(ctx, question_id, int32(revision_number))
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"revision": revision,
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Question revision provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// DiffQuestionRevisions compares two revisions, 'to' defaults to the question
+// as it is now.
+func (cfg *AppConfig) DiffQuestionRevisions(w http.ResponseWriter, r *http.Request) error {
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil || from < 1 {
+		return utils.NewBadRequest("'from' must be a positive revision number")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	from_revision, err := cfg.findQuestionRevision(ctx, question_id, int32(from))
+	if err != nil {
+		return err
+	}
+
+	// The current state, questions never edited here have no revision for it
+	var to_revision int32
+	var to_content models.QuestionContent
+	if to_param := r.URL.Query().Get("to"); to_param != "" {
+		to, err := strconv.Atoi(to_param)
+		if err != nil || to < 1 {
+			return utils.NewBadRequest("'to' must be a positive revision number")
+		}
+		revision, err := cfg.findQuestionRevision(ctx, question_id, int32(to))
+		if err != nil {
+			return err
+		}
+		to_revision, to_content = revision.Revision, revision.Content
+	} else {
+		question, err := cfg.findQuestion(ctx, question_id)
+		if err != nil {
+			return err
+		}
+		to_revision, to_content = question.Revision, question.Content()
+	}
+
+	response_payload := map[string]any{
+		"from":    from_revision.Revision,
+		"to":      to_revision,
+		"changes": diffQuestionContent(from_revision.Content, to_content),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Question revisions compared successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+type RollbackQuestionRequestBody struct {
+	Note            string `json:"note" validate:"omitempty,max=500"`
+	RescoreAttempts bool   `json:"rescoreAttempts"`
+}
+
+// RollbackQuestion restores the content of an older revision. History is never
+// rewritten, the restored content is committed as a new revision.
+func (cfg *AppConfig) RollbackQuestion(w http.ResponseWriter, r *http.Request) error {
+	_, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	revision_number, err := strconv.Atoi(chi.URLParam(r, "revision"))
+	if err != nil || revision_number < 1 {
+		return utils.NewBadRequest("Revision must be a positive number")
+	}
+
+	req_body := RollbackQuestionRequestBody{}
+	if r.ContentLength != 0 {
+		if err := utils.BodyParser(r.Body, &req_body); err != nil {
+			return utils.NewAppError("Error while parsing rollback request body", http.StatusBadRequest, err)
+		}
+	}
+	req_body.Note = sanitizeInput(req_body.Note)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	question, err := cfg.findQuestion(ctx, question_id)
+	if err != nil {
+		return err
+	}
+
+	if int32(revision_number) == question.Revision {
+		return utils.NewBadRequest("This revision is already the current one")
+	}
+
+	target, err := cfg.findQuestionRevision(ctx, question_id, int32(revision_number))
+	if err != nil {
+		return err
+	}
+
+	revision, err := cfg.commitQuestionRevision(ctx, &question, target.Content, models.REVISION_ACTION_ROLLBACK, user, req_body.Note, target.Revision)
+	if err != nil {
+		return err
+	}
+
+	response_payload := map[string]any{
+		"question": question,
+		"revision": revision,
+	}
+
+	if req_body.RescoreAttempts {
+		summary, err := cfg.rescoreQuestionAttempts(ctx, question, user.ID, false)
+		if err != nil {
+			return err
+		}
+		response_payload["rescore"] = summary
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Question rolled back successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+type RescoreQuestionRequestBody struct {
+	DryRun bool `json:"dryRun"`
+}
+
+// RescoreQuestionAttempts regrades every attempt whose copy of the answer key
+// differs from the question's current correct answer.
+func (cfg *AppConfig) RescoreQuestionAttempts(w http.ResponseWriter, r *http.Request) error {
+	_, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	question_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := RescoreQuestionRequestBody{}
+	if r.ContentLength != 0 {
+		if err := utils.BodyParser(r.Body, &req_body); err != nil {
+			return utils.NewAppError("Error while parsing rescore request body", http.StatusBadRequest, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	question, err := cfg.findQuestion(ctx, question_id)
+	if err != nil {
+		return err
+	}
+
+	summary, err := cfg.rescoreQuestionAttempts(ctx, question, user.ID, req_body.DryRun)
+	if err != nil {
+		return err
+	}
+
+	message := "Attempts rescored successfully"
+	if req_body.DryRun {
+		message = "Rescore preview generated successfully"
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		message,
+		summary,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func parseQuestionRequestBody(r *http.Request) (QuestionRequestBody, error) {
+	req_body := QuestionRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return req_body, utils.NewAppError("Error while parsing question request body", http.StatusBadRequest, err)
+	}
+
+	// Sanitize inputs
+	sanitizeQuestionRequest(&req_body)
+
+	// Apply validation tags
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return req_body, utils.NewValidationError(field_errors)
+	}
+
+	if valid, message := validateQuestionStructure(&req_body); !valid {
+		return req_body, utils.NewBadRequest(message)
+	}
+
+	return req_body, nil
+}
+
+func (cfg *AppConfig) findQuestion(ctx context.Context, question_id bson.ObjectID) (models.Question, error) {
+	var question models.Question
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	err := questions_coll.FindOne(ctx, bson.M{"_id": question_id}).Decode(&question)
+	if err == mongo.ErrNoDocuments {
+		return question, utils.NewNotFound("Question not found")
+	} else if err != nil {
+		return question, utils.NewInternalServerError(err)
+	}
+	return question, nil
+}
+
+func (cfg *AppConfig) findQuestionRevision(ctx context.Context, question_id bson.ObjectID, revision_number int32) (models.QuestionRevision, error) {
+	var revision models.QuestionRevision
+	revisions_coll := cfg.DATABASE.Collection(models.QUESTION_REVISIONS_COLLECTION)
+	err := revisions_coll.FindOne(ctx, bson.M{"question": question_id, "revision": revision_number}).Decode(&revision)
+	if err == mongo.ErrNoDocuments {
+		return revision, utils.NewNotFound("Revision " + strconv.Itoa(int(revision_number)) + " not found")
+	} else if err != nil {
+		return revision, utils.NewInternalServerError(err)
+	}
+	return revision, nil
+}
+
+// commitQuestionRevision records content as the next revision and then points
+// the question at it. The revision is written first so the live question never
+// holds content without a revision, and the question update is conditioned on
+// the revision we read so two admins editing at once can't silently overwrite
+// each other.
+func (cfg *AppConfig) commitQuestionRevision(ctx context.Context, question *models.Question, content models.QuestionContent, action string, author models.User, note string, restored_from int32) (models.QuestionRevision, error) {
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	revisions_coll := cfg.DATABASE.Collection(models.QUESTION_REVISIONS_COLLECTION)
+
+	// Questions created by the Node layer have no history yet, snapshot their
+	// current state first so the edit has something to be diffed against.
+	if err := cfg.ensureQuestionRevision(ctx, question); err != nil {
+		return models.QuestionRevision{}, err
+	}
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	revision := models.QuestionRevision{
+		ID:           bson.NewObjectID(),
+		QuestionID:   question.ID,
+		Revision:     question.Revision + 1,
+		Action:       action,
+		Content:      content,
+		RestoredFrom: restored_from,
+		AuthorID:     author.ID,
+		AuthorName:   author.FullName,
+		Note:         note,
+		CreatedAt:    now,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"questionId":    content.QuestionID,
+			"category":      content.Category,
+			"question":      content.Question,
+			"options":       content.Options,
+			"correctAnswer": content.CorrectAnswer,
+			"difficulty":    content.Difficulty,
+			"tags":          content.Tags,
+			"company":       content.Company,
+			"explanation":   content.Explanation,
+			"isActive":      content.IsActive,
+			"revision":      revision.Revision,
+			"revisionId":    revision.ID,
+			"updatedAt":     now,
+		},
+	}
+	if err := cfg.insertQuestionRevision(ctx, revision); err != nil {
+		return models.QuestionRevision{}, err
+	}
+
+	result, err := questions_coll.UpdateOne(ctx, bson.M{"_id": question.ID, "revision": question.Revision}, update)
+	if mongo.IsDuplicateKeyError(err) || (err == nil && result.MatchedCount == 0) {
+		// The question certainly doesn't point at the revision, take it back.
+		// After other errors it may, and an unused one is replaced later
+		if _, delete_err := revisions_coll.DeleteOne(ctx, bson.M{"_id": revision.ID}); delete_err != nil {
+			log.Printf("failed to remove unused revision %d of question %s: %s", revision.Revision, question.ID.Hex(), delete_err.Error())
+		}
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return models.QuestionRevision{}, utils.NewConflict("A question with this ID already exists")
+	} else if err != nil {
+		return models.QuestionRevision{}, utils.NewInternalServerError(err)
+	}
+	if result.MatchedCount == 0 {
+		return models.QuestionRevision{}, utils.NewConflict("The question was modified by someone else, reload it and try again")
+	}
+
+	question.ApplyContent(content)
+	question.Revision = revision.Revision
+	question.RevisionID = revision.ID
+	question.UpdatedAt = now
+
+	return revision, nil
+}
+
+// insertQuestionRevision stores a revision the question doesn't point at yet.
+// Its number can be taken by a revision left behind by an edit that stopped
+// before updating the question; once that one is old enough to not belong to
+// an edit still in flight, it is replaced.
+func (cfg *AppConfig) insertQuestionRevision(ctx context.Context, revision models.QuestionRevision) error {
+	revisions_coll := cfg.DATABASE.Collection(models.QUESTION_REVISIONS_COLLECTION)
+	_, err := revisions_coll.InsertOne(ctx, revision)
+	if err == nil {
+		return nil
+	} else if !mongo.IsDuplicateKeyError(err) {
+		return utils.NewInternalServerError(err)
+	}
+
+	conflict := utils.NewConflict("The question was modified by someone else, reload it and try again")
+	existing, err := cfg.findQuestionRevision(ctx, revision.QuestionID, revision.Revision)
+	if err != nil {
+		return err
+	}
+	if time.Since(existing.CreatedAt.Time()) < ABANDONED_REVISION_AGE {
+		return conflict
+	}
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	in_use, err := questions_coll.CountDocuments(ctx, bson.M{"_id": revision.QuestionID, "revision": bson.M{"$gte": existing.Revision}})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if in_use != 0 {
+		return conflict
+	}
+
+	if _, err := revisions_coll.DeleteOne(ctx, bson.M{"_id": existing.ID}); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	_, err = revisions_coll.InsertOne(ctx, revision)
+	if mongo.IsDuplicateKeyError(err) {
+		return conflict
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	return nil
+}
+
+type RescoredAttempt struct {
+	AttemptID     bson.ObjectID `json:"attemptId"`
+	UserID        bson.ObjectID `json:"userId"`
+	PreviousScore int32         `json:"previousScore"`
+	NewScore      int32         `json:"newScore"`
+}
+
+type RescoreSummary struct {
+	QuestionID    bson.ObjectID     `json:"questionId"`
+	Revision      int32             `json:"revision"`
+	CorrectAnswer string            `json:"correctAnswer"`
+	DryRun        bool              `json:"dryRun"`
+	Affected      int               `json:"affected"`
+	ScoreChanged  int               `json:"scoreChanged"`
+	Attempts      []RescoredAttempt `json:"attempts"`
+}
+
+// rescoreQuestionAttempts regrades the attempts that were graded against a
+// different answer key than the question's current one. Attempts keep a log
+// of the rescore and point at the revision they were regraded with.
+func (cfg *AppConfig) rescoreQuestionAttempts(ctx context.Context, question models.Question, rescored_by bson.ObjectID, dry_run bool) (RescoreSummary, error) {
+	summary := RescoreSummary{
+		QuestionID:    question.ID,
+		Revision:      question.Revision,
+		CorrectAnswer: question.CorrectAnswer,
+		DryRun:        dry_run,
+		Attempts:      []RescoredAttempt{},
+	}
+
+	// Answers are matched to options by position, so fixing the wording of
+	// an option doesn't turn the answers given with the old wording wrong.
+	// Attempts from before revisions were kept were graded on the imported one
+	revisions_coll := cfg.DATABASE.Collection(models.QUESTION_REVISIONS_COLLECTION)
+	cursor, err := revisions_coll.Find(ctx, bson.M{"question": question.ID})
+	if err != nil {
+		return summary, utils.NewInternalServerError(err)
+	}
+	revisions := []models.QuestionRevision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return summary, utils.NewInternalServerError(err)
+	}
+	graded_options := map[bson.ObjectID][]string{}
+	var legacy_options []string
+	for _, revision := range revisions {
+		graded_options[revision.ID] = revision.Content.Options
+		if revision.Revision == 1 {
+			legacy_options = revision.Content.Options
+		}
+	}
+	correct_index := slices.Index(question.Options, question.CorrectAnswer)
+
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	filter := bson.M{
+		"questions": bson.M{
+			"$elemMatch": bson.M{
+				"questionId":    question.ID,
+				"correctAnswer": bson.M{"$ne": question.CorrectAnswer},
+			},
+		},
+	}
+	cursor, err = attempts_coll.Find(ctx, filter)
+	if err != nil {
+		return summary, utils.NewInternalServerError(err)
+	}
+	defer cursor.Close(ctx)
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	for cursor.Next(ctx) {
+		var attempt models.TestAttempt
+		if err := cursor.Decode(&attempt); err != nil {
+			return summary, utils.NewInternalServerError(err)
+		}
+
+		previous_score := attempt.Score
+		from_revision := int32(0)
+		for i := range attempt.Questions {
+			entry := &attempt.Questions[i]
+			if entry.QuestionID != question.ID || entry.CorrectAnswer == question.CorrectAnswer {
+				continue
+			}
+			from_revision = entry.Revision
+			options, found := graded_options[entry.RevisionID]
+			if !found {
+				options = legacy_options
+			}
+			answer_index := slices.Index(options, entry.UserAnswer)
+			if answer_index >= 0 && answer_index < len(question.Options) {
+				entry.UserAnswer = question.Options[answer_index]
+				entry.IsCorrect = answer_index == correct_index
+			} else {
+				entry.IsCorrect = question.CheckAnswer(entry.UserAnswer)
+			}
+			entry.CorrectAnswer = question.CorrectAnswer
+			entry.Revision = question.Revision
+			entry.RevisionID = question.RevisionID
+		}
+		attempt.Recount()
+
+		summary.Affected++
+		if attempt.Score != previous_score {
+			summary.ScoreChanged++
+		}
+		summary.Attempts = append(summary.Attempts, RescoredAttempt{
+			AttemptID:     attempt.ID,
+			UserID:        attempt.UserID,
+			PreviousScore: previous_score,
+			NewScore:      attempt.Score,
+		})
+
+		if dry_run {
+			continue
+		}
+
+		update := bson.M{
+			"$set": bson.M{
+				"questions":      attempt.Questions,
+				"correctCount":   attempt.CorrectCount,
+				"totalQuestions": attempt.TotalQuestions,
+				"score":          attempt.Score,
+				"updatedAt":      now,
+			},
+			"$push": bson.M{
+				"rescores": models.AttemptRescore{
+					QuestionID:    question.ID,
+					FromRevision:  from_revision,
+					ToRevision:    question.Revision,
+					PreviousScore: previous_score,
+					NewScore:      attempt.Score,
+					RescoredBy:    rescored_by,
+					RescoredAt:    now,
+				},
+			},
+		}
+		if _, err := attempts_coll.UpdateOne(ctx, bson.M{"_id": attempt.ID}, update); err != nil {
+			return summary, utils.NewInternalServerError(err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return summary, utils.NewInternalServerError(err)
+	}
+
+	if !dry_run && summary.Affected > 0 {
+		log.Printf("rescored %d attempts for question %s (revision %d)", summary.Affected, question.ID.Hex(), question.Revision)
+	}
+
+	return summary, nil
+}
+
+// ensureQuestionRevision gives questions created outside the Go layer (revision
+// 0) an initial "import" revision holding their current content, so anything
+// graded against them can reference an exact revision.
+func (cfg *AppConfig) ensureQuestionRevision(ctx context.Context, question *models.Question) error {
+	if question.Revision != 0 {
+		return nil
+	}
+
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	revisions_coll := cfg.DATABASE.Collection(models.QUESTION_REVISIONS_COLLECTION)
+
+	baseline := models.QuestionRevision{
+		ID:         bson.NewObjectID(),
+		QuestionID: question.ID,
+		Revision:   1,
+		Action:     models.REVISION_ACTION_IMPORT,
+		Content:    question.Content(),
+		CreatedAt:  question.UpdatedAt,
+	}
+	_, err := revisions_coll.InsertOne(ctx, baseline)
+	if mongo.IsDuplicateKeyError(err) {
+		// Someone else imported it first, point the question at theirs
+		baseline, err = cfg.findQuestionRevision(ctx, question.ID, 1)
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	_, err = questions_coll.UpdateOne(ctx,
+		bson.M{"_id": question.ID, "revision": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revision": baseline.Revision, "revisionId": baseline.ID}},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	return cfg.reloadQuestion(ctx, question)
+}
+
+func (cfg *AppConfig) reloadQuestion(ctx context.Context, question *models.Question) error {
+	reloaded, err := cfg.findQuestion(ctx, question.ID)
+	if err != nil {
+		return err
+	}
+	*question = reloaded
+	return nil
+}
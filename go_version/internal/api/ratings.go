@@ -0,0 +1,703 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_version/internal/glicko"
+	"go_version/internal/irt"
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// Idle time that counts as one rating period without games
+	RATING_PERIOD = 7 * 24 * time.Hour
+
+	// Opponent deviations, calibrated questions are trusted more than labels
+	CALIBRATED_OPPONENT_RD   = 60.0
+	UNCALIBRATED_OPPONENT_RD = 150.0
+
+	// Ratings are shown as provisional until the deviation drops below this
+	PROVISIONAL_RD = 110.0
+
+	RATING_UPDATE_RETRIES = 3
+
+	DEFAULT_RATING_HISTORY_LIMIT = 50
+	MAX_RATING_HISTORY_LIMIT     = 200
+
+	DEFAULT_RATED_STUDENTS_LIMIT = 20
+	MAX_RATED_STUDENTS_LIMIT     = 100
+)
+
+type RatingView struct {
+	Category     string        `json:"category"`
+	Rating       float64       `json:"rating"`
+	RD           float64       `json:"rd"`
+	Volatility   float64       `json:"volatility"`
+	Interval     [2]float64    `json:"interval"`
+	Conservative float64       `json:"conservativeRating"`
+	Games        int32         `json:"games"`
+	Periods      int32         `json:"periods"`
+	Provisional  bool          `json:"provisional"`
+	LastPlayedAt bson.DateTime `json:"lastPlayedAt,omitempty"`
+}
+
+type RatedStudent struct {
+	UserID     bson.ObjectID `json:"userId"`
+	FullName   string        `json:"fullName"`
+	University string        `json:"university"`
+	Major      string        `json:"major"`
+	RatingView
+}
+
+// ratedAnswer is one game: a question answered right or wrong.
+type ratedAnswer struct {
+	QuestionID bson.ObjectID
+	IsCorrect  bool
+}
+
+// GetMyRatings returns the user's rating in every category they played, with
+// its 95% confidence interval.
+func (cfg *AppConfig) GetMyRatings(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	if err := cfg.backfillRatings(ctx, user_id); err != nil {
+		return err
+	}
+	ratings, err := cfg.findUserRatings(ctx, user_id)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Ratings provided successfully",
+		map[string]any{"ratings": ratings},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) GetMyRatingHistory(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	page, limit := parsePagination(r, DEFAULT_RATING_HISTORY_LIMIT, MAX_RATING_HISTORY_LIMIT)
+	filter := bson.M{"userId": user_id}
+	if category := strings.ToLower(r.URL.Query().Get("category")); category != "" {
+		if !slices.Contains(models.GetValidCategories(), category) {
+			return utils.NewBadRequest("Invalid category")
+		}
+		filter["category"] = category
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	if err := cfg.backfillRatings(ctx, user_id); err != nil {
+		return err
+	}
+
+	history_coll := cfg.DATABASE.Collection(models.RATING_HISTORY_COLLECTION)
+	total, err := history_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "occurredAt", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := history_coll.Find(ctx, filter, opts)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	history := []models.RatingChange{}
+	if err := cursor.All(ctx, &history); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	response_payload := map[string]any{
+		"history":    history,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Rating history provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetStudentRatings shows a student's ratings to companies and admins. A
+// student who opted out of search is only shown to the companies they shared
+// contact details with.
+func (cfg *AppConfig) GetStudentRatings(w http.ResponseWriter, r *http.Request) error {
+	_, viewer, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	student_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	var student models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err = users_coll.FindOne(ctx, bson.M{"_id": student_id, "role": models.ROLE_STUDENT, "isActive": true}).Decode(&student)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Student not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	profile_viewer, err := cfg.profileViewer(ctx, viewer, student)
+	if err != nil {
+		return err
+	}
+	if student.Privacy.HiddenFromSearch && viewer.Role == models.ROLE_COMPANY && !profile_viewer.ContactShared {
+		return utils.NewNotFound("Student not found")
+	}
+	visible := student.VisibleTo(profile_viewer)
+
+	if err := cfg.backfillRatings(ctx, student_id); err != nil {
+		return err
+	}
+	ratings, err := cfg.findUserRatings(ctx, student_id)
+	if err != nil {
+		return err
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Ratings provided successfully",
+		map[string]any{"userId": student_id, "fullName": visible.FullName, "ratings": ratings},
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetRatedStudents lets companies filter students by skill: category is
+// required, minRating applies to the conservative rating (the low end of the
+// confidence interval) and maxRd excludes ratings that are still uncertain.
+// Both filter on the ratings as shown, decayed to now. Students who opted out
+// of search aren't listed.
+func (cfg *AppConfig) GetRatedStudents(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	params := r.URL.Query()
+	category := strings.ToLower(params.Get("category"))
+	if !slices.Contains(models.GetValidCategories(), category) {
+		return utils.NewBadRequest("A valid category is required")
+	}
+	page, limit := parsePagination(r, DEFAULT_RATED_STUDENTS_LIMIT, MAX_RATED_STUDENTS_LIMIT)
+
+	match := bson.M{"category": category}
+	if value := params.Get("minRating"); value != "" {
+		min_rating, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return utils.NewBadRequest("Invalid minRating")
+		}
+		match["conservative"] = bson.M{"$gte": min_rating}
+	}
+	if value := params.Get("maxRd"); value != "" {
+		max_rd, err := strconv.ParseFloat(value, 64)
+		if err != nil || max_rd <= 0 {
+			return utils.NewBadRequest("Invalid maxRd")
+		}
+		match["currentRd"] = bson.M{"$lte": max_rd}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	pipeline := append(decayedRatingStages(time.Now()), mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         models.USERS_COLLECTION,
+			"localField":   "userId",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$match", Value: bson.M{
+			"user.role":                     models.ROLE_STUDENT,
+			"user.isActive":                 true,
+			"user.privacy.hiddenFromSearch": bson.M{"$ne": true},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "conservative", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$facet", Value: bson.M{
+			"students": bson.A{
+				bson.M{"$skip": (page - 1) * limit},
+				bson.M{"$limit": limit},
+			},
+			"total": bson.A{bson.M{"$count": "count"}},
+		}}},
+	}...)
+
+	ratings_coll := cfg.DATABASE.Collection(models.USER_RATINGS_COLLECTION)
+	cursor, err := ratings_coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	var results []struct {
+		Students []struct {
+			models.UserRating `bson:",inline"`
+			User              models.User `bson:"user"`
+		} `bson:"students"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	students := []RatedStudent{}
+	var total int64
+	if len(results) > 0 {
+		student_ids := make([]bson.ObjectID, 0, len(results[0].Students))
+		for _, row := range results[0].Students {
+			student_ids = append(student_ids, row.UserID)
+		}
+		consented, err := cfg.findContactConsents(ctx, user_id, student_ids)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range results[0].Students {
+			visible := row.User.VisibleTo(models.ProfileViewer{ID: user_id, Role: user.Role, ContactShared: slices.Contains(consented, row.UserID)})
+			students = append(students, RatedStudent{
+				UserID:     row.UserID,
+				FullName:   visible.FullName,
+				University: visible.University,
+				Major:      visible.Major,
+				RatingView: newRatingView(row.UserRating, time.Now()),
+			})
+		}
+		if len(results[0].Total) > 0 {
+			total = results[0].Total[0].Count
+		}
+	}
+
+	response_payload := map[string]any{
+		"students":   students,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Rated students provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+func (cfg *AppConfig) findUserRatings(ctx context.Context, user_id bson.ObjectID) ([]RatingView, error) {
+	ratings_coll := cfg.DATABASE.Collection(models.USER_RATINGS_COLLECTION)
+	cursor, err := ratings_coll.Find(ctx, bson.M{"userId": user_id}, options.Find().SetSort(bson.D{{Key: "category", Value: 1}}))
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	ratings := []models.UserRating{}
+	if err := cursor.All(ctx, &ratings); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+
+	now := time.Now()
+	views := make([]RatingView, 0, len(ratings))
+	for _, rating := range ratings {
+		views = append(views, newRatingView(rating, now))
+	}
+	return views, nil
+}
+
+// newRatingView shows the rating as of now: the deviation keeps growing while
+// the student doesn't play.
+func newRatingView(rating models.UserRating, now time.Time) RatingView {
+	current := decayRating(rating, now)
+	low, high := current.Interval()
+	return RatingView{
+		Category:     rating.Category,
+		Rating:       math.Round(current.Rating),
+		RD:           math.Round(current.RD),
+		Volatility:   current.Volatility,
+		Interval:     [2]float64{math.Round(low), math.Round(high)},
+		Conservative: math.Round(low),
+		Games:        rating.Games,
+		Periods:      rating.Periods,
+		Provisional:  current.RD > PROVISIONAL_RD,
+		LastPlayedAt: rating.LastPlayedAt,
+	}
+}
+
+func decayRating(rating models.UserRating, now time.Time) glicko.Rating {
+	current := glicko.Rating{Rating: rating.Rating, RD: rating.RD, Volatility: rating.Volatility}
+	if rating.LastPlayedAt == 0 {
+		return current
+	}
+	idle := math.Floor(float64(now.Sub(rating.LastPlayedAt.Time())) / float64(RATING_PERIOD))
+	return glicko.Decay(current, idle)
+}
+
+// decayedRatingStages adds the rounded current deviation (currentRd) and
+// conservative rating as newRatingView shows them, decayRating done in the
+// database so ratings can be filtered and sorted on them.
+func decayedRatingStages(now time.Time) mongo.Pipeline {
+	idle := bson.M{"$floor": bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$lastPlayedAt", now}}}},
+		RATING_PERIOD.Milliseconds(),
+	}}}
+	decayed := bson.M{"$sqrt": bson.M{"$add": bson.A{
+		bson.M{"$multiply": bson.A{"$rd", "$rd"}},
+		bson.M{"$multiply": bson.A{"$idle", "$volatility", "$volatility", glicko.SCALE * glicko.SCALE}},
+	}}}
+	return mongo.Pipeline{
+		{{Key: "$addFields", Value: bson.M{"idle": idle}}},
+		{{Key: "$addFields", Value: bson.M{"decayedRd": bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{"$idle", 0}},
+			bson.M{"$min": bson.A{bson.M{"$max": bson.A{decayed, glicko.MIN_RD}}, glicko.MAX_RD}},
+			"$rd",
+		}}}}},
+		{{Key: "$addFields", Value: bson.M{
+			"currentRd":    bson.M{"$round": bson.A{"$decayedRd", 0}},
+			"conservative": bson.M{"$round": bson.A{bson.M{"$subtract": bson.A{"$rating", bson.M{"$multiply": bson.A{1.96, "$decayedRd"}}}}, 0}},
+		}}},
+	}
+}
+
+// rateExamAttempt rates a graded exam as one rating period, an attempt that
+// was already rated is left alone.
+func (cfg *AppConfig) rateExamAttempt(ctx context.Context, event models.DomainEvent) error {
+	attempt, _, found, err := cfg.findGradedExam(ctx, event)
+	if err != nil || !found {
+		return err
+	}
+	if err := cfg.backfillRatings(ctx, attempt.UserID); err != nil {
+		return err
+	}
+	if err := cfg.rateResults(ctx, attempt.UserID, attempt.Category, models.RATING_SOURCE_EXAM, attempt.ID, examRatedAnswers(attempt), attempt.CompletedAt.Time()); err != nil {
+		return err
+	}
+	return cfg.markStudentMatchesStale(ctx, attempt.UserID)
+}
+
+func (cfg *AppConfig) rateChallenge(ctx context.Context, event models.DomainEvent) error {
+	challenge, found, err := cfg.findCompletedChallenge(ctx, event)
+	if err != nil || !found {
+		return err
+	}
+	if err := cfg.backfillRatings(ctx, challenge.UserID); err != nil {
+		return err
+	}
+	answers := []ratedAnswer{{QuestionID: challenge.QuestionID, IsCorrect: challenge.IsCorrect}}
+	if err := cfg.rateResults(ctx, challenge.UserID, challenge.Category, models.RATING_SOURCE_CHALLENGE, challenge.ID, answers, challenge.CompletedAt.Time()); err != nil {
+		return err
+	}
+	return cfg.markStudentMatchesStale(ctx, challenge.UserID)
+}
+
+func examRatedAnswers(attempt models.TestAttempt) []ratedAnswer {
+	answers := make([]ratedAnswer, 0, len(attempt.Questions))
+	for _, answer := range attempt.Questions {
+		answers = append(answers, ratedAnswer{QuestionID: answer.QuestionID, IsCorrect: answer.IsCorrect})
+	}
+	return answers
+}
+
+// rateResults updates the user's category rating with one rating period of
+// answers, each a game against the question. It is a no-op for a source that
+// was already rated.
+//
+// The history is the source of the rating: the change is recorded first,
+// numbered with the period it brings the rating to, and then applied. A change
+// left unapplied is applied by the next call for the category, a retried
+// source included.
+func (cfg *AppConfig) rateResults(ctx context.Context, user_id bson.ObjectID, category, source string, source_id bson.ObjectID, answers []ratedAnswer, played_at time.Time) error {
+	if category == "" || len(answers) == 0 {
+		return nil
+	}
+
+	history_coll := cfg.DATABASE.Collection(models.RATING_HISTORY_COLLECTION)
+	err := history_coll.FindOne(ctx, bson.M{"userId": user_id, "sourceId": source_id}).Err()
+	if err == nil {
+		_, _, err := cfg.syncUserRating(ctx, user_id, category)
+		return err
+	} else if err != mongo.ErrNoDocuments {
+		return utils.NewInternalServerError(err)
+	}
+
+	question_ids := make([]bson.ObjectID, 0, len(answers))
+	for _, answer := range answers {
+		question_ids = append(question_ids, answer.QuestionID)
+	}
+	opponents, err := cfg.questionOpponents(ctx, question_ids)
+	if err != nil {
+		return err
+	}
+
+	results := make([]glicko.Result, 0, len(answers))
+	var correct int32
+	for _, answer := range answers {
+		result := opponents[answer.QuestionID]
+		if answer.IsCorrect {
+			result.Score = 1
+			correct++
+		}
+		results = append(results, result)
+	}
+
+	for range RATING_UPDATE_RETRIES {
+		current, found, err := cfg.syncUserRating(ctx, user_id, category)
+		if err != nil {
+			return err
+		}
+
+		before := glicko.NewRating()
+		if found {
+			before = decayRating(current, played_at)
+		}
+		after := glicko.Update(before, results)
+
+		change := models.RatingChange{
+			ID:           bson.NewObjectID(),
+			UserID:       user_id,
+			Category:     category,
+			Source:       source,
+			SourceID:     source_id,
+			Period:       current.Periods + 1,
+			RatingBefore: before.Rating,
+			RDBefore:     before.RD,
+			Rating:       after.Rating,
+			RD:           after.RD,
+			Volatility:   after.Volatility,
+			Games:        int32(len(results)),
+			Correct:      correct,
+			OccurredAt:   bson.NewDateTimeFromTime(played_at),
+		}
+		_, err = history_coll.InsertOne(ctx, change)
+		if mongo.IsDuplicateKeyError(err) {
+			// Either the source was rated concurrently or another source took
+			// the period, only the latter is tried again
+			err := history_coll.FindOne(ctx, bson.M{"userId": user_id, "sourceId": source_id}).Err()
+			if err == nil {
+				return nil
+			} else if err != mongo.ErrNoDocuments {
+				return utils.NewInternalServerError(err)
+			}
+			continue
+		} else if err != nil {
+			return utils.NewInternalServerError(err)
+		}
+
+		return cfg.applyRatingChange(ctx, current, found, change)
+	}
+
+	return utils.NewConflict("The rating was updated concurrently, please try again")
+}
+
+// syncUserRating returns the user's category rating with the changes recorded
+// in the history applied, and whether the user has a rating at all.
+func (cfg *AppConfig) syncUserRating(ctx context.Context, user_id bson.ObjectID, category string) (models.UserRating, bool, error) {
+	ratings_coll := cfg.DATABASE.Collection(models.USER_RATINGS_COLLECTION)
+	history_coll := cfg.DATABASE.Collection(models.RATING_HISTORY_COLLECTION)
+
+	for range RATING_UPDATE_RETRIES {
+		var current models.UserRating
+		err := ratings_coll.FindOne(ctx, bson.M{"userId": user_id, "category": category}).Decode(&current)
+		found := err == nil
+		if err != nil && err != mongo.ErrNoDocuments {
+			return current, false, utils.NewInternalServerError(err)
+		}
+
+		var pending models.RatingChange
+		err = history_coll.FindOne(ctx, bson.M{"userId": user_id, "category": category, "period": current.Periods + 1}).Decode(&pending)
+		if err == mongo.ErrNoDocuments {
+			return current, found, nil
+		} else if err != nil {
+			return current, found, utils.NewInternalServerError(err)
+		}
+
+		if err := cfg.applyRatingChange(ctx, current, found, pending); err != nil {
+			return current, found, err
+		}
+	}
+
+	return models.UserRating{}, false, utils.NewConflict("The rating was updated concurrently, please try again")
+}
+
+// applyRatingChange moves the rating to the period of a recorded change. It is
+// guarded on the period count, a rating already moved on is left alone.
+func (cfg *AppConfig) applyRatingChange(ctx context.Context, current models.UserRating, found bool, change models.RatingChange) error {
+	ratings_coll := cfg.DATABASE.Collection(models.USER_RATINGS_COLLECTION)
+	now := bson.NewDateTimeFromTime(time.Now())
+
+	updated := current
+	updated.Rating = change.Rating
+	updated.RD = change.RD
+	updated.Volatility = change.Volatility
+	updated.Periods = change.Period
+	updated.Games = current.Games + change.Games
+	updated.LastPlayedAt = change.OccurredAt
+	updated.UpdatedAt = now
+
+	if !found {
+		updated.ID = bson.NewObjectID()
+		updated.UserID = change.UserID
+		updated.Category = change.Category
+		updated.CreatedAt = now
+		_, err := ratings_coll.InsertOne(ctx, updated)
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return utils.NewInternalServerError(err)
+		}
+		return nil
+	}
+
+	_, err := ratings_coll.ReplaceOne(ctx, bson.M{"_id": current.ID, "periods": current.Periods}, updated)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	return nil
+}
+
+// questionOpponents turns questions into Glicko opponents. Calibrated IRT
+// difficulties map onto the rating scale directly (both are logistic), other
+// questions fall back to their difficulty label with a wider deviation.
+func (cfg *AppConfig) questionOpponents(ctx context.Context, question_ids []bson.ObjectID) (map[bson.ObjectID]glicko.Result, error) {
+	opponents := make(map[bson.ObjectID]glicko.Result, len(question_ids))
+
+	questions_coll := cfg.DATABASE.Collection(models.QUESTIONS_COLLECTION)
+	cursor, err := questions_coll.Find(ctx,
+		bson.M{"_id": bson.M{"$in": question_ids}},
+		options.Find().SetProjection(bson.M{"difficulty": 1}),
+	)
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	questions := []models.Question{}
+	if err := cursor.All(ctx, &questions); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	for _, question := range questions {
+		opponents[question.ID] = glicko.Result{
+			OpponentRating: glicko.DEFAULT_RATING + irt.DefaultItem(question.Difficulty).B*glicko.SCALE,
+			OpponentRD:     UNCALIBRATED_OPPONENT_RD,
+		}
+	}
+
+	item_parameters_coll := cfg.DATABASE.Collection(models.ITEM_PARAMETERS_COLLECTION)
+	cursor, err = item_parameters_coll.Find(ctx, bson.M{"questionId": bson.M{"$in": question_ids}})
+	if err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	params := []models.ItemParameters{}
+	if err := cursor.All(ctx, &params); err != nil {
+		return nil, utils.NewInternalServerError(err)
+	}
+	for _, p := range params {
+		opponents[p.QuestionID] = glicko.Result{
+			OpponentRating: glicko.DEFAULT_RATING + p.B*glicko.SCALE,
+			OpponentRD:     CALIBRATED_OPPONENT_RD,
+		}
+	}
+
+	// Deleted questions still count, as an average one
+	for _, question_id := range question_ids {
+		if _, ok := opponents[question_id]; !ok {
+			opponents[question_id] = glicko.Result{OpponentRating: glicko.DEFAULT_RATING, OpponentRD: UNCALIBRATED_OPPONENT_RD}
+		}
+	}
+
+	return opponents, nil
+}
+
+// backfillRatings replays the exams and challenges a user completed before
+// ratings existed, in the order they happened, the first time it's needed.
+func (cfg *AppConfig) backfillRatings(ctx context.Context, user_id bson.ObjectID) error {
+	history_coll := cfg.DATABASE.Collection(models.RATING_HISTORY_COLLECTION)
+	err := history_coll.FindOne(ctx, bson.M{"userId": user_id}).Err()
+	if err == nil {
+		return nil
+	} else if err != mongo.ErrNoDocuments {
+		return utils.NewInternalServerError(err)
+	}
+
+	type period struct {
+		category  string
+		source    string
+		source_id bson.ObjectID
+		answers   []ratedAnswer
+		played_at time.Time
+	}
+	periods := []period{}
+
+	attempts_coll := cfg.DATABASE.Collection(models.TEST_ATTEMPTS_COLLECTION)
+	cursor, err := attempts_coll.Find(ctx, bson.M{"userId": user_id, "isPracticeMode": bson.M{"$ne": true}})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	attempts := []models.TestAttempt{}
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	for _, attempt := range attempts {
+		periods = append(periods, period{attempt.Category, models.RATING_SOURCE_EXAM, attempt.ID, examRatedAnswers(attempt), attempt.CompletedAt.Time()})
+	}
+
+	challenges_coll := cfg.DATABASE.Collection(models.DAILY_CHALLENGES_COLLECTION)
+	cursor, err = challenges_coll.Find(ctx, bson.M{"userId": user_id, "completed": true})
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	challenges := []models.DailyChallenge{}
+	if err := cursor.All(ctx, &challenges); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	for _, challenge := range challenges {
+		answers := []ratedAnswer{{QuestionID: challenge.QuestionID, IsCorrect: challenge.IsCorrect}}
+		periods = append(periods, period{challenge.Category, models.RATING_SOURCE_CHALLENGE, challenge.ID, answers, challenge.CompletedAt.Time()})
+	}
+
+	slices.SortStableFunc(periods, func(a, b period) int {
+		return a.played_at.Compare(b.played_at)
+	})
+	for _, p := range periods {
+		if err := cfg.rateResults(ctx, user_id, p.category, p.source, p.source_id, p.answers, p.played_at); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"go_version/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type Pagination struct {
+	Page  int64 `json:"page"`
+	Limit int64 `json:"limit"`
+	Total int64 `json:"total"`
+	Pages int64 `json:"pages"`
+}
+
+// parsePagination reads the page & limit query params the same way the Node
+// controllers do: page defaults to 1, limit is clamped between 1 and max_limit.
+func parsePagination(r *http.Request, default_limit, max_limit int64) (page, limit int64) {
+	page, err := strconv.ParseInt(r.URL.Query().Get("page"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err = strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+	if err != nil || limit < 1 {
+		limit = default_limit
+	}
+	if limit > max_limit {
+		limit = max_limit
+	}
+
+	return page, limit
+}
+
+func newPagination(page, limit, total int64) Pagination {
+	return Pagination{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+		Pages: (total + limit - 1) / limit,
+	}
+}
+
+// parseObjectIDParam reads a hex ObjectID from the chi url params.
+func parseObjectIDParam(r *http.Request, name string) (bson.ObjectID, error) {
+	id, err := bson.ObjectIDFromHex(chi.URLParam(r, name))
+	if err != nil {
+		return bson.ObjectID{}, utils.NewBadRequest("Invalid " + name)
+	}
+	return id, nil
+}
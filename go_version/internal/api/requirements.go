@@ -1,17 +1,21 @@
 package api
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"fmt"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 type AppRequirements struct {
-	Server     ServerRequirements
-	Database   DatabaseRequirements
-	Cloudinary CloudinaryRequirements
-	SMTP       SMTPRequirements
-	JWT        JWTRequirements
+	Server      ServerRequirements
+	Database    DatabaseRequirements
+	Cloudinary  CloudinaryRequirements
+	SMTP        SMTPRequirements
+	JWT         JWTRequirements
+	Certificate CertificateRequirements
 }
 
 type ServerRequirements struct {
@@ -45,6 +49,15 @@ type JWTRequirements struct {
 	JWTRefreshExpiresIn string
 }
 
+type CertificateRequirements struct {
+	SigningSeed []byte
+
+	// Keys of earlier rotations, certificates they signed stay verifiable
+	RetiredPublicKeys []ed25519.PublicKey
+
+	PassingScore int32
+}
+
 func LoadRequirements() (*AppRequirements, error) {
 	viper.SetConfigName(".env")
 	viper.SetConfigType("env")
@@ -99,6 +112,31 @@ func LoadRequirements() (*AppRequirements, error) {
 		jwt_refresh_expires_in = "30d"
 	}
 
+	// A key of its own, never derived from another secret. Certificates signed
+	// with an earlier key stay verifiable once its public key is retired below
+	signing_seed, err := base64.StdEncoding.DecodeString(viper.GetString("CERTIFICATE_SIGNING_KEY"))
+	if err != nil || len(signing_seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("set your 'CERTIFICATE_SIGNING_KEY' environment variable to 32 base64 encoded bytes")
+	}
+	retired_public_keys := []ed25519.PublicKey{}
+	for _, retired_key := range strings.Split(viper.GetString("CERTIFICATE_RETIRED_PUBLIC_KEYS"), ",") {
+		if retired_key = strings.TrimSpace(retired_key); retired_key == "" {
+			continue
+		}
+		public_key, err := base64.StdEncoding.DecodeString(retired_key)
+		if err != nil || len(public_key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("set your 'CERTIFICATE_RETIRED_PUBLIC_KEYS' environment variable to comma separated, base64 encoded 32 byte public keys")
+		}
+		retired_public_keys = append(retired_public_keys, public_key)
+	}
+	passing_score := viper.GetInt32("CERTIFICATE_PASSING_SCORE")
+	if passing_score == 0 {
+		passing_score = 70
+	}
+	if passing_score < 1 || passing_score > 100 {
+		return nil, fmt.Errorf("set your 'CERTIFICATE_PASSING_SCORE' environment variable between 1 and 100")
+	}
+
 	requirements := &AppRequirements{
 		Server: ServerRequirements{
 			Port:        server_port,
@@ -126,6 +164,11 @@ func LoadRequirements() (*AppRequirements, error) {
 			JWTExpiresIn:        jwt_expires_in,
 			JWTRefreshExpiresIn: jwt_refresh_expires_in,
 		},
+		Certificate: CertificateRequirements{
+			SigningSeed:       signing_seed,
+			RetiredPublicKeys: retired_public_keys,
+			PassingScore:      passing_score,
+		},
 	}
 
 	return requirements, nil
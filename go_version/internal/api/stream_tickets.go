@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// How long a stream ticket can be redeemed for
+const STREAM_TICKET_TTL = 30 * time.Second
+
+// CreateStreamTicket trades the access token for a ticket opening the exam
+// proctoring WebSocket or the notification stream. Browsers can't set headers
+// on a WebSocket or an EventSource, and a token in the URL ends up in proxy
+// and access logs, so the ticket passed instead works once and only for a few
+// seconds.
+func (cfg *AppConfig) CreateStreamTicket(w http.ResponseWriter, r *http.Request) error {
+	user_id, _, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	raw_ticket, ticket_hash, err := utils.GenerateStreamTicket()
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	now := time.Now()
+	ticket := models.StreamTicket{
+		TicketHash: ticket_hash,
+		UserID:     user_id,
+		ExpiresAt:  bson.NewDateTimeFromTime(now.Add(STREAM_TICKET_TTL)),
+		CreatedAt:  bson.NewDateTimeFromTime(now),
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	stream_tickets_coll := cfg.DATABASE.Collection(models.STREAM_TICKETS_COLLECTION)
+	if _, err := stream_tickets_coll.InsertOne(ctx, ticket); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Stream ticket created successfully",
+		map[string]any{"ticket": raw_ticket, "expiresAt": ticket.ExpiresAt},
+		http.StatusCreated,
+	)
+
+	return nil
+}
@@ -0,0 +1,497 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_TALENT_LIMIT = 20
+	MAX_TALENT_LIMIT     = 50
+
+	// Values listed per facet
+	TALENT_FACET_SIZE = 20
+
+	DEFAULT_COMPANIES_LIMIT = 20
+	MAX_COMPANIES_LIMIT     = 100
+)
+
+type CompanyVerificationRequestBody struct {
+	Verified *bool `json:"verified" validate:"required"`
+}
+
+// Fields the text search looks in. The text index covers copies of them in
+// searchText, which leaves out the ones the student keeps private
+var talentSearchFields = []string{models.PRIVACY_FIELD_INTERESTS, models.PRIVACY_FIELD_BIO, models.PRIVACY_FIELD_RESUME_TEXT}
+
+// Lower bounds of the conservative rating buckets of the skill facet, the
+// last one is open ended
+var talentRatingBoundaries = []int32{0, 1200, 1400, 1600, 1800, 2000}
+
+type TalentContact struct {
+	Email       string `json:"email"`
+	Phone       string `json:"phone,omitempty"`
+	LinkedInURL string `json:"linkedInUrl,omitempty"`
+}
+
+type TalentSkill struct {
+	Category     string  `json:"category"`
+	Rating       float64 `json:"rating"`
+	Conservative float64 `json:"conservative"`
+}
+
+// TalentResult is a student as companies find them. Contact details are only
+// filled in once the student shared them with the company.
+type TalentResult struct {
+	ID             bson.ObjectID  `json:"_id"`
+	FullName       string         `json:"fullName"`
+	ProfileImage   string         `json:"profileImage,omitempty"`
+	University     string         `json:"university,omitempty"`
+	Major          string         `json:"major,omitempty"`
+	GraduationYear string         `json:"graduationYear,omitempty"`
+	City           string         `json:"city,omitempty"`
+	Interests      []string       `json:"interests"`
+	Bio            string         `json:"bio,omitempty"`
+	Skill          *TalentSkill   `json:"skill,omitempty"`
+	Relevance      float64        `json:"relevance,omitempty"`
+	Contact        *TalentContact `json:"contact"`
+}
+
+type FacetCount struct {
+	Value string `bson:"_id" json:"value"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+type RatingFacetCount struct {
+	Min   int32 `bson:"_id" json:"min"`
+	Count int64 `bson:"count" json:"count"`
+}
+
+// SearchTalent lets companies an admin verified find students by text and
+// profile facets. Students who opted out of search are never listed, and
+// fields they keep private are neither shown, matched, filtered on nor counted
+// in facets.
+func (cfg *AppConfig) SearchTalent(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+	if !user.IsCompanyVerified {
+		return utils.NewForbidden("Your company has to be verified by an admin before searching for talent")
+	}
+
+	params := r.URL.Query()
+	page, limit := parsePagination(r, DEFAULT_TALENT_LIMIT, MAX_TALENT_LIMIT)
+
+	match := bson.M{
+		"role":                     models.ROLE_STUDENT,
+		"isActive":                 true,
+		"privacy.hiddenFromSearch": bson.M{"$ne": true},
+	}
+	q := strings.TrimSpace(params.Get("q"))
+	if q != "" {
+		match["$text"] = bson.M{"$search": q}
+	}
+	for param, field := range map[string]string{"major": "major", "university": "university", "graduationYear": "graduationYear", "city": "city", "interest": "interests"} {
+		if values := params[param]; len(values) != 0 {
+			match[field] = bson.M{"$in": values}
+			match["privacy.fields."+field] = bson.M{"$ne": models.VISIBILITY_PRIVATE}
+		}
+	}
+
+	category := strings.ToLower(params.Get("category"))
+	if category != "" && !slices.Contains(models.GetValidCategories(), category) {
+		return utils.NewBadRequest("Invalid category")
+	}
+	var min_rating *float64
+	if value := params.Get("minRating"); value != "" {
+		if category == "" {
+			return utils.NewBadRequest("Filtering by rating needs a category")
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return utils.NewBadRequest("Invalid minRating")
+		}
+		min_rating = &parsed
+	}
+
+	sort_by := params.Get("sort")
+	if sort_by == "" {
+		sort_by = "newest"
+		if q != "" {
+			sort_by = "relevance"
+		} else if category != "" {
+			sort_by = "rating"
+		}
+	}
+	var sort bson.D
+	switch sort_by {
+	case "newest":
+		sort = bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}
+	case "graduation":
+		sort = bson.D{{Key: "graduationYear", Value: 1}, {Key: "_id", Value: 1}}
+	case "relevance":
+		if q == "" {
+			return utils.NewBadRequest("Sorting by relevance needs a search query")
+		}
+		sort = bson.D{{Key: "relevance", Value: -1}, {Key: "_id", Value: 1}}
+	case "rating":
+		if category == "" {
+			return utils.NewBadRequest("Sorting by rating needs a category")
+		}
+		sort = bson.D{{Key: "skill.conservative", Value: -1}, {Key: "_id", Value: 1}}
+	default:
+		return utils.NewBadRequest("Invalid sort, expected newest, graduation, relevance or rating")
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+	}
+	if q != "" {
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"relevance": bson.M{"$meta": "textScore"}}}})
+	}
+	facets := bson.M{
+		"majors":          visibleFacet(models.PRIVACY_FIELD_MAJOR),
+		"universities":    visibleFacet(models.PRIVACY_FIELD_UNIVERSITY),
+		"graduationYears": visibleFacet(models.PRIVACY_FIELD_GRADUATION_YEAR),
+		"cities":          visibleFacet(models.PRIVACY_FIELD_CITY),
+	}
+	if category != "" {
+		// Rated on the deviation decayed to now, as the ratings endpoints show it
+		skill_pipeline := bson.A{bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$userId", "$$userId"}}, "category": category}}}
+		for _, stage := range decayedRatingStages(time.Now()) {
+			skill_pipeline = append(skill_pipeline, stage)
+		}
+		skill_pipeline = append(skill_pipeline, bson.M{"$project": bson.M{"_id": 0, "category": 1, "rating": 1, "conservative": 1}})
+		pipeline = append(pipeline,
+			bson.D{{Key: "$lookup", Value: bson.M{
+				"from":     models.USER_RATINGS_COLLECTION,
+				"let":      bson.M{"userId": "$_id"},
+				"pipeline": skill_pipeline,
+				"as":       "skill",
+			}}},
+			bson.D{{Key: "$unwind", Value: bson.M{"path": "$skill", "preserveNullAndEmptyArrays": min_rating == nil}}},
+		)
+		if min_rating != nil {
+			pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"skill.conservative": bson.M{"$gte": *min_rating}}}})
+		}
+		facets["ratings"] = bson.A{
+			bson.M{"$match": bson.M{"skill": bson.M{"$exists": true}}},
+			bson.M{"$bucket": bson.M{
+				"groupBy":    "$skill.conservative",
+				"boundaries": talentRatingBoundaries,
+				"default":    talentRatingBoundaries[len(talentRatingBoundaries)-1],
+			}},
+		}
+	}
+	facets["students"] = bson.A{
+		bson.M{"$sort": sort},
+		bson.M{"$skip": (page - 1) * limit},
+		bson.M{"$limit": limit},
+		bson.M{"$project": bson.M{"password": 0, "refreshToken": 0, "emailVerificationToken": 0, "pendingEvents": 0, "resumeText": 0, "searchText": 0}},
+	}
+	facets["total"] = bson.A{bson.M{"$count": "count"}}
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: facets}})
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	cursor, err := users_coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	var results []struct {
+		Students []struct {
+			models.User `bson:",inline"`
+			Relevance   float64 `bson:"relevance"`
+			Skill       *struct {
+				Category     string  `bson:"category"`
+				Rating       float64 `bson:"rating"`
+				Conservative float64 `bson:"conservative"`
+			} `bson:"skill"`
+		} `bson:"students"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Majors          []FacetCount       `bson:"majors"`
+		Universities    []FacetCount       `bson:"universities"`
+		GraduationYears []FacetCount       `bson:"graduationYears"`
+		Cities          []FacetCount       `bson:"cities"`
+		Ratings         []RatingFacetCount `bson:"ratings"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	students := []TalentResult{}
+	var total int64
+	facet_counts := map[string]any{}
+	if len(results) > 0 {
+		result := results[0]
+		student_ids := make([]bson.ObjectID, 0, len(result.Students))
+		for _, row := range result.Students {
+			student_ids = append(student_ids, row.ID)
+		}
+		consented, err := cfg.findContactConsents(ctx, user_id, student_ids)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range result.Students {
+			contact_shared := slices.Contains(consented, row.ID)
+			visible := row.VisibleTo(models.ProfileViewer{ID: user_id, Role: user.Role, ContactShared: contact_shared})
+			student := TalentResult{
+				ID:             visible.ID,
+				FullName:       visible.FullName,
+				ProfileImage:   visible.ProfileImage,
+				University:     visible.University,
+				Major:          visible.Major,
+				GraduationYear: visible.GraduationYear,
+				City:           visible.City,
+				Interests:      visible.Interests,
+				Bio:            visible.Bio,
+				Relevance:      math.Round(row.Relevance*100) / 100,
+			}
+			if student.Interests == nil {
+				student.Interests = []string{}
+			}
+			if row.Skill != nil {
+				student.Skill = &TalentSkill{
+					Category:     row.Skill.Category,
+					Rating:       math.Round(row.Skill.Rating),
+					Conservative: math.Round(row.Skill.Conservative),
+				}
+			}
+			if contact_shared {
+				student.Contact = &TalentContact{Email: visible.Email, Phone: visible.Phone, LinkedInURL: visible.LinkedInURL}
+			}
+			students = append(students, student)
+		}
+		if len(result.Total) > 0 {
+			total = result.Total[0].Count
+		}
+
+		facet_counts["majors"] = nonEmptyFacets(result.Majors)
+		facet_counts["universities"] = nonEmptyFacets(result.Universities)
+		facet_counts["graduationYears"] = nonEmptyFacets(result.GraduationYears)
+		facet_counts["cities"] = nonEmptyFacets(result.Cities)
+		if category != "" {
+			ratings := result.Ratings
+			if ratings == nil {
+				ratings = []RatingFacetCount{}
+			}
+			facet_counts["ratings"] = ratings
+		}
+	}
+
+	response_payload := map[string]any{
+		"students":   students,
+		"facets":     facet_counts,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Talent provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// GetCompanies lists company accounts for admins reviewing them, optionally
+// only the verified (verified=true) or unverified (verified=false) ones.
+func (cfg *AppConfig) GetCompanies(w http.ResponseWriter, r *http.Request) error {
+	_, admin, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	page, limit := parsePagination(r, DEFAULT_COMPANIES_LIMIT, MAX_COMPANIES_LIMIT)
+	filter := bson.M{"role": models.ROLE_COMPANY}
+	switch r.URL.Query().Get("verified") {
+	case "":
+	case "true":
+		filter["isCompanyVerified"] = true
+	case "false":
+		filter["isCompanyVerified"] = bson.M{"$ne": true}
+	default:
+		return utils.NewBadRequest("Invalid verified, expected true or false")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	total, err := users_coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := users_coll.Find(ctx, filter, opts)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	companies := []models.User{}
+	if err := cursor.All(ctx, &companies); err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	profiles := make([]any, 0, len(companies))
+	for _, company := range companies {
+		profiles = append(profiles, company.GetPublicProfile(admin.AsViewer()))
+	}
+
+	response_payload := map[string]any{
+		"companies":  profiles,
+		"pagination": newPagination(page, limit, total),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Companies provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// SetCompanyVerification verifies a company account, or takes the
+// verification back, which closes talent search to it right away.
+func (cfg *AppConfig) SetCompanyVerification(w http.ResponseWriter, r *http.Request) error {
+	admin_id, admin, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	company_id, err := parseObjectIDParam(r, "id")
+	if err != nil {
+		return err
+	}
+
+	req_body := CompanyVerificationRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing verification request body", http.StatusBadRequest, err)
+	}
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	if err := validator.Struct(req_body); err != nil {
+		field_errors := extractValidationErrors(err)
+		return utils.NewValidationError(field_errors)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	now := bson.NewDateTimeFromTime(time.Now())
+	update := bson.M{"$set": bson.M{
+		"isCompanyVerified": true,
+		"companyVerifiedAt": now,
+		"companyVerifiedBy": admin_id,
+		"updatedAt":         now,
+	}}
+	if !*req_body.Verified {
+		update = bson.M{
+			"$set":   bson.M{"isCompanyVerified": false, "updatedAt": now},
+			"$unset": bson.M{"companyVerifiedAt": "", "companyVerifiedBy": ""},
+		}
+	}
+
+	var company models.User
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	err = users_coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": company_id, "role": models.ROLE_COMPANY},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&company)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewNotFound("Company not found")
+	} else if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+
+	response_payload := map[string]any{
+		"company": company.GetPublicProfile(admin.AsViewer()),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Company verification updated successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// refreshSearchText recomputes what the text search matches a student on,
+// after their profile or privacy settings changed. It's computed from the
+// stored document, so concurrent changes can't leave a stale copy behind.
+func (cfg *AppConfig) refreshSearchText(ctx context.Context, user_id bson.ObjectID) error {
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	_, err := users_coll.UpdateOne(ctx,
+		bson.M{"_id": user_id, "role": models.ROLE_STUDENT},
+		mongo.Pipeline{searchTextStage()},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	return nil
+}
+
+// searchTextStage copies the searched fields into searchText, except the ones
+// the student keeps private.
+func searchTextStage() bson.D {
+	defaults := models.PrivacySettings{}
+	search_text := bson.M{}
+	for _, field := range talentSearchFields {
+		search_text[field] = bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$privacy.fields." + field, defaults.Visibility(field)}}, models.VISIBILITY_PRIVATE}},
+			"$$REMOVE",
+			"$" + field,
+		}}
+	}
+	return bson.D{{Key: "$set", Value: bson.M{"searchText": search_text}}}
+}
+
+// visibleFacet counts the values of a profile field, students who keep the
+// field private are counted as empty.
+func visibleFacet(field string) bson.A {
+	value := bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{"$privacy.fields." + field, models.VISIBILITY_PRIVATE}},
+		nil,
+		"$" + field,
+	}}
+	return bson.A{bson.M{"$sortByCount": value}, bson.M{"$limit": TALENT_FACET_SIZE}}
+}
+
+// nonEmptyFacets drops the students who left the field empty.
+func nonEmptyFacets(counts []FacetCount) []FacetCount {
+	facets := []FacetCount{}
+	for _, count := range counts {
+		if count.Value != "" {
+			facets = append(facets, count)
+		}
+	}
+	return facets
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go_version/internal/models"
+	"go_version/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Days are counted in the user's timezone, most of our users are in Palestine
+const DEFAULT_TIMEZONE = "Asia/Hebron"
+
+// Moving the timezone moves the day streaks and reviews are counted on, so it
+// can only change once per cooldown
+const TIMEZONE_CHANGE_COOLDOWN = 24 * time.Hour
+
+type TimezoneRequestBody struct {
+	Timezone string `json:"timezone"`
+}
+
+type TimezoneView struct {
+	Timezone     string         `json:"timezone"`
+	ChangeableAt *bson.DateTime `json:"changeableAt"`
+}
+
+// userLocation is the timezone the user's days are counted in: the one set on
+// their profile, the default until they set one.
+func userLocation(user models.User) *time.Location {
+	if user.Timezone != "" {
+		if location, err := time.LoadLocation(user.Timezone); err == nil {
+			return location
+		}
+	}
+	location, _ := time.LoadLocation(DEFAULT_TIMEZONE)
+	return location
+}
+
+func newTimezoneView(user models.User) TimezoneView {
+	view := TimezoneView{Timezone: userLocation(user).String()}
+	if user.TimezoneChangedAt != 0 {
+		changeable_at := bson.NewDateTimeFromTime(user.TimezoneChangedAt.Time().Add(TIMEZONE_CHANGE_COOLDOWN))
+		view.ChangeableAt = &changeable_at
+	}
+	return view
+}
+
+func (cfg *AppConfig) GetTimezone(w http.ResponseWriter, r *http.Request) error {
+	_, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	response_payload := map[string]any{
+		"timezone": newTimezoneView(user),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Timezone provided successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
+
+// UpdateTimezone sets the timezone the user's days are counted in. Setting it
+// the first time is free, changing it is limited to once per cooldown.
+func (cfg *AppConfig) UpdateTimezone(w http.ResponseWriter, r *http.Request) error {
+	user_id, user, err := getUserFromContext(r.Context())
+	if err != nil {
+		return utils.NewAppError(err.Error(), http.StatusUnauthorized, nil)
+	}
+
+	req_body := TimezoneRequestBody{}
+	if err := utils.BodyParser(r.Body, &req_body); err != nil {
+		return utils.NewAppError("Error while parsing timezone request body", http.StatusBadRequest, err)
+	}
+
+	// LoadLocation also accepts "" and "Local", neither is a user's timezone
+	timezone := strings.TrimSpace(req_body.Timezone)
+	location, err := time.LoadLocation(timezone)
+	if timezone == "" || timezone == "Local" || err != nil {
+		return utils.NewBadRequest("Invalid timezone, expected an IANA time zone such as Asia/Hebron")
+	}
+	if location.String() == user.Timezone {
+		return utils.NewBadRequest("This is already your timezone")
+	}
+
+	now := time.Now()
+	if user.TimezoneChangedAt != 0 {
+		changeable_at := user.TimezoneChangedAt.Time().Add(TIMEZONE_CHANGE_COOLDOWN)
+		if now.Before(changeable_at) {
+			return utils.NewAppError("You can change your timezone again after "+changeable_at.UTC().Format(time.RFC3339), http.StatusTooManyRequests, nil)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	// Only applied if nobody changed it in the meantime, so the cooldown holds
+	// against concurrent requests
+	current := bson.M{"_id": user_id, "timezoneChangedAt": user.TimezoneChangedAt}
+	if user.TimezoneChangedAt == 0 {
+		current["timezoneChangedAt"] = bson.M{"$exists": false}
+	}
+	users_coll := cfg.DATABASE.Collection(models.USERS_COLLECTION)
+	result, err := users_coll.UpdateOne(ctx,
+		current,
+		bson.M{"$set": bson.M{
+			"timezone":          location.String(),
+			"timezoneChangedAt": bson.NewDateTimeFromTime(now),
+			"updatedAt":         bson.NewDateTimeFromTime(now),
+		}},
+	)
+	if err != nil {
+		return utils.NewInternalServerError(err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewConflict("Your timezone was changed in the meantime, try again")
+	}
+	user.Timezone = location.String()
+	user.TimezoneChangedAt = bson.NewDateTimeFromTime(now)
+
+	response_payload := map[string]any{
+		"timezone": newTimezoneView(user),
+	}
+
+	utils.SuccessResponseWriter(
+		w,
+		"Timezone updated successfully",
+		response_payload,
+		http.StatusOK,
+	)
+
+	return nil
+}
@@ -0,0 +1,128 @@
+// Package assembly builds exam papers from blueprint sections. Everything here
+// is deterministic: the same seed and the same candidate pools always produce
+// the same paper, which is what lets any attempt's paper be reproduced later.
+package assembly
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"go_version/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// MAX_SEED keeps seeds within the integer range JSON clients can represent exactly.
+const MAX_SEED = 1 << 53
+
+var ErrNotEnoughQuestions = errors.New("not enough questions")
+
+type SectionPool struct {
+	Section    models.BlueprintSection
+	Candidates []models.QuestionRevision
+}
+
+type Pick struct {
+	Revision    models.QuestionRevision
+	Section     int
+	Repeated    bool
+	OptionOrder []int32
+}
+
+func NewSeed() int64 {
+	return rand.Int64N(MAX_SEED)
+}
+
+// Assemble picks Count questions for every section. Questions in seen are only
+// used once a section runs out of unseen ones, and a question is never picked
+// twice even when sections overlap.
+func Assemble(seed int64, pools []SectionPool, seen map[bson.ObjectID]bool, shuffle_options bool) ([]Pick, error) {
+	picked := map[bson.ObjectID]bool{}
+	picks := []Pick{}
+
+	for section_index, pool := range pools {
+		candidates := slices.Clone(pool.Candidates)
+		// Sort first, the order the database returned them in isn't stable
+		slices.SortFunc(candidates, func(a, b models.QuestionRevision) int {
+			return strings.Compare(a.QuestionID.Hex(), b.QuestionID.Hex())
+		})
+		rng := rand.New(rand.NewPCG(uint64(seed), uint64(section_index)))
+		rng.Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+
+		unseen := []models.QuestionRevision{}
+		repeats := []models.QuestionRevision{}
+		for _, candidate := range candidates {
+			if picked[candidate.QuestionID] {
+				continue
+			}
+			if seen[candidate.QuestionID] {
+				repeats = append(repeats, candidate)
+			} else {
+				unseen = append(unseen, candidate)
+			}
+		}
+
+		ordered := append(unseen, repeats...)
+		if len(ordered) < pool.Section.Count {
+			return nil, fmt.Errorf("%w for section %d (%s): need %d, found %d", ErrNotEnoughQuestions, section_index+1, pool.Section.Describe(), pool.Section.Count, len(ordered))
+		}
+
+		for i, candidate := range ordered[:pool.Section.Count] {
+			picked[candidate.QuestionID] = true
+			pick := Pick{
+				Revision: candidate,
+				Section:  section_index,
+				Repeated: i >= len(unseen),
+			}
+			if shuffle_options {
+				pick.OptionOrder = OptionOrder(seed, candidate.QuestionID, len(candidate.Content.Options))
+			}
+			picks = append(picks, pick)
+		}
+	}
+
+	// Interleave sections so a paper doesn't read section by section
+	rng := rand.New(rand.NewPCG(uint64(seed), uint64(len(pools))))
+	rng.Shuffle(len(picks), func(i, j int) {
+		picks[i], picks[j] = picks[j], picks[i]
+	})
+
+	return picks, nil
+}
+
+// OptionOrder returns the permutation used to display a question's options in
+// a given paper, derived from the paper seed and the question id only.
+func OptionOrder(seed int64, question_id bson.ObjectID, count int) []int32 {
+	id := question_id
+	var id_seed uint64
+	for _, b := range id[4:] {
+		id_seed = id_seed<<8 | uint64(b)
+	}
+
+	order := make([]int32, count)
+	for i := range order {
+		order[i] = int32(i)
+	}
+	rng := rand.New(rand.NewPCG(uint64(seed), id_seed))
+	rng.Shuffle(count, func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+	return order
+}
+
+// ApplyOptionOrder returns options in display order, an empty order keeps them as authored.
+func ApplyOptionOrder(options []string, order []int32) []string {
+	if len(order) != len(options) {
+		return options
+	}
+	shuffled := make([]string, len(options))
+	for i, index := range order {
+		shuffled[i] = options[index]
+	}
+	return shuffled
+}
@@ -0,0 +1,132 @@
+// Package calendar renders iCalendar (RFC 5545) invites. Calendar clients
+// match updates to the event they already hold by UID, and only apply them
+// when the sequence is higher than the one they have.
+package calendar
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	METHOD_REQUEST = "REQUEST"
+	METHOD_CANCEL  = "CANCEL"
+)
+
+const PRODUCT_ID = "-//TalentsPal//Interviews//EN"
+
+// Lines longer than this many octets are folded
+const MAX_LINE_LENGTH = 75
+
+type Attendee struct {
+	Name  string
+	Email string
+}
+
+type Event struct {
+	UID      string
+	Sequence int32
+
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+
+	StartsAt time.Time
+	EndsAt   time.Time
+
+	Organizer Attendee
+	Attendees []Attendee
+
+	Cancelled bool
+}
+
+// Method is the iTIP method the event is sent with.
+func (e *Event) Method() string {
+	if e.Cancelled {
+		return METHOD_CANCEL
+	}
+	return METHOD_REQUEST
+}
+
+// Render returns the event as an .ics file.
+func Render(event Event, now time.Time) []byte {
+	status := "CONFIRMED"
+	if event.Cancelled {
+		status = "CANCELLED"
+	}
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + PRODUCT_ID,
+		"CALSCALE:GREGORIAN",
+		"METHOD:" + event.Method(),
+		"BEGIN:VEVENT",
+		"UID:" + escapeText(event.UID),
+		"SEQUENCE:" + strconv.Itoa(int(event.Sequence)),
+		"DTSTAMP:" + formatTime(now),
+		"DTSTART:" + formatTime(event.StartsAt),
+		"DTEND:" + formatTime(event.EndsAt),
+		"SUMMARY:" + escapeText(event.Summary),
+		"STATUS:" + status,
+	}
+	if event.Description != "" {
+		lines = append(lines, "DESCRIPTION:"+escapeText(event.Description))
+	}
+	if event.Location != "" {
+		lines = append(lines, "LOCATION:"+escapeText(event.Location))
+	}
+	if event.URL != "" {
+		lines = append(lines, "URL:"+event.URL)
+	}
+	lines = append(lines, "ORGANIZER;CN="+quoteParam(event.Organizer.Name)+":mailto:"+event.Organizer.Email)
+	for _, attendee := range event.Attendees {
+		lines = append(lines, "ATTENDEE;CN="+quoteParam(attendee.Name)+";ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION:mailto:"+attendee.Email)
+	}
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+
+	var builder strings.Builder
+	for _, line := range lines {
+		builder.WriteString(fold(line))
+		builder.WriteString("\r\n")
+	}
+	return []byte(builder.String())
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeText escapes a TEXT value, section 3.3.11.
+func escapeText(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return replacer.Replace(value)
+}
+
+// quoteParam quotes a parameter value, which can't contain double quotes.
+func quoteParam(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, "'") + `"`
+}
+
+// fold splits a content line into lines of at most MAX_LINE_LENGTH octets,
+// continuation lines start with a space. Multi-byte characters aren't split.
+func fold(line string) string {
+	if len(line) <= MAX_LINE_LENGTH {
+		return line
+	}
+
+	var builder strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > MAX_LINE_LENGTH {
+			builder.WriteString("\r\n ")
+			length = 1
+		}
+		builder.WriteRune(r)
+		length += size
+	}
+	return builder.String()
+}
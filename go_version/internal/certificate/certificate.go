@@ -0,0 +1,128 @@
+// Package certificate signs skill certificates and renders them as PDF.
+// Certificates are signed with Ed25519 so anyone holding the public key can
+// check that the certified data was not altered.
+package certificate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const ALGORITHM = "Ed25519"
+
+// Payload is the certified data, the exact bytes that get signed.
+type Payload struct {
+	Code      string `json:"code"`
+	UserID    string `json:"userId"`
+	FullName  string `json:"fullName"`
+	Category  string `json:"category"`
+	Score     int32  `json:"score"`
+	AttemptID string `json:"attemptId"`
+	IssuedAt  string `json:"issuedAt"`
+}
+
+func NewPayload(code, user_id, full_name, category string, score int32, attempt_id string, issued_at time.Time) Payload {
+	return Payload{
+		Code:      code,
+		UserID:    user_id,
+		FullName:  full_name,
+		Category:  category,
+		Score:     score,
+		AttemptID: attempt_id,
+		IssuedAt:  issued_at.UTC().Format(time.RFC3339),
+	}
+}
+
+// Canonical is the signed form of the payload, JSON with fields in
+// declaration order.
+func (p Payload) Canonical() []byte {
+	canonical, _ := json.Marshal(p)
+	return canonical
+}
+
+type Signer struct {
+	privateKey ed25519.PrivateKey
+	keyID      string
+}
+
+// NewSigner derives the signing key from a 32 byte seed.
+func NewSigner(seed []byte) (*Signer, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, errors.New("certificate signing seed must be 32 bytes")
+	}
+	private_key := ed25519.NewKeyFromSeed(seed)
+	return &Signer{privateKey: private_key, keyID: KeyID(private_key.Public().(ed25519.PublicKey))}, nil
+}
+
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.privateKey.Public().(ed25519.PublicKey)
+}
+
+// Sign returns the base64url signature of the payload.
+func (s *Signer) Sign(p Payload) string {
+	return base64.RawURLEncoding.EncodeToString(ed25519.Sign(s.privateKey, p.Canonical()))
+}
+
+func Verify(public_key ed25519.PublicKey, p Payload, signature string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(public_key, p.Canonical(), raw)
+}
+
+// KeyRing holds the public keys certificates can be signed with by key ID:
+// the current signing key and the ones retired by rotations.
+type KeyRing map[string]ed25519.PublicKey
+
+func NewKeyRing(public_keys ...ed25519.PublicKey) KeyRing {
+	ring := KeyRing{}
+	for _, public_key := range public_keys {
+		ring[KeyID(public_key)] = public_key
+	}
+	return ring
+}
+
+// Verify checks the signature with the key the certificate names, a key
+// missing from the ring never verifies.
+func (k KeyRing) Verify(key_id string, p Payload, signature string) bool {
+	public_key, ok := k[key_id]
+	if !ok {
+		return false
+	}
+	return Verify(public_key, p, signature)
+}
+
+// KeyID names a public key by the start of its SHA-256, so certificates
+// signed before a key rotation can be told apart.
+func KeyID(public_key ed25519.PublicKey) string {
+	sum := sha256.Sum256(public_key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// NewCode returns a random, human friendly certificate ID such as
+// TP-7KQ2-M4XD-9PLA-WC3E.
+func NewCode() (string, error) {
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+	groups := []string{"TP"}
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
@@ -0,0 +1,120 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func testSigner(t *testing.T, seed byte) *Signer {
+	t.Helper()
+	signer, err := NewSigner(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func testPayload() Payload {
+	return NewPayload("TP-7KQ2-M4XD-9PLA-WC3E", "65f0c0ffee0000000000beef", "محمد عبدالله", "backend", 92, "65f0c0ffee0000000000cafe",
+		time.Date(2026, 3, 1, 12, 30, 0, 0, time.FixedZone("Hebron", 2*60*60)))
+}
+
+func TestCanonical(t *testing.T) {
+	want := `{"code":"TP-7KQ2-M4XD-9PLA-WC3E","userId":"65f0c0ffee0000000000beef","fullName":"محمد عبدالله","category":"backend",` +
+		`"score":92,"attemptId":"65f0c0ffee0000000000cafe","issuedAt":"2026-03-01T10:30:00Z"}`
+	if got := string(testPayload().Canonical()); got != want {
+		t.Errorf("Canonical() = %s, want %s", got, want)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer := testSigner(t, 1)
+	payload := testPayload()
+	signature := signer.Sign(payload)
+
+	tests := []struct {
+		name      string
+		key       ed25519.PublicKey
+		payload   func(p Payload) Payload
+		signature string
+		want      bool
+	}{
+		{"untouched", signer.PublicKey(), func(p Payload) Payload { return p }, signature, true},
+		{"score raised", signer.PublicKey(), func(p Payload) Payload { p.Score = 100; return p }, signature, false},
+		{"name changed", signer.PublicKey(), func(p Payload) Payload { p.FullName = "Someone Else"; return p }, signature, false},
+		{"issue date moved", signer.PublicKey(), func(p Payload) Payload { p.IssuedAt = "2026-03-02T10:30:00Z"; return p }, signature, false},
+		{"another key", testSigner(t, 2).PublicKey(), func(p Payload) Payload { return p }, signature, false},
+		{"signature cut short", signer.PublicKey(), func(p Payload) Payload { return p }, signature[:len(signature)-4], false},
+		{"signature not base64url", signer.PublicKey(), func(p Payload) Payload { return p }, "not a signature!", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Verify(test.key, test.payload(payload), test.signature); got != test.want {
+				t.Errorf("Verify() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewSignerRejectsShortSeeds(t *testing.T) {
+	if _, err := NewSigner(make([]byte, 16)); err == nil {
+		t.Error("NewSigner() accepted a 16 byte seed")
+	}
+}
+
+func TestKeyRing(t *testing.T) {
+	retired, current, unknown := testSigner(t, 1), testSigner(t, 2), testSigner(t, 3)
+	ring := NewKeyRing(retired.PublicKey(), current.PublicKey())
+	payload := testPayload()
+
+	tests := []struct {
+		name   string
+		signer *Signer
+		key_id string
+		want   bool
+	}{
+		{"current key", current, current.KeyID(), true},
+		{"retired key", retired, retired.KeyID(), true},
+		{"key not in the ring", unknown, unknown.KeyID(), false},
+		{"names another key of the ring", retired, current.KeyID(), false},
+		{"names no key", current, "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ring.Verify(test.key_id, payload, test.signer.Sign(payload)); got != test.want {
+				t.Errorf("Verify() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	signer := testSigner(t, 1)
+	if got := KeyID(signer.PublicKey()); got != signer.KeyID() || len(got) != 16 {
+		t.Errorf("KeyID() = %q, want the signer's 16 hex digit ID %q", got, signer.KeyID())
+	}
+	if KeyID(testSigner(t, 2).PublicKey()) == signer.KeyID() {
+		t.Error("two keys got the same ID")
+	}
+}
+
+func TestNewCode(t *testing.T) {
+	format := regexp.MustCompile(`^TP-[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}$`)
+	seen := map[string]bool{}
+	for range 100 {
+		code, err := NewCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !format.MatchString(code) {
+			t.Fatalf("NewCode() = %q, want TP- and four groups of base32", code)
+		}
+		if seen[code] {
+			t.Fatalf("NewCode() returned %q twice", code)
+		}
+		seen[code] = true
+	}
+}
@@ -0,0 +1,330 @@
+package certificate
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"sort"
+)
+
+// trueTypeFont is the part of a TrueType font a PDF needs: glyph lookup,
+// advance widths and the tables to embed a subset of it.
+type trueTypeFont struct {
+	tables map[string][]byte
+
+	unitsPerEm int
+	ascent     int
+	descent    int
+	capHeight  int
+	bbox       [4]int
+
+	cmap     map[rune]uint16
+	advances []uint16
+	loca     []uint32
+}
+
+// Tables a PDF reader needs to draw the glyphs of an embedded TrueType font
+var embeddedTables = []string{"cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "prep"}
+
+var errInvalidFont = errors.New("certificate: invalid TrueType font")
+
+func parseTrueType(data []byte) (*trueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, errInvalidFont
+	}
+	font := &trueTypeFont{tables: map[string][]byte{}}
+	num_tables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := range num_tables {
+		record := 12 + i*16
+		if record+16 > len(data) {
+			return nil, errInvalidFont
+		}
+		tag := string(data[record : record+4])
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset+length > len(data) {
+			return nil, errInvalidFont
+		}
+		font.tables[tag] = data[offset : offset+length]
+	}
+
+	head, hhea, maxp := font.tables["head"], font.tables["hhea"], font.tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errInvalidFont
+	}
+	font.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	for i := range font.bbox {
+		font.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	font.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	font.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	font.capHeight = font.ascent
+	if os2 := font.tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		font.capHeight = int(int16(binary.BigEndian.Uint16(os2[88:])))
+	}
+	num_glyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+
+	// Glyphs past the last horizontal metric share its advance
+	num_metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := font.tables["hmtx"]
+	if num_metrics == 0 || len(hmtx) < num_metrics*4 {
+		return nil, errInvalidFont
+	}
+	font.advances = make([]uint16, num_glyphs)
+	for glyph := range font.advances {
+		font.advances[glyph] = binary.BigEndian.Uint16(hmtx[min(glyph, num_metrics-1)*4:])
+	}
+
+	loca := font.tables["loca"]
+	long_offsets := binary.BigEndian.Uint16(head[50:]) == 1
+	font.loca = make([]uint32, num_glyphs+1)
+	for i := range font.loca {
+		if long_offsets {
+			if len(loca) < (i+1)*4 {
+				return nil, errInvalidFont
+			}
+			font.loca[i] = binary.BigEndian.Uint32(loca[i*4:])
+		} else {
+			if len(loca) < (i+1)*2 {
+				return nil, errInvalidFont
+			}
+			font.loca[i] = uint32(binary.BigEndian.Uint16(loca[i*2:])) * 2
+		}
+	}
+
+	cmap, err := parseCmap(font.tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	font.cmap = cmap
+
+	return font, nil
+}
+
+// parseCmap reads the Unicode mapping of the font, the full repertoire
+// (format 12) when there is one, the Basic Multilingual Plane (format 4)
+// otherwise.
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, errInvalidFont
+	}
+	subtables := map[uint32]int{}
+	for i := range int(binary.BigEndian.Uint16(cmap[2:])) {
+		record := 4 + i*8
+		if record+8 > len(cmap) {
+			return nil, errInvalidFont
+		}
+		platform_encoding := binary.BigEndian.Uint32(cmap[record:])
+		subtables[platform_encoding] = int(binary.BigEndian.Uint32(cmap[record+4:]))
+	}
+
+	glyphs := map[rune]uint16{}
+	if offset, ok := subtables[3<<16|10]; ok && offset+16 <= len(cmap) && binary.BigEndian.Uint16(cmap[offset:]) == 12 {
+		groups := int(binary.BigEndian.Uint32(cmap[offset+12:]))
+		for i := range groups {
+			group := offset + 16 + i*12
+			if group+12 > len(cmap) {
+				return nil, errInvalidFont
+			}
+			start := binary.BigEndian.Uint32(cmap[group:])
+			end := binary.BigEndian.Uint32(cmap[group+4:])
+			glyph := binary.BigEndian.Uint32(cmap[group+8:])
+			for r := start; r <= end && r <= 0x10FFFF; r++ {
+				glyphs[rune(r)] = uint16(glyph + r - start)
+			}
+		}
+		return glyphs, nil
+	}
+
+	offset, ok := subtables[3<<16|1]
+	if !ok || offset+14 > len(cmap) || binary.BigEndian.Uint16(cmap[offset:]) != 4 {
+		return nil, errors.New("certificate: the font has no Unicode character map")
+	}
+	segments := int(binary.BigEndian.Uint16(cmap[offset+6:])) / 2
+	ends := offset + 14
+	starts := ends + segments*2 + 2
+	deltas := starts + segments*2
+	range_offsets := deltas + segments*2
+	if range_offsets+segments*2 > len(cmap) {
+		return nil, errInvalidFont
+	}
+	for i := range segments {
+		end := int(binary.BigEndian.Uint16(cmap[ends+i*2:]))
+		start := int(binary.BigEndian.Uint16(cmap[starts+i*2:]))
+		delta := binary.BigEndian.Uint16(cmap[deltas+i*2:])
+		range_offset := int(binary.BigEndian.Uint16(cmap[range_offsets+i*2:]))
+		for r := start; r <= end && r != 0xFFFF; r++ {
+			glyph := uint16(r) + delta
+			if range_offset != 0 {
+				index := range_offsets + i*2 + range_offset + (r-start)*2
+				if index+2 > len(cmap) {
+					return nil, errInvalidFont
+				}
+				glyph = binary.BigEndian.Uint16(cmap[index:])
+				if glyph != 0 {
+					glyph += delta
+				}
+			}
+			if glyph != 0 {
+				glyphs[rune(r)] = glyph
+			}
+		}
+	}
+	return glyphs, nil
+}
+
+// glyph returns the glyph of r, 0 (the missing glyph box) if the font has none.
+func (f *trueTypeFont) glyph(r rune) uint16 {
+	return f.cmap[r]
+}
+
+// width is the advance of a glyph in 1/1000 em, the unit of PDF glyph widths.
+func (f *trueTypeFont) width(glyph uint16) int {
+	if int(glyph) >= len(f.advances) {
+		return 0
+	}
+	return int(f.advances[glyph]) * 1000 / f.unitsPerEm
+}
+
+// subset returns a font file holding only the outlines of the given glyphs
+// and the composite glyphs' parts. Glyph IDs are kept, the other glyphs are
+// left empty, so text can use the IDs of the full font.
+func (f *trueTypeFont) subset(glyphs []uint16) []byte {
+	kept := map[uint16]bool{}
+	pending := append([]uint16{0}, glyphs...)
+	for len(pending) > 0 {
+		glyph := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if kept[glyph] || int(glyph)+1 >= len(f.loca) {
+			continue
+		}
+		kept[glyph] = true
+		pending = append(pending, compositeParts(f.glyphData(glyph))...)
+	}
+
+	var new_glyf []byte
+	new_loca := make([]byte, 0, len(f.loca)*4)
+	for glyph := range len(f.loca) - 1 {
+		new_loca = binary.BigEndian.AppendUint32(new_loca, uint32(len(new_glyf)))
+		if kept[uint16(glyph)] {
+			new_glyf = append(new_glyf, f.glyphData(uint16(glyph))...)
+			// Glyphs stay 4 byte aligned
+			for len(new_glyf)%4 != 0 {
+				new_glyf = append(new_glyf, 0)
+			}
+		}
+	}
+	new_loca = binary.BigEndian.AppendUint32(new_loca, uint32(len(new_glyf)))
+
+	// The rebuilt loca has long offsets
+	head := slices.Clone(f.tables["head"])
+	binary.BigEndian.PutUint16(head[50:], 1)
+	binary.BigEndian.PutUint32(head[8:], 0)
+
+	tables := map[string][]byte{"glyf": new_glyf, "loca": new_loca, "head": head}
+	tags := []string{}
+	for _, tag := range embeddedTables {
+		if _, ok := tables[tag]; !ok {
+			if table, ok := f.tables[tag]; ok {
+				tables[tag] = table
+			} else {
+				continue
+			}
+		}
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	num_tables := len(tags)
+	entry_selector := 0
+	for 1<<(entry_selector+1) <= num_tables {
+		entry_selector++
+	}
+	search_range := (1 << entry_selector) * 16
+
+	out := binary.BigEndian.AppendUint32(nil, 0x00010000)
+	out = binary.BigEndian.AppendUint16(out, uint16(num_tables))
+	out = binary.BigEndian.AppendUint16(out, uint16(search_range))
+	out = binary.BigEndian.AppendUint16(out, uint16(entry_selector))
+	out = binary.BigEndian.AppendUint16(out, uint16(num_tables*16-search_range))
+
+	offset := 12 + num_tables*16
+	head_offset := 0
+	for _, tag := range tags {
+		table := tables[tag]
+		out = append(out, tag...)
+		out = binary.BigEndian.AppendUint32(out, tableChecksum(table))
+		out = binary.BigEndian.AppendUint32(out, uint32(offset))
+		out = binary.BigEndian.AppendUint32(out, uint32(len(table)))
+		if tag == "head" {
+			head_offset = offset
+		}
+		offset += (len(table) + 3) &^ 3
+	}
+	for _, tag := range tags {
+		out = append(out, tables[tag]...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	binary.BigEndian.PutUint32(out[head_offset+8:], 0xB1B0AFBA-tableChecksum(out))
+
+	return out
+}
+
+func (f *trueTypeFont) glyphData(glyph uint16) []byte {
+	glyf := f.tables["glyf"]
+	start, end := f.loca[glyph], f.loca[glyph+1]
+	if start >= end || int(end) > len(glyf) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+// compositeParts lists the glyphs a composite glyph is assembled from.
+func compositeParts(data []byte) []uint16 {
+	if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+	const (
+		ARGS_ARE_WORDS  = 0x0001
+		HAS_SCALE       = 0x0008
+		MORE_COMPONENTS = 0x0020
+		HAS_XY_SCALE    = 0x0040
+		HAS_TWO_BY_TWO  = 0x0080
+	)
+	parts := []uint16{}
+	offset := 10
+	for offset+4 <= len(data) {
+		flags := binary.BigEndian.Uint16(data[offset:])
+		parts = append(parts, binary.BigEndian.Uint16(data[offset+2:]))
+		offset += 4
+		if flags&ARGS_ARE_WORDS != 0 {
+			offset += 4
+		} else {
+			offset += 2
+		}
+		switch {
+		case flags&HAS_SCALE != 0:
+			offset += 2
+		case flags&HAS_XY_SCALE != 0:
+			offset += 4
+		case flags&HAS_TWO_BY_TWO != 0:
+			offset += 8
+		}
+		if flags&MORE_COMPONENTS == 0 {
+			break
+		}
+	}
+	return parts
+}
+
+func tableChecksum(table []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(table); i += 4 {
+		var word [4]byte
+		copy(word[:], table[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
@@ -0,0 +1,244 @@
+package certificate
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf16"
+)
+
+// A4 landscape in points
+const (
+	pageWidth  = 842.0
+	pageHeight = 595.0
+)
+
+// DejaVu Sans covers Latin, Greek, Cyrillic, Hebrew and Arabic, the names our
+// students have. Characters it lacks print as an empty box.
+//
+//go:embed fonts/DejaVuSans.ttf
+var dejaVuSans []byte
+
+var loadFont = sync.OnceValues(func() (*trueTypeFont, error) {
+	return parseTrueType(dejaVuSans)
+})
+
+// Document is what is printed on a certificate.
+type Document struct {
+	AppName   string
+	FullName  string
+	Title     string
+	Score     int32
+	IssuedOn  string
+	Code      string
+	VerifyURL string
+	KeyID     string
+	Signature string
+}
+
+type textLine struct {
+	text string
+	bold bool
+	size float64
+	y    float64
+	gray float64
+}
+
+// RenderPDF writes a one page PDF with a subset of the embedded font, no font
+// files or external tools needed at runtime. Arabic is shaped and laid out
+// right to left, and the text can be copied from the PDF.
+func RenderPDF(doc Document) ([]byte, error) {
+	font, err := loadFont()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []textLine{
+		{text: strings.ToUpper(doc.AppName), bold: true, size: 16, y: 500, gray: 0.35},
+		{text: "Certificate of Achievement", bold: true, size: 34, y: 440},
+		{text: "This certifies that", size: 14, y: 390, gray: 0.35},
+		{text: doc.FullName, bold: true, size: 28, y: 345},
+		{text: "passed the " + doc.Title + " exam with a score of " + fmt.Sprintf("%d%%", doc.Score), size: 15, y: 300},
+		{text: "Issued on " + doc.IssuedOn, size: 12, y: 270, gray: 0.35},
+		{text: "Certificate ID " + doc.Code, bold: true, size: 11, y: 150},
+		{text: "Verify at " + doc.VerifyURL, size: 10, y: 132, gray: 0.35},
+		{text: "Signed with " + ALGORITHM + " key " + doc.KeyID, size: 8, y: 100, gray: 0.5},
+	}
+	// The signature is too long for one line at a readable size
+	for i, chunk := range chunks(doc.Signature, 64) {
+		lines = append(lines, textLine{text: chunk, size: 8, y: 88 - float64(i)*10, gray: 0.5})
+	}
+
+	var content bytes.Buffer
+	// Double border
+	content.WriteString("0.2 0.3 0.55 RG 3 w 30 30 782 535 re S 1 w 40 40 762 515 re S\n")
+	// What every used glyph stands for, so the text can be copied
+	to_unicode := map[uint16][]rune{}
+	used := map[uint16]bool{0: true}
+	for _, line := range lines {
+		var glyphs []uint16
+		var width int
+		for _, char := range visualOrder(shapeArabic([]rune(line.text))) {
+			glyph := font.glyph(char.char)
+			glyphs = append(glyphs, glyph)
+			if _, ok := to_unicode[glyph]; !ok && glyph != 0 {
+				to_unicode[glyph] = slices.DeleteFunc(slices.Clone(char.text), func(r rune) bool {
+					return unicode.Is(unicode.Mn, r)
+				})
+			}
+			for _, mark := range char.marks {
+				if glyph := font.glyph(mark); glyph != 0 {
+					glyphs = append(glyphs, glyph)
+					to_unicode[glyph] = []rune{mark}
+				}
+			}
+		}
+		for _, glyph := range glyphs {
+			used[glyph] = true
+			width += font.width(glyph)
+		}
+
+		// The font has no bold face, bold text is outlined as well as filled
+		render := "0 Tr"
+		if line.bold {
+			render = fmt.Sprintf("2 Tr %.2f w", line.size*0.03)
+		}
+		x := (pageWidth - float64(width)*line.size/1000) / 2
+		fmt.Fprintf(&content, "BT %.2f g %.2f G %s /F1 %.1f Tf %.2f %.2f Td <", line.gray, line.gray, render, line.size, x, line.y)
+		for _, glyph := range glyphs {
+			fmt.Fprintf(&content, "%04X", glyph)
+		}
+		content.WriteString("> Tj ET\n")
+	}
+
+	subset_glyphs := []uint16{}
+	for glyph := range used {
+		subset_glyphs = append(subset_glyphs, glyph)
+	}
+	slices.Sort(subset_glyphs)
+	font_name := subsetTag(subset_glyphs) + "+DejaVuSans"
+
+	var glyph_widths strings.Builder
+	for _, glyph := range subset_glyphs {
+		fmt.Fprintf(&glyph_widths, "%d [%d] ", glyph, font.width(glyph))
+	}
+	scale := func(units int) int {
+		return units * 1000 / font.unitsPerEm
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>", pageWidth, pageHeight),
+		compressedStream("", content.Bytes()),
+		fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [6 0 R] /ToUnicode 9 0 R >>", font_name),
+		fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor 7 0 R /CIDToGIDMap /Identity /W [%s] >>", font_name, glyph_widths.String()),
+		fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 8 0 R >>",
+			font_name, scale(font.bbox[0]), scale(font.bbox[1]), scale(font.bbox[2]), scale(font.bbox[3]), scale(font.ascent), scale(font.descent), scale(font.capHeight)),
+	}
+	subset := font.subset(subset_glyphs)
+	objects = append(objects,
+		compressedStream(fmt.Sprintf("/Length1 %d", len(subset)), subset),
+		compressedStream("", toUnicodeCMap(to_unicode)),
+		fmt.Sprintf("<< /Title %s /Author %s /Subject %s /Keywords %s >>",
+			textString("Certificate "+doc.Code), textString(doc.AppName), textString(doc.VerifyURL), textString(doc.KeyID+" "+doc.Signature)),
+	)
+
+	var pdf bytes.Buffer
+	// The binary comment tells transfer tools the file isn't text
+	pdf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, len(objects), xref)
+
+	return pdf.Bytes(), nil
+}
+
+// compressedStream is a Flate compressed stream object, entries are added to
+// its dictionary.
+func compressedStream(entries string, data []byte) string {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	writer.Write(data)
+	writer.Close()
+	return fmt.Sprintf("<< /Length %d /Filter /FlateDecode %s>>\nstream\n%s\nendstream", compressed.Len(), entries, compressed.Bytes())
+}
+
+// toUnicodeCMap maps the glyphs back to the text they stand for.
+func toUnicodeCMap(to_unicode map[uint16][]rune) []byte {
+	glyphs := []uint16{}
+	for glyph := range to_unicode {
+		glyphs = append(glyphs, glyph)
+	}
+	slices.Sort(glyphs)
+
+	var cmap bytes.Buffer
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	cmap.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	cmap.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	cmap.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// At most 100 entries per block
+	for block := range slices.Chunk(glyphs, 100) {
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", len(block))
+		for _, glyph := range block {
+			fmt.Fprintf(&cmap, "<%04X> <%s>\n", glyph, utf16Hex(to_unicode[glyph]))
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return cmap.Bytes()
+}
+
+// subsetTag names a font subset after the glyphs in it, six capital letters
+// as PDF requires.
+func subsetTag(glyphs []uint16) string {
+	hash := sha256.New()
+	for _, glyph := range glyphs {
+		hash.Write([]byte{byte(glyph >> 8), byte(glyph)})
+	}
+	sum := hash.Sum(nil)
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + sum[i]%26
+	}
+	return string(tag)
+}
+
+// textString encodes text as a UTF-16 PDF string, for the document info.
+func textString(text string) string {
+	return "<FEFF" + utf16Hex([]rune(text)) + ">"
+}
+
+func utf16Hex(text []rune) string {
+	var out strings.Builder
+	for _, unit := range utf16.Encode(text) {
+		fmt.Fprintf(&out, "%04X", unit)
+	}
+	return out.String()
+}
+
+func chunks(text string, size int) []string {
+	parts := []string{}
+	for len(text) > size {
+		parts = append(parts, text[:size])
+		text = text[size:]
+	}
+	if text != "" {
+		parts = append(parts, text)
+	}
+	return parts
+}
@@ -0,0 +1,156 @@
+package certificate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestFontSubset(t *testing.T) {
+	font, err := loadFont()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	glyphs := []uint16{}
+	for _, r := range "Aliمحمد" {
+		glyph := font.glyph(r)
+		if glyph == 0 {
+			t.Fatalf("the font has no glyph for %q", r)
+		}
+		glyphs = append(glyphs, glyph)
+	}
+	subset := font.subset(glyphs)
+
+	if sum := tableChecksum(subset); sum != 0xB1B0AFBA {
+		t.Errorf("subset checksum is %#x, want 0xB1B0AFBA", sum)
+	}
+
+	// Every table matches the checksum in the directory, head is summed
+	// without its checksum adjustment
+	tables := map[string][]byte{}
+	for i := range int(binary.BigEndian.Uint16(subset[4:])) {
+		record := subset[12+i*16:]
+		tag := string(record[:4])
+		offset, length := binary.BigEndian.Uint32(record[8:]), binary.BigEndian.Uint32(record[12:])
+		table := bytes.Clone(subset[offset : offset+length])
+		if tag == "head" {
+			binary.BigEndian.PutUint32(table[8:], 0)
+		}
+		if sum := tableChecksum(table); sum != binary.BigEndian.Uint32(record[4:]) {
+			t.Errorf("table %q has checksum %#x, the directory says %#x", tag, sum, binary.BigEndian.Uint32(record[4:]))
+		}
+		tables[tag] = subset[offset : offset+length]
+	}
+	for _, tag := range []string{"glyf", "head", "hhea", "hmtx", "loca", "maxp"} {
+		if tables[tag] == nil {
+			t.Errorf("subset has no %q table", tag)
+		}
+	}
+
+	// Kept glyphs are where the full font has them, the others are empty
+	loca, glyf := tables["loca"], tables["glyf"]
+	outline := func(glyph uint16) []byte {
+		start, end := binary.BigEndian.Uint32(loca[glyph*4:]), binary.BigEndian.Uint32(loca[glyph*4+4:])
+		return bytes.TrimRight(glyf[start:end], "\x00")
+	}
+	for _, glyph := range glyphs {
+		if want := bytes.TrimRight(font.glyphData(glyph), "\x00"); !bytes.Equal(outline(glyph), want) {
+			t.Errorf("glyph %d differs from the full font", glyph)
+		}
+	}
+	if unused := font.glyph('Z'); len(outline(unused)) != 0 {
+		t.Errorf("unused glyph %d was kept", unused)
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  Document
+	}{
+		{"latin name", Document{AppName: "TalentPlatform", FullName: "Ali Hassan", Title: "Backend", Score: 92}},
+		{"arabic name", Document{AppName: "TalentPlatform", FullName: "محمد عبدالله", Title: "Frontend", Score: 100}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.doc.IssuedOn = "March 1, 2026"
+			test.doc.Code = "TP-7KQ2-M4XD-9PLA-WC3E"
+			test.doc.VerifyURL = "https://example.com/verify/TP-7KQ2-M4XD-9PLA-WC3E"
+			test.doc.KeyID = "0123456789abcdef"
+			test.doc.Signature = string(bytes.Repeat([]byte("a"), 86))
+
+			pdf, err := RenderPDF(test.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+				t.Fatal("the output isn't framed as a PDF")
+			}
+
+			// The cross-reference table points at every object
+			startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+			if startxref == nil {
+				t.Fatal("no startxref")
+			}
+			xref, _ := strconv.Atoi(string(startxref[1]))
+			if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+				t.Fatalf("startxref %d doesn't point at the xref table", xref)
+			}
+			entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+			if len(entries) != 10 {
+				t.Fatalf("xref has %d objects, want 10", len(entries))
+			}
+			for i, entry := range entries {
+				offset, _ := strconv.Atoi(string(entry[1]))
+				if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+					t.Errorf("xref entry %d points at %q", i+1, pdf[offset:min(offset+10, len(pdf))])
+				}
+			}
+
+			for _, want := range []string{"/Subtype /Type0", "/Encoding /Identity-H", "/FontFile2 8 0 R", "/ToUnicode 9 0 R"} {
+				if !bytes.Contains(pdf, []byte(want)) {
+					t.Errorf("the PDF has no %s", want)
+				}
+			}
+		})
+	}
+}
+
+func TestTextString(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Ali", "<FEFF0041006C0069>"},
+		{"علي", "<FEFF06390644064A>"},
+		{"😀", "<FEFFD83DDE00>"},
+	}
+	for _, test := range tests {
+		if got := textString(test.text); got != test.want {
+			t.Errorf("textString(%q) = %s, want %s", test.text, got, test.want)
+		}
+	}
+}
+
+func TestChunks(t *testing.T) {
+	tests := []struct {
+		text string
+		size int
+		want []string
+	}{
+		{"", 4, []string{}},
+		{"abc", 4, []string{"abc"}},
+		{"abcdefgh", 4, []string{"abcd", "efgh"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+	}
+	for _, test := range tests {
+		if got := chunks(test.text, test.size); !slices.Equal(got, test.want) {
+			t.Errorf("chunks(%q, %d) = %q, want %q", test.text, test.size, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package certificate
+
+import (
+	"slices"
+	"unicode"
+)
+
+// Arabic letters by their isolated presentation form (Arabic Presentation
+// Forms-B) and whether they join the letter after them. The final, initial
+// and medial forms follow the isolated one in that order.
+var arabicForms = map[rune]struct {
+	isolated rune
+	dual     bool
+}{
+	0x0621: {0xFE80, false}, 0x0622: {0xFE81, false}, 0x0623: {0xFE83, false}, 0x0624: {0xFE85, false},
+	0x0625: {0xFE87, false}, 0x0626: {0xFE89, true}, 0x0627: {0xFE8D, false}, 0x0628: {0xFE8F, true},
+	0x0629: {0xFE93, false}, 0x062A: {0xFE95, true}, 0x062B: {0xFE99, true}, 0x062C: {0xFE9D, true},
+	0x062D: {0xFEA1, true}, 0x062E: {0xFEA5, true}, 0x062F: {0xFEA9, false}, 0x0630: {0xFEAB, false},
+	0x0631: {0xFEAD, false}, 0x0632: {0xFEAF, false}, 0x0633: {0xFEB1, true}, 0x0634: {0xFEB5, true},
+	0x0635: {0xFEB9, true}, 0x0636: {0xFEBD, true}, 0x0637: {0xFEC1, true}, 0x0638: {0xFEC5, true},
+	0x0639: {0xFEC9, true}, 0x063A: {0xFECD, true}, 0x0641: {0xFED1, true}, 0x0642: {0xFED5, true},
+	0x0643: {0xFED9, true}, 0x0644: {0xFEDD, true}, 0x0645: {0xFEE1, true}, 0x0646: {0xFEE5, true},
+	0x0647: {0xFEE9, true}, 0x0648: {0xFEED, false}, 0x0649: {0xFEEF, false}, 0x064A: {0xFEF1, true},
+}
+
+// Lam followed by an alef is written as one ligature, isolated then final form
+var lamAlefLigatures = map[rune]rune{
+	0x0622: 0xFEF5,
+	0x0623: 0xFEF7,
+	0x0625: 0xFEF9,
+	0x0627: 0xFEFB,
+}
+
+const (
+	ARABIC_LAM     = 0x0644
+	ARABIC_TATWEEL = 0x0640
+	ARABIC_HAMZA   = 0x0621
+)
+
+// shapedChar is a character as it's drawn, with the diacritics on it, and the
+// text it stands for, a ligature stands for two letters.
+type shapedChar struct {
+	char  rune
+	marks []rune
+	text  []rune
+}
+
+// shapeArabic replaces Arabic letters by the form they take next to their
+// neighbours, diacritics don't break the joining.
+func shapeArabic(text []rune) []shapedChar {
+	// Index of the neighbouring letter, skipping diacritics, -1 if there's none
+	neighbour := func(i, step int) int {
+		for i += step; i >= 0 && i < len(text); i += step {
+			if !unicode.Is(unicode.Mn, text[i]) {
+				return i
+			}
+		}
+		return -1
+	}
+	letter := func(i int) rune {
+		if i < 0 {
+			return 0
+		}
+		return text[i]
+	}
+	joinsForward := func(r rune) bool {
+		form, ok := arabicForms[r]
+		return r == ARABIC_TATWEEL || ok && form.dual
+	}
+	joinsBackward := func(r rune) bool {
+		_, ok := arabicForms[r]
+		return r == ARABIC_TATWEEL || ok && r != ARABIC_HAMZA
+	}
+
+	shaped := make([]shapedChar, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		r := text[i]
+		if unicode.Is(unicode.Mn, r) && len(shaped) > 0 {
+			last := &shaped[len(shaped)-1]
+			last.marks = append(last.marks, r)
+			last.text = append(last.text, r)
+			continue
+		}
+		form, ok := arabicForms[r]
+		if !ok {
+			shaped = append(shaped, shapedChar{char: r, text: []rune{r}})
+			continue
+		}
+		joins_previous := joinsBackward(r) && joinsForward(letter(neighbour(i, -1)))
+
+		next := neighbour(i, 1)
+		if ligature, ok := lamAlefLigatures[letter(next)]; r == ARABIC_LAM && ok {
+			if joins_previous {
+				ligature++
+			}
+			// Diacritics on the lam move onto the ligature
+			lam_alef := shapedChar{char: ligature, text: []rune{r}}
+			for _, mark := range text[i+1 : next] {
+				lam_alef.marks = append(lam_alef.marks, mark)
+				lam_alef.text = append(lam_alef.text, mark)
+			}
+			lam_alef.text = append(lam_alef.text, text[next])
+			shaped = append(shaped, lam_alef)
+			i = next
+			continue
+		}
+
+		joins_next := form.dual && joinsBackward(letter(next))
+		char := form.isolated
+		switch {
+		case joins_previous && joins_next:
+			char += 3
+		case joins_next:
+			char += 2
+		case joins_previous:
+			char++
+		}
+		shaped = append(shaped, shapedChar{char: char, text: []rune{r}})
+	}
+	return shaped
+}
+
+// visualOrder lays a line out left to right: runs of right-to-left script
+// are reversed, and so is the order of the runs on a line that starts with
+// one. Numbers and Latin text keep their direction, which is as much of the
+// Unicode bidirectional algorithm as a name needs.
+func visualOrder(line []shapedChar) []shapedChar {
+	rtl := func(char shapedChar) bool {
+		return !unicode.IsDigit(char.text[0]) && unicode.In(char.text[0], unicode.Arabic, unicode.Hebrew)
+	}
+	strong := func(char shapedChar) bool {
+		return rtl(char) || unicode.IsLetter(char.text[0]) || unicode.IsDigit(char.text[0])
+	}
+	first_strong := slices.IndexFunc(line, strong)
+	line_rtl := first_strong >= 0 && rtl(line[first_strong])
+	// The direction on either side of a neutral, the line's past its ends
+	side := func(i int) bool {
+		if i < 0 || i >= len(line) {
+			return line_rtl
+		}
+		return rtl(line[i])
+	}
+
+	// Neutral characters take the direction of the text around them, the
+	// line's when it differs on either side
+	is_rtl := make([]bool, len(line))
+	for i := range line {
+		if strong(line[i]) {
+			is_rtl[i] = rtl(line[i])
+			continue
+		}
+		previous, next := i-1, i+1
+		for previous >= 0 && !strong(line[previous]) {
+			previous--
+		}
+		for next < len(line) && !strong(line[next]) {
+			next++
+		}
+		is_rtl[i] = line_rtl
+		if side(previous) == side(next) {
+			is_rtl[i] = side(previous)
+		}
+	}
+
+	runs := [][]shapedChar{}
+	for i := 0; i < len(line); {
+		end := i + 1
+		for end < len(line) && is_rtl[end] == is_rtl[i] {
+			end++
+		}
+		run := slices.Clone(line[i:end])
+		if is_rtl[i] {
+			slices.Reverse(run)
+		}
+		runs = append(runs, run)
+		i = end
+	}
+
+	if line_rtl {
+		slices.Reverse(runs)
+	}
+	return slices.Concat(runs...)
+}
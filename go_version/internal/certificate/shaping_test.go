@@ -0,0 +1,83 @@
+package certificate
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestShapeArabic(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []rune
+	}{
+		{"latin is untouched", "Ali", []rune("Ali")},
+		{"initial, medial and final forms", "محمد", []rune{0xFEE3, 0xFEA4, 0xFEE4, 0xFEAA}},
+		{"isolated letter", "ب", []rune{0xFE8F}},
+		{"letters that don't join forward break the word", "دار", []rune{0xFEA9, 0xFE8D, 0xFEAD}},
+		{"lam alef ligature", "لا", []rune{0xFEFB}},
+		{"joined lam alef ligature", "سلام", []rune{0xFEB3, 0xFEFC, 0xFEE1}},
+		{"words are shaped apart", "عبد الله", []rune{0xFECB, 0xFE92, 0xFEAA, ' ', 0xFE8D, 0xFEDF, 0xFEE0, 0xFEEA}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []rune{}
+			for _, char := range shapeArabic([]rune(test.text)) {
+				got = append(got, char.char)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("shapeArabic(%q) = %U, want %U", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestShapeArabicKeepsText(t *testing.T) {
+	// Diacritics don't break the joining and stay with their letter
+	text := "مُحَمَّد"
+	shaped := shapeArabic([]rune(text))
+
+	chars, original := []rune{}, []rune{}
+	for _, char := range shaped {
+		chars = append(chars, char.char)
+		original = append(original, char.text...)
+	}
+	if want := []rune{0xFEE3, 0xFEA4, 0xFEE4, 0xFEAA}; !slices.Equal(chars, want) {
+		t.Errorf("shaped %q as %U, want %U", text, chars, want)
+	}
+	if string(original) != text {
+		t.Errorf("shaped text stands for %q, want %q", string(original), text)
+	}
+	if want := []rune{0x064E, 0x0651}; !slices.Equal(shaped[2].marks, want) {
+		t.Errorf("second meem carries %U, want %U", shaped[2].marks, want)
+	}
+}
+
+func TestVisualOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"latin", "Ali Hassan", "Ali Hassan"},
+		{"arabic is reversed", "علي حسن", "نسح يلع"},
+		{"arabic inside latin", "Certificate for علي حسن 2026", "Certificate for نسح يلع 2026"},
+		{"numbers keep their direction in arabic", "علي 2026", "2026 يلع"},
+		{"latin inside arabic", "علي Ali حسن", "نسح Ali يلع"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			line := []shapedChar{}
+			for _, r := range test.text {
+				line = append(line, shapedChar{char: r, text: []rune{r}})
+			}
+			got := []rune{}
+			for _, char := range visualOrder(line) {
+				got = append(got, char.char)
+			}
+			if string(got) != test.want {
+				t.Errorf("visualOrder(%q) = %q, want %q", test.text, string(got), test.want)
+			}
+		})
+	}
+}
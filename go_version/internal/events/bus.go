@@ -0,0 +1,104 @@
+// Package events is the in-process domain event bus. Events reach it through
+// the Mongo outbox, so subscribers run at least once and must be idempotent.
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go_version/internal/models"
+)
+
+const (
+	// Deliveries failing this many times are dead-lettered
+	MAX_DELIVERY_ATTEMPTS = 8
+
+	BASE_RETRY_DELAY = 10 * time.Second
+	MAX_RETRY_DELAY  = time.Hour
+)
+
+type Handler func(ctx context.Context, event models.DomainEvent) error
+
+type Subscriber struct {
+	Name   string
+	Handle Handler
+}
+
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string][]Subscriber
+	wake        chan struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: map[string][]Subscriber{},
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Subscribe registers a handler for an event type. The name identifies the
+// subscriber in the delivery log, it must stay stable across deploys.
+func (b *Bus) Subscribe(event_type, name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[event_type] = append(b.subscribers[event_type], Subscriber{Name: name, Handle: handler})
+}
+
+func (b *Bus) Subscribers(event_type string) []Subscriber {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return slices.Clone(b.subscribers[event_type])
+}
+
+// Dispatch runs the subscribers of the event that aren't in delivered yet and
+// returns the updated delivered list. A failing subscriber doesn't stop the
+// others, their errors are joined.
+func (b *Bus) Dispatch(ctx context.Context, event models.DomainEvent, delivered []string) ([]string, error) {
+	var errs []error
+	for _, subscriber := range b.Subscribers(event.Type) {
+		if slices.Contains(delivered, subscriber.Name) {
+			continue
+		}
+		if err := run(ctx, subscriber, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", subscriber.Name, err))
+			continue
+		}
+		delivered = append(delivered, subscriber.Name)
+	}
+	return delivered, errors.Join(errs...)
+}
+
+func run(ctx context.Context, subscriber Subscriber, event models.DomainEvent) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return subscriber.Handle(ctx, event)
+}
+
+// Wake tells idle workers new events were written to the outbox.
+func (b *Bus) Wake() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Bus) Woken() <-chan struct{} {
+	return b.wake
+}
+
+// RetryDelay is the exponential backoff before the given (1-based) attempt is retried.
+func RetryDelay(attempt int32) time.Duration {
+	delay := BASE_RETRY_DELAY
+	for i := int32(1); i < attempt && delay < MAX_RETRY_DELAY; i++ {
+		delay *= 2
+	}
+	return min(delay, MAX_RETRY_DELAY)
+}
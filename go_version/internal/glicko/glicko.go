@@ -0,0 +1,143 @@
+// Package glicko implements the Glicko-2 rating system (Glickman, 2013).
+// Students are rated by "playing" questions: every answered question is a
+// game against an opponent whose rating reflects the question's difficulty.
+package glicko
+
+import (
+	"math"
+)
+
+const (
+	DEFAULT_RATING     = 1500.0
+	DEFAULT_RD         = 350.0
+	DEFAULT_VOLATILITY = 0.06
+
+	// Constrains how fast volatility changes, 0.3 to 1.2 are sensible
+	TAU = 0.5
+
+	// Ratings never get less certain than a new player's
+	MAX_RD = DEFAULT_RD
+	MIN_RD = 30.0
+
+	// Converts between the Glicko and Glicko-2 scales
+	SCALE = 173.7178
+
+	convergence = 1e-6
+)
+
+type Rating struct {
+	Rating     float64
+	RD         float64
+	Volatility float64
+}
+
+// Result is one game: the opponent's rating and deviation and the score,
+// 1 for a win (correct answer) and 0 for a loss.
+type Result struct {
+	OpponentRating float64
+	OpponentRD     float64
+	Score          float64
+}
+
+func NewRating() Rating {
+	return Rating{Rating: DEFAULT_RATING, RD: DEFAULT_RD, Volatility: DEFAULT_VOLATILITY}
+}
+
+// Interval is the approximate 95% confidence interval of the rating.
+func (r Rating) Interval() (float64, float64) {
+	return r.Rating - 1.96*r.RD, r.Rating + 1.96*r.RD
+}
+
+// Update rates one rating period. Without results only the deviation grows.
+func Update(player Rating, results []Result) Rating {
+	mu := (player.Rating - DEFAULT_RATING) / SCALE
+	phi := player.RD / SCALE
+	sigma := player.Volatility
+
+	if len(results) == 0 {
+		return Decay(player, 1)
+	}
+
+	var v_inverse, delta_sum float64
+	for _, result := range results {
+		mu_j := (result.OpponentRating - DEFAULT_RATING) / SCALE
+		g_j := g(result.OpponentRD / SCALE)
+		e_j := expected(mu, mu_j, g_j)
+		v_inverse += g_j * g_j * e_j * (1 - e_j)
+		delta_sum += g_j * (result.Score - e_j)
+	}
+	v := 1 / v_inverse
+	delta := v * delta_sum
+
+	sigma = newVolatility(sigma, phi, v, delta)
+
+	phi_star := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phi_star*phi_star)+1/v)
+	mu = mu + phi*phi*delta_sum
+
+	return Rating{
+		Rating:     mu*SCALE + DEFAULT_RATING,
+		RD:         clampRD(phi * SCALE),
+		Volatility: sigma,
+	}
+}
+
+// Decay grows the deviation for rating periods without games.
+func Decay(player Rating, periods float64) Rating {
+	if periods <= 0 {
+		return player
+	}
+	phi := player.RD / SCALE
+	phi = math.Sqrt(phi*phi + periods*player.Volatility*player.Volatility)
+	player.RD = clampRD(phi * SCALE)
+	return player
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expected(mu, mu_j, g_j float64) float64 {
+	return 1 / (1 + math.Exp(-g_j*(mu-mu_j)))
+}
+
+// newVolatility solves for the new volatility with the Illinois algorithm
+// (step 5 of the Glicko-2 paper).
+func newVolatility(sigma, phi, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-d)/(2*d*d) - (x-a)/(TAU*TAU)
+	}
+
+	x_a := a
+	var x_b float64
+	if delta*delta > phi*phi+v {
+		x_b = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*TAU) < 0 {
+			k++
+		}
+		x_b = a - k*TAU
+	}
+
+	f_a, f_b := f(x_a), f(x_b)
+	for math.Abs(x_b-x_a) > convergence {
+		c := x_a + (x_a-x_b)*f_a/(f_b-f_a)
+		f_c := f(c)
+		if f_c*f_b <= 0 {
+			x_a, f_a = x_b, f_b
+		} else {
+			f_a /= 2
+		}
+		x_b, f_b = c, f_c
+	}
+
+	return math.Exp(x_a / 2)
+}
+
+func clampRD(rd float64) float64 {
+	return min(max(rd, MIN_RD), MAX_RD)
+}
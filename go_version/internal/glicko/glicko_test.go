@@ -0,0 +1,87 @@
+package glicko
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		player  Rating
+		results []Result
+		want    Rating
+	}{
+		{
+			// The worked example of Glickman's "Example of the Glicko-2 system",
+			// which rounds its intermediate steps
+			name:   "paper example",
+			player: Rating{Rating: 1500, RD: 200, Volatility: 0.06},
+			results: []Result{
+				{OpponentRating: 1400, OpponentRD: 30, Score: 1},
+				{OpponentRating: 1550, OpponentRD: 100, Score: 0},
+				{OpponentRating: 1700, OpponentRD: 300, Score: 0},
+			},
+			want: Rating{Rating: 1464.06, RD: 151.52, Volatility: 0.05999},
+		},
+		{
+			name:    "no games only grows the deviation",
+			player:  Rating{Rating: 1500, RD: 200, Volatility: 0.06},
+			results: nil,
+			want:    Rating{Rating: 1500, RD: 200.27, Volatility: 0.06},
+		},
+		{
+			name:    "a win against an equal opponent",
+			player:  NewRating(),
+			results: []Result{{OpponentRating: 1500, OpponentRD: 350, Score: 1}},
+			want:    Rating{Rating: 1662.31, RD: 290.32, Volatility: 0.06},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Update(test.player, test.results)
+			if math.Abs(got.Rating-test.want.Rating) > 0.05 || math.Abs(got.RD-test.want.RD) > 0.05 || math.Abs(got.Volatility-test.want.Volatility) > 1e-5 {
+				t.Errorf("Update() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUpdateIsSymmetric(t *testing.T) {
+	opponent := Result{OpponentRating: 1500, OpponentRD: 100}
+	win := Update(NewRating(), []Result{{OpponentRating: opponent.OpponentRating, OpponentRD: opponent.OpponentRD, Score: 1}})
+	loss := Update(NewRating(), []Result{opponent})
+	if math.Abs((win.Rating-DEFAULT_RATING)+(loss.Rating-DEFAULT_RATING)) > 1e-6 || math.Abs(win.RD-loss.RD) > 1e-6 {
+		t.Errorf("a win gave %+v and a loss %+v, want mirrored ratings", win, loss)
+	}
+}
+
+func TestDecay(t *testing.T) {
+	tests := []struct {
+		name    string
+		player  Rating
+		periods float64
+		want    float64
+	}{
+		{"one period", Rating{Rating: 1500, RD: 200, Volatility: 0.06}, 1, 200.27},
+		{"ten periods", Rating{Rating: 1500, RD: 200, Volatility: 0.06}, 10, 202.70},
+		{"no periods", Rating{Rating: 1500, RD: 200, Volatility: 0.06}, 0, 200},
+		{"capped at a new player's", Rating{Rating: 1500, RD: 349, Volatility: 0.06}, 1000, MAX_RD},
+		{"raised to the minimum", Rating{Rating: 1500, RD: 10, Volatility: 0.01}, 1, MIN_RD},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Decay(test.player, test.periods)
+			if math.Abs(got.RD-test.want) > 0.01 || got.Rating != test.player.Rating || got.Volatility != test.player.Volatility {
+				t.Errorf("Decay(%v) = %+v, want RD %v", test.periods, got, test.want)
+			}
+		})
+	}
+}
+
+func TestInterval(t *testing.T) {
+	low, high := Rating{Rating: 1500, RD: 100}.Interval()
+	if math.Abs(low-1304) > 1e-9 || math.Abs(high-1696) > 1e-9 {
+		t.Errorf("Interval() = %v, %v, want 1304, 1696", low, high)
+	}
+}
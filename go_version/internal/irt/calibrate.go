@@ -0,0 +1,172 @@
+package irt
+
+import (
+	"math"
+)
+
+type CalibrationOptions struct {
+	Model         string
+	MaxIterations int
+	Tolerance     float64
+}
+
+type CalibrationResult struct {
+	Items      []Item
+	Iterations int
+	Converged  bool
+}
+
+// Pattern is one examinee's responses, keyed by item index.
+type Pattern map[int]bool
+
+// Calibrate estimates item parameters from response patterns with marginal
+// maximum likelihood (Bock-Aitkin EM). Weak priors on every parameter keep
+// items with few or extreme responses from running off to infinity.
+func Calibrate(item_count int, patterns []Pattern, opts CalibrationOptions) CalibrationResult {
+	if opts.MaxIterations == 0 {
+		opts.MaxIterations = 100
+	}
+	if opts.Tolerance == 0 {
+		opts.Tolerance = 1e-3
+	}
+
+	q := defaultQuadrature
+	items := make([]Item, item_count)
+	for i := range items {
+		items[i] = Item{A: 1, B: 0}
+		if opts.Model == MODEL_3PL {
+			items[i].C = 0.2
+		}
+	}
+
+	result := CalibrationResult{Items: items}
+	expected_n := make([][]float64, item_count)
+	expected_r := make([][]float64, item_count)
+	for i := range item_count {
+		expected_n[i] = make([]float64, len(q.points))
+		expected_r[i] = make([]float64, len(q.points))
+	}
+
+	posterior := make([]float64, len(q.points))
+	for iteration := 1; iteration <= opts.MaxIterations; iteration++ {
+		result.Iterations = iteration
+		for i := range item_count {
+			clear(expected_n[i])
+			clear(expected_r[i])
+		}
+
+		// E-step: posterior ability distribution of every examinee
+		for _, pattern := range patterns {
+			max_log := math.Inf(-1)
+			for k, theta := range q.points {
+				value := math.Log(q.weights[k])
+				for i, correct := range pattern {
+					value += logLikelihood(items[i].Probability(theta), correct)
+				}
+				posterior[k] = value
+				max_log = math.Max(max_log, value)
+			}
+			total := 0.0
+			for k := range posterior {
+				posterior[k] = math.Exp(posterior[k] - max_log)
+				total += posterior[k]
+			}
+			for k := range posterior {
+				weight := posterior[k] / total
+				for i, correct := range pattern {
+					expected_n[i][k] += weight
+					if correct {
+						expected_r[i][k] += weight
+					}
+				}
+			}
+		}
+
+		// M-step: refit every item against the expected counts
+		largest_change := 0.0
+		for i := range items {
+			fitted := fitItem(items[i], q.points, expected_n[i], expected_r[i], opts.Model)
+			largest_change = math.Max(largest_change, math.Abs(fitted.A-items[i].A))
+			largest_change = math.Max(largest_change, math.Abs(fitted.B-items[i].B))
+			largest_change = math.Max(largest_change, math.Abs(fitted.C-items[i].C))
+			items[i] = fitted
+		}
+
+		if largest_change < opts.Tolerance {
+			result.Converged = true
+			break
+		}
+	}
+
+	return result
+}
+
+// fitItem maximizes the expected complete-data log likelihood of one item plus
+// its log prior, by gradient ascent with numeric derivatives and backtracking.
+func fitItem(start Item, points, expected_n, expected_r []float64, model string) Item {
+	objective := func(it Item) float64 {
+		value := itemLogPrior(it, model)
+		for k, theta := range points {
+			if expected_n[k] == 0 {
+				continue
+			}
+			p := it.Probability(theta)
+			value += expected_r[k]*logLikelihood(p, true) + (expected_n[k]-expected_r[k])*logLikelihood(p, false)
+		}
+		return value
+	}
+
+	const h = 1e-4
+	current := start
+	current_value := objective(current)
+	for range 25 {
+		grad_a := (objective(Item{A: current.A + h, B: current.B, C: current.C}) - current_value) / h
+		grad_b := (objective(Item{A: current.A, B: current.B + h, C: current.C}) - current_value) / h
+		grad_c := 0.0
+		if model == MODEL_3PL {
+			grad_c = (objective(Item{A: current.A, B: current.B, C: current.C + h}) - current_value) / h
+		}
+
+		step := 0.1
+		improved := false
+		for step > 1e-6 {
+			candidate := clampItem(Item{
+				A: current.A + step*grad_a,
+				B: current.B + step*grad_b,
+				C: current.C + step*grad_c,
+			}, model)
+			if value := objective(candidate); value > current_value {
+				current, current_value, improved = candidate, value, true
+				break
+			}
+			step /= 2
+		}
+		if !improved {
+			break
+		}
+	}
+
+	return current
+}
+
+// itemLogPrior: log-normal(0, 0.5) on A, normal(0, 2) on B, beta(5, 17) on C.
+func itemLogPrior(it Item, model string) float64 {
+	log_a := math.Log(it.A)
+	value := -log_a - log_a*log_a/(2*0.25)
+	value += -it.B * it.B / (2 * 4)
+	if model == MODEL_3PL {
+		value += 4*math.Log(it.C) + 16*math.Log(1-it.C)
+	}
+	return value
+}
+
+func clampItem(it Item, model string) Item {
+	it.A = math.Min(math.Max(it.A, 0.05), 4)
+	it.B = math.Min(math.Max(it.B, -4), 4)
+	if model == MODEL_3PL {
+		it.C = math.Min(math.Max(it.C, 0.001), 0.5)
+	} else {
+		it.C = 0
+	}
+	return it
+}
@@ -0,0 +1,140 @@
+// Package irt implements the item response theory pieces behind adaptive
+// exams: 2PL/3PL item response functions, ability estimation and item
+// selection. Abilities are on the logistic metric (no 1.7 scaling constant).
+package irt
+
+import (
+	"math"
+)
+
+const (
+	MODEL_2PL = "2PL"
+	MODEL_3PL = "3PL"
+)
+
+// Item holds the parameters of one question: discrimination A, difficulty B
+// and pseudo-guessing C (always 0 under the 2PL model).
+type Item struct {
+	A float64
+	B float64
+	C float64
+}
+
+// DefaultItem gives uncalibrated questions rough parameters from the authored
+// difficulty label until enough attempts exist to calibrate them.
+func DefaultItem(difficulty string) Item {
+	switch difficulty {
+	case "easy":
+		return Item{A: 1, B: -1}
+	case "hard":
+		return Item{A: 1, B: 1}
+	default:
+		return Item{A: 1, B: 0}
+	}
+}
+
+// Probability of a correct answer at ability theta.
+func (it Item) Probability(theta float64) float64 {
+	return it.C + (1-it.C)/(1+math.Exp(-it.A*(theta-it.B)))
+}
+
+// Information is the Fisher information the item carries at theta.
+func (it Item) Information(theta float64) float64 {
+	p := it.Probability(theta)
+	if p <= 0 || p >= 1 {
+		return 0
+	}
+	q := 1 - p
+	ratio := (p - it.C) / (1 - it.C)
+	return it.A * it.A * (q / p) * ratio * ratio
+}
+
+type Response struct {
+	Item    Item
+	Correct bool
+}
+
+type Estimate struct {
+	Theta float64
+	SE    float64
+}
+
+// quadrature is a fixed grid over the ability scale with standard normal
+// weights, shared by estimation and calibration.
+type quadrature struct {
+	points  []float64
+	weights []float64
+}
+
+func newQuadrature(count int, bound float64) quadrature {
+	q := quadrature{points: make([]float64, count), weights: make([]float64, count)}
+	step := 2 * bound / float64(count-1)
+	total := 0.0
+	for k := range count {
+		theta := -bound + float64(k)*step
+		q.points[k] = theta
+		q.weights[k] = math.Exp(-theta * theta / 2)
+		total += q.weights[k]
+	}
+	for k := range q.weights {
+		q.weights[k] /= total
+	}
+	return q
+}
+
+var defaultQuadrature = newQuadrature(61, 4)
+
+// EstimateAbility returns the expected a posteriori ability under a standard
+// normal prior, and its posterior standard deviation as the standard error.
+// Unlike maximum likelihood it is defined for all-correct or all-wrong patterns.
+func EstimateAbility(responses []Response) Estimate {
+	q := defaultQuadrature
+	log_posterior := make([]float64, len(q.points))
+	max_log := math.Inf(-1)
+	for k, theta := range q.points {
+		value := math.Log(q.weights[k])
+		for _, response := range responses {
+			value += logLikelihood(response.Item.Probability(theta), response.Correct)
+		}
+		log_posterior[k] = value
+		max_log = math.Max(max_log, value)
+	}
+
+	total, mean := 0.0, 0.0
+	posterior := make([]float64, len(q.points))
+	for k, theta := range q.points {
+		posterior[k] = math.Exp(log_posterior[k] - max_log)
+		total += posterior[k]
+		mean += theta * posterior[k]
+	}
+	mean /= total
+
+	variance := 0.0
+	for k, theta := range q.points {
+		variance += (theta - mean) * (theta - mean) * posterior[k]
+	}
+	variance /= total
+
+	return Estimate{Theta: mean, SE: math.Sqrt(variance)}
+}
+
+// MostInformative returns the index of the candidate with the highest
+// information at theta, or -1 when there are no candidates.
+func MostInformative(theta float64, candidates []Item) int {
+	best, best_info := -1, -1.0
+	for i, item := range candidates {
+		if info := item.Information(theta); info > best_info {
+			best, best_info = i, info
+		}
+	}
+	return best
+}
+
+func logLikelihood(p float64, correct bool) float64 {
+	const epsilon = 1e-9
+	p = math.Min(math.Max(p, epsilon), 1-epsilon)
+	if correct {
+		return math.Log(p)
+	}
+	return math.Log(1 - p)
+}
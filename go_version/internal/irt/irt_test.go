@@ -0,0 +1,129 @@
+package irt
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestProbability(t *testing.T) {
+	tests := []struct {
+		name  string
+		item  Item
+		theta float64
+		want  float64
+	}{
+		{"at the difficulty", Item{A: 1, B: 0}, 0, 0.5},
+		{"one logit above", Item{A: 1, B: 0}, 1, 1 / (1 + math.Exp(-1))},
+		{"discrimination scales the logit", Item{A: 2, B: 1}, 0, 1 / (1 + math.Exp(2))},
+		{"guessing raises the floor", Item{A: 1, B: 0, C: 0.2}, 0, 0.6},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.item.Probability(test.theta); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("Probability(%v) = %v, want %v", test.theta, got, test.want)
+			}
+		})
+	}
+}
+
+func TestInformation(t *testing.T) {
+	tests := []struct {
+		name  string
+		item  Item
+		theta float64
+		want  float64
+	}{
+		{"2PL peaks at a squared over 4", Item{A: 1, B: 0}, 0, 0.25},
+		{"2PL with a of 2", Item{A: 2, B: 1}, 1, 1},
+		{"3PL at the difficulty", Item{A: 1, B: 0, C: 0.2}, 0, (0.4 / 0.6) * 0.5 * 0.5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.item.Information(test.theta); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("Information(%v) = %v, want %v", test.theta, got, test.want)
+			}
+		})
+	}
+}
+
+// Expected values integrate the posterior on a fine grid, the quadrature of
+// the package is within a few thousandths of them.
+func TestEstimateAbility(t *testing.T) {
+	rasch := Item{A: 1, B: 0}
+	tests := []struct {
+		name      string
+		responses []Response
+		theta     float64
+		se        float64
+	}{
+		{"no responses is the prior", nil, 0, 1},
+		{"one right", []Response{{rasch, true}}, 0.4132, 0.9106},
+		{"one wrong mirrors one right", []Response{{rasch, false}}, -0.4132, 0.9106},
+		{"one right and one wrong", []Response{{rasch, true}, {rasch, false}}, 0, 0.8354},
+		{"all right stays finite", []Response{{rasch, true}, {rasch, true}, {rasch, true}, {rasch, true}, {rasch, true}}, 1.2383, 0.7392},
+		{"mixed items", []Response{{Item{A: 1.5, B: -1}, true}, {rasch, true}, {Item{A: 1.2, B: 1}, false}}, 0.2977, 0.7543},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := EstimateAbility(test.responses)
+			if math.Abs(got.Theta-test.theta) > 5e-3 || math.Abs(got.SE-test.se) > 5e-3 {
+				t.Errorf("EstimateAbility() = %+v, want theta %v and SE %v", got, test.theta, test.se)
+			}
+		})
+	}
+}
+
+func TestMostInformative(t *testing.T) {
+	candidates := []Item{{A: 1, B: -2}, {A: 1, B: 0}, {A: 1, B: 2}, {A: 0.5, B: 0.5}}
+	tests := []struct {
+		name       string
+		theta      float64
+		candidates []Item
+		want       int
+	}{
+		{"closest difficulty", 0.2, candidates, 1},
+		{"high ability", 1.8, candidates, 2},
+		{"low ability", -3, candidates, 0},
+		{"no candidates", 0, nil, -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := MostInformative(test.theta, test.candidates); got != test.want {
+				t.Errorf("MostInformative(%v) = %d, want %d", test.theta, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCalibrateRecoversDifficulty(t *testing.T) {
+	truth := []Item{{A: 1, B: -1.5}, {A: 1, B: -0.5}, {A: 1, B: 0}, {A: 1, B: 0.5}, {A: 1, B: 1.5}}
+
+	// Simulated examinees of standard normal ability, seeded so the test is stable
+	random := rand.New(rand.NewPCG(1, 2))
+	patterns := make([]Pattern, 0, 1000)
+	for range 1000 {
+		theta := random.NormFloat64()
+		pattern := Pattern{}
+		for i, item := range truth {
+			pattern[i] = random.Float64() < item.Probability(theta)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	result := Calibrate(len(truth), patterns, CalibrationOptions{Model: MODEL_2PL})
+	if !result.Converged {
+		t.Fatalf("Calibrate did not converge in %d iterations", result.Iterations)
+	}
+	for i, item := range result.Items {
+		if math.Abs(item.B-truth[i].B) > 0.35 {
+			t.Errorf("item %d has difficulty %v, want about %v", i, item.B, truth[i].B)
+		}
+		if item.C != 0 {
+			t.Errorf("item %d has guessing %v under 2PL", i, item.C)
+		}
+		if i > 0 && item.B <= result.Items[i-1].B {
+			t.Errorf("item %d is easier than item %d", i, i-1)
+		}
+	}
+}
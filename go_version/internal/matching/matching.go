@@ -0,0 +1,161 @@
+// Package matching scores how well a student fits a job posting. Every factor
+// scores 0 to 1 and explains itself, factors the posting doesn't ask for (no
+// wanted majors, no skills, ...) are left out rather than scored as misses.
+package matching
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_version/internal/models"
+)
+
+const (
+	FACTOR_INTERESTS  = "interests"
+	FACTOR_MAJOR      = "major"
+	FACTOR_SKILLS     = "skills"
+	FACTOR_CITY       = "city"
+	FACTOR_GRADUATION = "graduation"
+)
+
+// Factor weights, they are renormalized over the factors that apply
+var weights = map[string]float64{
+	FACTOR_INTERESTS:  0.35,
+	FACTOR_MAJOR:      0.25,
+	FACTOR_SKILLS:     0.25,
+	FACTOR_CITY:       0.10,
+	FACTOR_GRADUATION: 0.05,
+}
+
+type Student struct {
+	Major          string
+	City           string
+	GraduationYear string
+	Interests      []string
+
+	// Skill level per exam category, 0 to 1
+	Skills map[string]float64
+}
+
+type Posting struct {
+	City           string
+	Majors         []string
+	Interests      []string
+	Categories     []string
+	EmploymentType string
+	Deadline       time.Time
+}
+
+type Result struct {
+	// 0 to 100
+	Score   float64
+	Reasons []models.MatchReason
+}
+
+// Score matches a student against a posting.
+func Score(student Student, posting Posting, now time.Time) Result {
+	reasons := []models.MatchReason{}
+	add := func(factor string, score float64, detail string) {
+		reasons = append(reasons, models.MatchReason{Factor: factor, Score: round(score, 2), Weight: weights[factor], Detail: detail})
+	}
+
+	if len(posting.Interests) != 0 {
+		matched := overlap(student.Interests, posting.Interests)
+		add(FACTOR_INTERESTS, float64(matched)/float64(len(posting.Interests)),
+			fmt.Sprintf("Matches %d of %d interests", matched, len(posting.Interests)))
+	}
+
+	if len(posting.Majors) != 0 {
+		if student.Major != "" && overlap([]string{student.Major}, posting.Majors) == 1 {
+			add(FACTOR_MAJOR, 1, student.Major+" is one of the wanted majors")
+		} else {
+			add(FACTOR_MAJOR, 0, "Major isn't one of the wanted majors")
+		}
+	}
+
+	if len(posting.Categories) != 0 {
+		total, rated := 0.0, 0
+		for _, category := range posting.Categories {
+			if level, ok := student.Skills[category]; ok {
+				total += level
+				rated++
+			}
+		}
+		level := total / float64(len(posting.Categories))
+		detail := fmt.Sprintf("Rated in %d of %d required skills", rated, len(posting.Categories))
+		if rated != 0 {
+			detail += fmt.Sprintf(", %d%% skill level", int(math.Round(level*100)))
+		}
+		add(FACTOR_SKILLS, level, detail)
+	}
+
+	if posting.City != "" && student.City != "" {
+		if strings.EqualFold(strings.TrimSpace(student.City), strings.TrimSpace(posting.City)) {
+			add(FACTOR_CITY, 1, "Lives in "+posting.City)
+		} else {
+			add(FACTOR_CITY, 0, "Lives outside "+posting.City)
+		}
+	}
+
+	if year, err := strconv.Atoi(strings.TrimSpace(student.GraduationYear)); err == nil {
+		score, detail := graduationFit(year, posting, now)
+		add(FACTOR_GRADUATION, score, detail)
+	}
+
+	total_weight, weighted := 0.0, 0.0
+	for _, reason := range reasons {
+		total_weight += reason.Weight
+		weighted += reason.Weight * reason.Score
+	}
+	if total_weight == 0 {
+		return Result{Score: 0, Reasons: reasons}
+	}
+	return Result{Score: round(100*weighted/total_weight, 1), Reasons: reasons}
+}
+
+// graduationFit favors current students for internships and part-time roles,
+// and graduates (or soon to be) for full-time and contract ones.
+func graduationFit(year int, posting Posting, now time.Time) (float64, string) {
+	start := now
+	if posting.Deadline.After(now) {
+		start = posting.Deadline
+	}
+
+	switch posting.EmploymentType {
+	case models.EMPLOYMENT_TYPE_INTERNSHIP, models.EMPLOYMENT_TYPE_PART_TIME:
+		if year >= start.Year() {
+			return 1, fmt.Sprintf("Still studying, graduates in %d", year)
+		}
+		return 0.3, fmt.Sprintf("Graduated in %d", year)
+	default:
+		if year <= start.Year()+1 {
+			return 1, fmt.Sprintf("Available after graduating in %d", year)
+		}
+		return 0.4, fmt.Sprintf("Graduates in %d", year)
+	}
+}
+
+// overlap counts the wanted values the student has, ignoring case.
+func overlap(have, wanted []string) int {
+	normalized := make([]string, 0, len(have))
+	for _, value := range have {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(value)))
+	}
+
+	count := 0
+	for _, value := range wanted {
+		if slices.Contains(normalized, strings.ToLower(strings.TrimSpace(value))) {
+			count++
+		}
+	}
+	return count
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
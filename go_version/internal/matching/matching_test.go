@@ -0,0 +1,142 @@
+package matching
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"go_version/internal/models"
+)
+
+func TestScore(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	type factor struct {
+		name   string
+		score  float64
+		detail string
+	}
+	tests := []struct {
+		name    string
+		student Student
+		posting Posting
+		score   float64
+		factors []factor
+	}{
+		{
+			name: "fits everything asked",
+			student: Student{
+				Major: "Computer Science", City: "Ramallah", GraduationYear: "2026",
+				Interests: []string{"Go", "Docker", "Music"},
+				Skills:    map[string]float64{models.CATEGORY_BACKEND: 0.8},
+			},
+			posting: Posting{
+				City: "Ramallah", Majors: []string{"Computer Science"}, Interests: []string{"go", "docker"},
+				Categories: []string{models.CATEGORY_BACKEND}, EmploymentType: models.EMPLOYMENT_TYPE_FULL_TIME,
+			},
+			score: 95,
+			factors: []factor{
+				{FACTOR_INTERESTS, 1, "Matches 2 of 2 interests"},
+				{FACTOR_MAJOR, 1, "Computer Science is one of the wanted majors"},
+				{FACTOR_SKILLS, 0.8, "Rated in 1 of 1 required skills, 80% skill level"},
+				{FACTOR_CITY, 1, "Lives in Ramallah"},
+				{FACTOR_GRADUATION, 1, "Available after graduating in 2026"},
+			},
+		},
+		{
+			name:    "unknown fields are left out",
+			student: Student{Major: "Computer Engineering", Interests: []string{"Go", "SQL"}},
+			posting: Posting{City: "Ramallah", Majors: []string{"Computer Science"}, Interests: []string{"go", "docker", "kubernetes", "sql"}},
+			score:   29.2,
+			factors: []factor{
+				{FACTOR_INTERESTS, 0.5, "Matches 2 of 4 interests"},
+				{FACTOR_MAJOR, 0, "Major isn't one of the wanted majors"},
+			},
+		},
+		{
+			name:    "nothing to match on",
+			student: Student{Major: "Computer Science"},
+			posting: Posting{},
+			score:   0,
+			factors: []factor{},
+		},
+		{
+			name:    "skills averaged over the required ones",
+			student: Student{Skills: map[string]float64{models.CATEGORY_BACKEND: 0.6}},
+			posting: Posting{Categories: []string{models.CATEGORY_BACKEND, models.CATEGORY_FRONTEND}},
+			score:   30,
+			factors: []factor{{FACTOR_SKILLS, 0.3, "Rated in 1 of 2 required skills, 30% skill level"}},
+		},
+		{
+			name:    "no rated skills",
+			student: Student{},
+			posting: Posting{Categories: []string{models.CATEGORY_FRONTEND}},
+			score:   0,
+			factors: []factor{{FACTOR_SKILLS, 0, "Rated in 0 of 1 required skills"}},
+		},
+		{
+			name:    "city ignores case and spaces",
+			student: Student{City: "  ramallah "},
+			posting: Posting{City: "Ramallah"},
+			score:   100,
+			factors: []factor{{FACTOR_CITY, 1, "Lives in Ramallah"}},
+		},
+		{
+			name:    "graduates applying to an internship",
+			student: Student{GraduationYear: "2024"},
+			posting: Posting{EmploymentType: models.EMPLOYMENT_TYPE_INTERNSHIP},
+			score:   30,
+			factors: []factor{{FACTOR_GRADUATION, 0.3, "Graduated in 2024"}},
+		},
+		{
+			name:    "students applying part-time",
+			student: Student{GraduationYear: "2027"},
+			posting: Posting{EmploymentType: models.EMPLOYMENT_TYPE_PART_TIME},
+			score:   100,
+			factors: []factor{{FACTOR_GRADUATION, 1, "Still studying, graduates in 2027"}},
+		},
+		{
+			name:    "full-time years before graduating",
+			student: Student{GraduationYear: "2030"},
+			posting: Posting{EmploymentType: models.EMPLOYMENT_TYPE_FULL_TIME},
+			score:   40,
+			factors: []factor{{FACTOR_GRADUATION, 0.4, "Graduates in 2030"}},
+		},
+		{
+			name:    "full-time counts from a later deadline",
+			student: Student{GraduationYear: "2028"},
+			posting: Posting{EmploymentType: models.EMPLOYMENT_TYPE_FULL_TIME, Deadline: time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC)},
+			score:   100,
+			factors: []factor{{FACTOR_GRADUATION, 1, "Available after graduating in 2028"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Score(test.student, test.posting, now)
+			if math.Abs(got.Score-test.score) > 1e-9 {
+				t.Errorf("Score() = %v, want %v", got.Score, test.score)
+			}
+			if len(got.Reasons) != len(test.factors) {
+				t.Fatalf("Score() gave %d reasons, want %d: %+v", len(got.Reasons), len(test.factors), got.Reasons)
+			}
+			for i, want := range test.factors {
+				reason := got.Reasons[i]
+				if reason.Factor != want.name || math.Abs(reason.Score-want.score) > 1e-9 || reason.Detail != want.detail {
+					t.Errorf("reason %d = %+v, want %s scoring %v with %q", i, reason, want.name, want.score, want.detail)
+				}
+				if reason.Weight != weights[want.name] {
+					t.Errorf("reason %d has weight %v, want %v", i, reason.Weight, weights[want.name])
+				}
+			}
+		})
+	}
+}
+
+func TestWeightsSumToOne(t *testing.T) {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("weights sum to %v, want 1", total)
+	}
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const ACHIEVEMENTS_COLLECTION = "achievements"
+const ACHIEVEMENT_DEFINITIONS_COLLECTION = "achievementdefinitions"
+const ACHIEVEMENT_EVENTS_COLLECTION = "achievementevents"
+
+// Domain events achievements are evaluated against
+const (
+	EVENT_EXAM_COMPLETED      = "exam_completed"
+	EVENT_PRACTICE_ANSWERED   = "practice_answered"
+	EVENT_CHALLENGE_COMPLETED = "challenge_completed"
+)
+
+const (
+	ACHIEVEMENT_AGGREGATE_COUNT = "count"
+	ACHIEVEMENT_AGGREGATE_SUM   = "sum"
+	ACHIEVEMENT_AGGREGATE_MAX   = "max"
+)
+
+const (
+	CONDITION_OP_EQ  = "eq"
+	CONDITION_OP_NE  = "ne"
+	CONDITION_OP_GT  = "gt"
+	CONDITION_OP_GTE = "gte"
+	CONDITION_OP_LT  = "lt"
+	CONDITION_OP_LTE = "lte"
+)
+
+func GetValidAchievementEvents() []string {
+	Events := []string{EVENT_EXAM_COMPLETED, EVENT_PRACTICE_ANSWERED, EVENT_CHALLENGE_COMPLETED}
+	return Events
+}
+
+// Achievement is a badge unlocked by a user, same shape as the Node model.
+type Achievement struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	UserID      bson.ObjectID `bson:"userId" json:"userId"`
+	Type        string        `bson:"type" json:"type"`
+	Title       string        `bson:"title" json:"title"`
+	Description string        `bson:"description" json:"description"`
+	Icon        string        `bson:"icon" json:"icon"`
+	UnlockedAt  bson.DateTime `bson:"unlockedAt" json:"unlockedAt"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+
+	// Version (__v)
+	Version int32 `bson:"__v,omitempty" json:"-"`
+}
+
+// AchievementDefinition is a badge rule: the events of one type matching all
+// conditions are aggregated (counted, or a payload field summed or maxed) and
+// the badge unlocks once the result reaches the threshold.
+type AchievementDefinition struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	Type        string `bson:"type" json:"type"`
+	Title       string `bson:"title" json:"title"`
+	Description string `bson:"description" json:"description"`
+	Icon        string `bson:"icon" json:"icon"`
+
+	// Rule
+	Event      string                 `bson:"event" json:"event"`
+	Conditions []AchievementCondition `bson:"conditions" json:"conditions"`
+	Aggregate  string                 `bson:"aggregate" json:"aggregate"`
+	Field      string                 `bson:"field,omitempty" json:"field,omitempty"`
+	Threshold  float64                `bson:"threshold" json:"threshold"`
+
+	IsActive  bool  `bson:"isActive" json:"isActive"`
+	SortOrder int32 `bson:"sortOrder" json:"sortOrder"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
+
+// AchievementCondition compares a field of the event payload to a value.
+type AchievementCondition struct {
+	Field string `bson:"field" json:"field" validate:"required,max=50"`
+	Op    string `bson:"op" json:"op" validate:"required,oneof=eq ne gt gte lt lte"`
+	Value any    `bson:"value" json:"value"`
+}
+
+// AchievementEvent is a domain event as recorded for achievement evaluation.
+// EventID is derived from the source record so an event is only counted once.
+type AchievementEvent struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	EventID    string        `bson:"eventId" json:"eventId"`
+	UserID     bson.ObjectID `bson:"userId" json:"userId"`
+	Type       string        `bson:"type" json:"type"`
+	Payload    bson.M        `bson:"payload" json:"payload"`
+	OccurredAt bson.DateTime `bson:"occurredAt" json:"occurredAt"`
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const APPLICATIONS_COLLECTION = "applications"
+
+const EVENT_APPLICATION_STAGE_CHANGED = "application_stage_changed"
+
+// Default pipeline stages, companies can rename, reorder and add their own
+const (
+	STAGE_APPLIED   = "applied"
+	STAGE_SCREENING = "screening"
+	STAGE_INTERVIEW = "interview"
+	STAGE_OFFER     = "offer"
+	STAGE_REJECTED  = "rejected"
+)
+
+// Statuses students see, stages map onto them so internal stage names stay
+// private to the company
+const (
+	APPLICATION_STATUS_SUBMITTED    = "submitted"
+	APPLICATION_STATUS_IN_REVIEW    = "in_review"
+	APPLICATION_STATUS_INTERVIEWING = "interviewing"
+	APPLICATION_STATUS_OFFERED      = "offered"
+	APPLICATION_STATUS_NOT_SELECTED = "not_selected"
+	APPLICATION_STATUS_WITHDRAWN    = "withdrawn"
+)
+
+func GetValidApplicationStatuses() []string {
+	Statuses := []string{APPLICATION_STATUS_SUBMITTED, APPLICATION_STATUS_IN_REVIEW, APPLICATION_STATUS_INTERVIEWING, APPLICATION_STATUS_OFFERED, APPLICATION_STATUS_NOT_SELECTED}
+	return Statuses
+}
+
+type PipelineStage struct {
+	Key           string `bson:"key" json:"key" validate:"required,min=2,max=30"`
+	Name          string `bson:"name" json:"name" validate:"required,min=2,max=50"`
+	StudentStatus string `bson:"studentStatus" json:"studentStatus" validate:"required,oneof=submitted in_review interviewing offered not_selected"`
+}
+
+func DefaultPipelineStages() []PipelineStage {
+	Stages := []PipelineStage{
+		{Key: STAGE_APPLIED, Name: "Applied", StudentStatus: APPLICATION_STATUS_SUBMITTED},
+		{Key: STAGE_SCREENING, Name: "Screening", StudentStatus: APPLICATION_STATUS_IN_REVIEW},
+		{Key: STAGE_INTERVIEW, Name: "Interview", StudentStatus: APPLICATION_STATUS_INTERVIEWING},
+		{Key: STAGE_OFFER, Name: "Offer", StudentStatus: APPLICATION_STATUS_OFFERED},
+		{Key: STAGE_REJECTED, Name: "Rejected", StudentStatus: APPLICATION_STATUS_NOT_SELECTED},
+	}
+	return Stages
+}
+
+// Application links a student to a job posting and tracks it through the
+// posting's pipeline.
+type Application struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	PostingID bson.ObjectID `bson:"postingId" json:"postingId"`
+	CompanyID bson.ObjectID `bson:"companyId" json:"companyId"`
+	StudentID bson.ObjectID `bson:"studentId" json:"studentId"`
+
+	CoverLetter string `bson:"coverLetter,omitempty" json:"coverLetter,omitempty"`
+	ResumeURL   string `bson:"resumeUrl,omitempty" json:"resumeUrl,omitempty"`
+
+	Stage         string        `bson:"stage" json:"stage"`
+	StudentStatus string        `bson:"studentStatus" json:"studentStatus"`
+	History       []StageChange `bson:"history" json:"history"`
+
+	// Private to the company
+	Notes  []ApplicationNote `bson:"notes,omitempty" json:"notes"`
+	Rating int32             `bson:"rating,omitempty" json:"rating,omitempty"`
+
+	WithdrawnAt bson.DateTime `bson:"withdrawnAt,omitempty" json:"withdrawnAt,omitempty"`
+
+	// Events not yet relayed to the outbox
+	PendingEvents []DomainEvent `bson:"pendingEvents,omitempty" json:"-"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
+
+type StageChange struct {
+	From          string        `bson:"from,omitempty" json:"from,omitempty"`
+	To            string        `bson:"to" json:"to"`
+	StudentStatus string        `bson:"studentStatus" json:"studentStatus"`
+	ChangedBy     bson.ObjectID `bson:"changedBy" json:"changedBy"`
+	ChangedAt     bson.DateTime `bson:"changedAt" json:"changedAt"`
+}
+
+type ApplicationNote struct {
+	ID        bson.ObjectID `bson:"_id" json:"_id"`
+	AuthorID  bson.ObjectID `bson:"authorId" json:"authorId"`
+	Content   string        `bson:"content" json:"content"`
+	CreatedAt bson.DateTime `bson:"createdAt" json:"createdAt"`
+}
+
+func (a *Application) IsWithdrawn() bool {
+	return a.StudentStatus == APPLICATION_STATUS_WITHDRAWN
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const CERTIFICATES_COLLECTION = "certificates"
+
+const (
+	CERTIFICATE_STATUS_VALID   = "valid"
+	CERTIFICATE_STATUS_REVOKED = "revoked"
+)
+
+// Certificate proves a passing exam score. The certified fields are signed,
+// Signature is checked against them on every verification.
+type Certificate struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"-"`
+
+	// Public certificate ID, e.g. TP-7KQ2-M4XD-9PLA-WC3E
+	Code string `bson:"code" json:"code"`
+
+	UserID    bson.ObjectID `bson:"userId" json:"userId"`
+	AttemptID bson.ObjectID `bson:"attemptId" json:"attemptId"`
+	FullName  string        `bson:"fullName" json:"fullName"`
+	Category  string        `bson:"category" json:"category"`
+	Score     int32         `bson:"score" json:"score"`
+	IssuedAt  bson.DateTime `bson:"issuedAt" json:"issuedAt"`
+
+	KeyID     string `bson:"keyId" json:"keyId"`
+	Signature string `bson:"signature" json:"signature"`
+
+	Status           string        `bson:"status" json:"status"`
+	RevokedAt        bson.DateTime `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	RevokedBy        bson.ObjectID `bson:"revokedBy,omitempty" json:"-"`
+	RevocationReason string        `bson:"revocationReason,omitempty" json:"revocationReason,omitempty"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
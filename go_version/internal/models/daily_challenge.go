@@ -0,0 +1,152 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const DAILY_CHALLENGES_COLLECTION = "dailychallenges"
+const USER_STREAKS_COLLECTION = "userstreaks"
+
+// Streak freeze tokens: one is earned every STREAK_FREEZE_EARN_EVERY days of
+// streak, up to MAX_STREAK_FREEZES held at once. A token covers one missed day.
+const (
+	STREAK_FREEZE_EARN_EVERY = 7
+	MAX_STREAK_FREEZES       = 2
+)
+
+type DailyChallenge struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	UserID bson.ObjectID `bson:"userId" json:"userId"`
+
+	// Midnight UTC of the calendar day, kept for the Node model. The day
+	// itself is the user's local date in Timezone.
+	Date     bson.DateTime `bson:"date" json:"date"`
+	Day      string        `bson:"day" json:"day"`
+	Timezone string        `bson:"timezone" json:"timezone"`
+
+	QuestionID bson.ObjectID `bson:"questionId" json:"questionId"`
+	Revision   int32         `bson:"revision,omitempty" json:"revision,omitempty"`
+	RevisionID bson.ObjectID `bson:"revisionId,omitempty" json:"revisionId,omitempty"`
+	Category   string        `bson:"category" json:"category"`
+	Difficulty string        `bson:"difficulty" json:"difficulty"`
+
+	Completed   bool          `bson:"completed" json:"completed"`
+	UserAnswer  string        `bson:"userAnswer,omitempty" json:"userAnswer,omitempty"`
+	IsCorrect   bool          `bson:"isCorrect,omitempty" json:"isCorrect"`
+	CompletedAt bson.DateTime `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+
+	// Domain events waiting to be relayed to the outbox
+	PendingEvents []DomainEvent `bson:"pendingEvents,omitempty" json:"-"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+
+	// Version (__v)
+	Version int32 `bson:"__v,omitempty" json:"-"`
+}
+
+type UserStreak struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	UserID                   bson.ObjectID `bson:"userId" json:"userId"`
+	CurrentStreak            int32         `bson:"currentStreak" json:"currentStreak"`
+	LongestStreak            int32         `bson:"longestStreak" json:"longestStreak"`
+	LastCompletedDate        bson.DateTime `bson:"lastCompletedDate,omitempty" json:"lastCompletedDate"`
+	TotalChallengesCompleted int32         `bson:"totalChallengesCompleted" json:"totalChallengesCompleted"`
+
+	// Local date (YYYY-MM-DD) of the last completion and the timezone days are counted in
+	LastCompletedDay string `bson:"lastCompletedDay,omitempty" json:"lastCompletedDay,omitempty"`
+	Timezone         string `bson:"timezone,omitempty" json:"timezone"`
+
+	// Streak freezes
+	FreezeTokens int32    `bson:"freezeTokens" json:"freezeTokens"`
+	FrozenDays   []string `bson:"frozenDays,omitempty" json:"frozenDays"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+
+	// Version (__v)
+	Version int32 `bson:"__v,omitempty" json:"-"`
+}
+
+// DaysBetween returns the number of calendar days from one YYYY-MM-DD date to
+// another, or false if either can't be parsed.
+func DaysBetween(from, to string) (int, bool) {
+	from_day, err := time.Parse(time.DateOnly, from)
+	if err != nil {
+		return 0, false
+	}
+	to_day, err := time.Parse(time.DateOnly, to)
+	if err != nil {
+		return 0, false
+	}
+	return int(to_day.Sub(from_day).Hours() / 24), true
+}
+
+// missedDays lists the days strictly between the last completion and day.
+func (s *UserStreak) missedDays(day string) []string {
+	gap, ok := DaysBetween(s.LastCompletedDay, day)
+	if !ok || gap <= 1 {
+		return nil
+	}
+	start, _ := time.Parse(time.DateOnly, s.LastCompletedDay)
+	missed := make([]string, 0, gap-1)
+	for i := 1; i < gap; i++ {
+		missed = append(missed, start.AddDate(0, 0, i).Format(time.DateOnly))
+	}
+	return missed
+}
+
+// RecordCompletion counts a challenge completed on day. Missed days since the
+// last completion are covered by freeze tokens when there are enough of them,
+// otherwise the streak starts over. Returns the days that were frozen, or
+// false without counting anything when day isn't after the last completion.
+func (s *UserStreak) RecordCompletion(day string) ([]string, bool) {
+	if s.LastCompletedDay != "" {
+		if gap, ok := DaysBetween(s.LastCompletedDay, day); !ok || gap <= 0 {
+			return nil, false
+		}
+	}
+
+	frozen := []string{}
+	switch missed := s.missedDays(day); {
+	case s.LastCompletedDay == "" || s.CurrentStreak == 0:
+		s.CurrentStreak = 1
+	case len(missed) == 0:
+		s.CurrentStreak++
+	case int(s.FreezeTokens) >= len(missed):
+		s.FreezeTokens -= int32(len(missed))
+		s.FrozenDays = append(s.FrozenDays, missed...)
+		frozen = missed
+		s.CurrentStreak++
+	default:
+		s.CurrentStreak = 1
+	}
+
+	if s.CurrentStreak%STREAK_FREEZE_EARN_EVERY == 0 && s.FreezeTokens < MAX_STREAK_FREEZES {
+		s.FreezeTokens++
+	}
+	s.LongestStreak = max(s.LongestStreak, s.CurrentStreak)
+	s.TotalChallengesCompleted++
+	s.LastCompletedDay = day
+
+	return frozen, true
+}
+
+// EffectiveStreak is the streak as it stands on today, without modifying it:
+// still alive if today's challenge can continue it, possibly using freezes.
+func (s *UserStreak) EffectiveStreak(today string) int32 {
+	if s.LastCompletedDay == "" {
+		return 0
+	}
+	// The missed days up to and including yesterday must be covered
+	if int(s.FreezeTokens) >= len(s.missedDays(today)) {
+		return s.CurrentStreak
+	}
+	return 0
+}
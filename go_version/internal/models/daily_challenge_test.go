@@ -0,0 +1,275 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRecordCompletion(t *testing.T) {
+	tests := []struct {
+		name    string
+		streak  UserStreak
+		day     string
+		ok      bool
+		current int32
+		longest int32
+		tokens  int32
+		frozen  []string
+	}{
+		{
+			name:    "first completion",
+			streak:  UserStreak{},
+			day:     "2026-03-02",
+			ok:      true,
+			current: 1, longest: 1,
+			frozen: []string{},
+		},
+		{
+			name:    "the next day continues the streak",
+			streak:  UserStreak{CurrentStreak: 3, LongestStreak: 3, LastCompletedDay: "2026-03-01"},
+			day:     "2026-03-02",
+			ok:      true,
+			current: 4, longest: 4,
+			frozen: []string{},
+		},
+		{
+			name:    "across the end of the month",
+			streak:  UserStreak{CurrentStreak: 3, LongestStreak: 3, LastCompletedDay: "2026-02-28"},
+			day:     "2026-03-01",
+			ok:      true,
+			current: 4, longest: 4,
+			frozen: []string{},
+		},
+		{
+			name:    "once a day",
+			streak:  UserStreak{CurrentStreak: 3, LongestStreak: 3, LastCompletedDay: "2026-03-02"},
+			day:     "2026-03-02",
+			ok:      false,
+			current: 3, longest: 3,
+		},
+		{
+			name:    "a day already behind the last completion",
+			streak:  UserStreak{CurrentStreak: 3, LongestStreak: 3, LastCompletedDay: "2026-03-02"},
+			day:     "2026-03-01",
+			ok:      false,
+			current: 3, longest: 3,
+		},
+		{
+			name:    "not a date",
+			streak:  UserStreak{CurrentStreak: 3, LongestStreak: 3, LastCompletedDay: "2026-03-02"},
+			day:     "tomorrow",
+			ok:      false,
+			current: 3, longest: 3,
+		},
+		{
+			name:    "a missed day without freezes starts over",
+			streak:  UserStreak{CurrentStreak: 5, LongestStreak: 5, LastCompletedDay: "2026-03-01"},
+			day:     "2026-03-03",
+			ok:      true,
+			current: 1, longest: 5,
+			frozen: []string{},
+		},
+		{
+			name:    "a freeze covers a missed day",
+			streak:  UserStreak{CurrentStreak: 5, LongestStreak: 5, LastCompletedDay: "2026-03-01", FreezeTokens: 1},
+			day:     "2026-03-03",
+			ok:      true,
+			current: 6, longest: 6,
+			frozen: []string{"2026-03-02"},
+		},
+		{
+			name:    "two freezes cover two missed days",
+			streak:  UserStreak{CurrentStreak: 5, LongestStreak: 5, LastCompletedDay: "2026-03-01", FreezeTokens: 2},
+			day:     "2026-03-04",
+			ok:      true,
+			current: 6, longest: 6,
+			frozen: []string{"2026-03-02", "2026-03-03"},
+		},
+		{
+			name:    "freezes are kept when too few to cover the gap",
+			streak:  UserStreak{CurrentStreak: 5, LongestStreak: 8, LastCompletedDay: "2026-03-01", FreezeTokens: 2},
+			day:     "2026-03-05",
+			ok:      true,
+			current: 1, longest: 8, tokens: 2,
+			frozen: []string{},
+		},
+		{
+			name:    "a week of streak earns a freeze",
+			streak:  UserStreak{CurrentStreak: 6, LongestStreak: 6, LastCompletedDay: "2026-03-01"},
+			day:     "2026-03-02",
+			ok:      true,
+			current: 7, longest: 7, tokens: 1,
+			frozen: []string{},
+		},
+		{
+			name:    "no more than two freezes are held",
+			streak:  UserStreak{CurrentStreak: 13, LongestStreak: 13, LastCompletedDay: "2026-03-01", FreezeTokens: MAX_STREAK_FREEZES},
+			day:     "2026-03-02",
+			ok:      true,
+			current: 14, longest: 14, tokens: MAX_STREAK_FREEZES,
+			frozen: []string{},
+		},
+		{
+			name:    "a freeze spent on the seventh day is earned back",
+			streak:  UserStreak{CurrentStreak: 6, LongestStreak: 6, LastCompletedDay: "2026-03-01", FreezeTokens: 1},
+			day:     "2026-03-03",
+			ok:      true,
+			current: 7, longest: 7, tokens: 1,
+			frozen: []string{"2026-03-02"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streak := test.streak
+			frozen, ok := streak.RecordCompletion(test.day)
+			if ok != test.ok {
+				t.Fatalf("RecordCompletion(%q) = %v, want %v", test.day, ok, test.ok)
+			}
+			if streak.CurrentStreak != test.current || streak.LongestStreak != test.longest || streak.FreezeTokens != test.tokens {
+				t.Errorf("streak is %d, longest %d with %d freezes, want %d, longest %d with %d freezes",
+					streak.CurrentStreak, streak.LongestStreak, streak.FreezeTokens, test.current, test.longest, test.tokens)
+			}
+			if !slices.Equal(frozen, test.frozen) {
+				t.Errorf("RecordCompletion(%q) froze %q, want %q", test.day, frozen, test.frozen)
+			}
+
+			want_total, want_day := test.streak.TotalChallengesCompleted, test.streak.LastCompletedDay
+			if ok {
+				want_total, want_day = want_total+1, test.day
+			}
+			if streak.TotalChallengesCompleted != want_total || streak.LastCompletedDay != want_day {
+				t.Errorf("completed %d, last on %q, want %d, last on %q", streak.TotalChallengesCompleted, streak.LastCompletedDay, want_total, want_day)
+			}
+			if len(frozen) != 0 && !slices.Equal(streak.FrozenDays[len(streak.FrozenDays)-len(frozen):], frozen) {
+				t.Errorf("frozen days are %q, want them to end with %q", streak.FrozenDays, frozen)
+			}
+		})
+	}
+}
+
+func TestRecordCompletionInTimezone(t *testing.T) {
+	location := func(name string) *time.Location {
+		location, err := time.LoadLocation(name)
+		if err != nil {
+			t.Skipf("no timezone data for %s: %s", name, err)
+		}
+		return location
+	}
+	hebron, berlin := location("Asia/Hebron"), location("Europe/Berlin")
+
+	tests := []struct {
+		name        string
+		location    *time.Location
+		completions []time.Time
+		current     int32
+		counted     int
+	}{
+		{
+			name:        "late evening in UTC is the next day in Hebron",
+			location:    hebron,
+			completions: []time.Time{time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), time.Date(2026, 1, 11, 22, 30, 0, 0, time.UTC)},
+			current:     1,
+			counted:     2,
+		},
+		{
+			name:        "two UTC days can be one day in Hebron",
+			location:    hebron,
+			completions: []time.Time{time.Date(2026, 1, 10, 22, 30, 0, 0, time.UTC), time.Date(2026, 1, 11, 21, 30, 0, 0, time.UTC)},
+			current:     1,
+			counted:     1,
+		},
+		{
+			name:        "the same instants are two days in UTC",
+			location:    time.UTC,
+			completions: []time.Time{time.Date(2026, 1, 10, 22, 30, 0, 0, time.UTC), time.Date(2026, 1, 11, 21, 30, 0, 0, time.UTC)},
+			current:     2,
+			counted:     2,
+		},
+		{
+			name:     "a 23 hour day when the clocks go forward",
+			location: berlin,
+			completions: []time.Time{
+				time.Date(2026, 3, 28, 23, 30, 0, 0, berlin),
+				time.Date(2026, 3, 29, 23, 30, 0, 0, berlin),
+				time.Date(2026, 3, 30, 0, 30, 0, 0, berlin),
+			},
+			current: 3,
+			counted: 3,
+		},
+		{
+			name:     "a 25 hour day when the clocks go back",
+			location: berlin,
+			completions: []time.Time{
+				time.Date(2026, 10, 25, 0, 30, 0, 0, berlin),
+				time.Date(2026, 10, 25, 23, 30, 0, 0, berlin),
+				time.Date(2026, 10, 26, 0, 30, 0, 0, berlin),
+			},
+			current: 2,
+			counted: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streak := UserStreak{}
+			counted := 0
+			for _, at := range test.completions {
+				if _, ok := streak.RecordCompletion(at.In(test.location).Format(time.DateOnly)); ok {
+					counted++
+				}
+			}
+			if streak.CurrentStreak != test.current || counted != test.counted {
+				t.Errorf("streak is %d after counting %d completions, want %d after %d", streak.CurrentStreak, counted, test.current, test.counted)
+			}
+		})
+	}
+}
+
+func TestEffectiveStreak(t *testing.T) {
+	tests := []struct {
+		name   string
+		streak UserStreak
+		today  string
+		want   int32
+	}{
+		{"never completed", UserStreak{}, "2026-03-02", 0},
+		{"completed today", UserStreak{CurrentStreak: 4, LastCompletedDay: "2026-03-02"}, "2026-03-02", 4},
+		{"completed yesterday", UserStreak{CurrentStreak: 4, LastCompletedDay: "2026-03-01"}, "2026-03-02", 4},
+		{"missed yesterday", UserStreak{CurrentStreak: 4, LastCompletedDay: "2026-02-28"}, "2026-03-02", 0},
+		{"missed yesterday with a freeze", UserStreak{CurrentStreak: 4, LastCompletedDay: "2026-02-28", FreezeTokens: 1}, "2026-03-02", 4},
+		{"two missed days with two freezes", UserStreak{CurrentStreak: 4, LastCompletedDay: "2026-02-27", FreezeTokens: 2}, "2026-03-02", 4},
+		{"three missed days with two freezes", UserStreak{CurrentStreak: 4, LastCompletedDay: "2026-02-26", FreezeTokens: 2}, "2026-03-02", 0},
+		{"across the clocks going forward", UserStreak{CurrentStreak: 4, LastCompletedDay: "2026-03-28"}, "2026-03-29", 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.streak.EffectiveStreak(test.today); got != test.want {
+				t.Errorf("EffectiveStreak(%q) = %d, want %d", test.today, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     int
+		ok       bool
+	}{
+		{"2026-03-01", "2026-03-02", 1, true},
+		{"2026-03-02", "2026-03-01", -1, true},
+		{"2026-02-28", "2026-03-01", 1, true},
+		{"2028-02-28", "2028-03-01", 2, true},
+		{"2026-03-28", "2026-03-30", 2, true},
+		{"2026-10-24", "2026-10-26", 2, true},
+		{"2025-12-31", "2026-01-01", 1, true},
+		{"", "2026-03-01", 0, false},
+		{"2026-03-01", "01/03/2026", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := DaysBetween(test.from, test.to)
+		if got != test.want || ok != test.ok {
+			t.Errorf("DaysBetween(%q, %q) = %d, %v, want %d, %v", test.from, test.to, got, ok, test.want, test.ok)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const EXAM_BLUEPRINTS_COLLECTION = "examblueprints"
+
+// ExamBlueprint describes how an exam paper is put together, questions are
+// drawn per section so every paper built from it has the same shape.
+type ExamBlueprint struct {
+	ID          bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Name        string        `bson:"name" json:"name"`
+	Description string        `bson:"description,omitempty" json:"description,omitempty"`
+
+	// Category recorded on the resulting attempts
+	Category string             `bson:"category" json:"category"`
+	Sections []BlueprintSection `bson:"sections" json:"sections"`
+
+	// Options
+	DurationSeconds int32 `bson:"durationSeconds,omitempty" json:"durationSeconds,omitempty"`
+	ExcludeSeen     bool  `bson:"excludeSeen" json:"excludeSeen"`
+	ShuffleOptions  bool  `bson:"shuffleOptions" json:"shuffleOptions"`
+	IsActive        bool  `bson:"isActive" json:"isActive"`
+
+	// Company sponsoring the exam, it gets to review attempts and their
+	// integrity
+	SponsorID bson.ObjectID `bson:"sponsorId,omitempty" json:"sponsorId,omitzero"`
+
+	CreatedBy bson.ObjectID `bson:"createdBy,omitempty" json:"createdBy"`
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
+
+type BlueprintSection struct {
+	Category   string   `bson:"category" json:"category" validate:"required,oneof=backend frontend"`
+	Difficulty string   `bson:"difficulty,omitempty" json:"difficulty,omitempty" validate:"omitempty,oneof=easy medium hard"`
+	Tags       []string `bson:"tags,omitempty" json:"tags,omitempty" validate:"max=10"`
+	Count      int      `bson:"count" json:"count" validate:"required,min=1,max=50"`
+}
+
+func (s BlueprintSection) Describe() string {
+	parts := []string{s.Category}
+	if s.Difficulty != "" {
+		parts = append(parts, s.Difficulty)
+	}
+	if len(s.Tags) != 0 {
+		parts = append(parts, "tags: "+strings.Join(s.Tags, ","))
+	}
+	return strconv.Itoa(s.Count) + "x " + strings.Join(parts, " ")
+}
+
+func (b *ExamBlueprint) TotalQuestions() int {
+	total := 0
+	for _, section := range b.Sections {
+		total += section.Count
+	}
+	return total
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const EXAM_SESSIONS_COLLECTION = "examsessions"
+
+const (
+	EXAM_SESSION_IN_PROGRESS = "in_progress"
+	EXAM_SESSION_SUBMITTED   = "submitted"
+)
+
+const (
+	EXAM_MODE_FIXED    = "fixed"
+	EXAM_MODE_ADAPTIVE = "adaptive"
+)
+
+type ExamSession struct {
+	ID bson.ObjectID `bson:"_id,omitempty"`
+
+	UserID     bson.ObjectID         `bson:"userId"`
+	Category   string                `bson:"category"`
+	Difficulty string                `bson:"difficulty,omitempty"`
+	Status     string                `bson:"status"`
+	Mode       string                `bson:"mode,omitempty"`
+	Questions  []ExamSessionQuestion `bson:"questions"`
+
+	// Only set for adaptive sessions
+	Adaptive *AdaptiveState `bson:"adaptive,omitempty"`
+
+	// Assembly, the seed together with the start time reproduces the paper
+	BlueprintID    bson.ObjectID      `bson:"blueprintId,omitempty"`
+	Sections       []BlueprintSection `bson:"sections"`
+	ExcludeSeen    bool               `bson:"excludeSeen"`
+	ShuffleOptions bool               `bson:"shuffleOptions"`
+	Seed           int64              `bson:"seed"`
+
+	// Timer, the deadline is authoritative and answers past it are rejected
+	DurationSeconds int32         `bson:"durationSeconds"`
+	StartedAt       bson.DateTime `bson:"startedAt"`
+	Deadline        bson.DateTime `bson:"deadline"`
+
+	// Company the blueprint is sponsored by, it may review the attempt
+	SponsorID bson.ObjectID `bson:"sponsorId,omitempty"`
+
+	// Set once the exam page connects its proctoring channel
+	Proctoring *ProctoringState `bson:"proctoring,omitempty"`
+
+	// Submission
+	SubmittedAt   bson.DateTime `bson:"submittedAt,omitempty"`
+	AutoSubmitted bool          `bson:"autoSubmitted,omitempty"`
+	AttemptID     bson.ObjectID `bson:"attemptId,omitempty"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty"`
+}
+
+type ExamSessionQuestion struct {
+	QuestionID bson.ObjectID `bson:"questionId"`
+	Revision   int32         `bson:"revision"`
+	RevisionID bson.ObjectID `bson:"revisionId"`
+
+	// Display order of the revision's options, empty when not shuffled
+	OptionOrder []int32 `bson:"optionOrder,omitempty"`
+	Section     int32   `bson:"section"`
+	Repeated    bool    `bson:"repeated,omitempty"`
+
+	// IRT parameters the adaptive engine used when it picked the question
+	Item *ItemSnapshot `bson:"item,omitempty"`
+
+	// Answer, empty until the student answers
+	Answer     string        `bson:"answer,omitempty"`
+	AnsweredAt bson.DateTime `bson:"answeredAt,omitempty"`
+}
+
+type AdaptiveState struct {
+	Model     string  `bson:"model"`
+	TargetSE  float64 `bson:"targetSE"`
+	MinLength int32   `bson:"minLength"`
+	MaxLength int32   `bson:"maxLength"`
+
+	// Current estimate, updated after every answer
+	Theta   float64           `bson:"theta"`
+	SE      float64           `bson:"se"`
+	History []AbilityEstimate `bson:"history"`
+
+	StopReason string `bson:"stopReason,omitempty"`
+}
+
+type ItemSnapshot struct {
+	A          float64 `bson:"a"`
+	B          float64 `bson:"b"`
+	C          float64 `bson:"c"`
+	Calibrated bool    `bson:"calibrated"`
+}
+
+type AbilityEstimate struct {
+	Theta float64 `bson:"theta" json:"theta"`
+	SE    float64 `bson:"se" json:"se"`
+}
+
+func (s *ExamSession) IsAdaptive() bool {
+	return s.Mode == EXAM_MODE_ADAPTIVE
+}
+
+func (s *ExamSession) IsExpired(now time.Time) bool {
+	return !now.Before(s.Deadline.Time())
+}
+
+func (s *ExamSession) RemainingSeconds(now time.Time) int64 {
+	remaining := int64(s.Deadline.Time().Sub(now).Seconds())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
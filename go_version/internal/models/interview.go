@@ -0,0 +1,76 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const INTERVIEW_SLOTS_COLLECTION = "interviewslots"
+const INTERVIEWS_COLLECTION = "interviews"
+
+const (
+	INTERVIEW_STATUS_SCHEDULED = "scheduled"
+	INTERVIEW_STATUS_CANCELLED = "cancelled"
+)
+
+func GetValidInterviewStatuses() []string {
+	Statuses := []string{INTERVIEW_STATUS_SCHEDULED, INTERVIEW_STATUS_CANCELLED}
+	return Statuses
+}
+
+// InterviewSlot is a time a company is available to interview applicants to
+// one of its postings.
+type InterviewSlot struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	CompanyID bson.ObjectID `bson:"companyId" json:"companyId"`
+	PostingID bson.ObjectID `bson:"postingId" json:"postingId"`
+
+	StartsAt   bson.DateTime `bson:"startsAt" json:"startsAt"`
+	EndsAt     bson.DateTime `bson:"endsAt" json:"endsAt"`
+	Location   string        `bson:"location,omitempty" json:"location,omitempty"`
+	MeetingURL string        `bson:"meetingUrl,omitempty" json:"meetingUrl,omitempty"`
+
+	// Set while an interview holds the slot
+	InterviewID bson.ObjectID `bson:"interviewId,omitempty" json:"-"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
+
+func (s *InterviewSlot) IsBooked() bool {
+	return !s.InterviewID.IsZero()
+}
+
+// Interview is a slot booked by an applicant. UID identifies the calendar
+// event for its whole life, every reschedule or cancellation bumps Sequence
+// so calendar clients replace the event they hold.
+type Interview struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	UID      string `bson:"uid" json:"uid"`
+	Sequence int32  `bson:"sequence" json:"sequence"`
+	Status   string `bson:"status" json:"status"`
+
+	ApplicationID bson.ObjectID `bson:"applicationId" json:"applicationId"`
+	PostingID     bson.ObjectID `bson:"postingId" json:"postingId"`
+	CompanyID     bson.ObjectID `bson:"companyId" json:"companyId"`
+	StudentID     bson.ObjectID `bson:"studentId" json:"studentId"`
+	SlotID        bson.ObjectID `bson:"slotId" json:"slotId"`
+
+	JobTitle    string `bson:"jobTitle" json:"jobTitle"`
+	CompanyName string `bson:"companyName" json:"companyName"`
+
+	StartsAt   bson.DateTime `bson:"startsAt" json:"startsAt"`
+	EndsAt     bson.DateTime `bson:"endsAt" json:"endsAt"`
+	Location   string        `bson:"location,omitempty" json:"location,omitempty"`
+	MeetingURL string        `bson:"meetingUrl,omitempty" json:"meetingUrl,omitempty"`
+
+	CancelledBy        bson.ObjectID `bson:"cancelledBy,omitempty" json:"cancelledBy,omitempty"`
+	CancelledAt        bson.DateTime `bson:"cancelledAt,omitempty" json:"cancelledAt,omitempty"`
+	CancellationReason string        `bson:"cancellationReason,omitempty" json:"cancellationReason,omitempty"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const ITEM_PARAMETERS_COLLECTION = "itemparameters"
+
+// ItemParameters are the IRT parameters of a question, calibrated offline
+// from historical attempts by the calibrate command.
+type ItemParameters struct {
+	ID         bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	QuestionID bson.ObjectID `bson:"questionId" json:"questionId"`
+	Category   string        `bson:"category" json:"category"`
+	Model      string        `bson:"model" json:"model"`
+
+	// Discrimination, difficulty & pseudo-guessing
+	A float64 `bson:"a" json:"a"`
+	B float64 `bson:"b" json:"b"`
+	C float64 `bson:"c" json:"c"`
+
+	Responses    int32         `bson:"responses" json:"responses"`
+	CalibratedAt bson.DateTime `bson:"calibratedAt" json:"calibratedAt"`
+}
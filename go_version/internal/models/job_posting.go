@@ -0,0 +1,97 @@
+package models
+
+import (
+	"slices"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const JOB_POSTINGS_COLLECTION = "jobpostings"
+
+const (
+	EMPLOYMENT_TYPE_FULL_TIME  = "full_time"
+	EMPLOYMENT_TYPE_PART_TIME  = "part_time"
+	EMPLOYMENT_TYPE_INTERNSHIP = "internship"
+	EMPLOYMENT_TYPE_CONTRACT   = "contract"
+)
+
+const (
+	JOB_STATUS_DRAFT     = "draft"
+	JOB_STATUS_PUBLISHED = "published"
+	JOB_STATUS_CLOSED    = "closed"
+)
+
+func GetValidEmploymentTypes() []string {
+	EmploymentTypes := []string{EMPLOYMENT_TYPE_FULL_TIME, EMPLOYMENT_TYPE_PART_TIME, EMPLOYMENT_TYPE_INTERNSHIP, EMPLOYMENT_TYPE_CONTRACT}
+	return EmploymentTypes
+}
+
+func GetValidJobStatuses() []string {
+	Statuses := []string{JOB_STATUS_DRAFT, JOB_STATUS_PUBLISHED, JOB_STATUS_CLOSED}
+	return Statuses
+}
+
+// A draft is published once, a published posting can be closed and a closed
+// one reopened. Postings never go back to draft, students may have seen them.
+var jobStatusTransitions = map[string][]string{
+	JOB_STATUS_DRAFT:     {JOB_STATUS_PUBLISHED},
+	JOB_STATUS_PUBLISHED: {JOB_STATUS_CLOSED},
+	JOB_STATUS_CLOSED:    {JOB_STATUS_PUBLISHED},
+}
+
+// JobPosting is a job or internship offered by a company account.
+type JobPosting struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	// Owning company user, its name is copied for search
+	CompanyID   bson.ObjectID `bson:"companyId" json:"companyId"`
+	CompanyName string        `bson:"companyName" json:"companyName"`
+
+	Title          string   `bson:"title" json:"title"`
+	Description    string   `bson:"description" json:"description"`
+	City           string   `bson:"city" json:"city"`
+	Majors         []string `bson:"majors" json:"majors"`
+	Interests      []string `bson:"interests" json:"interests"`
+	EmploymentType string   `bson:"employmentType" json:"employmentType"`
+
+	// Exam categories the role needs, matched against students' skill ratings
+	Categories []string `bson:"categories,omitempty" json:"categories"`
+
+	Deadline bson.DateTime `bson:"deadline" json:"deadline"`
+
+	// Applicant pipeline, the default stages when empty. Private to the
+	// company, served by its own endpoint
+	Stages []PipelineStage `bson:"stages,omitempty" json:"-"`
+
+	Status      string        `bson:"status" json:"status"`
+	PublishedAt bson.DateTime `bson:"publishedAt,omitempty" json:"publishedAt,omitempty"`
+	ClosedAt    bson.DateTime `bson:"closedAt,omitempty" json:"closedAt,omitempty"`
+
+	// Set when the posting changed and its matches need recomputing
+	MatchesStaleAt    bson.DateTime `bson:"matchesStaleAt,omitempty" json:"-"`
+	MatchesComputedAt bson.DateTime `bson:"matchesComputedAt,omitempty" json:"-"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
+
+func (j *JobPosting) CanTransitionTo(status string) bool {
+	return slices.Contains(jobStatusTransitions[j.Status], status)
+}
+
+func (j *JobPosting) PipelineStages() []PipelineStage {
+	if len(j.Stages) == 0 {
+		return DefaultPipelineStages()
+	}
+	return j.Stages
+}
+
+func (j *JobPosting) FindStage(key string) (PipelineStage, bool) {
+	for _, stage := range j.PipelineStages() {
+		if stage.Key == key {
+			return stage, true
+		}
+	}
+	return PipelineStage{}, false
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const LEADERBOARDS_COLLECTION = "leaderboards"
+const LEADERBOARD_ENTRIES_COLLECTION = "leaderboardentries"
+
+const (
+	LEADERBOARD_WINDOW_WEEKLY   = "weekly"
+	LEADERBOARD_WINDOW_MONTHLY  = "monthly"
+	LEADERBOARD_WINDOW_ALL_TIME = "all"
+)
+
+const (
+	LEADERBOARD_SCOPE_GLOBAL     = "global"
+	LEADERBOARD_SCOPE_UNIVERSITY = "university"
+	LEADERBOARD_SCOPE_MAJOR      = "major"
+)
+
+// Leaderboards over every category use this instead of a category name
+const LEADERBOARD_CATEGORY_ALL = "all"
+
+func GetValidLeaderboardWindows() []string {
+	Windows := []string{LEADERBOARD_WINDOW_WEEKLY, LEADERBOARD_WINDOW_MONTHLY, LEADERBOARD_WINDOW_ALL_TIME}
+	return Windows
+}
+
+// Leaderboard points to the current snapshot of one window, period and
+// category. Snapshots are rebuilt aside and swapped in by changing SnapshotID.
+type Leaderboard struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"-"`
+
+	Key      string `bson:"key" json:"key"`
+	Window   string `bson:"window" json:"window"`
+	Period   string `bson:"period" json:"period"`
+	Category string `bson:"category" json:"category"`
+
+	// Zero for the all-time window
+	PeriodStart bson.DateTime `bson:"periodStart,omitempty" json:"periodStart,omitempty"`
+	PeriodEnd   bson.DateTime `bson:"periodEnd,omitempty" json:"periodEnd,omitempty"`
+
+	SnapshotID bson.ObjectID `bson:"snapshotId" json:"snapshotId"`
+	TotalUsers int64         `bson:"totalUsers" json:"totalUsers"`
+	ComputedAt bson.DateTime `bson:"computedAt" json:"computedAt"`
+
+	// Computed after the period ended, it won't change anymore
+	Final bool `bson:"final" json:"final"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"-"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"-"`
+}
+
+// LeaderboardEntry is one user's standing in a snapshot. Rank is the
+// competition rank (ties share it), Position breaks ties by user id.
+type LeaderboardEntry struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"-"`
+
+	Key        string        `bson:"key" json:"-"`
+	SnapshotID bson.ObjectID `bson:"snapshotId" json:"-"`
+
+	UserID     bson.ObjectID `bson:"userId" json:"userId"`
+	FullName   string        `bson:"fullName" json:"fullName"`
+	University string        `bson:"university,omitempty" json:"university"`
+	Major      string        `bson:"major,omitempty" json:"major"`
+
+	Rank     int64 `bson:"rank" json:"rank"`
+	Position int64 `bson:"position" json:"-"`
+
+	Points        int64 `bson:"points" json:"points"`
+	TotalAttempts int32 `bson:"totalAttempts" json:"totalAttempts"`
+	AverageScore  int32 `bson:"averageScore" json:"averageScore"`
+	HighestScore  int32 `bson:"highestScore" json:"highestScore"`
+	Accuracy      int32 `bson:"accuracy" json:"accuracy"`
+
+	CreatedAt bson.DateTime `bson:"createdAt" json:"-"`
+}
+
+func LeaderboardKey(window, period, category string) string {
+	return window + ":" + period + ":" + category
+}
+
+// LeaderboardPeriod names the period of the window t falls in, ISO weeks
+// (2026-W07) and calendar months (2026-02) in UTC, and returns its bounds.
+func LeaderboardPeriod(window string, t time.Time) (string, time.Time, time.Time) {
+	t = t.UTC()
+	switch window {
+	case LEADERBOARD_WINDOW_WEEKLY:
+		year, week := t.ISOWeek()
+		start := isoWeekStart(year, week)
+		return fmt.Sprintf("%04d-W%02d", year, week), start, start.AddDate(0, 0, 7)
+	case LEADERBOARD_WINDOW_MONTHLY:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start.Format("2006-01"), start, start.AddDate(0, 1, 0)
+	default:
+		return LEADERBOARD_WINDOW_ALL_TIME, time.Time{}, time.Time{}
+	}
+}
+
+// ParseLeaderboardPeriod returns the bounds of a period named by LeaderboardPeriod.
+func ParseLeaderboardPeriod(window, period string) (time.Time, time.Time, bool) {
+	switch window {
+	case LEADERBOARD_WINDOW_WEEKLY:
+		var year, week int
+		if _, err := fmt.Sscanf(period, "%04d-W%02d", &year, &week); err != nil || week < 1 || week > 53 {
+			return time.Time{}, time.Time{}, false
+		}
+		start := isoWeekStart(year, week)
+		if y, w := start.ISOWeek(); y != year || w != week {
+			return time.Time{}, time.Time{}, false
+		}
+		return start, start.AddDate(0, 0, 7), true
+	case LEADERBOARD_WINDOW_MONTHLY:
+		start, err := time.Parse("2006-01", period)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		return start, start.AddDate(0, 1, 0), true
+	default:
+		return time.Time{}, time.Time{}, period == LEADERBOARD_WINDOW_ALL_TIME
+	}
+}
+
+// isoWeekStart is the Monday of an ISO week, week 1 holds January 4th.
+func isoWeekStart(year, week int) time.Time {
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, (week-1)*7)
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const MATCHES_COLLECTION = "matches"
+
+// Match is a precomputed student to job posting score. Matches are rebuilt
+// when the student or the posting is marked stale, so they may briefly lag
+// behind a profile edit.
+type Match struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"-"`
+
+	StudentID bson.ObjectID `bson:"studentId" json:"studentId"`
+	PostingID bson.ObjectID `bson:"postingId" json:"postingId"`
+	CompanyID bson.ObjectID `bson:"companyId" json:"companyId"`
+
+	// 0 to 100, with the factors behind it
+	Score   float64       `bson:"score" json:"score"`
+	Reasons []MatchReason `bson:"reasons" json:"reasons"`
+
+	ComputedAt bson.DateTime `bson:"computedAt" json:"computedAt"`
+}
+
+// MatchReason explains one factor of a match, e.g. "Matches 3 of 4 interests".
+type MatchReason struct {
+	Factor string  `bson:"factor" json:"factor"`
+	Score  float64 `bson:"score" json:"score"`
+	Weight float64 `bson:"weight" json:"weight"`
+	Detail string  `bson:"detail" json:"detail"`
+}
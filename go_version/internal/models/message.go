@@ -0,0 +1,115 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const CONVERSATIONS_COLLECTION = "conversations"
+const MESSAGES_COLLECTION = "messages"
+const MESSAGE_ATTACHMENTS_COLLECTION = "messageattachments"
+const USER_BLOCKS_COLLECTION = "userblocks"
+
+// Conversation is the one thread between a company and a student. Companies
+// start it, students can only reply.
+type Conversation struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	CompanyID   bson.ObjectID `bson:"companyId" json:"companyId"`
+	CompanyName string        `bson:"companyName" json:"companyName"`
+	StudentID   bson.ObjectID `bson:"studentId" json:"studentId"`
+	StudentName string        `bson:"studentName" json:"studentName"`
+
+	// The posting the company reached out about, if any
+	PostingID bson.ObjectID `bson:"postingId,omitempty" json:"postingId,omitempty"`
+
+	LastMessage   *MessagePreview `bson:"lastMessage,omitempty" json:"lastMessage,omitempty"`
+	LastMessageAt bson.DateTime   `bson:"lastMessageAt" json:"lastMessageAt"`
+
+	// Unread messages and last read time of each side
+	CompanyUnread int32         `bson:"companyUnread" json:"companyUnread"`
+	StudentUnread int32         `bson:"studentUnread" json:"studentUnread"`
+	CompanyReadAt bson.DateTime `bson:"companyReadAt,omitempty" json:"companyReadAt,omitempty"`
+	StudentReadAt bson.DateTime `bson:"studentReadAt,omitempty" json:"studentReadAt,omitempty"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
+
+// Participant tells whether the user takes part in the conversation and on
+// which side.
+func (c *Conversation) Participant(user_id bson.ObjectID) (role string, ok bool) {
+	switch user_id {
+	case c.CompanyID:
+		return ROLE_COMPANY, true
+	case c.StudentID:
+		return ROLE_STUDENT, true
+	}
+	return "", false
+}
+
+type MessagePreview struct {
+	SenderID bson.ObjectID `bson:"senderId" json:"senderId"`
+	Body     string        `bson:"body" json:"body"`
+	SentAt   bson.DateTime `bson:"sentAt" json:"sentAt"`
+}
+
+type Message struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	ConversationID bson.ObjectID   `bson:"conversationId" json:"conversationId"`
+	SenderID       bson.ObjectID   `bson:"senderId" json:"senderId"`
+	Body           string          `bson:"body,omitempty" json:"body"`
+	Attachments    []AttachmentRef `bson:"attachments,omitempty" json:"attachments"`
+	SentAt         bson.DateTime   `bson:"sentAt" json:"sentAt"`
+
+	// Read receipt, set once the recipient opened the conversation
+	ReadAt bson.DateTime `bson:"readAt,omitempty" json:"readAt,omitempty"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+}
+
+// AttachmentRef points a message to a file in the media store.
+type AttachmentRef struct {
+	ID          bson.ObjectID `bson:"_id" json:"_id"`
+	URL         string        `bson:"url" json:"url"`
+	Name        string        `bson:"name" json:"name"`
+	ContentType string        `bson:"contentType" json:"contentType"`
+	Size        int64         `bson:"size" json:"size"`
+}
+
+// MessageAttachment is a file a user uploaded to the media store to attach to
+// a message. It can be attached once, by its uploader.
+type MessageAttachment struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	OwnerID     bson.ObjectID `bson:"ownerId" json:"-"`
+	PublicID    string        `bson:"publicId" json:"-"`
+	URL         string        `bson:"url" json:"url"`
+	Name        string        `bson:"name" json:"name"`
+	ContentType string        `bson:"contentType" json:"contentType"`
+	Size        int64         `bson:"size" json:"size"`
+
+	// Set once attached
+	MessageID bson.ObjectID `bson:"messageId,omitempty" json:"-"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+}
+
+func (a *MessageAttachment) Ref() AttachmentRef {
+	return AttachmentRef{ID: a.ID, URL: a.URL, Name: a.Name, ContentType: a.ContentType, Size: a.Size}
+}
+
+// UserBlock stops two users from messaging each other, whoever blocked whom.
+type UserBlock struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	BlockerID   bson.ObjectID `bson:"blockerId" json:"-"`
+	BlockedID   bson.ObjectID `bson:"blockedId" json:"blockedId"`
+	BlockedName string        `bson:"blockedName" json:"blockedName"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+}
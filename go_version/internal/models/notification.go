@@ -0,0 +1,61 @@
+package models
+
+import (
+	"slices"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const NOTIFICATIONS_COLLECTION = "notifications"
+
+// What a notification is about, users can turn each type off
+const (
+	NOTIFICATION_APPLICATION_UPDATE = "application_update"
+	NOTIFICATION_INTERVIEW          = "interview"
+	NOTIFICATION_MESSAGE            = "message"
+	NOTIFICATION_CONTACT_REQUEST    = "contact_request"
+)
+
+func GetValidNotificationTypes() []string {
+	Types := []string{NOTIFICATION_APPLICATION_UPDATE, NOTIFICATION_INTERVIEW, NOTIFICATION_MESSAGE, NOTIFICATION_CONTACT_REQUEST}
+	return Types
+}
+
+type Notification struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	UserID bson.ObjectID `bson:"userId" json:"-"`
+	Type   string        `bson:"type" json:"type"`
+	Title  string        `bson:"title" json:"title"`
+	Body   string        `bson:"body,omitempty" json:"body,omitempty"`
+
+	// Frontend path the notification opens
+	Link string `bson:"link,omitempty" json:"link,omitempty"`
+
+	// Event the notification was created for, so a redelivered event doesn't
+	// notify twice
+	SourceID bson.ObjectID `bson:"sourceId,omitempty" json:"-"`
+
+	ReadAt bson.DateTime `bson:"readAt,omitempty" json:"readAt,omitempty"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+}
+
+type NotificationPreferences struct {
+	// Types the user turned off, everything else is delivered
+	Muted []string `bson:"muted,omitempty" json:"-"`
+}
+
+func (p *NotificationPreferences) Enabled(notification_type string) bool {
+	return !slices.Contains(p.Muted, notification_type)
+}
+
+// View lists every type with whether it is delivered.
+func (p *NotificationPreferences) View() map[string]bool {
+	view := map[string]bool{}
+	for _, notification_type := range GetValidNotificationTypes() {
+		view[notification_type] = p.Enabled(notification_type)
+	}
+	return view
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const OUTBOX_COLLECTION = "outboxevents"
+
+// Domain events published on the event bus
+const (
+	EVENT_USER_REGISTERED        = "user_registered"
+	EVENT_EMAIL_VERIFIED         = "email_verified"
+	EVENT_VERIFICATION_REQUESTED = "verification_requested"
+	EVENT_PASSWORD_CHANGED       = "password_changed"
+	EVENT_PROFILE_UPDATED        = "profile_updated"
+)
+
+const (
+	OUTBOX_STATUS_PENDING    = "pending"
+	OUTBOX_STATUS_PROCESSING = "processing"
+	OUTBOX_STATUS_DISPATCHED = "dispatched"
+	OUTBOX_STATUS_DEAD       = "dead"
+)
+
+// DomainEvent is something that happened to an aggregate (a user, ...). It is
+// first pushed onto the aggregate's pendingEvents in the same write as the
+// state change, then relayed to the outbox collection for dispatch.
+type DomainEvent struct {
+	ID          bson.ObjectID `bson:"_id" json:"_id"`
+	Type        string        `bson:"type" json:"type"`
+	AggregateID bson.ObjectID `bson:"aggregateId" json:"aggregateId"`
+	Payload     bson.M        `bson:"payload,omitempty" json:"payload,omitempty"`
+	OccurredAt  bson.DateTime `bson:"occurredAt" json:"occurredAt"`
+}
+
+func NewDomainEvent(event_type string, aggregate_id bson.ObjectID, payload bson.M) DomainEvent {
+	return DomainEvent{
+		ID:          bson.NewObjectID(),
+		Type:        event_type,
+		AggregateID: aggregate_id,
+		Payload:     payload,
+		OccurredAt:  bson.NewDateTimeFromTime(time.Now()),
+	}
+}
+
+// OutboxMessage is a relayed event with its delivery state. Subscribers that
+// already handled it are listed in Delivered so a retry only runs the others.
+type OutboxMessage struct {
+	DomainEvent `bson:",inline"`
+
+	Source string `bson:"source" json:"source"`
+
+	// Delivery
+	Status        string        `bson:"status" json:"status"`
+	Attempts      int32         `bson:"attempts" json:"attempts"`
+	NextAttemptAt bson.DateTime `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	LockedUntil   bson.DateTime `bson:"lockedUntil,omitempty" json:"lockedUntil,omitempty"`
+	Delivered     []string      `bson:"delivered" json:"delivered"`
+	LastError     string        `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	DispatchedAt  bson.DateTime `bson:"dispatchedAt,omitempty" json:"dispatchedAt,omitempty"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"slices"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const CONTACT_REQUESTS_COLLECTION = "contactrequests"
+
+// Who can see a profile field
+const (
+	VISIBILITY_PUBLIC    = "public"
+	VISIBILITY_COMPANIES = "companies"
+	VISIBILITY_PRIVATE   = "private"
+)
+
+// Profile fields students control, by their bson/json name
+const (
+	PRIVACY_FIELD_EMAIL           = "email"
+	PRIVACY_FIELD_PHONE           = "phone"
+	PRIVACY_FIELD_CITY            = "city"
+	PRIVACY_FIELD_LINKEDIN_URL    = "linkedInUrl"
+	PRIVACY_FIELD_UNIVERSITY      = "university"
+	PRIVACY_FIELD_MAJOR           = "major"
+	PRIVACY_FIELD_GRADUATION_YEAR = "graduationYear"
+	PRIVACY_FIELD_INTERESTS       = "interests"
+	PRIVACY_FIELD_BIO             = "bio"
+	PRIVACY_FIELD_RESUME_TEXT     = "resumeText"
+)
+
+const (
+	CONTACT_REQUEST_STATUS_PENDING  = "pending"
+	CONTACT_REQUEST_STATUS_APPROVED = "approved"
+	CONTACT_REQUEST_STATUS_DECLINED = "declined"
+	CONTACT_REQUEST_STATUS_REVOKED  = "revoked"
+)
+
+func GetValidVisibilities() []string {
+	Visibilities := []string{VISIBILITY_PUBLIC, VISIBILITY_COMPANIES, VISIBILITY_PRIVATE}
+	return Visibilities
+}
+
+func GetValidContactRequestStatuses() []string {
+	Statuses := []string{CONTACT_REQUEST_STATUS_PENDING, CONTACT_REQUEST_STATUS_APPROVED, CONTACT_REQUEST_STATUS_DECLINED, CONTACT_REQUEST_STATUS_REVOKED}
+	return Statuses
+}
+
+// Visibility of the fields a student hasn't set. Contact details stay private
+// until the student shares them with a company.
+var defaultFieldVisibility = map[string]string{
+	PRIVACY_FIELD_EMAIL:           VISIBILITY_PRIVATE,
+	PRIVACY_FIELD_PHONE:           VISIBILITY_PRIVATE,
+	PRIVACY_FIELD_CITY:            VISIBILITY_PUBLIC,
+	PRIVACY_FIELD_LINKEDIN_URL:    VISIBILITY_COMPANIES,
+	PRIVACY_FIELD_UNIVERSITY:      VISIBILITY_PUBLIC,
+	PRIVACY_FIELD_MAJOR:           VISIBILITY_PUBLIC,
+	PRIVACY_FIELD_GRADUATION_YEAR: VISIBILITY_PUBLIC,
+	PRIVACY_FIELD_INTERESTS:       VISIBILITY_PUBLIC,
+	PRIVACY_FIELD_BIO:             VISIBILITY_PUBLIC,
+	PRIVACY_FIELD_RESUME_TEXT:     VISIBILITY_COMPANIES,
+}
+
+// Fields revealed to a company the student shared contact details with
+var contactFields = []string{PRIVACY_FIELD_EMAIL, PRIVACY_FIELD_PHONE, PRIVACY_FIELD_LINKEDIN_URL}
+
+func GetPrivacyFields() []string {
+	Fields := []string{
+		PRIVACY_FIELD_EMAIL, PRIVACY_FIELD_PHONE, PRIVACY_FIELD_CITY, PRIVACY_FIELD_LINKEDIN_URL, PRIVACY_FIELD_UNIVERSITY,
+		PRIVACY_FIELD_MAJOR, PRIVACY_FIELD_GRADUATION_YEAR, PRIVACY_FIELD_INTERESTS, PRIVACY_FIELD_BIO, PRIVACY_FIELD_RESUME_TEXT,
+	}
+	return Fields
+}
+
+type PrivacySettings struct {
+	// Visibility per field, unset fields use their default
+	Fields map[string]string `bson:"fields,omitempty" json:"fields"`
+
+	// Students hidden from search aren't listed to companies
+	HiddenFromSearch bool `bson:"hiddenFromSearch,omitempty" json:"-"`
+}
+
+// Visibility returns who can see a field.
+func (p *PrivacySettings) Visibility(field string) string {
+	if visibility, ok := p.Fields[field]; ok {
+		return visibility
+	}
+	return defaultFieldVisibility[field]
+}
+
+// EffectiveFields lists the visibility of every field, defaults included.
+func (p *PrivacySettings) EffectiveFields() map[string]string {
+	fields := make(map[string]string, len(defaultFieldVisibility))
+	for _, field := range GetPrivacyFields() {
+		fields[field] = p.Visibility(field)
+	}
+	return fields
+}
+
+// PrivacyView is how students see their own settings.
+type PrivacyView struct {
+	Fields       map[string]string `json:"fields"`
+	Discoverable bool              `json:"discoverable"`
+}
+
+func (p *PrivacySettings) View() PrivacyView {
+	return PrivacyView{Fields: p.EffectiveFields(), Discoverable: !p.HiddenFromSearch}
+}
+
+// ProfileViewer is who a profile is projected for, the zero value is an
+// anonymous visitor.
+type ProfileViewer struct {
+	ID   bson.ObjectID
+	Role string
+
+	// The student shared contact details with the viewer, by approving a
+	// contact request or applying to one of its postings
+	ContactShared bool
+}
+
+// CanSee reports whether the viewer may see a field of the user's profile.
+func (u *User) CanSee(viewer ProfileViewer, field string) bool {
+	if u.Role != ROLE_STUDENT || viewer.ID == u.ID || viewer.Role == ROLE_ADMIN {
+		return true
+	}
+	if viewer.ContactShared && viewer.Role == ROLE_COMPANY && slices.Contains(contactFields, field) {
+		return true
+	}
+
+	switch u.Privacy.Visibility(field) {
+	case VISIBILITY_PUBLIC:
+		return true
+	case VISIBILITY_COMPANIES:
+		return viewer.Role == ROLE_COMPANY
+	}
+	return false
+}
+
+// VisibleTo returns a copy of the user without the fields the viewer may not
+// see. Anything that shows a student to someone else projects it through here.
+func (u *User) VisibleTo(viewer ProfileViewer) User {
+	visible := *u
+	hide := func(field string, clear func()) {
+		if !u.CanSee(viewer, field) {
+			clear()
+		}
+	}
+	hide(PRIVACY_FIELD_EMAIL, func() { visible.Email = "" })
+	hide(PRIVACY_FIELD_PHONE, func() { visible.Phone = "" })
+	hide(PRIVACY_FIELD_CITY, func() { visible.City = "" })
+	hide(PRIVACY_FIELD_LINKEDIN_URL, func() { visible.LinkedInURL = "" })
+	hide(PRIVACY_FIELD_UNIVERSITY, func() { visible.University = "" })
+	hide(PRIVACY_FIELD_MAJOR, func() { visible.Major = "" })
+	hide(PRIVACY_FIELD_GRADUATION_YEAR, func() { visible.GraduationYear = "" })
+	hide(PRIVACY_FIELD_INTERESTS, func() { visible.Interests = nil })
+	hide(PRIVACY_FIELD_BIO, func() { visible.Bio = "" })
+	hide(PRIVACY_FIELD_RESUME_TEXT, func() { visible.ResumeText = "" })
+	return visible
+}
+
+// ContactRequest is a company asking a student to share contact details.
+type ContactRequest struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	CompanyID   bson.ObjectID `bson:"companyId" json:"companyId"`
+	CompanyName string        `bson:"companyName" json:"companyName"`
+	StudentID   bson.ObjectID `bson:"studentId" json:"studentId"`
+
+	Message string `bson:"message,omitempty" json:"message,omitempty"`
+	Status  string `bson:"status" json:"status"`
+
+	RequestedAt bson.DateTime `bson:"requestedAt" json:"requestedAt"`
+	RespondedAt bson.DateTime `bson:"respondedAt,omitempty" json:"respondedAt,omitempty"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCanSee(t *testing.T) {
+	student_id := bson.NewObjectID()
+	viewers := []struct {
+		name   string
+		viewer ProfileViewer
+	}{
+		{"anonymous", ProfileViewer{}},
+		{"another student", ProfileViewer{ID: bson.NewObjectID(), Role: ROLE_STUDENT}},
+		{"company", ProfileViewer{ID: bson.NewObjectID(), Role: ROLE_COMPANY}},
+		{"company with contact shared", ProfileViewer{ID: bson.NewObjectID(), Role: ROLE_COMPANY, ContactShared: true}},
+		{"student with contact shared", ProfileViewer{ID: bson.NewObjectID(), Role: ROLE_STUDENT, ContactShared: true}},
+		{"admin", ProfileViewer{ID: bson.NewObjectID(), Role: ROLE_ADMIN}},
+		{"the student", ProfileViewer{ID: student_id, Role: ROLE_STUDENT}},
+	}
+
+	// Whether each viewer above sees the field
+	tests := []struct {
+		name       string
+		field      string
+		visibility string
+		want       []bool
+	}{
+		{"public field", PRIVACY_FIELD_MAJOR, VISIBILITY_PUBLIC, []bool{true, true, true, true, true, true, true}},
+		{"field for companies", PRIVACY_FIELD_MAJOR, VISIBILITY_COMPANIES, []bool{false, false, true, true, false, true, true}},
+		{"private field", PRIVACY_FIELD_MAJOR, VISIBILITY_PRIVATE, []bool{false, false, false, false, false, true, true}},
+		{"public contact field", PRIVACY_FIELD_EMAIL, VISIBILITY_PUBLIC, []bool{true, true, true, true, true, true, true}},
+		{"contact field for companies", PRIVACY_FIELD_PHONE, VISIBILITY_COMPANIES, []bool{false, false, true, true, false, true, true}},
+		{"private contact field", PRIVACY_FIELD_LINKEDIN_URL, VISIBILITY_PRIVATE, []bool{false, false, false, true, false, true, true}},
+	}
+	for _, test := range tests {
+		for i, viewer := range viewers {
+			t.Run(test.name+" to "+viewer.name, func(t *testing.T) {
+				student := User{
+					ID:      student_id,
+					Role:    ROLE_STUDENT,
+					Privacy: PrivacySettings{Fields: map[string]string{test.field: test.visibility}},
+				}
+				if got := student.CanSee(viewer.viewer, test.field); got != test.want[i] {
+					t.Errorf("CanSee(%s) = %v, want %v", test.field, got, test.want[i])
+				}
+			})
+		}
+	}
+}
+
+func TestCanSeeDefaults(t *testing.T) {
+	student := User{ID: bson.NewObjectID(), Role: ROLE_STUDENT}
+	anonymous, company := ProfileViewer{}, ProfileViewer{ID: bson.NewObjectID(), Role: ROLE_COMPANY}
+
+	tests := []struct {
+		field     string
+		anonymous bool
+		company   bool
+	}{
+		{PRIVACY_FIELD_EMAIL, false, false},
+		{PRIVACY_FIELD_PHONE, false, false},
+		{PRIVACY_FIELD_LINKEDIN_URL, false, true},
+		{PRIVACY_FIELD_RESUME_TEXT, false, true},
+		{PRIVACY_FIELD_CITY, true, true},
+		{PRIVACY_FIELD_UNIVERSITY, true, true},
+		{PRIVACY_FIELD_MAJOR, true, true},
+		{PRIVACY_FIELD_GRADUATION_YEAR, true, true},
+		{PRIVACY_FIELD_INTERESTS, true, true},
+		{PRIVACY_FIELD_BIO, true, true},
+	}
+	for _, test := range tests {
+		if got := student.CanSee(anonymous, test.field); got != test.anonymous {
+			t.Errorf("CanSee(%s) = %v for an anonymous visitor, want %v", test.field, got, test.anonymous)
+		}
+		if got := student.CanSee(company, test.field); got != test.company {
+			t.Errorf("CanSee(%s) = %v for a company, want %v", test.field, got, test.company)
+		}
+	}
+	if len(tests) != len(GetPrivacyFields()) {
+		t.Errorf("checked %d fields, there are %d", len(tests), len(GetPrivacyFields()))
+	}
+}
+
+func TestCanSeeOnlyLimitsStudents(t *testing.T) {
+	private := PrivacySettings{Fields: map[string]string{PRIVACY_FIELD_EMAIL: VISIBILITY_PRIVATE}}
+	for _, role := range []string{ROLE_COMPANY, ROLE_ADMIN} {
+		user := User{ID: bson.NewObjectID(), Role: role, Privacy: private}
+		if !user.CanSee(ProfileViewer{}, PRIVACY_FIELD_EMAIL) {
+			t.Errorf("a %s's email is hidden", role)
+		}
+	}
+}
+
+func TestVisibleTo(t *testing.T) {
+	student := User{
+		ID:             bson.NewObjectID(),
+		Role:           ROLE_STUDENT,
+		FullName:       "Lina Saleh",
+		Email:          "lina@example.com",
+		Phone:          "+970599000000",
+		City:           "Nablus",
+		LinkedInURL:    "https://linkedin.com/in/lina",
+		University:     "An-Najah National University",
+		Major:          "Computer Science",
+		GraduationYear: "2027",
+		Interests:      []string{"Go", "Databases"},
+		Bio:            "Backend developer in the making, mostly Go and Postgres.",
+		ResumeText:     "Teaching assistant for data structures, two internships.",
+		Privacy: PrivacySettings{Fields: map[string]string{
+			PRIVACY_FIELD_MAJOR:     VISIBILITY_PRIVATE,
+			PRIVACY_FIELD_BIO:       VISIBILITY_COMPANIES,
+			PRIVACY_FIELD_INTERESTS: VISIBILITY_PRIVATE,
+		}},
+	}
+	original := student
+	original.Interests = slices.Clone(student.Interests)
+
+	// The fields each viewer gets back
+	tests := []struct {
+		name   string
+		viewer ProfileViewer
+		want   []string
+	}{
+		{
+			name:   "anonymous",
+			viewer: ProfileViewer{},
+			want:   []string{PRIVACY_FIELD_CITY, PRIVACY_FIELD_UNIVERSITY, PRIVACY_FIELD_GRADUATION_YEAR},
+		},
+		{
+			name:   "company",
+			viewer: ProfileViewer{ID: bson.NewObjectID(), Role: ROLE_COMPANY},
+			want:   []string{PRIVACY_FIELD_CITY, PRIVACY_FIELD_LINKEDIN_URL, PRIVACY_FIELD_UNIVERSITY, PRIVACY_FIELD_GRADUATION_YEAR, PRIVACY_FIELD_BIO, PRIVACY_FIELD_RESUME_TEXT},
+		},
+		{
+			name:   "company with contact shared",
+			viewer: ProfileViewer{ID: bson.NewObjectID(), Role: ROLE_COMPANY, ContactShared: true},
+			want: []string{PRIVACY_FIELD_EMAIL, PRIVACY_FIELD_PHONE, PRIVACY_FIELD_CITY, PRIVACY_FIELD_LINKEDIN_URL, PRIVACY_FIELD_UNIVERSITY,
+				PRIVACY_FIELD_GRADUATION_YEAR, PRIVACY_FIELD_BIO, PRIVACY_FIELD_RESUME_TEXT},
+		},
+		{
+			name:   "admin",
+			viewer: ProfileViewer{ID: bson.NewObjectID(), Role: ROLE_ADMIN},
+			want:   GetPrivacyFields(),
+		},
+		{
+			name:   "the student",
+			viewer: student.AsViewer(),
+			want:   GetPrivacyFields(),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			visible := student.VisibleTo(test.viewer)
+			values := map[string]bool{
+				PRIVACY_FIELD_EMAIL:           visible.Email != "",
+				PRIVACY_FIELD_PHONE:           visible.Phone != "",
+				PRIVACY_FIELD_CITY:            visible.City != "",
+				PRIVACY_FIELD_LINKEDIN_URL:    visible.LinkedInURL != "",
+				PRIVACY_FIELD_UNIVERSITY:      visible.University != "",
+				PRIVACY_FIELD_MAJOR:           visible.Major != "",
+				PRIVACY_FIELD_GRADUATION_YEAR: visible.GraduationYear != "",
+				PRIVACY_FIELD_INTERESTS:       visible.Interests != nil,
+				PRIVACY_FIELD_BIO:             visible.Bio != "",
+				PRIVACY_FIELD_RESUME_TEXT:     visible.ResumeText != "",
+			}
+			for _, field := range GetPrivacyFields() {
+				if want := slices.Contains(test.want, field); values[field] != want {
+					t.Errorf("VisibleTo() kept %s: %v, want %v", field, values[field], want)
+				}
+			}
+			if visible.ID != student.ID || visible.FullName != student.FullName {
+				t.Errorf("VisibleTo() = %s %q, want the student's ID and name", visible.ID.Hex(), visible.FullName)
+			}
+		})
+	}
+
+	if student.Email != original.Email || student.Major != original.Major || !slices.Equal(student.Interests, original.Interests) {
+		t.Error("VisibleTo() changed the student it projects")
+	}
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"math"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const PROCTORING_SIGNALS_COLLECTION = "proctoringsignals"
+
+// What the exam page reports while a session runs
+const (
+	PROCTORING_TAB_SWITCH      = "tab_switch"
+	PROCTORING_FOCUS_LOSS      = "focus_loss"
+	PROCTORING_COPY            = "copy"
+	PROCTORING_PASTE           = "paste"
+	PROCTORING_FULLSCREEN_EXIT = "fullscreen_exit"
+)
+
+func GetValidProctoringSignalTypes() []string {
+	Types := []string{PROCTORING_TAB_SWITCH, PROCTORING_FOCUS_LOSS, PROCTORING_COPY, PROCTORING_PASTE, PROCTORING_FULLSCREEN_EXIT}
+	return Types
+}
+
+// Points an integrity score loses per signal
+var proctoringSignalPenalties = map[string]float64{
+	PROCTORING_TAB_SWITCH:      5,
+	PROCTORING_FOCUS_LOSS:      3,
+	PROCTORING_COPY:            3,
+	PROCTORING_PASTE:           8,
+	PROCTORING_FULLSCREEN_EXIT: 4,
+}
+
+const (
+	// Time away from the exam costs a point per started step...
+	PROCTORING_AWAY_PENALTY_STEP_MS = 10_000
+
+	// ...up to this many points per signal
+	MAX_PROCTORING_AWAY_PENALTY = 10
+
+	// Time the channel wasn't connected, past what loading the page and
+	// reconnecting take, costs a point per started step...
+	PROCTORING_UNMONITORED_TOLERANCE_MS    = 30_000
+	PROCTORING_UNMONITORED_PENALTY_STEP_MS = 60_000
+
+	// ...up to this many points per session
+	MAX_PROCTORING_UNMONITORED_PENALTY = 40
+)
+
+type ProctoringSignal struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	SessionID bson.ObjectID `bson:"sessionId" json:"-"`
+	UserID    bson.ObjectID `bson:"userId" json:"-"`
+	Type      string        `bson:"type" json:"type"`
+
+	// Client clock, kept close to the server's. How long the student was away
+	// for tab switches and focus losses
+	OccurredAt bson.DateTime `bson:"occurredAt" json:"occurredAt"`
+	DurationMs int64         `bson:"durationMs,omitempty" json:"durationMs,omitempty"`
+
+	ReceivedAt bson.DateTime `bson:"receivedAt" json:"receivedAt"`
+}
+
+// ProctoringState records on the session when the exam page was connected,
+// a session without signals only means something for the time it was.
+type ProctoringState struct {
+	FirstConnectedAt bson.DateTime `bson:"firstConnectedAt"`
+	LastConnectedAt  bson.DateTime `bson:"lastConnectedAt"`
+	Connections      int32         `bson:"connections"`
+	Signals          int32         `bson:"signals"`
+
+	// Added when a connection ends, the part of it within the session.
+	// Connections still open count up to the end of the session
+	ConnectedMs        int64         `bson:"connectedMs"`
+	OpenConnections    int32         `bson:"openConnections"`
+	LastDisconnectedAt bson.DateTime `bson:"lastDisconnectedAt,omitempty"`
+}
+
+type IntegrityReport struct {
+	// Out of 100, empty when the session wasn't monitored
+	Score     *int32 `json:"score"`
+	Monitored bool   `json:"monitored"`
+
+	// The channel was down for part of the session, for longer than the
+	// tolerance
+	PartiallyMonitored bool  `json:"partiallyMonitored"`
+	UnmonitoredSeconds int64 `json:"unmonitoredSeconds"`
+
+	SignalCounts    map[string]int32 `json:"signalCounts"`
+	TimeAwaySeconds int64            `json:"timeAwaySeconds"`
+}
+
+// ComputeIntegrity scores a session from its signals: every signal costs
+// points by type, and being away from the exam costs more the longer it lasts.
+// So does the time the channel wasn't connected, nothing was seen of it.
+func ComputeIntegrity(session ExamSession, signals []ProctoringSignal) IntegrityReport {
+	state := session.Proctoring
+	report := IntegrityReport{
+		Monitored:    state != nil && state.Connections > 0,
+		SignalCounts: map[string]int32{},
+	}
+	for _, signal_type := range GetValidProctoringSignalTypes() {
+		report.SignalCounts[signal_type] = 0
+	}
+	if !report.Monitored {
+		return report
+	}
+
+	penalty := 0.0
+	var away_ms int64
+	for _, signal := range signals {
+		report.SignalCounts[signal.Type]++
+		penalty += proctoringSignalPenalties[signal.Type]
+		if signal.DurationMs > 0 {
+			away_ms += signal.DurationMs
+			steps := math.Ceil(float64(signal.DurationMs) / PROCTORING_AWAY_PENALTY_STEP_MS)
+			penalty += math.Min(steps, MAX_PROCTORING_AWAY_PENALTY)
+		}
+	}
+	report.TimeAwaySeconds = away_ms / 1000
+
+	unmonitored_ms := session.UnmonitoredMs()
+	report.UnmonitoredSeconds = unmonitored_ms / 1000
+	if unmonitored_ms > PROCTORING_UNMONITORED_TOLERANCE_MS {
+		report.PartiallyMonitored = true
+		steps := math.Ceil(float64(unmonitored_ms-PROCTORING_UNMONITORED_TOLERANCE_MS) / PROCTORING_UNMONITORED_PENALTY_STEP_MS)
+		penalty += math.Min(steps, MAX_PROCTORING_UNMONITORED_PENALTY)
+	}
+
+	score := int32(math.Round(math.Max(0, 100-penalty)))
+	report.Score = &score
+	return report
+}
+
+// UnmonitoredMs is how much of the session ran without the proctoring channel,
+// from the start to the submission or the deadline.
+func (s *ExamSession) UnmonitoredMs() int64 {
+	end := s.Deadline
+	if s.SubmittedAt != 0 && s.SubmittedAt < end {
+		end = s.SubmittedAt
+	}
+	duration_ms := max(int64(end-s.StartedAt), 0)
+	if s.Proctoring == nil {
+		return duration_ms
+	}
+
+	connected_ms := s.Proctoring.ConnectedMs
+	if s.Proctoring.OpenConnections > 0 {
+		connected_ms += max(int64(end-s.Proctoring.LastConnectedAt), 0)
+	}
+	return max(duration_ms-connected_ms, 0)
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const PROFILE_HANDLES_COLLECTION = "profilehandles"
+
+// ProfileHandle is a handle a student holds. The current one has no expiry,
+// one the student moved away from keeps redirecting to their profile, and
+// can't be claimed by anyone else, until it expires.
+type ProfileHandle struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"-"`
+
+	Handle string        `bson:"handle" json:"handle"`
+	UserID bson.ObjectID `bson:"userId" json:"-"`
+
+	ReleasedAt bson.DateTime `bson:"releasedAt,omitempty" json:"releasedAt,omitempty"`
+	ExpiresAt  bson.DateTime `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
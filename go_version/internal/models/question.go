@@ -0,0 +1,157 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const QUESTIONS_COLLECTION = "questions"
+const QUESTION_REVISIONS_COLLECTION = "questionrevisions"
+
+const (
+	CATEGORY_BACKEND  = "backend"
+	CATEGORY_FRONTEND = "frontend"
+)
+
+const (
+	DIFFICULTY_EASY   = "easy"
+	DIFFICULTY_MEDIUM = "medium"
+	DIFFICULTY_HARD   = "hard"
+)
+
+const (
+	REVISION_ACTION_IMPORT   = "import"
+	REVISION_ACTION_CREATE   = "create"
+	REVISION_ACTION_UPDATE   = "update"
+	REVISION_ACTION_ROLLBACK = "rollback"
+	REVISION_ACTION_DELETE   = "delete"
+)
+
+type Question struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	QuestionID    int32    `bson:"questionId" json:"questionId"`
+	Category      string   `bson:"category" json:"category"`
+	Question      string   `bson:"question" json:"question"`
+	Options       []string `bson:"options" json:"options"`
+	CorrectAnswer string   `bson:"correctAnswer" json:"correctAnswer"`
+	Difficulty    string   `bson:"difficulty,omitempty" json:"difficulty,omitempty"`
+	Tags          []string `bson:"tags,omitempty" json:"tags,omitempty"`
+	Company       string   `bson:"company,omitempty" json:"company,omitempty"`
+	Explanation   string   `bson:"explanation,omitempty" json:"explanation,omitempty"`
+	IsActive      bool     `bson:"isActive" json:"isActive"`
+
+	// Current revision, questions created by the Node layer start at 0 until their first edit
+	Revision   int32         `bson:"revision,omitempty" json:"revision"`
+	RevisionID bson.ObjectID `bson:"revisionId,omitempty" json:"revisionId"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+
+	// Version (__v)
+	Version int32 `bson:"__v,omitempty" json:"-"`
+}
+
+func GetValidCategories() []string {
+	Categories := []string{CATEGORY_BACKEND, CATEGORY_FRONTEND}
+	return Categories
+}
+
+func GetValidDifficulties() []string {
+	Difficulties := []string{DIFFICULTY_EASY, DIFFICULTY_MEDIUM, DIFFICULTY_HARD}
+	return Difficulties
+}
+
+func (q *Question) CheckAnswer(answer string) bool {
+	return q.CorrectAnswer == answer
+}
+
+// QuestionContent is the editable part of a question. Revisions store a full
+// copy of it so any past state can be diffed against or restored.
+type QuestionContent struct {
+	QuestionID    int32    `bson:"questionId" json:"questionId"`
+	Category      string   `bson:"category" json:"category"`
+	Question      string   `bson:"question" json:"question"`
+	Options       []string `bson:"options" json:"options"`
+	CorrectAnswer string   `bson:"correctAnswer" json:"correctAnswer"`
+	Difficulty    string   `bson:"difficulty,omitempty" json:"difficulty,omitempty"`
+	Tags          []string `bson:"tags,omitempty" json:"tags,omitempty"`
+	Company       string   `bson:"company,omitempty" json:"company,omitempty"`
+	Explanation   string   `bson:"explanation,omitempty" json:"explanation,omitempty"`
+	IsActive      bool     `bson:"isActive" json:"isActive"`
+}
+
+func (q *Question) Content() QuestionContent {
+	return QuestionContent{
+		QuestionID:    q.QuestionID,
+		Category:      q.Category,
+		Question:      q.Question,
+		Options:       q.Options,
+		CorrectAnswer: q.CorrectAnswer,
+		Difficulty:    q.Difficulty,
+		Tags:          q.Tags,
+		Company:       q.Company,
+		Explanation:   q.Explanation,
+		IsActive:      q.IsActive,
+	}
+}
+
+func (q *Question) ApplyContent(content QuestionContent) {
+	q.QuestionID = content.QuestionID
+	q.Category = content.Category
+	q.Question = content.Question
+	q.Options = content.Options
+	q.CorrectAnswer = content.CorrectAnswer
+	q.Difficulty = content.Difficulty
+	q.Tags = content.Tags
+	q.Company = content.Company
+	q.Explanation = content.Explanation
+	q.IsActive = content.IsActive
+}
+
+// QuestionRevision is an immutable snapshot of a question, written on every
+// edit. Revisions are only ever inserted, never updated or deleted.
+type QuestionRevision struct {
+	ID         bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	QuestionID bson.ObjectID `bson:"question" json:"question"`
+	Revision   int32         `bson:"revision" json:"revision"`
+	Action     string        `bson:"action" json:"action"`
+
+	Content QuestionContent `bson:"content" json:"content"`
+
+	// Set when the revision restores an older one
+	RestoredFrom int32 `bson:"restoredFrom,omitempty" json:"restoredFrom,omitempty"`
+
+	// Author
+	AuthorID   bson.ObjectID `bson:"authorId,omitempty" json:"authorId"`
+	AuthorName string        `bson:"authorName,omitempty" json:"authorName"`
+	Note       string        `bson:"note,omitempty" json:"note,omitempty"`
+
+	CreatedAt bson.DateTime `bson:"createdAt" json:"createdAt"`
+}
+
+type PublicQuestion struct {
+	ID         bson.ObjectID `json:"_id"`
+	QuestionID int32         `json:"questionId"`
+	Category   string        `json:"category"`
+	Question   string        `json:"question"`
+	Options    []string      `json:"options"`
+	Difficulty string        `json:"difficulty"`
+	Tags       []string      `json:"tags"`
+	Company    string        `json:"company,omitempty"`
+}
+
+// GetPublicQuestion mirrors toPublicJSON on the Node model, it never exposes
+// the correct answer.
+func (q *Question) GetPublicQuestion() PublicQuestion {
+	return PublicQuestion{
+		ID:         q.ID,
+		QuestionID: q.QuestionID,
+		Category:   q.Category,
+		Question:   q.Question,
+		Options:    q.Options,
+		Difficulty: q.Difficulty,
+		Tags:       q.Tags,
+		Company:    q.Company,
+	}
+}
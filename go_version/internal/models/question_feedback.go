@@ -0,0 +1,71 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const QUESTION_BOOKMARKS_COLLECTION = "questionbookmarks"
+const QUESTION_NOTES_COLLECTION = "questionnotes"
+const QUESTION_FLAGS_COLLECTION = "questionflags"
+
+const (
+	FLAG_REASON_WRONG_ANSWER = "wrong_answer"
+	FLAG_REASON_TYPO         = "typo"
+	FLAG_REASON_UNCLEAR      = "unclear"
+	FLAG_REASON_OUTDATED     = "outdated"
+	FLAG_REASON_OTHER        = "other"
+)
+
+const (
+	FLAG_STATUS_OPEN     = "open"
+	FLAG_STATUS_RESOLVED = "resolved"
+	FLAG_STATUS_REJECTED = "rejected"
+)
+
+func GetValidFlagReasons() []string {
+	Reasons := []string{FLAG_REASON_WRONG_ANSWER, FLAG_REASON_TYPO, FLAG_REASON_UNCLEAR, FLAG_REASON_OUTDATED, FLAG_REASON_OTHER}
+	return Reasons
+}
+
+type QuestionBookmark struct {
+	ID         bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	UserID     bson.ObjectID `bson:"userId" json:"userId"`
+	QuestionID bson.ObjectID `bson:"questionId" json:"questionId"`
+	CreatedAt  bson.DateTime `bson:"createdAt" json:"createdAt"`
+}
+
+// QuestionNote is a student's private note on a question, one per question.
+type QuestionNote struct {
+	ID         bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	UserID     bson.ObjectID `bson:"userId" json:"userId"`
+	QuestionID bson.ObjectID `bson:"questionId" json:"questionId"`
+	Content    string        `bson:"content" json:"content"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
+
+// QuestionFlag is a student's report of a mistake in a question, waiting in
+// the admin moderation queue until it is resolved or rejected.
+type QuestionFlag struct {
+	ID         bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	ReporterID bson.ObjectID `bson:"reporterId" json:"reporterId"`
+	QuestionID bson.ObjectID `bson:"questionId" json:"questionId"`
+
+	// Revision the student was looking at when reporting
+	Revision int32 `bson:"revision" json:"revision"`
+
+	Reason  string `bson:"reason" json:"reason"`
+	Details string `bson:"details,omitempty" json:"details,omitempty"`
+	Status  string `bson:"status" json:"status"`
+
+	// Moderation
+	ResolutionNote string        `bson:"resolutionNote,omitempty" json:"resolutionNote,omitempty"`
+	ModeratorID    bson.ObjectID `bson:"moderatorId,omitempty" json:"moderatorId,omitempty"`
+	ModeratedAt    bson.DateTime `bson:"moderatedAt,omitempty" json:"moderatedAt,omitempty"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
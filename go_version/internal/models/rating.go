@@ -0,0 +1,60 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const USER_RATINGS_COLLECTION = "userratings"
+const RATING_HISTORY_COLLECTION = "ratinghistory"
+
+const (
+	RATING_SOURCE_EXAM      = "exam"
+	RATING_SOURCE_CHALLENGE = "challenge"
+)
+
+// UserRating is a student's Glicko-2 skill rating in one category.
+type UserRating struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"-"`
+
+	UserID   bson.ObjectID `bson:"userId" json:"userId"`
+	Category string        `bson:"category" json:"category"`
+
+	Rating     float64 `bson:"rating" json:"rating"`
+	RD         float64 `bson:"rd" json:"rd"`
+	Volatility float64 `bson:"volatility" json:"volatility"`
+
+	// Rating periods played, also guards concurrent updates
+	Periods      int32         `bson:"periods" json:"periods"`
+	Games        int32         `bson:"games" json:"games"`
+	LastPlayedAt bson.DateTime `bson:"lastPlayedAt,omitempty" json:"lastPlayedAt,omitempty"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
+
+// RatingChange records one rating period: the exam or challenge that was
+// rated and the rating before and after it. It is written before the rating,
+// a change whose period the rating hasn't reached yet is still to be applied.
+type RatingChange struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	UserID   bson.ObjectID `bson:"userId" json:"userId"`
+	Category string        `bson:"category" json:"category"`
+	Source   string        `bson:"source" json:"source"`
+	SourceID bson.ObjectID `bson:"sourceId" json:"sourceId"`
+
+	// The rating's period count once applied
+	Period int32 `bson:"period" json:"period"`
+
+	RatingBefore float64 `bson:"ratingBefore" json:"ratingBefore"`
+	RDBefore     float64 `bson:"rdBefore" json:"rdBefore"`
+	Rating       float64 `bson:"rating" json:"rating"`
+	RD           float64 `bson:"rd" json:"rd"`
+	Volatility   float64 `bson:"volatility" json:"volatility"`
+
+	Games   int32 `bson:"games" json:"games"`
+	Correct int32 `bson:"correct" json:"correct"`
+
+	OccurredAt bson.DateTime `bson:"occurredAt" json:"occurredAt"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const REVIEW_CARDS_COLLECTION = "reviewcards"
+const REVIEW_LOGS_COLLECTION = "reviewlogs"
+
+// ReviewCard is the spaced repetition schedule of one practice question for
+// one student.
+type ReviewCard struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	UserID     bson.ObjectID `bson:"userId" json:"userId"`
+	QuestionID bson.ObjectID `bson:"questionId" json:"questionId"`
+	Category   string        `bson:"category" json:"category"`
+
+	// SM-2 state
+	Ease         float64 `bson:"ease" json:"ease"`
+	IntervalDays int32   `bson:"intervalDays" json:"intervalDays"`
+	Repetitions  int32   `bson:"repetitions" json:"repetitions"`
+	Lapses       int32   `bson:"lapses" json:"lapses"`
+	Reviews      int32   `bson:"reviews" json:"reviews"`
+
+	LastQuality    int32         `bson:"lastQuality" json:"lastQuality"`
+	LastReviewedAt bson.DateTime `bson:"lastReviewedAt" json:"lastReviewedAt"`
+	DueAt          bson.DateTime `bson:"dueAt" json:"dueAt"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
+
+// ReviewLog records every scheduled review, daily statistics are built from it.
+type ReviewLog struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	UserID     bson.ObjectID `bson:"userId" json:"userId"`
+	QuestionID bson.ObjectID `bson:"questionId" json:"questionId"`
+	AttemptID  bson.ObjectID `bson:"attemptId,omitempty" json:"attemptId,omitempty"`
+	Category   string        `bson:"category" json:"category"`
+
+	IsCorrect bool  `bson:"isCorrect" json:"isCorrect"`
+	TimeSpent int32 `bson:"timeSpent" json:"timeSpent"` // in seconds
+	Quality   int32 `bson:"quality" json:"quality"`
+
+	// First review of the question, i.e. a new card rather than a review
+	IsNew          bool  `bson:"isNew" json:"isNew"`
+	IntervalBefore int32 `bson:"intervalBefore" json:"intervalBefore"`
+	IntervalAfter  int32 `bson:"intervalAfter" json:"intervalAfter"`
+
+	ReviewedAt bson.DateTime `bson:"reviewedAt" json:"reviewedAt"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const STREAM_TICKETS_COLLECTION = "streamtickets"
+
+// StreamTicket stands in for the access token on connections browsers can't
+// set headers on. Only its hash is stored, it is deleted when redeemed and
+// expires within seconds otherwise.
+type StreamTicket struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"-"`
+
+	TicketHash string        `bson:"ticketHash" json:"-"`
+	UserID     bson.ObjectID `bson:"userId" json:"-"`
+
+	ExpiresAt bson.DateTime `bson:"expiresAt" json:"expiresAt"`
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+}
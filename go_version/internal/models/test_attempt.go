@@ -0,0 +1,94 @@
+package models
+
+import (
+	"math"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const TEST_ATTEMPTS_COLLECTION = "testattempts"
+
+// Published once a server-timed exam is graded, the attempt is the aggregate
+const EVENT_EXAM_GRADED = "exam_graded"
+
+type TestAttempt struct {
+	ID bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+
+	UserID         bson.ObjectID     `bson:"userId" json:"userId"`
+	Category       string            `bson:"category" json:"category"`
+	IsPracticeMode bool              `bson:"isPracticeMode" json:"isPracticeMode"`
+	Questions      []AttemptQuestion `bson:"questions" json:"questions"`
+
+	// Set when the attempt was graded from a server-timed exam session
+	ExamSessionID bson.ObjectID `bson:"examSessionId,omitempty" json:"examSessionId,omitempty"`
+
+	// Results
+	Score          int32 `bson:"score" json:"score"`
+	CorrectCount   int32 `bson:"correctCount" json:"correctCount"`
+	TotalQuestions int32 `bson:"totalQuestions" json:"totalQuestions"`
+	TimeSpent      int32 `bson:"timeSpent" json:"timeSpent"` // in seconds
+
+	// Final ability estimate of adaptive exams
+	Ability *AbilityEstimate `bson:"ability,omitempty" json:"ability,omitempty"`
+
+	// Answer-key corrections applied after the attempt was graded
+	Rescores []AttemptRescore `bson:"rescores,omitempty" json:"rescores,omitempty"`
+
+	// Events not yet relayed to the outbox
+	PendingEvents []DomainEvent `bson:"pendingEvents,omitempty" json:"-"`
+
+	StartedAt   bson.DateTime `bson:"startedAt" json:"startedAt"`
+	CompletedAt bson.DateTime `bson:"completedAt" json:"completedAt"`
+
+	// Timestamps
+	CreatedAt bson.DateTime `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty" json:"updatedAt"`
+
+	// Version (__v)
+	Version int32 `bson:"__v,omitempty" json:"-"`
+}
+
+type AttemptQuestion struct {
+	QuestionID    bson.ObjectID `bson:"questionId" json:"questionId"`
+	UserAnswer    string        `bson:"userAnswer" json:"userAnswer"`
+	CorrectAnswer string        `bson:"correctAnswer" json:"correctAnswer"`
+	IsCorrect     bool          `bson:"isCorrect" json:"isCorrect"`
+
+	// Seconds since the previous answer, only known for server-timed exams
+	TimeSpent int32 `bson:"timeSpent,omitempty" json:"timeSpent,omitempty"`
+
+	// Exact question revision the answer was graded against
+	Revision   int32         `bson:"revision,omitempty" json:"revision,omitempty"`
+	RevisionID bson.ObjectID `bson:"revisionId,omitempty" json:"revisionId,omitempty"`
+}
+
+type AttemptRescore struct {
+	QuestionID    bson.ObjectID `bson:"questionId" json:"questionId"`
+	FromRevision  int32         `bson:"fromRevision" json:"fromRevision"`
+	ToRevision    int32         `bson:"toRevision" json:"toRevision"`
+	PreviousScore int32         `bson:"previousScore" json:"previousScore"`
+	NewScore      int32         `bson:"newScore" json:"newScore"`
+	RescoredBy    bson.ObjectID `bson:"rescoredBy,omitempty" json:"rescoredBy"`
+	RescoredAt    bson.DateTime `bson:"rescoredAt" json:"rescoredAt"`
+}
+
+// CalculateScore mirrors calculateScore in the Node questionFormatter.
+func CalculateScore(correct, total int32) int32 {
+	if total == 0 {
+		return 0
+	}
+	return int32(math.Round(float64(correct) / float64(total) * 100))
+}
+
+// Recount recomputes CorrectCount, TotalQuestions and Score from Questions.
+func (a *TestAttempt) Recount() {
+	var correct int32
+	for _, q := range a.Questions {
+		if q.IsCorrect {
+			correct++
+		}
+	}
+	a.CorrectCount = correct
+	a.TotalQuestions = int32(len(a.Questions))
+	a.Score = CalculateScore(a.CorrectCount, a.TotalQuestions)
+}
@@ -38,6 +38,23 @@ type User struct {
 	Role      string   `bson:"role,omitempty"`
 	Interests []string `bson:"interests,omitempty"`
 
+	// Plain text résumé, searched by companies
+	ResumeText string `bson:"resumeText,omitempty"`
+
+	// Vanity handle of the student's public profile page
+	Handle          string        `bson:"handle,omitempty"`
+	HandleChangedAt bson.DateTime `bson:"handleChangedAt,omitempty"`
+
+	// IANA time zone days are counted in for streaks and reviews
+	Timezone          string        `bson:"timezone,omitempty"`
+	TimezoneChangedAt bson.DateTime `bson:"timezoneChangedAt,omitempty"`
+
+	// Who sees which profile fields, and whether companies can find the student
+	Privacy PrivacySettings `bson:"privacy,omitempty"`
+
+	// Notification types the user turned off
+	NotificationPreferences NotificationPreferences `bson:"notificationPreferences,omitempty"`
+
 	// Email verification fields
 	EmailVerificationToken   string        `bson:"emailVerificationToken,omitempty"`
 	EmailVerificationExpires bson.DateTime `bson:"emailVerificationExpires,omitempty"`
@@ -49,15 +66,27 @@ type User struct {
 	Industry        string `bson:"industry,omitempty"`
 	Description     string `bson:"description,omitempty"`
 
+	// Set by an admin who checked the company is real, needed to search talent
+	IsCompanyVerified bool          `bson:"isCompanyVerified,omitempty"`
+	CompanyVerifiedAt bson.DateTime `bson:"companyVerifiedAt,omitempty"`
+	CompanyVerifiedBy bson.ObjectID `bson:"companyVerifiedBy,omitempty"`
+
 	// Flags
 	IsEmailVerified   bool `bson:"isEmailVerified,omitempty"`
 	IsProfileComplete bool `bson:"isProfileComplete,omitempty"`
 	IsActive          bool `bson:"isActive,omitempty"`
 
+	// Set when the profile or skill ratings changed and matches need recomputing
+	MatchesStaleAt    bson.DateTime `bson:"matchesStaleAt,omitempty"`
+	MatchesComputedAt bson.DateTime `bson:"matchesComputedAt,omitempty"`
+
 	// Refresh JWT Token
 	RefreshToken    string        `bson:"refreshToken,omitempty"`
 	RefreshTokenExp bson.DateTime `bson:"refreshTokenExp,omitempty"`
 
+	// Domain events waiting to be relayed to the outbox
+	PendingEvents []DomainEvent `bson:"pendingEvents,omitempty"`
+
 	// Timestamps
 	CreatedAt bson.DateTime `bson:"createdAt,omitempty"`
 	UpdatedAt bson.DateTime `bson:"updatedAt,omitempty"`
@@ -88,12 +117,17 @@ type BasePublicProfile struct {
 
 type StudentPublicProfile struct {
 	BasePublicProfile
+	Handle         string   `json:"handle,omitempty"`
 	LinkedInURL    string   `json:"linkedInUrl"`
 	University     string   `json:"university"`
 	Major          string   `json:"major"`
 	GraduationYear string   `json:"graduationYear"`
 	Interests      []string `json:"interests"`
 	Bio            string   `json:"bio"`
+	ResumeText     string   `json:"resumeText,omitempty"`
+
+	// Only on the student's own profile
+	Privacy *PrivacyView `json:"privacy,omitempty"`
 }
 
 type CompanyPublicProfile struct {
@@ -103,9 +137,20 @@ type CompanyPublicProfile struct {
 	CompanyLocation string `json:"companyLocation"`
 	Industry        string `json:"industry"`
 	Description     string `json:"description"`
+	IsVerified      bool   `json:"isVerified"`
+}
+
+// AsViewer is the user looking at profiles, their own included.
+func (u *User) AsViewer() ProfileViewer {
+	return ProfileViewer{ID: u.ID, Role: u.Role}
 }
 
-func (u *User) GetPublicProfile() interface{} {
+// GetPublicProfile projects the user for a viewer, hiding the fields the
+// student's privacy settings keep from them.
+func (u *User) GetPublicProfile(viewer ProfileViewer) interface{} {
+	visible := u.VisibleTo(viewer)
+	u = &visible
+
 	base := BasePublicProfile{
 		ID:                u.ID,
 		FullName:          u.FullName,
@@ -123,15 +168,22 @@ func (u *User) GetPublicProfile() interface{} {
 
 	switch u.Role {
 	case "student":
-		return StudentPublicProfile{
+		profile := StudentPublicProfile{
 			BasePublicProfile: base,
+			Handle:            u.Handle,
 			LinkedInURL:       u.LinkedInURL,
 			University:        u.University,
 			Major:             u.Major,
 			GraduationYear:    u.GraduationYear,
 			Interests:         u.Interests,
 			Bio:               u.Bio,
+			ResumeText:        u.ResumeText,
+		}
+		if viewer.ID == u.ID {
+			privacy := u.Privacy.View()
+			profile.Privacy = &privacy
 		}
+		return profile
 
 	case "company":
 		return CompanyPublicProfile{
@@ -141,6 +193,7 @@ func (u *User) GetPublicProfile() interface{} {
 			CompanyLocation:   u.CompanyLocation,
 			Industry:          u.Industry,
 			Description:       u.Description,
+			IsVerified:        u.IsCompanyVerified,
 		}
 	}
 
@@ -0,0 +1,80 @@
+// Package pubsub fans messages out to every subscriber of a channel, across
+// server instances when backed by a shared broker. Delivery is best effort:
+// subscribers that fall behind or aren't connected miss messages, so anything
+// that matters must also be persisted.
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// Messages buffered per subscriber before new ones are dropped
+const SUBSCRIBER_BUFFER = 32
+
+type PubSub interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	Subscribe(ctx context.Context, channel string) (*Subscription, error)
+}
+
+// Subscription receives the messages published on a channel until closed.
+type Subscription struct {
+	C <-chan []byte
+
+	close_once sync.Once
+	close      func()
+}
+
+func NewSubscription(messages <-chan []byte, close func()) *Subscription {
+	return &Subscription{C: messages, close: close}
+}
+
+// Close stops the subscription, it is safe to call more than once.
+func (s *Subscription) Close() {
+	s.close_once.Do(s.close)
+}
+
+// Memory is a PubSub within a single process, for one instance deployments
+// and development.
+type Memory struct {
+	mu       sync.RWMutex
+	channels map[string]map[chan []byte]struct{}
+}
+
+func NewMemory() *Memory {
+	return &Memory{channels: map[string]map[chan []byte]struct{}{}}
+}
+
+func (m *Memory) Publish(ctx context.Context, channel string, payload []byte) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for subscriber := range m.channels[channel] {
+		select {
+		case subscriber <- payload:
+		default:
+			// The subscriber fell behind, it can catch up from the store
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, channel string) (*Subscription, error) {
+	messages := make(chan []byte, SUBSCRIBER_BUFFER)
+
+	m.mu.Lock()
+	if m.channels[channel] == nil {
+		m.channels[channel] = map[chan []byte]struct{}{}
+	}
+	m.channels[channel][messages] = struct{}{}
+	m.mu.Unlock()
+
+	return NewSubscription(messages, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.channels[channel], messages)
+		if len(m.channels[channel]) == 0 {
+			delete(m.channels, channel)
+		}
+		close(messages)
+	}), nil
+}
@@ -0,0 +1,102 @@
+// Package srs schedules practice reviews with the SM-2 spaced repetition
+// algorithm. Students don't grade themselves here, the recall quality is
+// derived from answer correctness and how long the answer took.
+package srs
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	DEFAULT_EASE = 2.5
+	MIN_EASE     = 1.3
+
+	// Intervals of at least this many days count as well known ("mature")
+	MATURE_INTERVAL_DAYS = 21
+
+	MAX_INTERVAL_DAYS = 365
+)
+
+// Response time thresholds, in seconds, separating effortless from hesitant recall
+const (
+	FAST_ANSWER_SECONDS = 20
+	SLOW_ANSWER_SECONDS = 60
+)
+
+// Card is the scheduling state of one question for one student.
+type Card struct {
+	Ease         float64
+	IntervalDays int32
+	Repetitions  int32
+	Lapses       int32
+	Due          time.Time
+}
+
+// NewCard returns the state of a question that was never reviewed.
+func NewCard() Card {
+	return Card{Ease: DEFAULT_EASE}
+}
+
+// Quality maps an answer to the SM-2 0-5 recall scale. A time of zero means
+// unknown and is treated as an ordinary recall.
+func Quality(correct bool, time_spent_seconds int32) int {
+	if !correct {
+		// Quick wrong answers are usually guesses, slow ones near misses
+		if time_spent_seconds > SLOW_ANSWER_SECONDS {
+			return 2
+		}
+		return 1
+	}
+
+	switch {
+	case time_spent_seconds <= 0:
+		return 4
+	case time_spent_seconds <= FAST_ANSWER_SECONDS:
+		return 5
+	case time_spent_seconds <= SLOW_ANSWER_SECONDS:
+		return 4
+	default:
+		return 3
+	}
+}
+
+// Review applies one review of the given quality at now and returns the new
+// state. Failed recalls (quality < 3) start the card over from a one day
+// interval and count as a lapse.
+func Review(card Card, quality int, now time.Time) Card {
+	quality = max(0, min(5, quality))
+	if card.Ease == 0 {
+		card.Ease = DEFAULT_EASE
+	}
+
+	if quality < 3 {
+		card.Repetitions = 0
+		card.IntervalDays = 1
+		if card.Due != (time.Time{}) {
+			card.Lapses++
+		}
+	} else {
+		switch card.Repetitions {
+		case 0:
+			card.IntervalDays = 1
+		case 1:
+			card.IntervalDays = 6
+		default:
+			card.IntervalDays = int32(math.Round(float64(card.IntervalDays) * card.Ease))
+		}
+		card.Repetitions++
+	}
+	card.IntervalDays = min(card.IntervalDays, MAX_INTERVAL_DAYS)
+
+	q := float64(5 - quality)
+	card.Ease = max(MIN_EASE, card.Ease+0.1-q*(0.08+q*0.02))
+	card.Due = now.AddDate(0, 0, int(card.IntervalDays))
+
+	return card
+}
+
+// IsMature reports whether the card is considered learned.
+func (c Card) IsMature() bool {
+	return c.IntervalDays >= MATURE_INTERVAL_DAYS
+}
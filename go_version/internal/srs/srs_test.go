@@ -0,0 +1,145 @@
+package srs
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestQuality(t *testing.T) {
+	tests := []struct {
+		name    string
+		correct bool
+		seconds int32
+		want    int
+	}{
+		{"fast right", true, 10, 5},
+		{"right at the fast threshold", true, FAST_ANSWER_SECONDS, 5},
+		{"right", true, 45, 4},
+		{"slow right", true, 90, 3},
+		{"right in unknown time", true, 0, 4},
+		{"quick wrong", false, 5, 1},
+		{"slow wrong", false, 90, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Quality(test.correct, test.seconds); got != test.want {
+				t.Errorf("Quality(%v, %d) = %d, want %d", test.correct, test.seconds, got, test.want)
+			}
+		})
+	}
+}
+
+func TestReviewSequences(t *testing.T) {
+	type step struct {
+		quality     int
+		interval    int32
+		ease        float64
+		repetitions int32
+		lapses      int32
+	}
+	tests := []struct {
+		name  string
+		card  Card
+		steps []step
+	}{
+		{
+			name: "perfect recall grows the ease",
+			card: NewCard(),
+			steps: []step{
+				{5, 1, 2.6, 1, 0},
+				{5, 6, 2.7, 2, 0},
+				{5, 16, 2.8, 3, 0},
+				{5, 45, 2.9, 4, 0},
+			},
+		},
+		{
+			name: "good recall keeps the ease",
+			card: NewCard(),
+			steps: []step{
+				{4, 1, 2.5, 1, 0},
+				{4, 6, 2.5, 2, 0},
+				{4, 15, 2.5, 3, 0},
+				{4, 38, 2.5, 4, 0},
+			},
+		},
+		{
+			name: "hard recall lowers the ease",
+			card: NewCard(),
+			steps: []step{
+				{3, 1, 2.36, 1, 0},
+				{3, 6, 2.22, 2, 0},
+				{3, 13, 2.08, 3, 0},
+				{3, 27, 1.94, 4, 0},
+			},
+		},
+		{
+			name: "a lapse starts over",
+			card: NewCard(),
+			steps: []step{
+				{5, 1, 2.6, 1, 0},
+				{5, 6, 2.7, 2, 0},
+				{1, 1, 2.16, 0, 1},
+				{4, 1, 2.16, 1, 1},
+				{4, 6, 2.16, 2, 1},
+			},
+		},
+		{
+			name: "failing a new card isn't a lapse",
+			card: NewCard(),
+			steps: []step{
+				{0, 1, 1.7, 0, 0},
+				{0, 1, MIN_EASE, 0, 1},
+			},
+		},
+		{
+			name: "intervals are capped",
+			card: Card{Ease: 2.5, IntervalDays: 300, Repetitions: 5, Due: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+			steps: []step{
+				{4, MAX_INTERVAL_DAYS, 2.5, 6, 0},
+			},
+		},
+		{
+			name: "a card without ease gets the default",
+			card: Card{},
+			steps: []step{
+				{4, 1, DEFAULT_EASE, 1, 0},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			card := test.card
+			now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+			for i, step := range test.steps {
+				card = Review(card, step.quality, now)
+				if card.IntervalDays != step.interval || math.Abs(card.Ease-step.ease) > 1e-9 ||
+					card.Repetitions != step.repetitions || card.Lapses != step.lapses {
+					t.Fatalf("review %d: got interval %d, ease %v, repetitions %d, lapses %d; want %d, %v, %d, %d",
+						i+1, card.IntervalDays, card.Ease, card.Repetitions, card.Lapses, step.interval, step.ease, step.repetitions, step.lapses)
+				}
+				if want := now.AddDate(0, 0, int(step.interval)); !card.Due.Equal(want) {
+					t.Fatalf("review %d: due %v, want %v", i+1, card.Due, want)
+				}
+				now = card.Due
+			}
+		})
+	}
+}
+
+func TestIsMature(t *testing.T) {
+	tests := []struct {
+		interval int32
+		want     bool
+	}{
+		{0, false},
+		{MATURE_INTERVAL_DAYS - 1, false},
+		{MATURE_INTERVAL_DAYS, true},
+		{MAX_INTERVAL_DAYS, true},
+	}
+	for _, test := range tests {
+		if got := (Card{IntervalDays: test.interval}).IsMature(); got != test.want {
+			t.Errorf("IsMature() with an interval of %d days = %v, want %v", test.interval, got, test.want)
+		}
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"html"
+	"io"
 	"time"
 
+	"go_version/internal/models"
+
 	gomail "gopkg.in/mail.v2"
 )
 
@@ -80,3 +84,203 @@ func GenerateVerificationToken() (string, error) {
 	}
 	return hex.EncodeToString(tokenBytes), nil
 }
+
+// SendEmail sends an html email, errors are reported on error_channel like
+// SendVerificationEmail.
+func SendEmail(error_channel chan<- error, app_name, from, to, subject, html_body, smtp_host, smtp_user, smtp_pass string, smtp_port int) {
+	message := gomail.NewMessage()
+	message.SetHeader("From", "\""+app_name+"\" <"+from+">")
+	message.SetHeader("To", to)
+	message.SetHeader("Subject", subject)
+	message.SetBody("text/html", html_body)
+
+	dialer := gomail.NewDialer(smtp_host, smtp_port, smtp_user, smtp_pass)
+	if err := dialer.DialAndSend(message); err != nil {
+		error_channel <- fmt.Errorf("Error while sending email %q: %w", subject, err)
+	}
+}
+
+// SendCalendarEmail sends an html email with an iCalendar invite, both as an
+// alternative part calendar clients act on and as an .ics attachment.
+func SendCalendarEmail(error_channel chan<- error, app_name, from, to, subject, html_body, method string, invite []byte, smtp_host, smtp_user, smtp_pass string, smtp_port int) {
+	content_type := "text/calendar; charset=UTF-8; method=" + method
+	write_invite := func(w io.Writer) error {
+		_, err := w.Write(invite)
+		return err
+	}
+
+	message := gomail.NewMessage()
+	message.SetHeader("From", "\""+app_name+"\" <"+from+">")
+	message.SetHeader("To", to)
+	message.SetHeader("Subject", subject)
+	message.SetBody("text/html", html_body)
+	message.AddAlternativeWriter(content_type, write_invite)
+	message.Attach("invite.ics", gomail.SetCopyFunc(write_invite), gomail.SetHeader(map[string][]string{"Content-Type": {content_type}}))
+
+	dialer := gomail.NewDialer(smtp_host, smtp_port, smtp_user, smtp_pass)
+	if err := dialer.DialAndSend(message); err != nil {
+		error_channel <- fmt.Errorf("Error while sending email %q: %w", subject, err)
+	}
+}
+
+// FlagOutcomeEmailBody tells a student what happened to the question they reported.
+func FlagOutcomeEmailBody(full_name, question, outcome, note string) string {
+	headline := "Thanks to you, this question has been fixed."
+	if outcome == models.FLAG_STATUS_REJECTED {
+		headline = "We reviewed this question and decided to keep it as it is."
+	}
+
+	note_block := ""
+	if note != "" {
+		note_block = `<p style="color: #555; font-size: 16px;"><strong>Reviewer note:</strong> ` + html.EscapeString(note) + `</p>`
+	}
+
+	return `
+      <!DOCTYPE html>
+      <html>
+        <head>
+          <meta charset="UTF-8">
+          <meta name="viewport" content="width=device-width, initial-scale=1.0">
+        </head>
+        <body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px; background-color: #f5f5f5;">
+          <div style="background: #f9f9f9; padding: 30px; border-radius: 10px;">
+            <h2 style="color: #333; margin-top: 0;">Hi ` + html.EscapeString(full_name) + `,</h2>
+            <p style="color: #555; font-size: 16px;">You reported a problem with this question:</p>
+            <p style="background: #fff; padding: 10px; border-radius: 5px; color: #333; font-size: 14px; border: 1px solid #ddd;">` + html.EscapeString(question) + `</p>
+            <p style="color: #555; font-size: 16px;">` + headline + `</p>
+            ` + note_block + `
+            <p style="color: #555; font-size: 16px;">Best regards,<br>The TalentsPal Team</p>
+          </div>
+          <div style="text-align: center; margin-top: 20px; color: #666; font-size: 12px;">
+            <p style="margin: 5px 0;">© ` + fmt.Sprintf("%d", time.Now().Year()) + ` TalentsPal. All rights reserved.</p>
+            <p style="margin: 5px 0;">This is an automated email. Please do not reply to this message.</p>
+          </div>
+        </body>
+      </html>
+    `
+}
+
+// PasswordChangedEmailBody warns a user their password was changed, in case it wasn't them.
+func PasswordChangedEmailBody(full_name string, changed_at time.Time) string {
+	return `
+      <!DOCTYPE html>
+      <html>
+        <head>
+          <meta charset="UTF-8">
+          <meta name="viewport" content="width=device-width, initial-scale=1.0">
+        </head>
+        <body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px; background-color: #f5f5f5;">
+          <div style="background: #f9f9f9; padding: 30px; border-radius: 10px;">
+            <h2 style="color: #333; margin-top: 0;">Hi ` + html.EscapeString(full_name) + `,</h2>
+            <p style="color: #555; font-size: 16px;">The password of your TalentsPal account was changed on ` + changed_at.UTC().Format("January 2, 2006 at 15:04 UTC") + `.</p>
+            <div style="background: #fff3cd; border-left: 4px solid #ffc107; padding: 15px; margin: 20px 0;">
+              <strong style="color: #856404;">⚠️ Wasn't you?</strong> <span style="color: #856404;">Reset your password right away and contact our support team.</span>
+            </div>
+            <p style="color: #555; font-size: 16px;">Best regards,<br>The TalentsPal Team</p>
+          </div>
+          <div style="text-align: center; margin-top: 20px; color: #666; font-size: 12px;">
+            <p style="margin: 5px 0;">© ` + fmt.Sprintf("%d", time.Now().Year()) + ` TalentsPal. All rights reserved.</p>
+            <p style="margin: 5px 0;">This is an automated email. Please do not reply to this message.</p>
+          </div>
+        </body>
+      </html>
+    `
+}
+
+func ApplicationStatusEmailBody(full_name, job_title, company_name, status_label, applications_url string) string {
+	return `
+      <!DOCTYPE html>
+      <html>
+        <head>
+          <meta charset="UTF-8">
+          <meta name="viewport" content="width=device-width, initial-scale=1.0">
+        </head>
+        <body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px; background-color: #f5f5f5;">
+          <div style="background: #f9f9f9; padding: 30px; border-radius: 10px;">
+            <h2 style="color: #333; margin-top: 0;">Hi ` + html.EscapeString(full_name) + `,</h2>
+            <p style="color: #555; font-size: 16px;">Your application for <strong>` + html.EscapeString(job_title) + `</strong> at ` + html.EscapeString(company_name) + ` was updated.</p>
+            <p style="color: #555; font-size: 16px;">Status: <strong>` + html.EscapeString(status_label) + `</strong></p>
+            <div style="text-align: center; margin: 30px 0;">
+              <a href="` + applications_url + `" style="background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 14px 40px; text-decoration: none; border-radius: 5px; display: inline-block; font-weight: bold; font-size: 16px;">View my applications</a>
+            </div>
+            <p style="color: #555; font-size: 16px;">Best regards,<br>The TalentsPal Team</p>
+          </div>
+          <div style="text-align: center; margin-top: 20px; color: #666; font-size: 12px;">
+            <p style="margin: 5px 0;">© ` + fmt.Sprintf("%d", time.Now().Year()) + ` TalentsPal. All rights reserved.</p>
+            <p style="margin: 5px 0;">This is an automated email. Please do not reply to this message.</p>
+          </div>
+        </body>
+      </html>
+    `
+}
+
+func ContactRequestEmailBody(full_name, company_name, message, requests_url string) string {
+	message_html := ""
+	if message != "" {
+		message_html = `<p style="color: #555; font-size: 16px; border-left: 3px solid #667eea; padding-left: 12px;">` + html.EscapeString(message) + `</p>`
+	}
+	return `
+      <!DOCTYPE html>
+      <html>
+        <head>
+          <meta charset="UTF-8">
+          <meta name="viewport" content="width=device-width, initial-scale=1.0">
+        </head>
+        <body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px; background-color: #f5f5f5;">
+          <div style="background: #f9f9f9; padding: 30px; border-radius: 10px;">
+            <h2 style="color: #333; margin-top: 0;">Hi ` + html.EscapeString(full_name) + `,</h2>
+            <p style="color: #555; font-size: 16px;"><strong>` + html.EscapeString(company_name) + `</strong> would like to contact you.</p>
+            ` + message_html + `
+            <p style="color: #555; font-size: 16px;">Your email and phone number stay hidden unless you approve the request.</p>
+            <div style="text-align: center; margin: 30px 0;">
+              <a href="` + requests_url + `" style="background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 14px 40px; text-decoration: none; border-radius: 5px; display: inline-block; font-weight: bold; font-size: 16px;">Review the request</a>
+            </div>
+            <p style="color: #555; font-size: 16px;">Best regards,<br>The TalentsPal Team</p>
+          </div>
+          <div style="text-align: center; margin-top: 20px; color: #666; font-size: 12px;">
+            <p style="margin: 5px 0;">© ` + fmt.Sprintf("%d", time.Now().Year()) + ` TalentsPal. All rights reserved.</p>
+            <p style="margin: 5px 0;">This is an automated email. Please do not reply to this message.</p>
+          </div>
+        </body>
+      </html>
+    `
+}
+
+// InterviewEmailBody tells a participant an interview was scheduled, moved or
+// cancelled, the invite is attached to the email.
+func InterviewEmailBody(full_name, headline, job_title, company_name, when, location, meeting_url, interviews_url string) string {
+	details := `<p style="color: #555; font-size: 16px;"><strong>When:</strong> ` + html.EscapeString(when) + `</p>`
+	if location != "" {
+		details += `<p style="color: #555; font-size: 16px;"><strong>Where:</strong> ` + html.EscapeString(location) + `</p>`
+	}
+	if meeting_url != "" {
+		details += `<p style="color: #555; font-size: 16px;"><strong>Meeting link:</strong> <a href="` + html.EscapeString(meeting_url) + `">` + html.EscapeString(meeting_url) + `</a></p>`
+	}
+
+	return `
+      <!DOCTYPE html>
+      <html>
+        <head>
+          <meta charset="UTF-8">
+          <meta name="viewport" content="width=device-width, initial-scale=1.0">
+        </head>
+        <body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px; background-color: #f5f5f5;">
+          <div style="background: #f9f9f9; padding: 30px; border-radius: 10px;">
+            <h2 style="color: #333; margin-top: 0;">Hi ` + html.EscapeString(full_name) + `,</h2>
+            <p style="color: #555; font-size: 16px;">` + html.EscapeString(headline) + `</p>
+            <p style="color: #555; font-size: 16px;">Interview for <strong>` + html.EscapeString(job_title) + `</strong> at ` + html.EscapeString(company_name) + `</p>
+            ` + details + `
+            <p style="color: #555; font-size: 16px;">The attached invite adds it to your calendar.</p>
+            <div style="text-align: center; margin: 30px 0;">
+              <a href="` + interviews_url + `" style="background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 14px 40px; text-decoration: none; border-radius: 5px; display: inline-block; font-weight: bold; font-size: 16px;">View my interviews</a>
+            </div>
+            <p style="color: #555; font-size: 16px;">Best regards,<br>The TalentsPal Team</p>
+          </div>
+          <div style="text-align: center; margin-top: 20px; color: #666; font-size: 12px;">
+            <p style="margin: 5px 0;">© ` + fmt.Sprintf("%d", time.Now().Year()) + ` TalentsPal. All rights reserved.</p>
+            <p style="margin: 5px 0;">This is an automated email. Please do not reply to this message.</p>
+          </div>
+        </body>
+      </html>
+    `
+}
@@ -63,6 +63,23 @@ func GenerateRefreshToken() (string, string, error) {
 	return raw_token, hashed_string, nil
 }
 
+// GenerateStreamTicket returns a random ticket for the client and the hash
+// stored in its place, like GenerateRefreshToken.
+func GenerateStreamTicket() (string, string, error) {
+	raw_ticket, err := GenerateVerificationToken()
+	if err != nil {
+		return "", "", errors.New("Error while generating a stream ticket: " + err.Error())
+	}
+
+	return raw_ticket, HashStreamTicket(raw_ticket), nil
+}
+
+// HashStreamTicket returns the hash a ticket is stored and looked up by.
+func HashStreamTicket(raw_ticket string) string {
+	hashed := sha256.Sum256([]byte(raw_ticket))
+	return hex.EncodeToString(hashed[:])
+}
+
 // ParseDurationWithDays parses strings like "30d", "12h", "45m"
 func ParseDurationWithDays(s string) (time.Duration, error) {
 	if strings.HasSuffix(s, "d") {
@@ -0,0 +1,292 @@
+// Package websocket is a small RFC 6455 server side: text messages, ping/pong
+// and close, without extensions or subprotocols. It is meant for browsers
+// sending short JSON messages, everything else is refused.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	OPCODE_CONTINUATION = 0x0
+	OPCODE_TEXT         = 0x1
+	OPCODE_BINARY       = 0x2
+	OPCODE_CLOSE        = 0x8
+	OPCODE_PING         = 0x9
+	OPCODE_PONG         = 0xA
+)
+
+// Close codes
+const (
+	CLOSE_NORMAL           = 1000
+	CLOSE_GOING_AWAY       = 1001
+	CLOSE_PROTOCOL_ERROR   = 1002
+	CLOSE_UNSUPPORTED_DATA = 1003
+	CLOSE_INVALID_PAYLOAD  = 1007
+	CLOSE_POLICY_VIOLATION = 1008
+	CLOSE_MESSAGE_TOO_BIG  = 1009
+)
+
+// Appended to the client's key to prove the server speaks WebSocket
+const ACCEPT_GUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Control frames can't carry more than this
+const MAX_CONTROL_PAYLOAD = 125
+
+// How long a single write may block
+const WRITE_TIMEOUT = 10 * time.Second
+
+// ErrClosed is returned once the peer closed the connection.
+var ErrClosed = errors.New("websocket: connection closed")
+
+// Conn is an upgraded connection. Reads must happen from one goroutine,
+// writes are safe from several.
+type Conn struct {
+	conn             net.Conn
+	reader           *bufio.Reader
+	max_message_size int64
+
+	write_mu   sync.Mutex
+	close_once sync.Once
+}
+
+// CheckHandshake reports why a request isn't a WebSocket handshake this
+// package can accept, so callers can answer it with a plain HTTP error.
+func CheckHandshake(r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return errors.New("a WebSocket handshake must be a GET request")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return errors.New("not a WebSocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return errors.New("unsupported WebSocket version")
+	}
+	key, err := base64.StdEncoding.DecodeString(r.Header.Get("Sec-WebSocket-Key"))
+	if err != nil || len(key) != 16 {
+		return errors.New("invalid Sec-WebSocket-Key")
+	}
+	return nil
+}
+
+// Upgrade completes the handshake and takes over the connection. Messages
+// larger than max_message_size bytes close the connection.
+func Upgrade(w http.ResponseWriter, r *http.Request, max_message_size int64) (*Conn, error) {
+	if err := CheckHandshake(r); err != nil {
+		return nil, err
+	}
+
+	conn, buffered, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: failed to take over the connection: %w", err)
+	}
+
+	// The server's own deadlines don't apply to a hijacked connection
+	conn.SetDeadline(time.Time{})
+	conn.SetWriteDeadline(time.Now().Add(WRITE_TIMEOUT))
+	buffered.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	buffered.WriteString("Upgrade: websocket\r\n")
+	buffered.WriteString("Connection: Upgrade\r\n")
+	buffered.WriteString("Sec-WebSocket-Accept: " + acceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+	if err := buffered.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("websocket: failed to complete the handshake: %w", err)
+	}
+
+	return &Conn{conn: conn, reader: buffered.Reader, max_message_size: max_message_size}, nil
+}
+
+// SetReadDeadline makes ReadMessage fail once t passes.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// ReadMessage returns the next text message. Pings are answered and pongs
+// dropped on the way. A violation of the protocol closes the connection with
+// the matching code.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	in_message := false
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case OPCODE_PING:
+			if err := c.writeFrame(OPCODE_PONG, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case OPCODE_PONG:
+			continue
+		case OPCODE_CLOSE:
+			code := CLOSE_NORMAL
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.Close(code, "")
+			return nil, ErrClosed
+		case OPCODE_BINARY:
+			return nil, c.fail(CLOSE_UNSUPPORTED_DATA, "only text messages are supported")
+		case OPCODE_TEXT:
+			if in_message {
+				return nil, c.fail(CLOSE_PROTOCOL_ERROR, "expected a continuation frame")
+			}
+			in_message = true
+		case OPCODE_CONTINUATION:
+			if !in_message {
+				return nil, c.fail(CLOSE_PROTOCOL_ERROR, "unexpected continuation frame")
+			}
+		default:
+			return nil, c.fail(CLOSE_PROTOCOL_ERROR, "unknown opcode")
+		}
+
+		if int64(len(message)+len(payload)) > c.max_message_size {
+			return nil, c.fail(CLOSE_MESSAGE_TOO_BIG, "message too big")
+		}
+		message = append(message, payload...)
+		if fin {
+			if !utf8.Valid(message) {
+				return nil, c.fail(CLOSE_INVALID_PAYLOAD, "text message isn't valid UTF-8")
+			}
+			return message, nil
+		}
+	}
+}
+
+// WriteText sends data as a single text message.
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(OPCODE_TEXT, data)
+}
+
+// Ping asks the client for a pong, browsers answer on their own. It keeps
+// proxies from dropping quiet connections.
+func (c *Conn) Ping() error {
+	return c.writeFrame(OPCODE_PING, nil)
+}
+
+// Close sends a close frame and closes the connection without waiting for
+// the client's reply, it is safe to call more than once.
+func (c *Conn) Close(code int, reason string) error {
+	var err error
+	c.close_once.Do(func() {
+		payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+		if len(payload)+len(reason) <= MAX_CONTROL_PAYLOAD {
+			payload = append(payload, reason...)
+		}
+		c.writeFrame(OPCODE_CLOSE, payload)
+		err = c.conn.Close()
+	})
+	return err
+}
+
+func (c *Conn) fail(code int, reason string) error {
+	c.Close(code, reason)
+	return errors.New("websocket: " + reason)
+}
+
+// readFrame reads one frame and unmasks its payload. Client frames must be
+// masked and control frames can't be fragmented.
+func (c *Conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CLOSE_PROTOCOL_ERROR, "reserved bits set")
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(CLOSE_PROTOCOL_ERROR, "client frames must be masked")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+
+	if opcode >= OPCODE_CLOSE && (!fin || length > MAX_CONTROL_PAYLOAD) {
+		return false, 0, nil, c.fail(CLOSE_PROTOCOL_ERROR, "invalid control frame")
+	}
+	if length > uint64(c.max_message_size) {
+		return false, 0, nil, c.fail(CLOSE_MESSAGE_TOO_BIG, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// writeFrame sends an unfragmented, unmasked frame as servers do.
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	frame = append(frame, payload...)
+
+	c.write_mu.Lock()
+	defer c.write_mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(WRITE_TIMEOUT))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func acceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + ACCEPT_GUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
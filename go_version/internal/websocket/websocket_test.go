@@ -0,0 +1,429 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clientFrame builds a frame the way browsers send them, masked.
+func clientFrame(fin bool, opcode byte, payload []byte) []byte {
+	frame := unmaskedFrame(fin, opcode, payload)
+	mask := [4]byte{0x37, 0xFA, 0x21, 0x3D}
+	header_length := len(frame) - len(payload)
+	frame[1] |= 0x80
+	masked := slices.Concat(frame[:header_length], mask[:], payload)
+	for i := range payload {
+		masked[header_length+4+i] ^= mask[i%4]
+	}
+	return masked
+}
+
+func unmaskedFrame(fin bool, opcode byte, payload []byte) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	return append(frame, payload...)
+}
+
+func closePayload(code int) []byte {
+	return binary.BigEndian.AppendUint16(nil, uint16(code))
+}
+
+// readServerFrame reads a frame sent by Conn and describes it, e.g. "pong p"
+// or "close 1002".
+func readServerFrame(r io.Reader) (string, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return "", err
+	}
+	if header[0]&0x80 == 0 || header[1]&0x80 != 0 {
+		return "", errors.New("server frames must be final and unmasked")
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return "", err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return "", err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return "", err
+	}
+
+	switch opcode := header[0] & 0x0F; opcode {
+	case OPCODE_TEXT:
+		return "text " + string(payload), nil
+	case OPCODE_PING:
+		return "ping " + string(payload), nil
+	case OPCODE_PONG:
+		return "pong " + string(payload), nil
+	case OPCODE_CLOSE:
+		if len(payload) < 2 {
+			return "", errors.New("close frame without a code")
+		}
+		return fmt.Sprintf("close %d", binary.BigEndian.Uint16(payload)), nil
+	default:
+		return "", fmt.Errorf("unexpected opcode %d", opcode)
+	}
+}
+
+// pipe connects a Conn to a fake client. Everything the Conn sends is read
+// into the returned channel, which is closed with the connection.
+func pipe(t *testing.T, max_message_size int64) (*Conn, net.Conn, <-chan string) {
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+
+	frames := make(chan string, 16)
+	go func() {
+		defer close(frames)
+		reader := bufio.NewReader(client)
+		for {
+			frame, err := readServerFrame(reader)
+			if err != nil {
+				return
+			}
+			frames <- frame
+		}
+	}()
+
+	return &Conn{conn: server, reader: bufio.NewReader(server), max_message_size: max_message_size}, client, frames
+}
+
+func TestReadMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     int64
+		frames  [][]byte
+		want    string
+		err     error
+		replies []string
+	}{
+		{
+			name:   "masked text frame",
+			frames: [][]byte{clientFrame(true, OPCODE_TEXT, []byte("hello"))},
+			want:   "hello",
+		},
+		{
+			name:   "empty text frame",
+			frames: [][]byte{clientFrame(true, OPCODE_TEXT, nil)},
+			want:   "",
+		},
+		{
+			name:   "16 bit length",
+			frames: [][]byte{clientFrame(true, OPCODE_TEXT, []byte(strings.Repeat("a", 300)))},
+			want:   strings.Repeat("a", 300),
+		},
+		{
+			name:   "64 bit length",
+			max:    1 << 17,
+			frames: [][]byte{clientFrame(true, OPCODE_TEXT, []byte(strings.Repeat("a", 70000)))},
+			want:   strings.Repeat("a", 70000),
+		},
+		{
+			name:    "unmasked frame",
+			frames:  [][]byte{unmaskedFrame(true, OPCODE_TEXT, []byte("hello"))},
+			replies: []string{"close 1002"},
+		},
+		{
+			name:    "reserved bits",
+			frames:  [][]byte{append([]byte{0x80 | 0x40 | OPCODE_TEXT}, clientFrame(true, OPCODE_TEXT, []byte("hello"))[1:]...)},
+			replies: []string{"close 1002"},
+		},
+		{
+			name: "fragmented message",
+			frames: [][]byte{
+				clientFrame(false, OPCODE_TEXT, []byte("hel")),
+				clientFrame(false, OPCODE_CONTINUATION, []byte("l")),
+				clientFrame(true, OPCODE_CONTINUATION, []byte("o")),
+			},
+			want: "hello",
+		},
+		{
+			name: "ping between fragments",
+			frames: [][]byte{
+				clientFrame(false, OPCODE_TEXT, []byte("hel")),
+				clientFrame(true, OPCODE_PING, []byte("p")),
+				clientFrame(true, OPCODE_CONTINUATION, []byte("lo")),
+			},
+			want:    "hello",
+			replies: []string{"pong p"},
+		},
+		{
+			name: "pong is dropped",
+			frames: [][]byte{
+				clientFrame(true, OPCODE_PONG, nil),
+				clientFrame(true, OPCODE_TEXT, []byte("hello")),
+			},
+			want: "hello",
+		},
+		{
+			name:    "continuation without a message",
+			frames:  [][]byte{clientFrame(true, OPCODE_CONTINUATION, []byte("lo"))},
+			replies: []string{"close 1002"},
+		},
+		{
+			name: "new message before the last one ended",
+			frames: [][]byte{
+				clientFrame(false, OPCODE_TEXT, []byte("hel")),
+				clientFrame(true, OPCODE_TEXT, []byte("lo")),
+			},
+			replies: []string{"close 1002"},
+		},
+		{
+			name:    "fragmented ping",
+			frames:  [][]byte{clientFrame(false, OPCODE_PING, []byte("p"))},
+			replies: []string{"close 1002"},
+		},
+		{
+			name:    "ping too long",
+			frames:  [][]byte{clientFrame(true, OPCODE_PING, []byte(strings.Repeat("p", MAX_CONTROL_PAYLOAD+1)))},
+			replies: []string{"close 1002"},
+		},
+		{
+			name:    "unknown opcode",
+			frames:  [][]byte{clientFrame(true, 0x3, []byte("hello"))},
+			replies: []string{"close 1002"},
+		},
+		{
+			name:    "oversized frame",
+			max:     8,
+			frames:  [][]byte{clientFrame(true, OPCODE_TEXT, []byte("123456789"))},
+			replies: []string{"close 1009"},
+		},
+		{
+			name: "oversized fragmented message",
+			max:  8,
+			frames: [][]byte{
+				clientFrame(false, OPCODE_TEXT, []byte("12345")),
+				clientFrame(true, OPCODE_CONTINUATION, []byte("6789")),
+			},
+			replies: []string{"close 1009"},
+		},
+		{
+			name:   "message of the maximum size",
+			max:    8,
+			frames: [][]byte{clientFrame(true, OPCODE_TEXT, []byte("12345678"))},
+			want:   "12345678",
+		},
+		{
+			name:    "binary frame",
+			frames:  [][]byte{clientFrame(true, OPCODE_BINARY, []byte{0x00, 0x01})},
+			replies: []string{"close 1003"},
+		},
+		{
+			name:    "invalid UTF-8",
+			frames:  [][]byte{clientFrame(true, OPCODE_TEXT, []byte{0xFF, 0xFE})},
+			replies: []string{"close 1007"},
+		},
+		{
+			name: "UTF-8 split across fragments",
+			frames: [][]byte{
+				clientFrame(false, OPCODE_TEXT, []byte("مر")[:3]),
+				clientFrame(true, OPCODE_CONTINUATION, []byte("مر")[3:]),
+			},
+			want: "مر",
+		},
+		{
+			name:    "close frame",
+			frames:  [][]byte{clientFrame(true, OPCODE_CLOSE, closePayload(CLOSE_GOING_AWAY))},
+			err:     ErrClosed,
+			replies: []string{"close 1001"},
+		},
+		{
+			name:    "close frame without a code",
+			frames:  [][]byte{clientFrame(true, OPCODE_CLOSE, nil)},
+			err:     ErrClosed,
+			replies: []string{"close 1000"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			max := test.max
+			if max == 0 {
+				max = 1024
+			}
+			conn, client, frames := pipe(t, max)
+			go client.Write(slices.Concat(test.frames...))
+
+			message, err := conn.ReadMessage()
+			failed := len(test.replies) > 0 && strings.HasPrefix(test.replies[len(test.replies)-1], "close")
+			switch {
+			case test.err != nil:
+				if !errors.Is(err, test.err) {
+					t.Fatalf("ReadMessage() error = %v, want %v", err, test.err)
+				}
+			case failed:
+				if err == nil {
+					t.Fatalf("ReadMessage() = %q, want an error", message)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("ReadMessage() error = %v", err)
+				}
+				if string(message) != test.want {
+					t.Errorf("ReadMessage() = %q, want %q", message, test.want)
+				}
+				conn.Close(CLOSE_NORMAL, "")
+			}
+
+			var replies []string
+			for frame := range frames {
+				replies = append(replies, frame)
+			}
+			want := test.replies
+			if test.err == nil && !failed {
+				want = append(slices.Clone(want), "close 1000")
+			}
+			if !slices.Equal(replies, want) {
+				t.Errorf("server sent %q, want %q", replies, want)
+			}
+		})
+	}
+}
+
+func TestReadMessageDeadline(t *testing.T) {
+	conn, _, _ := pipe(t, 1024)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	var net_err net.Error
+	if _, err := conn.ReadMessage(); !errors.As(err, &net_err) || !net_err.Timeout() {
+		t.Errorf("ReadMessage() error = %v, want a timeout", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	conn, _, frames := pipe(t, 1024)
+	long := strings.Repeat("a", 70000)
+
+	go func() {
+		conn.WriteText([]byte("hello"))
+		conn.WriteText([]byte(long))
+		conn.Ping()
+		conn.Close(CLOSE_POLICY_VIOLATION, "policy")
+		conn.Close(CLOSE_NORMAL, "")
+	}()
+
+	var sent []string
+	for frame := range frames {
+		sent = append(sent, frame)
+	}
+	want := []string{"text hello", "text " + long, "ping ", "close 1008"}
+	if !slices.Equal(sent, want) {
+		t.Errorf("server sent %d frames, want %d: %.40q", len(sent), len(want), sent)
+	}
+}
+
+func TestCheckHandshake(t *testing.T) {
+	handshake := func(change func(r *http.Request)) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		r.Header.Set("Connection", "keep-alive, Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Sec-WebSocket-Version", "13")
+		r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if change != nil {
+			change(r)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		ok      bool
+	}{
+		{"valid", handshake(nil), true},
+		{"case of the tokens", handshake(func(r *http.Request) { r.Header.Set("Upgrade", "WebSocket") }), true},
+		{"POST", handshake(func(r *http.Request) { r.Method = http.MethodPost }), false},
+		{"no upgrade", handshake(func(r *http.Request) { r.Header.Del("Upgrade") }), false},
+		{"no connection upgrade", handshake(func(r *http.Request) { r.Header.Set("Connection", "keep-alive") }), false},
+		{"old version", handshake(func(r *http.Request) { r.Header.Set("Sec-WebSocket-Version", "8") }), false},
+		{"key not base64", handshake(func(r *http.Request) { r.Header.Set("Sec-WebSocket-Key", "not a key") }), false},
+		{"short key", handshake(func(r *http.Request) { r.Header.Set("Sec-WebSocket-Key", "c2hvcnQ=") }), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CheckHandshake(test.request); (err == nil) != test.ok {
+				t.Errorf("CheckHandshake() error = %v, want ok %v", err, test.ok)
+			}
+		})
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, 1024)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close(CLOSE_NORMAL, "")
+		message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.WriteText(message)
+	}))
+	defer server.Close()
+
+	client, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The example handshake from RFC 6455
+	fmt.Fprint(client, "GET /ws HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	reader := bufio.NewReader(client)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want %d", response.StatusCode, http.StatusSwitchingProtocols)
+	}
+	if accept := response.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q, want %q", accept, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+	}
+
+	client.Write(clientFrame(true, OPCODE_TEXT, []byte("echo")))
+	for _, want := range []string{"text echo", "close 1000"} {
+		if frame, err := readServerFrame(reader); frame != want {
+			t.Errorf("server sent %q (%v), want %q", frame, err, want)
+		}
+	}
+}